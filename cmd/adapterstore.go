@@ -0,0 +1,565 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// adapterStore is the set-adapters file: adapter lists for reuse, and
+// documentation for the adapters themselves.
+//
+//	adapters:
+//	  billing:
+//	    description: Invoices through the new billing service
+//	    owners: ["@org/payments"]
+//	    default: "0"
+//	    tags: [payments]
+//	lists:
+//	  - adapters: [billing, search]
+//	  - repo: org/svc
+//	    env: prod
+//	    adapters: [billing]
+type adapterStore struct {
+	Adapters map[string]adapterMeta `yaml:"adapters,omitempty"`
+	Lists    []adapterSet           `yaml:"lists,omitempty"`
+}
+
+// adapterMeta documents an adapter.
+type adapterMeta struct {
+	Description string   `yaml:"description,omitempty"`
+	Owners      []string `yaml:"owners,omitempty"`
+	Default     string   `yaml:"default,omitempty"`
+	Tags        []string `yaml:"tags,omitempty"`
+}
+
+func (m adapterMeta) empty() bool {
+	return m.Description == "" && len(m.Owners) == 0 && m.Default == "" && len(m.Tags) == 0
+}
+
+// merge returns m with the fields set in o replaced.
+func (m adapterMeta) merge(o adapterMeta) adapterMeta {
+	if o.Description != "" {
+		m.Description = o.Description
+	}
+	if o.Owners != nil {
+		m.Owners = o.Owners
+	}
+	if o.Default != "" {
+		m.Default = o.Default
+	}
+	if o.Tags != nil {
+		m.Tags = o.Tags
+	}
+	return m
+}
+
+// adapterScope is what a stored adapter list applies to: a repository, an
+// environment, both, or everything (the zero value).
+type adapterScope struct {
+	Repo string `yaml:"repo,omitempty"`
+	Env  string `yaml:"env,omitempty"`
+}
+
+func (s adapterScope) String() string {
+	switch {
+	case s.Repo == "" && s.Env == "":
+		return "all repos"
+	case s.Env == "":
+		return s.Repo
+	case s.Repo == "":
+		return "env " + s.Env
+	}
+	return s.Repo + " env " + s.Env
+}
+
+func (s adapterScope) matches(o adapterScope) bool {
+	return strings.EqualFold(s.Repo, o.Repo) && s.Env == o.Env
+}
+
+// adapterSet is one stored adapter list.
+type adapterSet struct {
+	Scope    adapterScope `yaml:",inline"`
+	Adapters []string     `yaml:"adapters"`
+}
+
+func getAdapterConfigPath() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "adapters.yaml"), nil
+}
+
+// legacyAdapterPath is the plain list older versions stored; it is read
+// until the first write, which replaces it with adapters.yaml.
+func legacyAdapterPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "adapters.txt")
+}
+
+// loadAdapterStore reads the stored adapters, from adapters.txt if no
+// adapters.yaml has been written yet. Nothing stored is not an error.
+func loadAdapterStore() (adapterStore, error) {
+	var store adapterStore
+	configPath, err := getAdapterConfigPath()
+	if err != nil {
+		return store, err
+	}
+	content, err := os.ReadFile(configPath) // #nosec G304 - configPath is controlled
+	if errors.Is(err, os.ErrNotExist) {
+		legacy := legacyAdapterPath(configPath)
+		content, err := os.ReadFile(legacy) // #nosec G304 - path is under the config dir
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		if err != nil {
+			return store, err
+		}
+		if store.Lists, err = parseAdapterSets(string(content)); err != nil {
+			return store, fmt.Errorf("%s: %w", legacy, err)
+		}
+		return store, nil
+	}
+	if err != nil {
+		return store, err
+	}
+	return parseAdapterStore(content, configPath)
+}
+
+// parseAdapterStore reads an adapters.yaml read from name.
+func parseAdapterStore(content []byte, name string) (adapterStore, error) {
+	var store adapterStore
+	// JSON is valid YAML, so the file may be written in either.
+	if err := yaml.Unmarshal(content, &store); err != nil {
+		return store, fmt.Errorf("%s: %w", name, err)
+	}
+	for _, set := range store.Lists {
+		if set.Scope.Repo == "*" || strings.ContainsAny(set.Scope.Repo+set.Scope.Env, " ") {
+			return store, fmt.Errorf("%s: invalid list scope %q %q", name, set.Scope.Repo, set.Scope.Env)
+		}
+		for _, a := range set.Adapters {
+			if strings.TrimSpace(a) == "" {
+				return store, fmt.Errorf("%s: empty adapter name in the list for %s", name, set.Scope)
+			}
+		}
+	}
+	return store, nil
+}
+
+func encodeAdapterStore(store adapterStore) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("# Written by gh aca set-adapters; edit freely.\n")
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(store); err != nil {
+		return nil, fmt.Errorf("encode adapters: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// saveAdapterStore writes store to adapters.yaml.
+func saveAdapterStore(store adapterStore) (string, error) {
+	b, err := encodeAdapterStore(store)
+	if err != nil {
+		return "", err
+	}
+	return writeAdapterFile(b)
+}
+
+// writeAdapterFile replaces adapters.yaml with content and removes a
+// legacy adapters.txt, whose lists content now holds.
+func writeAdapterFile(content []byte) (string, error) {
+	configPath, err := getAdapterConfigPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(configPath, content, 0600); err != nil {
+		return "", fmt.Errorf("failed to write adapter file: %w", err)
+	}
+	legacy := legacyAdapterPath(configPath)
+	if err := os.Remove(legacy); err == nil {
+		infof("Moved the stored adapters from %s to %s", legacy, configPath)
+	}
+	return configPath, nil
+}
+
+// parseAdapterSets reads the legacy adapters.txt: one adapter per line,
+// with lists for a repo and/or environment under "[ORG/REPO]",
+// "[ORG/REPO ENV]" or "[* ENV]" headers. Lines before the first header
+// are the global list.
+func parseAdapterSets(content string) ([]adapterSet, error) {
+	// Handle both Unix (\n) and Windows (\r\n) line endings
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	sets := []adapterSet{{}}
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			f := strings.Fields(line[1 : len(line)-1])
+			if len(f) == 0 || len(f) > 2 || (len(f) == 1 && f[0] == "*") {
+				return nil, fmt.Errorf("line %d: invalid section %s", i+1, line)
+			}
+			var scope adapterScope
+			if f[0] != "*" {
+				scope.Repo = f[0]
+			}
+			if len(f) == 2 {
+				scope.Env = f[1]
+			}
+			sets = append(sets, adapterSet{Scope: scope})
+			continue
+		}
+		last := &sets[len(sets)-1]
+		last.Adapters = append(last.Adapters, line)
+	}
+	if len(sets[0].Adapters) == 0 {
+		sets = sets[1:]
+	}
+	return sets, nil
+}
+
+// storedAdaptersFor returns the most specific list stored for repo and
+// env: for both, then for the repo, then for the environment, then the
+// global list.
+func storedAdaptersFor(sets []adapterSet, repo, env string) (adapterSet, bool) {
+	for _, scope := range []adapterScope{{repo, env}, {repo, ""}, {"", env}, {}} {
+		for _, set := range sets {
+			if set.Scope.matches(scope) {
+				return set, true
+			}
+		}
+	}
+	return adapterSet{}, false
+}
+
+// validAdapterList splits and checks the --adapters value of set-adapters.
+func validAdapterList(adapters string) ([]string, error) {
+	adapterList := splitCSV(adapters, nil)
+	if len(adapterList) == 0 {
+		return nil, usageErrorf("no valid adapters provided")
+	}
+	validAdapters := make([]string, 0, len(adapterList))
+	for _, adapter := range adapterList {
+		trimmed := strings.TrimSpace(adapter)
+		if trimmed == "" {
+			return nil, fmt.Errorf("empty adapter name not allowed: %q", adapter)
+		}
+		validAdapters = append(validAdapters, trimmed)
+	}
+	return validAdapters, nil
+}
+
+// editAdapterList returns list without remove and with add appended, in
+// the order given; adapters already listed aren't added again. dedupe also
+// drops repeats within list, keeping the first.
+func editAdapterList(list, add, remove []string, dedupe bool) []string {
+	drop := map[string]bool{}
+	for _, a := range remove {
+		drop[a] = true
+	}
+	seen := map[string]bool{}
+	out := make([]string, 0, len(list)+len(add))
+	for _, a := range list {
+		if drop[a] || (dedupe && seen[a]) {
+			continue
+		}
+		seen[a] = true
+		out = append(out, a)
+	}
+	for _, a := range add {
+		if !drop[a] && !seen[a] {
+			seen[a] = true
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func storeAdapters(adapters string, scope adapterScope, dedupe bool) error {
+	validAdapters, err := validAdapterList(adapters)
+	if err != nil {
+		return err
+	}
+	return updateAdapterList(scope, func([]string) []string {
+		return editAdapterList(validAdapters, nil, nil, dedupe)
+	})
+}
+
+// seedAdapters returns the keys of env's parameters file under root, in
+// file order, keeping only those matching one of filters when given.
+func seedAdapters(root, env, paramFile string, filters []string) ([]string, error) {
+	values, err := readEnvValues(root, env, paramFile, nil)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, v := range values {
+		if len(filters) == 0 || matchesAny(filters, v.Adapter) {
+			keys = append(keys, v.Adapter)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys in env/%s/%s match %s", env, paramFile, strings.Join(filters, ","))
+	}
+	return keys, nil
+}
+
+// seedStoredAdapters stores the keys of env's parameters file in repo
+// as the list for scope.
+func seedStoredAdapters(repo, env, paramFile string, filters []string, scope adapterScope) error {
+	if env == "" || isEnvPattern(env) {
+		return usageErrorf("--from-repo needs --env with the environment to read keys from (e.g., dev)")
+	}
+	if err := checkParamFile(paramFile); err != nil {
+		return err
+	}
+	for _, f := range filters {
+		if _, err := path.Match(f, ""); err != nil {
+			return usageErrorf("invalid --filter %q: %w", f, err)
+		}
+	}
+	tmpDir, cleanup, err := cloneOrDownload(repo, "")
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if _, err := resolveEnvs(tmpDir, env); err != nil {
+		return err
+	}
+	keys, err := seedAdapters(tmpDir, env, paramFile, filters)
+	if err != nil {
+		return err
+	}
+	return updateAdapterList(scope, func([]string) []string {
+		return editAdapterList(keys, nil, nil, true)
+	})
+}
+
+// editStoredAdapters adds and removes adapters in the list stored for
+// exactly scope, creating it if needed.
+func editStoredAdapters(scope adapterScope, add, remove string, dedupe bool) error {
+	var toAdd, toRemove []string
+	var err error
+	if add != "" {
+		if toAdd, err = validAdapterList(add); err != nil {
+			return err
+		}
+	}
+	if remove != "" {
+		if toRemove, err = validAdapterList(remove); err != nil {
+			return err
+		}
+	}
+	return updateAdapterList(scope, func(list []string) []string {
+		for _, a := range toRemove {
+			if !slices.Contains(list, a) {
+				warnf("%s is not in the list for %s", a, scope)
+			}
+		}
+		return editAdapterList(list, toAdd, toRemove, dedupe)
+	})
+}
+
+// updateAdapterList replaces the list stored for scope with edit's result
+// and prints it; an empty result removes the list.
+func updateAdapterList(scope adapterScope, edit func(list []string) []string) error {
+	store, err := loadAdapterStore()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(store.Lists, func(set adapterSet) bool { return set.Scope.matches(scope) })
+	var list []string
+	if i >= 0 {
+		list = store.Lists[i].Adapters
+	}
+	list = edit(list)
+	switch {
+	case len(list) == 0 && i >= 0:
+		store.Lists = slices.Delete(store.Lists, i, i+1)
+	case len(list) == 0:
+		fmt.Fprintf(stdout(), "No adapters stored for %s\n", scope)
+		return nil
+	case i >= 0:
+		store.Lists[i].Adapters = list
+	default:
+		store.Lists = append(store.Lists, adapterSet{Scope: scope, Adapters: list})
+	}
+	configPath, err := saveAdapterStore(store)
+	if err != nil {
+		return err
+	}
+
+	if len(list) == 0 {
+		fmt.Fprintf(stdout(), "Removed the now empty list for %s from %s\n", scope, configPath)
+		return nil
+	}
+	fmt.Fprintf(stdout(), "Stored %d adapter(s) for %s in %s:\n", len(list), scope, configPath)
+	for _, adapter := range list {
+		fmt.Fprintf(stdout(), "  - %s\n", adapter)
+	}
+	return nil
+}
+
+// describeAdapters merges meta into the documentation of each adapter.
+func describeAdapters(adapters string, meta adapterMeta) error {
+	validAdapters, err := validAdapterList(adapters)
+	if err != nil {
+		return err
+	}
+	store, err := loadAdapterStore()
+	if err != nil {
+		return err
+	}
+	if store.Adapters == nil {
+		store.Adapters = map[string]adapterMeta{}
+	}
+	for _, a := range validAdapters {
+		store.Adapters[a] = store.Adapters[a].merge(meta)
+	}
+	configPath, err := saveAdapterStore(store)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout(), "Updated %d adapter description(s) in %s\n", len(validAdapters), configPath)
+	return nil
+}
+
+// listStoredAdapters prints the stored lists with each adapter's
+// documentation, or with a scope the list flip-adapters would use for it.
+func listStoredAdapters(scope adapterScope) error {
+	configPath, err := getAdapterConfigPath()
+	if err != nil {
+		return err
+	}
+	store, err := loadAdapterStore()
+	if err != nil {
+		return err
+	}
+	if len(store.Lists) == 0 && len(store.Adapters) == 0 {
+		fmt.Fprintf(stdout(), "No adapters stored yet. Use 'gh aca set-adapters --adapters adapter1,adapter2' to store adapters.\n")
+		return nil
+	}
+
+	sets := store.Lists
+	if scope != (adapterScope{}) {
+		set, ok := storedAdaptersFor(sets, scope.Repo, scope.Env)
+		if !ok {
+			fmt.Fprintf(stdout(), "No adapters stored for %s in %s\n", scope, configPath)
+			return nil
+		}
+		sets = []adapterSet{set}
+	}
+	fmt.Fprintf(stdout(), "Stored adapters (%s):\n", configPath)
+	listed := map[string]bool{}
+	for _, set := range sets {
+		fmt.Fprintf(stdout(), "\n%s:\n", set.Scope)
+		printAdapterMeta(set.Adapters, store.Adapters)
+		for _, a := range set.Adapters {
+			listed[a] = true
+		}
+	}
+	if scope == (adapterScope{}) {
+		var other []string
+		for a := range store.Adapters {
+			if !listed[a] {
+				other = append(other, a)
+			}
+		}
+		if len(other) > 0 {
+			sort.Strings(other)
+			fmt.Fprintf(stdout(), "\nDocumented, not in a list:\n")
+			printAdapterMeta(other, store.Adapters)
+		}
+	}
+	return nil
+}
+
+func printAdapterMeta(adapters []string, meta map[string]adapterMeta) {
+	documented := false
+	for _, a := range adapters {
+		documented = documented || !meta[a].empty()
+	}
+	if !documented {
+		for _, a := range adapters {
+			fmt.Fprintf(stdout(), "  - %s\n", a)
+		}
+		return
+	}
+	w := newTable()
+	w.AddRow("Adapter", "Default", "Owners", "Tags", "Description")
+	for _, a := range adapters {
+		m := meta[a]
+		w.AddRow(a, m.Default, strings.Join(m.Owners, ","), strings.Join(m.Tags, ","), m.Description)
+	}
+	w.Render()
+}
+
+// clearStoredAdapters removes the list for scope, or with the zero scope
+// every stored list. Adapter documentation is kept.
+func clearStoredAdapters(scope adapterScope) error {
+	configPath, err := getAdapterConfigPath()
+	if err != nil {
+		return err
+	}
+	store, err := loadAdapterStore()
+	if err != nil {
+		return err
+	}
+	kept := make([]adapterSet, 0, len(store.Lists))
+	for _, set := range store.Lists {
+		if scope != (adapterScope{}) && !set.Scope.matches(scope) {
+			kept = append(kept, set)
+		}
+	}
+	if len(kept) == len(store.Lists) && scope != (adapterScope{}) {
+		fmt.Fprintf(stdout(), "No adapters stored for %s in %s\n", scope, configPath)
+		return nil
+	}
+	store.Lists = kept
+	if len(store.Lists) == 0 && len(store.Adapters) == 0 {
+		for _, p := range []string{configPath, legacyAdapterPath(configPath)} {
+			if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to clear adapters file: %w", err)
+			}
+		}
+	} else if _, err := saveAdapterStore(store); err != nil {
+		return err
+	}
+	if scope == (adapterScope{}) {
+		fmt.Fprintf(stdout(), "Cleared stored adapters from %s\n", configPath)
+	} else {
+		fmt.Fprintf(stdout(), "Cleared stored adapters for %s from %s\n", scope, configPath)
+	}
+	return nil
+}
+
+// adaptersOrStored returns the --adapters list, or when the flag is empty
+// the adapters set-adapters stored for repo and env (see
+// storedAdaptersFor).
+func adaptersOrStored(adaptersCSV, repo, env string) ([]string, error) {
+	if adaptersCSV != "" {
+		return splitCSV(adaptersCSV, nil), nil
+	}
+	store, err := loadAdapterStore()
+	if err != nil {
+		return nil, err
+	}
+	set, ok := storedAdaptersFor(store.Lists, repo, env)
+	if !ok || len(set.Adapters) == 0 {
+		return nil, usageErrorf("--adapters is required (comma list) or run 'gh aca set-adapters' to store adapters first")
+	}
+	if set.Scope != (adapterScope{}) {
+		infof("Using adapters stored for %s: %s", set.Scope, strings.Join(set.Adapters, ","))
+	}
+	return set.Adapters, nil
+}
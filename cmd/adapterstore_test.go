@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAdapterSets(t *testing.T) {
+	content := "billing\r\nsearch\r\n# comment\n\n[org/svc]\ncrm\n\n[org/svc prod]\npayments\n\n[* prod]\naudit\n"
+	sets, err := parseAdapterSets(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sets) != 4 {
+		t.Fatalf("parseAdapterSets = %+v", sets)
+	}
+	tests := []struct {
+		repo, env, want string
+	}{
+		{"org/svc", "prod", "payments"},
+		{"ORG/Svc", "dev", "crm"},
+		{"org/other", "prod", "audit"},
+		{"org/other", "dev", "billing,search"},
+		{"", "", "billing,search"},
+	}
+	for _, tt := range tests {
+		set, ok := storedAdaptersFor(sets, tt.repo, tt.env)
+		if got := strings.Join(set.Adapters, ","); !ok || got != tt.want {
+			t.Errorf("storedAdaptersFor(%q, %q) = %s, %v; want %s", tt.repo, tt.env, got, ok, tt.want)
+		}
+	}
+
+	if sets, _ := parseAdapterSets("[org/svc]\ncrm\n"); len(sets) != 1 {
+		t.Errorf("a file without a global list parsed as %+v", sets)
+	}
+	if _, ok := storedAdaptersFor(nil, "org/svc", "prod"); ok {
+		t.Error("storedAdaptersFor found a list in no sets")
+	}
+	for _, bad := range []string{"[*]\nx\n", "[]\n", "[a b c]\n"} {
+		if _, err := parseAdapterSets(bad); err == nil {
+			t.Errorf("parseAdapterSets(%q) should fail", bad)
+		}
+	}
+}
+
+func TestStoreAdaptersScoped(t *testing.T) {
+	t.Setenv("ACA_CONFIG_DIR", t.TempDir())
+	var out bytes.Buffer
+	resultOutput = &out
+	defer func() { resultOutput = nil }()
+
+	if err := storeAdapters("billing,search", adapterScope{}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := storeAdapters("crm", adapterScope{Repo: "org/svc", Env: "prod"}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := storeAdapters("payments", adapterScope{Repo: "org/svc", Env: "prod"}, false); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := adaptersOrStored("", "org/svc", "prod"); err != nil || strings.Join(got, ",") != "payments" {
+		t.Errorf("adaptersOrStored(org/svc, prod) = %v, %v", got, err)
+	}
+	if err := clearStoredAdapters(adapterScope{Repo: "org/svc", Env: "prod"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := adaptersOrStored("", "org/svc", "prod"); err != nil || strings.Join(got, ",") != "billing,search" {
+		t.Errorf("after clearing the scoped list: %v, %v", got, err)
+	}
+	if err := clearStoredAdapters(adapterScope{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := adaptersOrStored("", "org/svc", "prod"); err == nil {
+		t.Error("adaptersOrStored should fail with nothing stored")
+	}
+}
+
+func TestAdapterStoreMigration(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ACA_CONFIG_DIR", dir)
+	var out bytes.Buffer
+	resultOutput = &out
+	defer func() { resultOutput = nil }()
+
+	if err := os.WriteFile(filepath.Join(dir, "adapters.txt"), []byte("billing\nsearch\n\n[org/svc]\ncrm\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := adaptersOrStored("", "org/svc", ""); err != nil || strings.Join(got, ",") != "crm" {
+		t.Errorf("adaptersOrStored from adapters.txt = %v, %v", got, err)
+	}
+
+	owners := []string{"@org/payments"}
+	if err := describeAdapters("billing", adapterMeta{Description: "New billing", Owners: owners, Default: "0"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := describeAdapters("billing", adapterMeta{Tags: []string{"payments"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "adapters.txt")); !os.IsNotExist(err) {
+		t.Errorf("adapters.txt was not replaced: %v", err)
+	}
+	store, err := loadAdapterStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.Lists) != 2 || strings.Join(store.Lists[0].Adapters, ",") != "billing,search" {
+		t.Errorf("lists after migration = %+v", store.Lists)
+	}
+	m := store.Adapters["billing"]
+	if m.Description != "New billing" || m.Default != "0" || strings.Join(m.Owners, ",") != "@org/payments" || strings.Join(m.Tags, ",") != "payments" {
+		t.Errorf("billing = %+v", m)
+	}
+
+	out.Reset()
+	if err := listStoredAdapters(adapterScope{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"all repos:", "New billing", "@org/payments", "org/svc:", "  - crm"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("--list output lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	if err := clearStoredAdapters(adapterScope{}); err != nil {
+		t.Fatal(err)
+	}
+	if store, err := loadAdapterStore(); err != nil || len(store.Lists) != 0 || store.Adapters["billing"].Description == "" {
+		t.Errorf("after --clear: %+v, %v; want the descriptions kept", store, err)
+	}
+}
+
+func TestEditAdapterList(t *testing.T) {
+	tests := []struct {
+		list, add, remove []string
+		dedupe            bool
+		want              string
+	}{
+		{[]string{"a", "b"}, []string{"c", "a", "c"}, nil, false, "a,b,c"},
+		{[]string{"a", "b", "c"}, nil, []string{"b", "x"}, false, "a,c"},
+		{[]string{"b", "a", "b"}, nil, nil, false, "b,a,b"},
+		{[]string{"b", "a", "b"}, nil, nil, true, "b,a"},
+		{nil, []string{"a"}, []string{"a"}, false, ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(editAdapterList(tt.list, tt.add, tt.remove, tt.dedupe), ","); got != tt.want {
+			t.Errorf("editAdapterList(%v, +%v, -%v, %v) = %s, want %s", tt.list, tt.add, tt.remove, tt.dedupe, got, tt.want)
+		}
+	}
+}
+
+func TestEditStoredAdapters(t *testing.T) {
+	t.Setenv("ACA_CONFIG_DIR", t.TempDir())
+	var out bytes.Buffer
+	resultOutput = &out
+	defer func() { resultOutput = nil }()
+
+	scope := adapterScope{Repo: "org/svc"}
+	stored := func() string {
+		store, err := loadAdapterStore()
+		if err != nil {
+			t.Fatal(err)
+		}
+		set, _ := storedAdaptersFor(store.Lists, "org/svc", "")
+		return strings.Join(set.Adapters, ",")
+	}
+	if err := editStoredAdapters(scope, "billing,search", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := editStoredAdapters(scope, "crm", "search", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := stored(); got != "billing,crm" {
+		t.Errorf("after add/remove: %s", got)
+	}
+	if err := storeAdapters("crm,billing,crm", scope, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := editStoredAdapters(scope, "", "", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := stored(); got != "crm,billing" {
+		t.Errorf("after --dedupe: %s", got)
+	}
+	if err := editStoredAdapters(scope, "", "crm,billing", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := stored(); got != "" {
+		t.Errorf("after removing everything: %s", got)
+	}
+}
+
+func TestSeedAdapters(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "env", "dev")
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	content := "# adapters\nadapter.billing=1\nadapter.search=0\ndb.host=10.0.0.5\nadapter.crm=on\n"
+	if err := os.WriteFile(filepath.Join(dir, "parameters.properties"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		filters []string
+		want    string
+	}{
+		{nil, "adapter.billing,adapter.search,db.host,adapter.crm"},
+		{[]string{"adapter.*"}, "adapter.billing,adapter.search,adapter.crm"},
+		{[]string{"db.*", "*.crm"}, "db.host,adapter.crm"},
+		{[]string{"cache.*"}, "error"},
+	}
+	for _, tt := range tests {
+		keys, err := seedAdapters(root, "dev", "parameters.properties", tt.filters)
+		got := strings.Join(keys, ",")
+		if err != nil {
+			got = "error"
+		}
+		if got != tt.want {
+			t.Errorf("seedAdapters(%v) = %s, want %s", tt.filters, got, tt.want)
+		}
+	}
+}
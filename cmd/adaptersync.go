@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// defaultRemoteFile is the shared adapters file name when the remote
+// doesn't give one.
+const defaultRemoteFile = "adapters.yaml"
+
+// adapterRemote is where set-adapters --push and --pull keep the team's
+// shared adapters file: a file in a repository's default branch
+// (ORG/REPO[:PATH]) or in a gist (gist:ID[/FILE]).
+type adapterRemote struct {
+	Repo, Gist string
+	File       string
+}
+
+func parseAdapterRemote(s string) (adapterRemote, error) {
+	if id, ok := strings.CutPrefix(s, "gist:"); ok {
+		id, file, _ := strings.Cut(id, "/")
+		if id == "" || strings.ContainsAny(file, "/\\") {
+			return adapterRemote{}, usageErrorf("invalid remote %q: want gist:ID or gist:ID/FILE", s)
+		}
+		if file == "" {
+			file = defaultRemoteFile
+		}
+		return adapterRemote{Gist: id, File: file}, nil
+	}
+	repo, file, _ := strings.Cut(s, ":")
+	if parts := strings.Split(repo, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return adapterRemote{}, usageErrorf("invalid remote %q: want ORG/REPO[:PATH] or gist:ID[/FILE]", s)
+	}
+	if file == "" {
+		file = defaultRemoteFile
+	}
+	if file = path.Clean(strings.TrimPrefix(file, "/")); strings.HasPrefix(file, "..") {
+		return adapterRemote{}, usageErrorf("invalid remote %q: path leaves the repository", s)
+	}
+	return adapterRemote{Repo: repo, File: file}, nil
+}
+
+func (r adapterRemote) String() string {
+	if r.Gist != "" {
+		return "gist:" + r.Gist + "/" + r.File
+	}
+	return r.Repo + ":" + r.File
+}
+
+// isNotFound reports whether err is a 404 from gh api.
+func isNotFound(err error) bool {
+	return httpStatus(err) == http.StatusNotFound
+}
+
+// errNoRemoteFile is returned by read when the remote has no adapters
+// file yet.
+var errNoRemoteFile = errors.New("no shared adapters file")
+
+// read returns the remote file and, for repository files, the blob SHA an
+// update must name.
+func (r adapterRemote) read() ([]byte, string, error) {
+	if r.Gist != "" {
+		var gist struct {
+			Files map[string]struct {
+				Content   string `json:"content"`
+				Truncated bool   `json:"truncated"`
+			} `json:"files"`
+		}
+		if err := ghAPI("GET", "gists/"+r.Gist, nil, &gist); err != nil {
+			return nil, "", err
+		}
+		f, ok := gist.Files[r.File]
+		if !ok {
+			return nil, "", errNoRemoteFile
+		}
+		if f.Truncated {
+			return nil, "", fmt.Errorf("%s is too large to read from the gist API", r)
+		}
+		return []byte(f.Content), "", nil
+	}
+	var file struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+		SHA      string `json:"sha"`
+	}
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/contents/%s", r.Repo, escapePath(r.File)), nil, &file); err != nil {
+		if isNotFound(err) {
+			return nil, "", errNoRemoteFile
+		}
+		return nil, "", err
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return nil, "", fmt.Errorf("decode %s: %w", r, err)
+	}
+	return b, file.SHA, nil
+}
+
+// write replaces the remote file with content; sha is the blob read
+// returned, empty to create the file.
+func (r adapterRemote) write(content []byte, sha string) error {
+	if r.Gist != "" {
+		body := map[string]any{"files": map[string]any{r.File: map[string]string{"content": string(content)}}}
+		return ghAPI("PATCH", "gists/"+r.Gist, body, nil)
+	}
+	body := map[string]string{
+		"message": "chore: update shared adapters from gh aca set-adapters --push",
+		"content": base64.StdEncoding.EncodeToString(content),
+	}
+	if sha != "" {
+		body["sha"] = sha
+	}
+	return ghAPI("PUT", fmt.Sprintf("repos/%s/contents/%s", r.Repo, escapePath(r.File)), body, nil)
+}
+
+// resolveAdapterRemote returns --remote, or adapters.remote from the global
+// config.
+func resolveAdapterRemote(flag string) (adapterRemote, error) {
+	if flag == "" {
+		flag, _ = loadConfig("")["adapters.remote"].Value.(string)
+	}
+	if flag == "" {
+		return adapterRemote{}, usageErrorf("no shared adapters location: pass --remote ORG/REPO[:PATH] or gist:ID, or set adapters.remote in the config")
+	}
+	return parseAdapterRemote(flag)
+}
+
+// pushAdapters uploads the local adapters file to r.
+func pushAdapters(r adapterRemote) error {
+	store, err := loadAdapterStore()
+	if err != nil {
+		return err
+	}
+	if len(store.Lists) == 0 && len(store.Adapters) == 0 {
+		return fmt.Errorf("no adapters stored to push")
+	}
+	// Push the file as written, comments and all; a legacy adapters.txt is
+	// converted first.
+	configPath, err := getAdapterConfigPath()
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(configPath) // #nosec G304 - configPath is controlled
+	if errors.Is(err, os.ErrNotExist) {
+		content, err = encodeAdapterStore(store)
+	}
+	if err != nil {
+		return err
+	}
+
+	remote, sha, err := r.read()
+	if err != nil && !errors.Is(err, errNoRemoteFile) {
+		return err
+	}
+	if bytes.Equal(remote, content) {
+		fmt.Fprintf(stdout(), "%s is already up to date\n", r)
+		return nil
+	}
+	if err := r.write(content, sha); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout(), "Pushed %d list(s) and %d adapter description(s) to %s\n", len(store.Lists), len(store.Adapters), r)
+	return nil
+}
+
+// pullAdapters replaces the local adapters file with r's, keeping the
+// previous one as adapters.yaml.bak.
+func pullAdapters(r adapterRemote) error {
+	content, _, err := r.read()
+	if errors.Is(err, errNoRemoteFile) {
+		return fmt.Errorf("%s does not exist yet; push it first with --push", r)
+	}
+	if err != nil {
+		return err
+	}
+	store, err := parseAdapterStore(content, r.String())
+	if err != nil {
+		return err
+	}
+	configPath, err := getAdapterConfigPath()
+	if err != nil {
+		return err
+	}
+	if old, err := os.ReadFile(configPath); err == nil { // #nosec G304 - configPath is controlled
+		if bytes.Equal(old, content) {
+			fmt.Fprintf(stdout(), "%s is already up to date with %s\n", configPath, r)
+			return nil
+		}
+		if err := os.WriteFile(configPath+".bak", old, 0600); err != nil {
+			return fmt.Errorf("back up %s: %w", configPath, err)
+		}
+	}
+	if _, err := writeAdapterFile(content); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout(), "Pulled %d list(s) and %d adapter description(s) from %s into %s\n", len(store.Lists), len(store.Adapters), r, configPath)
+	return nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseAdapterRemote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"org/team", "org/team:adapters.yaml"},
+		{"org/team:/config/aca.yaml", "org/team:config/aca.yaml"},
+		{"gist:abc123", "gist:abc123/adapters.yaml"},
+		{"gist:abc123/team.yaml", "gist:abc123/team.yaml"},
+		{"gist:", "error"},
+		{"gist:abc/a/b", "error"},
+		{"team", "error"},
+		{"org/team:../x", "error"},
+	}
+	for _, tt := range tests {
+		got := "error"
+		if r, err := parseAdapterRemote(tt.in); err == nil {
+			got = r.String()
+		}
+		if got != tt.want {
+			t.Errorf("parseAdapterRemote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+// fakeGistGH serves gist abc with a shared adapters file and a repository
+// without one, logging writes.
+func fakeGistGH(t *testing.T) string {
+	t.Helper()
+	return fakeGitHub(t, func(_ *http.Request, call string) (int, string) {
+		switch call {
+		case "GET gists/abc":
+			return 200, `{"files":{"adapters.yaml":{"content":"lists:\n  - adapters: [billing, crm]\n"}}}`
+		case "PATCH gists/abc", "PUT repos/org/team/contents/adapters.yaml":
+			return 200, `{}`
+		}
+		return 404, ""
+	})
+}
+
+func TestPushPullAdapters(t *testing.T) {
+	log := fakeGistGH(t)
+	dir := t.TempDir()
+	t.Setenv("ACA_CONFIG_DIR", dir)
+	var out bytes.Buffer
+	resultOutput = &out
+	defer func() { resultOutput = nil }()
+
+	gist, _ := parseAdapterRemote("gist:abc")
+	if err := storeAdapters("search", adapterScope{}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := pullAdapters(gist); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := adaptersOrStored("", "org/svc", "dev"); err != nil || strings.Join(got, ",") != "billing,crm" {
+		t.Errorf("after --pull: %v, %v", got, err)
+	}
+	bak, err := os.ReadFile(filepath.Join(dir, "adapters.yaml.bak"))
+	if err != nil || !strings.Contains(string(bak), "search") {
+		t.Errorf("backup = %q, %v", bak, err)
+	}
+
+	if err := pushAdapters(gist); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "already up to date") {
+		t.Errorf("pushing the pulled file should be a no-op:\n%s", out.String())
+	}
+	repo, _ := parseAdapterRemote("org/team")
+	if err := pushAdapters(repo); err != nil {
+		t.Fatal(err)
+	}
+	calls, _ := os.ReadFile(log)
+	if !strings.Contains(string(calls), "PUT repos/org/team/contents/adapters.yaml\n{") || strings.Contains(string(calls), `"sha"`) {
+		t.Errorf("calls:\n%s", calls)
+	}
+}
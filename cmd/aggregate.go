@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// uniqueRow is one distinct IP/port value pair with every file it occurs in.
+type uniqueRow struct {
+	IPValue   string   `json:"ipValue"`
+	PortValue string   `json:"portValue"`
+	Count     int      `json:"count"`
+	Files     []string `json:"files"`
+}
+
+// collapseUnique merges rows with identical IP and port values, keeping the
+// order in which each value pair was first seen.
+func collapseUnique(rows []matchRow) []uniqueRow {
+	type valueKey struct{ ip, port string }
+	index := map[valueKey]int{}
+	seenFile := map[valueKey]map[string]bool{}
+	var out []uniqueRow
+
+	for _, r := range rows {
+		k := valueKey{r.IPValue, r.PortValue}
+		i, ok := index[k]
+		if !ok {
+			i = len(out)
+			index[k] = i
+			seenFile[k] = map[string]bool{}
+			out = append(out, uniqueRow{IPValue: r.IPValue, PortValue: r.PortValue, Files: []string{}})
+		}
+		out[i].Count++
+		if !seenFile[k][r.location()] {
+			seenFile[k][r.location()] = true
+			out[i].Files = append(out[i].Files, r.location())
+		}
+	}
+	return out
+}
+
+func printUniqueRows(rows []uniqueRow, mode outputMode) error {
+	switch mode {
+	case outCSV:
+		fmt.Fprintln(stdout(), "IP Value,Port Value,Count,Files")
+		for _, r := range rows {
+			fmt.Fprintf(stdout(), "%s,%s,%d,%s\n", csvEsc(r.IPValue), csvEsc(r.PortValue), r.Count, csvEsc(strings.Join(r.Files, ";")))
+		}
+	case outTable:
+		w := newTable()
+		w.AddRow("IP Value", "Port Value", "Count", "Files")
+		for _, r := range rows {
+			w.AddRow(r.IPValue, r.PortValue, fmt.Sprintf("%d", r.Count), strings.Join(r.Files, ", "))
+		}
+		w.Render()
+	case outJSON:
+		enc := json.NewEncoder(stdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+	return nil
+}
+
+// groupRow aggregates findings sharing one IP, port, file or key.
+type groupRow struct {
+	Group   string   `json:"group"`
+	Count   int      `json:"count"`
+	Members []string `json:"members"`
+}
+
+var groupByHeaders = map[string]string{
+	"ip":   "IP Value",
+	"port": "Port Value",
+	"file": "File Path",
+	"key":  "Key",
+}
+
+func parseGroupBy(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return "", nil
+	}
+	if _, ok := groupByHeaders[s]; !ok {
+		return "", usageErrorf("invalid --group-by %q: expected ip, port, file or key", s)
+	}
+	return s, nil
+}
+
+// groupRows aggregates rows by the given dimension. Grouping by ip, port or
+// key lists the locations (or, for keys, the values) involved; grouping by
+// file lists the values found in it. Groups keep first-seen order.
+func groupRows(rows []matchRow, by string) []groupRow {
+	index := map[string]int{}
+	seen := map[string]map[string]bool{}
+	var out []groupRow
+
+	add := func(group, member string) {
+		if group == "" {
+			return
+		}
+		i, ok := index[group]
+		if !ok {
+			i = len(out)
+			index[group] = i
+			seen[group] = map[string]bool{}
+			out = append(out, groupRow{Group: group, Members: []string{}})
+		}
+		out[i].Count++
+		if member != "" && !seen[group][member] {
+			seen[group][member] = true
+			out[i].Members = append(out[i].Members, member)
+		}
+	}
+
+	for _, r := range rows {
+		location := fmt.Sprintf("%s:%d", r.location(), r.LineNumber)
+		switch by {
+		case "ip":
+			add(r.IPValue, location)
+		case "port":
+			add(r.PortValue, location)
+		case "file":
+			add(r.location(), rowValue(r))
+		case "key":
+			add(r.IPKey, r.IPValue)
+			if r.PortKey != r.IPKey {
+				add(r.PortKey, r.PortValue)
+			}
+		}
+	}
+	return out
+}
+
+// rowValue renders a row's IP and port as a single endpoint-like string.
+func rowValue(r matchRow) string {
+	switch {
+	case r.IPValue != "" && r.PortValue != "":
+		if strings.Contains(r.IPValue, ":") {
+			return fmt.Sprintf("[%s]:%s", r.IPValue, r.PortValue)
+		}
+		return r.IPValue + ":" + r.PortValue
+	case r.IPValue != "":
+		return r.IPValue
+	default:
+		return r.PortValue
+	}
+}
+
+func printGroups(groups []groupRow, by string, mode outputMode) error {
+	header := groupByHeaders[by]
+	switch mode {
+	case outCSV:
+		fmt.Fprintf(stdout(), "%s,Count,Members\n", header)
+		for _, g := range groups {
+			fmt.Fprintf(stdout(), "%s,%d,%s\n", csvEsc(g.Group), g.Count, csvEsc(strings.Join(g.Members, ";")))
+		}
+	case outTable:
+		w := newTable()
+		w.AddRow(header, "Count", "Members")
+		for _, g := range groups {
+			w.AddRow(g.Group, fmt.Sprintf("%d", g.Count), strings.Join(g.Members, ", "))
+		}
+		w.Render()
+	case outJSON:
+		enc := json.NewEncoder(stdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(groups)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollapseUnique(t *testing.T) {
+	rows := []matchRow{
+		{IPKey: "gw", IPValue: "10.5.0.12", RelPath: "a.properties", LineNumber: 1},
+		{IPKey: "db.host", IPValue: "10.0.0.5", PortKey: "db.port", PortValue: "5432", RelPath: "a.properties", LineNumber: 2},
+		{IPKey: "gateway", IPValue: "10.5.0.12", RelPath: "b.yml", LineNumber: 7},
+		{IPKey: "gw", IPValue: "10.5.0.12", RelPath: "a.properties", LineNumber: 9},
+		{IPKey: "db.host", IPValue: "10.0.0.5", RelPath: "c.env", LineNumber: 3},
+	}
+
+	want := []uniqueRow{
+		{IPValue: "10.5.0.12", Count: 3, Files: []string{"a.properties", "b.yml"}},
+		{IPValue: "10.0.0.5", PortValue: "5432", Count: 1, Files: []string{"a.properties"}},
+		{IPValue: "10.0.0.5", Count: 1, Files: []string{"c.env"}},
+	}
+
+	got := collapseUnique(rows)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collapseUnique() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestGroupRows(t *testing.T) {
+	rows := []matchRow{
+		{IPKey: "gw", IPValue: "10.5.0.12", RelPath: "a.properties", LineNumber: 1},
+		{IPKey: "db.host", IPValue: "10.0.0.5", PortKey: "db.port", PortValue: "5432", RelPath: "a.properties", LineNumber: 2},
+		{IPKey: "gateway", IPValue: "10.5.0.12", RelPath: "b.yml", LineNumber: 7},
+		{PortKey: "server.port", PortValue: "5432", RelPath: "b.yml", LineNumber: 8},
+	}
+
+	tests := []struct {
+		by   string
+		want []groupRow
+	}{
+		{"ip", []groupRow{
+			{Group: "10.5.0.12", Count: 2, Members: []string{"a.properties:1", "b.yml:7"}},
+			{Group: "10.0.0.5", Count: 1, Members: []string{"a.properties:2"}},
+		}},
+		{"port", []groupRow{
+			{Group: "5432", Count: 2, Members: []string{"a.properties:2", "b.yml:8"}},
+		}},
+		{"file", []groupRow{
+			{Group: "a.properties", Count: 2, Members: []string{"10.5.0.12", "10.0.0.5:5432"}},
+			{Group: "b.yml", Count: 2, Members: []string{"10.5.0.12", "5432"}},
+		}},
+		{"key", []groupRow{
+			{Group: "gw", Count: 1, Members: []string{"10.5.0.12"}},
+			{Group: "db.host", Count: 1, Members: []string{"10.0.0.5"}},
+			{Group: "db.port", Count: 1, Members: []string{"5432"}},
+			{Group: "gateway", Count: 1, Members: []string{"10.5.0.12"}},
+			{Group: "server.port", Count: 1, Members: []string{"5432"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			got := groupRows(rows, tt.by)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groupRows(%q) =\n%+v\nwant\n%+v", tt.by, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGroupBy(t *testing.T) {
+	for _, valid := range []string{"", "ip", "PORT", " file ", "key"} {
+		if _, err := parseGroupBy(valid); err != nil {
+			t.Errorf("parseGroupBy(%q) unexpected error: %v", valid, err)
+		}
+	}
+	if _, err := parseGroupBy("branch"); err == nil {
+		t.Error("Expected error for unsupported group-by")
+	}
+}
+
+func TestCollapseUnique_Branches(t *testing.T) {
+	rows := []matchRow{
+		{IPValue: "10.0.0.1", RelPath: "app.env", LineNumber: 1, Branch: "main"},
+		{IPValue: "10.0.0.1", RelPath: "app.env", LineNumber: 1, Branch: "dev"},
+	}
+	got := collapseUnique(rows)
+	want := []uniqueRow{{IPValue: "10.0.0.1", Count: 2, Files: []string{"main:app.env", "dev:app.env"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collapseUnique() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// inventoryEntry is one approved IP/port pair of an --allowlist file. An
+// empty or "*" field matches any value; the IP may be a CIDR.
+type inventoryEntry struct {
+	IP   string
+	Port string
+	Line int
+
+	prefix netip.Prefix
+	seen   bool
+}
+
+func (e *inventoryEntry) String() string {
+	ip, port := e.IP, e.Port
+	if ip == "" {
+		ip = "*"
+	}
+	if port == "" {
+		return ip
+	}
+	return ip + ":" + port
+}
+
+func (e *inventoryEntry) matches(r matchRow) bool {
+	if e.prefix.IsValid() {
+		addr, ok := rowAddr(r)
+		if !ok || !e.prefix.Contains(addr) {
+			return false
+		}
+	} else if r.IPValue != "" {
+		return false // a port-only entry doesn't approve an IP
+	}
+	if e.Port != "" && stripQuotes(r.PortValue) != e.Port {
+		return false
+	}
+	return true
+}
+
+// inventory is an approved-inventory file, e.g. an IPAM export.
+type inventory struct {
+	file    string
+	entries []*inventoryEntry
+}
+
+// parseInventory reads "ip,port" CSV records. A header row starting with
+// "ip" and lines starting with # are skipped; extra columns are ignored.
+func parseInventory(r io.Reader) ([]*inventoryEntry, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	var out []*inventoryEntry
+	for first := true; ; first = false {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		if first && strings.EqualFold(strings.TrimSpace(rec[0]), "ip") {
+			continue
+		}
+		e := &inventoryEntry{IP: strings.TrimSpace(rec[0]), Line: line}
+		if len(rec) > 1 {
+			e.Port = strings.TrimSpace(rec[1])
+		}
+		if e.IP == "*" {
+			e.IP = ""
+		}
+		if e.Port == "*" {
+			e.Port = ""
+		}
+		if e.IP == "" && e.Port == "" {
+			continue
+		}
+		if e.IP != "" {
+			p, err := parsePolicyCIDR(e.IP)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			e.prefix = p
+		}
+		if e.Port != "" {
+			if _, err := parsePort(e.Port); err != nil {
+				return nil, fmt.Errorf("line %d: invalid port %q: %w", line, e.Port, err)
+			}
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func loadInventory(file string) (*inventory, error) {
+	if file == "" {
+		return nil, nil
+	}
+	fh, err := os.Open(file) // #nosec G304 - user-supplied inventory file
+	if err != nil {
+		return nil, fmt.Errorf("failed to open allowlist: %w", err)
+	}
+	defer fh.Close()
+	entries, err := parseInventory(fh)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return &inventory{file: file, entries: entries}, nil
+}
+
+// filter drops approved findings and marks the entries that approved them
+// as seen. A nil inventory keeps every row.
+func (inv *inventory) filter(rows []matchRow) []matchRow {
+	if inv == nil {
+		return rows
+	}
+	out := rows[:0]
+	for _, r := range rows {
+		approved := false
+		for _, e := range inv.entries {
+			if e.matches(r) {
+				e.seen, approved = true, true
+			}
+		}
+		if !approved {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// unseen returns the entries no finding matched.
+func (inv *inventory) unseen() []*inventoryEntry {
+	var out []*inventoryEntry
+	for _, e := range inv.entries {
+		if !e.seen {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// warnUnseen reports inventory entries that no longer appear in the scan,
+// which usually means the inventory is out of date.
+func (inv *inventory) warnUnseen() {
+	for _, e := range inv.unseen() {
+		warnf("%s:%d: inventory entry %s was not found in the scan", inv.file, e.Line, e)
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInventoryFilter(t *testing.T) {
+	entries, err := parseInventory(strings.NewReader(`ip,port,owner
+# approved by netops
+10.0.0.5,5432,db team
+10.1.0.0/16,*
+*,8080
+192.168.9.9,443
+`))
+	if err != nil {
+		t.Fatalf("parseInventory failed: %v", err)
+	}
+	if len(entries) != 4 || entries[0].Line != 3 || entries[2].IP != "" {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+
+	inv := &inventory{file: "inv.csv", entries: entries}
+	rows := []matchRow{
+		{IPValue: "10.0.0.5", PortValue: "5432"},   // approved pair
+		{IPValue: "10.0.0.5", PortValue: "5433"},   // wrong port
+		{IPValue: "10.1.2.3", PortValue: "\"80\""}, // approved range
+		{PortValue: "8080"},                        // approved port
+		{IPValue: "10.9.9.9", PortValue: "8080"},   // port-only entries don't approve IPs
+	}
+	got := inv.filter(rows)
+	if len(got) != 2 || got[0].PortValue != "5433" || got[1].IPValue != "10.9.9.9" {
+		t.Errorf("Unexpected unapproved rows: %+v", got)
+	}
+	if unseen := inv.unseen(); len(unseen) != 1 || unseen[0].String() != "192.168.9.9:443" {
+		t.Errorf("Unexpected unseen entries: %v", unseen)
+	}
+
+	var nilInv *inventory
+	if got := nilInv.filter(rows[:1]); len(got) != 1 {
+		t.Errorf("Expected nil inventory to keep rows, got %+v", got)
+	}
+	for _, bad := range []string{"bogus,80", "10.0.0.1,99999"} {
+		if _, err := parseInventory(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
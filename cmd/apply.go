@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/greenstevester/gh-aca-utils/pkg/jsonptr"
+	"github.com/greenstevester/gh-aca-utils/pkg/props"
+	"github.com/spf13/cobra"
+)
+
+// loadAdapterState reads a document in the export-adapters JSON format.
+func loadAdapterState(file string) (adapterExport, error) {
+	var x adapterExport
+	b, err := os.ReadFile(file) // #nosec G304 - user-supplied state file
+	if err != nil {
+		return x, fmt.Errorf("read state file: %w", err)
+	}
+	if err := json.Unmarshal(b, &x); err != nil {
+		return x, fmt.Errorf("%s: %w", file, err)
+	}
+	if x.File == "" {
+		x.File = "parameters.properties"
+	}
+	seen := map[string]bool{}
+	for _, e := range x.Environments {
+		if e.Env == "" || isEnvPattern(e.Env) {
+			return x, fmt.Errorf("%s: invalid environment %q", file, e.Env)
+		}
+		if seen[e.Env] {
+			return x, fmt.Errorf("%s: environment %q is listed twice", file, e.Env)
+		}
+		seen[e.Env] = true
+	}
+	return x, nil
+}
+
+// planState plans setting the parameters file rel, with content b, to the
+// values in state. Adapters not in the file are added with create and
+// reported as not found otherwise; adapters not in state are left alone.
+func planState(b []byte, rel string, state map[string]string, create bool) ([]change, []byte, error) {
+	want := make([]string, 0, len(state))
+	for a := range state {
+		want = append(want, a)
+	}
+	sort.Strings(want)
+	next := func(adapter, v string) (string, bool) {
+		s := state[adapter]
+		return s, s != v
+	}
+
+	var has func(adapter string) bool
+	var lines []string
+	if isJSONParams(rel) {
+		has = func(a string) bool {
+			_, err := jsonptr.Find(b, adapterPointer(a))
+			return err == nil || !jsonCreatable(b, adapterPointer(a))
+		}
+	} else {
+		lines = props.SplitLinesEOL(b)
+		index := indexKeys(lines)
+		has = func(a string) bool {
+			_, ok := index[a]
+			return ok
+		}
+	}
+	var creates []change
+	if create {
+		var missing []string
+		want, missing = splitMissing(want, has)
+		for _, a := range missing {
+			creates = append(creates, planCreates([]string{a}, state[a], rel)...)
+		}
+	}
+
+	if isJSONParams(rel) {
+		changes, err := planJSONValues(b, want, rel, next)
+		if err != nil {
+			return nil, nil, err
+		}
+		changes = append(changes, creates...)
+		return changes, applyJSONFlips(b, changes), nil
+	}
+	changes := append(planValues(lines, indexKeys(lines), want, rel, next), creates...)
+	return changes, []byte(strings.Join(applyFlips(append([]string(nil), lines...), changes), "")), nil
+}
+
+func cmdApplyAdapters() *cobra.Command {
+	var repo, from, branch, mode string
+	var create, doCommit, doPR, dryRun, yes bool
+
+	cmd := &cobra.Command{
+		Use:   "apply-adapters",
+		Short: "Set adapter values from a state document in the export-adapters format",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if from == "" {
+				return usageErrorf("--from FILE is required")
+			}
+			state, err := loadAdapterState(from)
+			if err != nil {
+				return err
+			}
+			if repo == "" {
+				repo = state.Repo
+			}
+			if repo == "" {
+				return usageErrorf("--repo ORG/REPO is required when the state file names no repo")
+			}
+			if err := checkParamFile(state.File); err != nil {
+				return err
+			}
+			modeVal := parseMode(mode, outTable)
+			doCommit = doCommit || doPR
+			var access pushAccess
+			if doCommit && !dryRun {
+				if access, err = preflight(repo, false); err != nil {
+					return err
+				}
+			}
+
+			tmpDir, cleanup, err := cloneOrDownload(repo, state.Ref)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			var changes []change
+			var edits []lineEdit
+			var rels, envs []string
+			after := map[string][]byte{}
+			for _, e := range state.Environments {
+				if _, err := resolveEnvs(tmpDir, e.Env); err != nil {
+					return err
+				}
+				rel := path.Join("env", e.Env, state.File)
+				b, err := os.ReadFile(filepath.Join(tmpDir, filepath.FromSlash(rel))) // #nosec G304 - env and file names are validated above
+				if err != nil {
+					return fmt.Errorf("read %s: %w", rel, err)
+				}
+				envChanges, content, err := planState(b, rel, e.Adapters, create)
+				if err != nil {
+					return err
+				}
+				if len(envChanges) == 0 {
+					continue
+				}
+				changes = append(changes, envChanges...)
+				edits = append(edits, fileEdits(rel, string(b), string(content))...)
+				rels = append(rels, rel)
+				envs = append(envs, e.Env)
+				after[rel] = content
+			}
+			cfg := loadConfig(tmpDir)
+			if err := checkConstraints(cfg, changes); err != nil {
+				return err
+			}
+			if modeVal == outJSON {
+				if err := printChangeReport(changes, outJSON); err != nil {
+					return err
+				}
+			} else if err := printRewrites(edits, outTable); err != nil {
+				return err
+			}
+			if dryRun || len(changes) == 0 {
+				if len(changes) == 0 {
+					infof("%s already matches %s.", repo, from)
+				}
+				return nil
+			}
+
+			patterns, _ := cfg["protect.environments"].Value.([]string)
+			typeName, _ := cfg["protect.typeName"].Value.(bool)
+			if err := confirmProtected(repo, protectedEnvs(envs, patterns), typeName, yes, newPrompter()); err != nil {
+				return err
+			}
+			approved, err := requireApproval(repo, protectedEnvs(envs, patterns), cfg)
+			if err != nil {
+				return err
+			}
+			for _, rel := range rels {
+				if err := os.WriteFile(filepath.Join(tmpDir, filepath.FromSlash(rel)), after[rel], 0600); err != nil {
+					return fmt.Errorf("write %s: %w", rel, err)
+				}
+			}
+			if !doCommit {
+				return nil
+			}
+			if branch == "" {
+				branch = "apply/adapters-" + branchSlug(strings.TrimSuffix(filepath.Base(from), filepath.Ext(from)))
+			}
+			msg := fmt.Sprintf("chore(env:%s): apply adapter state from %s", strings.Join(envs, ","), filepath.Base(from))
+			title := fmt.Sprintf("Apply adapter state to %s", strings.Join(envs, ", "))
+			body := fmt.Sprintf("Automated via gh aca-utils apply-adapters.\n\nSets %d adapter value(s) in %d environment(s) to match `%s`.", len(changes), len(envs), filepath.Base(from))
+			if err := access.prepare(tmpDir); err != nil {
+				return err
+			}
+			prURL, err := commitRewrites(tmpDir, branch, rels, msg, title, body, doPR)
+			if err != nil {
+				return err
+			}
+
+			entry := journalEntry{Command: "apply", Repo: repo, File: state.File, Branch: branch, PRURL: prURL, Approver: approved.By, ApprovalRun: approved.Run}
+			for _, c := range changes {
+				entry.Changes = append(entry.Changes, journalChange{Env: path.Base(path.Dir(c.FilePath)), Adapter: c.Adapter, Old: c.OldValue, New: c.NewValue, Created: c.Created})
+			}
+			recordJournal(entry)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "State document, as written by export-adapters --output json (required)")
+	cmd.Flags().StringVar(&repo, "repo", "", "Target repo as ORG/REPO (default: the repo named in the state file)")
+	cmd.Flags().BoolVar(&create, "create", false, "Add adapters that are in the state file but not in the parameters file")
+	cmd.Flags().StringVar(&branch, "branch", "", "Branch name to create (default: apply/adapters-<state file name>)")
+	cmd.Flags().BoolVar(&doCommit, "commit", false, "Commit the change to a new branch and push")
+	cmd.Flags().BoolVar(&doPR, "pr", false, "Create a pull request (implies --commit)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", true, "Show the diff without writing")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Don't ask before changing protected environments")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table (diff)|json")
+	setOutputModes(cmd, "table", "json")
+	cmd.Flags().StringVar(&approvalRun, "approval-run", "", "Workflow run whose approved deployment review approves protected environments (protect.approval: environment)")
+	return cmd
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanState(t *testing.T) {
+	tests := []struct {
+		name, rel, in string
+		state         map[string]string
+		create        bool
+		want          string
+		changes       int
+	}{
+		{"properties", "env/dev/parameters.properties", "billing=0\nsearch = 1 # on\ncrm=off\n",
+			map[string]string{"billing": "1", "search": "1", "new": "x"}, false,
+			"billing=1\nsearch = 1 # on\ncrm=off\n", 1},
+		{"properties create", "env/dev/parameters.properties", "billing=0\n",
+			map[string]string{"billing": "0", "new": "x"}, true,
+			"billing=0\nnew=x\n", 1},
+		{"json", "env/dev/appsettings.json", `{"Features": {"Billing": false, "Mode": "a"}}`,
+			map[string]string{"/Features/Billing": "true", "/Features/Mode": "b", "/Features/New": "1"}, true,
+			`{"Features": {"Billing": true, "Mode": "b", "New": 1}}`, 3},
+	}
+	for _, tt := range tests {
+		changes, got, err := planState([]byte(tt.in), tt.rel, tt.state, tt.create)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want || len(changes) != tt.changes {
+			t.Errorf("%s: planState = %d change(s), %q; want %d, %q", tt.name, len(changes), got, tt.changes, tt.want)
+		}
+	}
+}
+
+func TestLoadAdapterState(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"ok.json":     `{"repo": "org/svc", "environments": [{"env": "dev", "adapters": {"billing": "1"}}]}`,
+		"glob.json":   `{"environments": [{"env": "prod*", "adapters": {}}]}`,
+		"twice.json":  `{"environments": [{"env": "dev"}, {"env": "dev"}]}`,
+		"broken.json": `{"environments": [`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	x, err := loadAdapterState(filepath.Join(dir, "ok.json"))
+	if err != nil || x.Repo != "org/svc" || x.File != "parameters.properties" || x.Environments[0].Adapters["billing"] != "1" {
+		t.Errorf("loadAdapterState(ok) = %+v, %v", x, err)
+	}
+	for _, name := range []string{"glob.json", "twice.json", "broken.json", "missing.json"} {
+		if _, err := loadAdapterState(filepath.Join(dir, name)); err == nil {
+			t.Errorf("loadAdapterState(%s) should fail", name)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Approval modes for protect.approval: how a change to a protected
+// environment gets its second person before anything is committed.
+const (
+	approvalNone        = "none"
+	approvalToken       = "token"       // a second user's token in ACA_APPROVER_TOKEN
+	approvalEnvironment = "environment" // an approved deployment review on a workflow run
+)
+
+// approverTokenEnv holds the approving user's token in token mode. It is
+// read from the environment only, so it never shows in shell history.
+const approverTokenEnv = "ACA_APPROVER_TOKEN"
+
+// writePermissions are the repository roles that may approve a change.
+var writePermissions = map[string]bool{"admin": true, "maintain": true, "write": true}
+
+// approvalRun is the approval-run flag shared by the commands that write
+// to protected environments.
+var approvalRun string
+
+// approvalMaxAge is how old the workflow run of an environment approval
+// may be. An approval is asked for a change about to be made, not for
+// whatever change comes later.
+const approvalMaxAge = 24 * time.Hour
+
+// approval is who approved a change and, for environment approvals, the
+// workflow run their approval is on.
+type approval struct {
+	By  string
+	Run string
+}
+
+// approvedRuns are the repo/run pairs that approved changes in this
+// process; a run may approve every stage of one rollout.
+var approvedRuns = map[string]bool{}
+
+// requireApproval enforces protect.approval for a change to the protected
+// environments of repo and returns who approved it, or the zero approval
+// when none is required. The approver must be someone other than the
+// authenticated user, with write access to repo.
+func requireApproval(repo string, protected []string, cfg map[string]configValue) (approval, error) {
+	mode, _ := cfg["protect.approval"].Value.(string)
+	if len(protected) == 0 || mode == "" || mode == approvalNone {
+		return approval{}, nil
+	}
+	me := ghLogin()
+	if me == "" {
+		return approval{}, fmt.Errorf("approval: can't tell which user is running; check `gh auth status`")
+	}
+	list := strings.Join(protected, ", ")
+	switch mode {
+	case approvalToken:
+		token := os.Getenv(approverTokenEnv)
+		if token == "" {
+			return approval{}, fmt.Errorf("%s in %s needs a second approver (protect.approval: token): have them set %s to their token", list, repo, approverTokenEnv)
+		}
+		approver, err := tokenLogin(token)
+		if err != nil {
+			return approval{}, fmt.Errorf("approval: %s: %w", approverTokenEnv, err)
+		}
+		if strings.EqualFold(approver, me) {
+			return approval{}, fmt.Errorf("approval: %s belongs to %s, who is running the change; the approver must be someone else", approverTokenEnv, me)
+		}
+		if err := checkCanApprove(repo, approver); err != nil {
+			return approval{}, err
+		}
+		infof("Change to %s in %s approved by %s", list, repo, approver)
+		return approval{By: approver}, nil
+	case approvalEnvironment:
+		if approvalRun == "" {
+			return approval{}, fmt.Errorf("%s in %s needs an environment approval (protect.approval: environment): pass --approval-run with the ID of a workflow run whose deployment review was approved", list, repo)
+		}
+		if err := checkRunUnused(repo, approvalRun, time.Now()); err != nil {
+			return approval{}, err
+		}
+		envs := protected
+		if name, _ := cfg["protect.approvalEnvironment"].Value.(string); name != "" {
+			envs = []string{name}
+		}
+		approvers, err := runApprovers(repo, approvalRun, envs, me)
+		if err != nil {
+			return approval{}, err
+		}
+		approvedRuns[repo+"/"+approvalRun] = true
+		infof("Change to %s in %s approved by %s in run %s", list, repo, approvers, approvalRun)
+		return approval{By: approvers, Run: approvalRun}, nil
+	}
+	return approval{}, fmt.Errorf("approval: unknown protect.approval %q", mode)
+}
+
+// checkRunUnused fails if workflow run of repo is older than
+// approvalMaxAge at now, or already approved a change recorded in the
+// journal: each approval is good for one change.
+func checkRunUnused(repo, run string, now time.Time) error {
+	if approvedRuns[repo+"/"+run] {
+		return nil
+	}
+	var info struct {
+		CreatedAt time.Time `json:"created_at"`
+	}
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/actions/runs/%s", repo, run), nil, &info); err != nil {
+		return fmt.Errorf("approval: read run %s: %w", run, err)
+	}
+	if age := now.Sub(info.CreatedAt); age > approvalMaxAge {
+		return fmt.Errorf("approval: run %s started %s ago; approvals are good for %s, so ask for a new one", run, age.Round(time.Minute), approvalMaxAge)
+	}
+	path, err := journalPath()
+	if err != nil {
+		return err
+	}
+	entries, err := readJournal(path)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Repo == repo && e.ApprovalRun == run {
+			return fmt.Errorf("approval: run %s already approved the change of %s (journal entry %s); ask for a new one", run, e.Time.Format(time.RFC3339), e.ID)
+		}
+	}
+	return nil
+}
+
+// tokenLogin returns the login of the user token belongs to.
+func tokenLogin(token string) (string, error) {
+	resp, err := apiRequest(token, "GET", "user", nil)
+	if err != nil {
+		return "", err
+	}
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := decodeResponse(resp, "user", &user); err != nil {
+		return "", err
+	}
+	if user.Login == "" {
+		return "", fmt.Errorf("token has no user")
+	}
+	return user.Login, nil
+}
+
+// checkCanApprove fails unless login has write access to repo.
+func checkCanApprove(repo, login string) error {
+	var perm struct {
+		Permission string `json:"permission"`
+	}
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/collaborators/%s/permission", repo, login), nil, &perm); err != nil {
+		return fmt.Errorf("approval: check %s's access to %s: %w", login, repo, err)
+	}
+	if !writePermissions[perm.Permission] {
+		return fmt.Errorf("approval: %s has %s access to %s; approvers need write access", login, perm.Permission, repo)
+	}
+	return nil
+}
+
+// runApproval is one deployment review of a workflow run.
+type runApproval struct {
+	State        string `json:"state"`
+	Environments []struct {
+		Name string `json:"name"`
+	} `json:"environments"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+}
+
+// runApprovers returns who approved the deployment of run to each of envs,
+// comma-separated, ignoring approvals by me.
+func runApprovers(repo, run string, envs []string, me string) (string, error) {
+	var reviews []runApproval
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/actions/runs/%s/approvals", repo, run), nil, &reviews); err != nil {
+		return "", fmt.Errorf("approval: read run %s: %w", run, err)
+	}
+	return approversFor(reviews, envs, me, run)
+}
+
+// approversFor checks that each of envs has an approval in reviews by
+// someone other than me.
+func approversFor(reviews []runApproval, envs []string, me, run string) (string, error) {
+	approvers := map[string]bool{}
+	for _, env := range envs {
+		found := false
+		for _, r := range reviews {
+			if r.State != "approved" || strings.EqualFold(r.User.Login, me) {
+				continue
+			}
+			for _, e := range r.Environments {
+				if e.Name == env {
+					found = true
+					approvers[r.User.Login] = true
+				}
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("approval: run %s has no approval for environment %q by anyone other than %s", run, env, me)
+		}
+	}
+	return strings.Join(sortedKeys(approvers), ","), nil
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]bool) []string {
+	keys := mapKeys(m)
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeApprovalGH serves an API for which the default token is alice's and
+// "bob-token" is bob's; bob can write to org/svc and carol can only read
+// it.
+func fakeApprovalGH(t *testing.T) {
+	t.Helper()
+	fakeGitHub(t, func(r *http.Request, call string) (int, string) {
+		switch call {
+		case "GET user":
+			switch requestToken(r) {
+			case "bob-token":
+				return 200, `{"login":"bob"}`
+			case "carol-token":
+				return 200, `{"login":"carol"}`
+			}
+			return 200, `{"login":"alice"}`
+		case "GET repos/org/svc/collaborators/bob/permission":
+			return 200, `{"permission":"write"}`
+		case "GET repos/org/svc/collaborators/carol/permission":
+			return 200, `{"permission":"read"}`
+		case "GET repos/org/svc/actions/runs/42", "GET repos/org/svc/actions/runs/44":
+			return 200, fmt.Sprintf(`{"created_at":%q}`, time.Now().Add(-time.Hour).Format(time.RFC3339))
+		case "GET repos/org/svc/actions/runs/43":
+			return 200, `{"created_at":"2020-01-02T03:04:05Z"}`
+		case "GET repos/org/svc/actions/runs/42/approvals", "GET repos/org/svc/actions/runs/43/approvals", "GET repos/org/svc/actions/runs/44/approvals":
+			return 200, `[{"state":"approved","environments":[{"name":"prod"}],"user":{"login":"bob"}}]`
+		}
+		return 404, ""
+	})
+}
+
+func TestRequireApproval(t *testing.T) {
+	fakeApprovalGH(t)
+	t.Setenv("ACA_CONFIG_DIR", t.TempDir())
+	t.Cleanup(func() { approvedRuns = map[string]bool{} })
+	path, err := journalPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := appendJournal(path, journalEntry{Command: "flip", Repo: "org/svc", Approver: "bob", ApprovalRun: "44"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := func(mode string) map[string]configValue {
+		return map[string]configValue{"protect.approval": {Value: mode}}
+	}
+	tests := []struct {
+		name, mode, token, run string
+		protected              []string
+		want, wantErr          string
+	}{
+		{name: "off", mode: approvalNone, protected: []string{"prod"}},
+		{name: "not protected", mode: approvalToken},
+		{name: "token missing", mode: approvalToken, protected: []string{"prod"}, wantErr: approverTokenEnv},
+		{name: "token", mode: approvalToken, token: "bob-token", protected: []string{"prod"}, want: "bob"},
+		{name: "own token", mode: approvalToken, token: "alice-token", protected: []string{"prod"}, wantErr: "someone else"},
+		{name: "read only approver", mode: approvalToken, token: "carol-token", protected: []string{"prod"}, wantErr: "need write access"},
+		{name: "run missing", mode: approvalEnvironment, protected: []string{"prod"}, wantErr: "--approval-run"},
+		{name: "run", mode: approvalEnvironment, run: "42", protected: []string{"prod"}, want: "bob"},
+		{name: "run for other env", mode: approvalEnvironment, run: "42", protected: []string{"prod-eu"}, wantErr: `"prod-eu"`},
+		{name: "old run", mode: approvalEnvironment, run: "43", protected: []string{"prod"}, wantErr: "ask for a new one"},
+		{name: "used run", mode: approvalEnvironment, run: "44", protected: []string{"prod"}, wantErr: "already approved"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(approverTokenEnv, tt.token)
+			approvalRun = tt.run
+			defer func() { approvalRun = "" }()
+			got, err := requireApproval("org/svc", tt.protected, cfg(tt.mode))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got.By != tt.want {
+				t.Fatalf("requireApproval = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApproversFor(t *testing.T) {
+	review := func(state, login string, envs ...string) runApproval {
+		r := runApproval{State: state}
+		r.User.Login = login
+		for _, e := range envs {
+			r.Environments = append(r.Environments, struct {
+				Name string `json:"name"`
+			}{e})
+		}
+		return r
+	}
+	tests := []struct {
+		name    string
+		reviews []runApproval
+		envs    []string
+		want    string
+		wantErr bool
+	}{
+		{"approved", []runApproval{review("approved", "bob", "prod")}, []string{"prod"}, "bob", false},
+		{"two envs two approvers", []runApproval{review("approved", "dan", "prod-us"), review("approved", "bob", "prod-eu")}, []string{"prod-eu", "prod-us"}, "bob,dan", false},
+		{"rejected", []runApproval{review("rejected", "bob", "prod")}, []string{"prod"}, "", true},
+		{"self approved", []runApproval{review("approved", "alice", "prod")}, []string{"prod"}, "", true},
+		{"one env missing", []runApproval{review("approved", "bob", "prod-eu")}, []string{"prod-eu", "prod-us"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := approversFor(tt.reviews, tt.envs, "alice", "42")
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("approversFor = %q, %v, want %q (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
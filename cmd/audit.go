@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// auditEvent is one adapter value change found in a parameters file's
+// history.
+type auditEvent struct {
+	Time    time.Time `json:"time"`
+	Commit  string    `json:"commit"`
+	Author  string    `json:"author"`
+	PR      int       `json:"pr,omitempty"`
+	Subject string    `json:"subject"`
+	Env     string    `json:"env"`
+	Adapter string    `json:"adapter"`
+	Old     string    `json:"old"`
+	New     string    `json:"new"`
+	Added   bool      `json:"added,omitempty"`
+	Removed bool      `json:"removed,omitempty"`
+}
+
+// prNumberRe finds the pull request in squash ("Title (#12)") and merge
+// ("Merge pull request #12 from ...") commit subjects.
+var prNumberRe = regexp.MustCompile(`\(#(\d+)\)\s*$|^Merge pull request #(\d+)`)
+
+func prFromSubject(subject string) int {
+	m := prNumberRe.FindStringSubmatch(subject)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1] + m[2])
+	return n
+}
+
+// auditFile walks the first-parent history of rel in the clone at dir,
+// oldest first, and returns every change to the values of want (all
+// adapters when empty). Following first parents attributes each change to
+// the commit that brought it to the branch, which for merged pull requests
+// names the PR.
+func auditFile(dir, env, rel string, want []string) ([]auditEvent, error) {
+	out, err := gitOutput(dir, "log", "--first-parent", "--reverse", "--format=%H%x1f%an%x1f%aI%x1f%s", "HEAD", "--", rel)
+	if err != nil {
+		return nil, err
+	}
+	keep := map[string]bool{}
+	for _, a := range want {
+		keep[a] = true
+	}
+	var events []auditEvent
+	prev := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Split(line, "\x1f")
+		if len(f) != 4 {
+			continue
+		}
+		when, _ := time.Parse(time.RFC3339, f[2])
+		base := auditEvent{Time: when, Commit: f[0][:min(7, len(f[0]))], Author: f[1], Subject: f[3], PR: prFromSubject(f[3]), Env: env}
+
+		cur := map[string]string{}
+		// The file is missing at commits that deleted it.
+		if content, err := command("", "git", "-C", dir, "show", f[0]+":"+rel).Output(); err == nil { // #nosec G204 - rel is built from validated env and file names
+			values, err := parseEnvValues(content, env, rel, nil)
+			if err != nil {
+				warnf("%s at %s: %v", rel, base.Commit, err)
+				continue
+			}
+			for _, v := range values {
+				cur[v.Adapter] = v.Value
+			}
+		}
+
+		keys := make([]string, 0, len(cur)+len(prev))
+		for k := range cur {
+			keys = append(keys, k)
+		}
+		for k := range prev {
+			if _, ok := cur[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if len(keep) > 0 && !keep[k] {
+				continue
+			}
+			old, had := prev[k]
+			v, has := cur[k]
+			if had && has && old == v {
+				continue
+			}
+			e := base
+			e.Adapter, e.Old, e.New, e.Added, e.Removed = k, old, v, !had, !has
+			events = append(events, e)
+		}
+		prev = cur
+	}
+	return events, nil
+}
+
+// cloneHistory clones repo without file contents or a checkout; blobs are
+// fetched as git needs them.
+func cloneHistory(repo string) (string, func(), error) {
+	if useGoGit() {
+		return "", nil, fmt.Errorf("audit reads history with the git binary; install git or pass --git-backend git")
+	}
+	tmp, cleanup, err := makeTemp("gh-aca-utils-")
+	if err != nil {
+		return "", nil, err
+	}
+	if err := cloneRepo(repo, tmp, cloneOptions{partial: true, noCheckout: true, quiet: true}); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to clone repository: %w", err)
+	}
+	return tmp, cleanup, nil
+}
+
+// historyEnvs lists the environment directories at HEAD matching env.
+func historyEnvs(dir, env string) ([]string, error) {
+	if strings.ContainsAny(env, `/\`) || strings.Contains(env, "..") {
+		return nil, usageErrorf("invalid environment name: %q", env)
+	}
+	out, err := gitOutput(dir, "ls-tree", "-d", "--name-only", "HEAD", "env/")
+	if err != nil {
+		return nil, err
+	}
+	var envs []string
+	for _, p := range strings.Split(out, "\n") {
+		if ok, _ := path.Match(env, path.Base(p)); ok && p != "" {
+			envs = append(envs, path.Base(p))
+		}
+	}
+	if len(envs) == 0 {
+		return nil, fmt.Errorf("no environment matching %q under env/", env)
+	}
+	return envs, nil
+}
+
+func printAudit(events []auditEvent, mode outputMode) error {
+	switch mode {
+	case outJSON:
+		enc := json.NewEncoder(stdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(events)
+	case outCSV:
+		fmt.Fprintln(stdout(), "time,commit,author,pr,env,adapter,old,new")
+		for _, e := range events {
+			pr := ""
+			if e.PR > 0 {
+				pr = strconv.Itoa(e.PR)
+			}
+			fmt.Fprintln(stdout(), strings.Join([]string{e.Time.Format(time.RFC3339), e.Commit, csvEsc(e.Author), pr, csvEsc(e.Env), csvEsc(e.Adapter), csvEsc(e.Old), csvEsc(e.New)}, ","))
+		}
+		return nil
+	}
+	if len(events) == 0 {
+		fmt.Fprintln(stdout(), "No adapter changes found.")
+		return nil
+	}
+	w := newTable()
+	w.AddRow("Date", "Commit", "Author", "PR", "Env", "Adapter", "Old", "New")
+	for _, e := range events {
+		pr, old, cur := "", e.Old, e.New
+		if e.PR > 0 {
+			pr = "#" + strconv.Itoa(e.PR)
+		}
+		if e.Added {
+			old = "(new)"
+		}
+		if e.Removed {
+			cur = "(removed)"
+		}
+		w.AddRow(e.Time.Local().Format("2006-01-02 15:04"), e.Commit, e.Author, pr, e.Env, e.Adapter, old, cur)
+	}
+	w.Render()
+	return nil
+}
+
+func cmdAudit() *cobra.Command {
+	var repo, envName, adaptersCSV, paramFile, since, mode string
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Show who changed adapter values in an environment's parameters file, from git history",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if repo == "" {
+				return usageErrorf("--repo ORG/REPO is required")
+			}
+			if envName == "" {
+				return usageErrorf("--env is required (e.g., prod)")
+			}
+			if err := checkParamFile(paramFile); err != nil {
+				return err
+			}
+			sinceTime, err := parseSince(since, time.Now())
+			if err != nil {
+				return err
+			}
+			want := splitCSV(adaptersCSV, nil)
+			modeVal := parseMode(mode, outTable)
+
+			tmpDir, cleanup, err := cloneHistory(repo)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			envs, err := historyEnvs(tmpDir, envName)
+			if err != nil {
+				return err
+			}
+
+			events := make([]auditEvent, 0)
+			for _, env := range envs {
+				envEvents, err := auditFile(tmpDir, env, path.Join("env", env, paramFile), want)
+				if err != nil {
+					return err
+				}
+				events = append(events, envEvents...)
+			}
+			filtered := events[:0]
+			for _, e := range events {
+				if !e.Time.Before(sinceTime) {
+					filtered = append(filtered, e)
+				}
+			}
+			// Newest first, like history.
+			sort.SliceStable(filtered, func(i, j int) bool { return filtered[i].Time.After(filtered[j].Time) })
+			return printAudit(filtered, modeVal)
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Target repo as ORG/REPO (required)")
+	cmd.Flags().StringVar(&envName, "env", "", "Environment name or glob (required)")
+	cmd.Flags().StringVar(&adaptersCSV, "adapters", "", "Only these comma-separated adapter keys (default: all)")
+	cmd.Flags().StringVar(&paramFile, "file", "parameters.properties", "Parameters file in each env directory")
+	cmd.Flags().StringVar(&since, "since", "", "Only changes after a date, RFC 3339 time or age such as 30d")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table|json|csv")
+	setOutputModes(cmd, "table", "json", "csv")
+	return cmd
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPRFromSubject(t *testing.T) {
+	for subject, want := range map[string]int{
+		"Flip billing (#12)":                        12,
+		"Merge pull request #7 from org/toggle/dev": 7,
+		"chore(env:dev): flip billing":              0,
+		"Fix #3 in the middle":                      0,
+	} {
+		if got := prFromSubject(subject); got != want {
+			t.Errorf("prFromSubject(%q) = %d, want %d", subject, got, want)
+		}
+	}
+}
+
+func TestAuditFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, kv := range []string{"GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1"} {
+		k, v, _ := strings.Cut(kv, "=")
+		t.Setenv(k, v)
+	}
+	git := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "--quiet", "-b", "main")
+	rel := "env/prod/parameters.properties"
+	if err := os.MkdirAll(filepath.Join(dir, "env", "prod"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	commit := func(author, subject, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, rel), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "-A")
+		git("-c", "user.name="+author, "commit", "--quiet", "--allow-empty", "-m", subject)
+	}
+	commit("alice", "Add prod parameters", "billing=0\nsearch=0\n")
+	commit("bob", "Flip billing (#12)", "billing=1\nsearch=0\n")
+	commit("alice", "Reword comment", "# adapters\nbilling=1\nsearch=0\n")
+	commit("carol", "Merge pull request #15 from org/toggle", "billing=1\ncrm=on\n")
+
+	events, err := auditFile(dir, "prod", rel, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, fmt.Sprintf("%s #%d %s %q->%q added=%v removed=%v", e.Author, e.PR, e.Adapter, e.Old, e.New, e.Added, e.Removed))
+	}
+	want := []string{
+		`alice #0 billing ""->"0" added=true removed=false`,
+		`alice #0 search ""->"0" added=true removed=false`,
+		`bob #12 billing "0"->"1" added=false removed=false`,
+		`carol #15 crm ""->"on" added=true removed=false`,
+		`carol #15 search "0"->"" added=false removed=true`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("auditFile =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	events, err = auditFile(dir, "prod", rel, []string{"billing"})
+	if err != nil || len(events) != 2 {
+		t.Errorf("auditFile(billing) = %d events, %v; want 2", len(events), err)
+	}
+
+	envs, err := historyEnvs(dir, "pr*")
+	if err != nil || fmt.Sprint(envs) != "[prod]" {
+		t.Errorf("historyEnvs = %v, %v", envs, err)
+	}
+	if _, err := historyEnvs(dir, "dev"); err == nil {
+		t.Error("historyEnvs(dev) should fail")
+	}
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// mergeMethods are the --merge-method values, as accepted by `gh pr merge`.
+var mergeMethods = []string{"squash", "merge", "rebase"}
+
+func checkMergeMethod(method string) error {
+	for _, m := range mergeMethods {
+		if method == m {
+			return nil
+		}
+	}
+	return usageErrorf("invalid --merge-method %q: want one of %s", method, strings.Join(mergeMethods, ", "))
+}
+
+// enableAutoMerge queues the pull request at prURL to merge with method
+// once its required checks and reviews pass.
+func enableAutoMerge(prURL, method string) error {
+	if err := enableAutoMergeAPI(prURL, method); err != nil {
+		return fmt.Errorf("enable auto-merge: %w", err)
+	}
+	infof("Auto-merge (%s) enabled for %s", method, prURL)
+	return nil
+}
+
+// enableAutoMergeAPI enables auto-merge through the GraphQL mutation
+// `gh pr merge --auto` uses.
+func enableAutoMergeAPI(prURL, method string) error {
+	m := prURLRe.FindStringSubmatch(prURL)
+	if m == nil {
+		return fmt.Errorf("not a pull request URL: %s", prURL)
+	}
+	var pr struct {
+		NodeID string `json:"node_id"`
+	}
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/pulls/%s", m[1], m[2]), nil, &pr); err != nil {
+		return err
+	}
+	query := `mutation($id: ID!, $method: PullRequestMergeMethod!) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: $method}) { clientMutationId }
+}`
+	return graphQL(query, map[string]any{"id": pr.NodeID, "method": strings.ToUpper(method)}, nil)
+}
+
+// checkRun is the part of a check run that --wait-checks looks at.
+type checkRun struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+}
+
+// checkSummary is the state of all check runs on a commit.
+type checkSummary struct {
+	Total   int
+	Pending []string
+	Failed  []string
+}
+
+// summarizeChecks sorts check runs into pending and failed; success,
+// neutral and skipped conclusions pass.
+func summarizeChecks(runs []checkRun) checkSummary {
+	s := checkSummary{Total: len(runs)}
+	for _, r := range runs {
+		switch {
+		case r.Status != "completed":
+			s.Pending = append(s.Pending, r.Name)
+		case r.Conclusion == "success" || r.Conclusion == "neutral" || r.Conclusion == "skipped":
+		default:
+			s.Failed = append(s.Failed, r.Name+" ("+r.Conclusion+")")
+		}
+	}
+	return s
+}
+
+// state is the overall result: pending, failure or success.
+func (s checkSummary) state() string {
+	switch {
+	case len(s.Pending) > 0:
+		return "pending"
+	case len(s.Failed) > 0:
+		return "failure"
+	}
+	return "success"
+}
+
+// checksInterval is how often --wait-checks polls.
+const checksInterval = 15 * time.Second
+
+// checksGrace is how long waitChecks waits for the first check run to be
+// reported before concluding the repository has no checks.
+const checksGrace = time.Minute
+
+// waitChecks polls the check runs on sha in repo until all of them have
+// completed or timeout passes, and returns the final state. Failed checks
+// and timeouts are errors.
+func waitChecks(repo, sha string, interval, timeout time.Duration) (string, error) {
+	start := time.Now()
+	infof("Waiting for checks on %s...", sha[:min(7, len(sha))])
+	for {
+		var page struct {
+			CheckRuns []checkRun `json:"check_runs"`
+		}
+		if err := ghAPI("GET", fmt.Sprintf("repos/%s/commits/%s/check-runs?per_page=100", repo, sha), nil, &page); err != nil {
+			return "", fmt.Errorf("list check runs: %w", err)
+		}
+		s := summarizeChecks(page.CheckRuns)
+		elapsed := time.Since(start)
+		switch {
+		case s.Total == 0 && elapsed >= checksGrace:
+			infof("No checks reported for %s", repo)
+			return "none", nil
+		case s.Total > 0 && s.state() == "success":
+			infof("Checks passed (%d)", s.Total)
+			return "success", nil
+		case s.state() == "failure":
+			return "failure", fmt.Errorf("%d of %d checks failed: %s", len(s.Failed), s.Total, strings.Join(s.Failed, ", "))
+		case elapsed >= timeout:
+			return "pending", fmt.Errorf("timed out after %s waiting for checks: %s", timeout, strings.Join(s.Pending, ", "))
+		}
+		sleepCtx(interval)
+		if canceled() {
+			return "pending", fmt.Errorf("stopped waiting for checks: %w", context.Cause(runCtx))
+		}
+	}
+}
+
+// waitMerged polls the pull request at prURL until it is merged, and
+// returns the SHA of its merge commit on the base branch. A pull request
+// closed without merging, or not merged within timeout, is an error.
+func waitMerged(prURL string, interval, timeout time.Duration) (string, error) {
+	m := prURLRe.FindStringSubmatch(prURL)
+	if m == nil {
+		return "", fmt.Errorf("not a pull request URL: %s", prURL)
+	}
+	start := time.Now()
+	infof("Waiting for %s to merge...", prURL)
+	for {
+		var pr struct {
+			State          string `json:"state"`
+			Merged         bool   `json:"merged"`
+			MergeCommitSHA string `json:"merge_commit_sha"`
+		}
+		if err := ghAPI("GET", fmt.Sprintf("repos/%s/pulls/%s", m[1], m[2]), nil, &pr); err != nil {
+			return "", err
+		}
+		switch {
+		case pr.Merged && pr.MergeCommitSHA != "":
+			infof("Merged as %s", pr.MergeCommitSHA[:min(7, len(pr.MergeCommitSHA))])
+			return pr.MergeCommitSHA, nil
+		case pr.State == "closed":
+			return "", fmt.Errorf("%s was closed without merging", prURL)
+		case time.Since(start) >= timeout:
+			return "", fmt.Errorf("timed out after %s waiting for %s to merge", timeout, prURL)
+		}
+		sleepCtx(interval)
+		if canceled() {
+			return "", fmt.Errorf("stopped waiting for the merge: %w", context.Cause(runCtx))
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarizeChecks(t *testing.T) {
+	tests := []struct {
+		name  string
+		runs  []checkRun
+		state string
+		info  string
+	}{
+		{"none", nil, "success", ""},
+		{"passed", []checkRun{{"build", "completed", "success"}, {"docs", "completed", "skipped"}, {"opt", "completed", "neutral"}}, "success", ""},
+		{"pending wins", []checkRun{{"build", "in_progress", ""}, {"lint", "completed", "failure"}}, "pending", "build,lint (failure)"},
+		{"failed", []checkRun{{"build", "completed", "success"}, {"lint", "completed", "timed_out"}}, "failure", "lint (timed_out)"},
+	}
+	for _, tt := range tests {
+		s := summarizeChecks(tt.runs)
+		info := strings.Join(append(s.Pending, s.Failed...), ",")
+		if s.state() != tt.state || info != tt.info || s.Total != len(tt.runs) {
+			t.Errorf("%s: state %s (%s), want %s (%s)", tt.name, s.state(), info, tt.state, tt.info)
+		}
+	}
+}
+
+func TestCheckMergeMethod(t *testing.T) {
+	for _, m := range []string{"squash", "merge", "rebase"} {
+		if err := checkMergeMethod(m); err != nil {
+			t.Errorf("%s: %v", m, err)
+		}
+	}
+	if err := checkMergeMethod("ff"); err == nil {
+		t.Error("want error for ff")
+	}
+}
+
+func TestWaitChecksFailure(t *testing.T) {
+	fakeGH(t)
+	state, err := waitChecks("org/svc", "commit1234567", 0, time.Minute)
+	if state != "failure" || err == nil || !strings.Contains(err.Error(), "1 of 2 checks failed: lint (failure)") {
+		t.Errorf("waitChecks = %s, %v", state, err)
+	}
+}
+
+func TestWaitMerged(t *testing.T) {
+	tests := []struct {
+		name, pr string
+		want     string
+		wantErr  string
+	}{
+		{"merged", `{"state":"closed","merged":true,"merge_commit_sha":"merge123"}`, "merge123", ""},
+		{"closed", `{"state":"closed","merged":false}`, "", "closed without merging"},
+		{"open", `{"state":"open","merged":false}`, "", "timed out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeGitHub(t, func(_ *http.Request, call string) (int, string) {
+				if call == "GET repos/org/svc/pulls/7" {
+					return 200, tt.pr
+				}
+				return 404, ""
+			})
+			got, err := waitMerged("https://github.com/org/svc/pull/7", 0, 0)
+			if got != tt.want || (err == nil) != (tt.wantErr == "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("waitMerged = %q, %v; want %q, %q", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
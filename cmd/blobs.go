@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// blobCache holds scan results by git blob SHA so identical file content
+// on several branches is only scanned once.
+type blobCache struct {
+	mu   sync.Mutex
+	rows map[string][]matchRow
+}
+
+func newBlobCache() *blobCache {
+	return &blobCache{rows: map[string][]matchRow{}}
+}
+
+// blobKey includes the file extension because .properties files are parsed
+// differently from the same bytes under another name.
+func blobKey(sha, rel string) string {
+	return sha + "|" + strings.ToLower(filepath.Ext(rel))
+}
+
+// scan returns the findings for a file with the given blob SHA, calling
+// scanFn only on the first sighting of that blob. Cached rows are copied
+// with RelPath rewritten to rel.
+func (c *blobCache) scan(sha, rel string, scanFn func() []matchRow) []matchRow {
+	key := blobKey(sha, rel)
+	c.mu.Lock()
+	cached, ok := c.rows[key]
+	c.mu.Unlock()
+
+	if ok {
+		out := make([]matchRow, len(cached))
+		for i, r := range cached {
+			r.RelPath = rel
+			out[i] = r
+		}
+		return out
+	}
+
+	rows := scanFn()
+	c.mu.Lock()
+	c.rows[key] = rows
+	c.mu.Unlock()
+	return rows
+}
+
+// parseLsTree maps paths to blob SHAs from `git ls-tree -r -z` output.
+// Symbolic links and submodules aren't files to scan and are left out.
+func parseLsTree(out string) map[string]string {
+	blobs := map[string]string{}
+	for _, rec := range strings.Split(out, "\x00") {
+		meta, path, ok := strings.Cut(rec, "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) == 3 && fields[1] == "blob" && fields[0] != "120000" {
+			blobs[path] = fields[2]
+		}
+	}
+	return blobs
+}
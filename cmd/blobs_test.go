@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLsTree(t *testing.T) {
+	out := "100644 blob aaa111\tapp.properties\x00" +
+		"100644 blob bbb222\tdir with space/x.yml\x00" +
+		"160000 commit ccc333\tvendor/sub\x00" +
+		"120000 blob ddd444\tlink.properties\x00"
+	want := map[string]string{"app.properties": "aaa111", "dir with space/x.yml": "bbb222"}
+	if got := parseLsTree(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLsTree() = %v, want %v", got, want)
+	}
+}
+
+func TestBlobCache(t *testing.T) {
+	c := newBlobCache()
+	calls := 0
+	scan := func() []matchRow {
+		calls++
+		return []matchRow{{IPValue: "10.0.0.1", RelPath: "a/app.properties", LineNumber: 3}}
+	}
+
+	c.scan("sha1", "a/app.properties", scan)
+	got := c.scan("sha1", "b/app.properties", scan)
+	if calls != 1 {
+		t.Errorf("Expected one scan for a repeated blob, got %d", calls)
+	}
+	if len(got) != 1 || got[0].RelPath != "b/app.properties" || got[0].LineNumber != 3 {
+		t.Errorf("Expected cached row rewritten to the new path, got %+v", got)
+	}
+
+	// The same bytes under another extension are parsed differently.
+	c.scan("sha1", "a/app.txt", scan)
+	if calls != 2 {
+		t.Errorf("Expected a rescan for a different extension, got %d scans", calls)
+	}
+}
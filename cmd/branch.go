@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// branchPolicy decides what a commit does when its branch already exists
+// on the remote.
+type branchPolicy int
+
+const (
+	branchFail   branchPolicy = iota // stop with an error
+	branchSuffix                     // commit to <branch>-<timestamp> instead
+	branchReuse                      // add a commit on top of the branch
+	branchForce                      // replace the branch
+)
+
+// newBranchPolicy maps --branch-suffix-timestamp, --reuse-branch and
+// --force-push to a policy; at most one may be set.
+func newBranchPolicy(suffix, reuse, force bool) (branchPolicy, error) {
+	n, p := 0, branchFail
+	for _, f := range []struct {
+		set bool
+		p   branchPolicy
+	}{{suffix, branchSuffix}, {reuse, branchReuse}, {force, branchForce}} {
+		if f.set {
+			n, p = n+1, f.p
+		}
+	}
+	if n > 1 {
+		return branchFail, usageErrorf("--branch-suffix-timestamp, --reuse-branch and --force-push are mutually exclusive")
+	}
+	return p, nil
+}
+
+// resolve returns the branch to commit to given whether it exists.
+func (p branchPolicy) resolve(branch string, exists bool, now time.Time) (string, error) {
+	if !exists {
+		return branch, nil
+	}
+	switch p {
+	case branchSuffix:
+		return branch + "-" + now.UTC().Format("20060102-150405"), nil
+	case branchReuse:
+		infof("Branch %s exists; adding a commit on top of it", branch)
+	case branchForce:
+		infof("Branch %s exists; replacing it", branch)
+	default:
+		return "", fmt.Errorf("branch %s already exists; use --branch-suffix-timestamp, --reuse-branch or --force-push", branch)
+	}
+	return branch, nil
+}
+
+// remoteBranchExists reports whether origin has branch.
+func remoteBranchExists(dir, branch string) (bool, error) {
+	if useGoGit() {
+		return goGitRemoteBranchExists(dir, branch)
+	}
+	out, err := gitOutput(dir, "ls-remote", "--heads", "origin", "refs/heads/"+branch)
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// gitCommitBranch commits paths in the clone at dir to branch and pushes
+// it. An existing branch is built on (branchReuse) or overwritten
+// (branchForce); with branchReuse only paths change on it, and nothing is
+// committed when they already match.
+func gitCommitBranch(dir, branch, msg string, paths []string, exists bool, p branchPolicy) error {
+	if useGoGit() {
+		return goGitCommitBranch(dir, branch, msg, paths, exists, p)
+	}
+	if err := gitIn(dir, "checkout", "-b", branch); err != nil {
+		return err
+	}
+	if exists && p == branchReuse {
+		if err := gitFetchBranch(dir, branch); err != nil {
+			return err
+		}
+		// Point the branch at the remote tip and keep the edited files.
+		if err := gitIn(dir, "reset", "--quiet", "FETCH_HEAD"); err != nil {
+			return err
+		}
+	}
+	if err := gitIn(dir, append([]string{"add", "--"}, paths...)...); err != nil {
+		return err
+	}
+	if exists && p == branchReuse {
+		if _, err := gitOutput(dir, "diff", "--cached", "--quiet"); err == nil {
+			infof("Branch %s already has these changes", branch)
+			return nil
+		}
+	}
+	if err := gitIn(dir, "commit", "-m", msg); err != nil {
+		return err
+	}
+	push := []string{"-u", "origin", branch}
+	if exists && p == branchForce {
+		push = append(push, "--force")
+	}
+	return gitPush(dir, push...)
+}
+
+// existingPR returns the URL of the open pull request from owner's branch
+// in repo, or "" when there is none.
+func existingPR(repo, owner, branch string) (string, error) {
+	var prs []struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/pulls?state=open&head=%s", repo, url.QueryEscape(owner+":"+branch)), nil, &prs); err != nil {
+		return "", fmt.Errorf("look up pull request for %s: %w", branch, err)
+	}
+	if len(prs) == 0 {
+		return "", nil
+	}
+	return prs[0].HTMLURL, nil
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBranchPolicyResolve(t *testing.T) {
+	if _, err := newBranchPolicy(true, true, false); err == nil {
+		t.Error("want error for two policies")
+	}
+	now := time.Date(2026, 3, 1, 14, 5, 9, 0, time.UTC)
+	tests := []struct {
+		suffix, reuse, force bool
+		exists               bool
+		want                 string
+	}{
+		{false, false, false, false, "toggle/adapters-dev"},
+		{false, false, false, true, "error"},
+		{true, false, false, true, "toggle/adapters-dev-20260301-140509"},
+		{true, false, false, false, "toggle/adapters-dev"},
+		{false, true, false, true, "toggle/adapters-dev"},
+		{false, false, true, true, "toggle/adapters-dev"},
+	}
+	for _, tt := range tests {
+		p, err := newBranchPolicy(tt.suffix, tt.reuse, tt.force)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := p.resolve("toggle/adapters-dev", tt.exists, now)
+		if err != nil {
+			got = "error"
+		}
+		if got != tt.want {
+			t.Errorf("policy %d, exists %v: got %s, want %s", p, tt.exists, got, tt.want)
+		}
+	}
+}
+
+func TestGitCommitBranch(t *testing.T) {
+	origin := initTestRepo(t, map[string]map[string]string{
+		"main":                {"env/dev/parameters.properties": "billing=0\nsearch=0\n"},
+		"toggle/adapters-dev": {"env/dev/parameters.properties": "billing=1\nsearch=0\n", "NOTES.md": "keep\n"},
+	}, "main", "toggle/adapters-dev")
+	for _, kv := range []string{"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1"} {
+		k, v, _ := strings.Cut(kv, "=")
+		t.Setenv(k, v)
+	}
+	rel := filepath.Join("env", "dev", "parameters.properties")
+	run := func(p branchPolicy, content string) {
+		t.Helper()
+		dir := filepath.Join(t.TempDir(), "clone")
+		if out, err := exec.Command("git", "clone", "--quiet", "--depth", "1", "file://"+origin, dir).CombinedOutput(); err != nil {
+			t.Fatalf("clone: %v\n%s", err, out)
+		}
+		if err := os.WriteFile(filepath.Join(dir, rel), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		exists, err := remoteBranchExists(dir, "toggle/adapters-dev")
+		if err != nil || !exists {
+			t.Fatalf("remoteBranchExists = %v, %v", exists, err)
+		}
+		if err := gitCommitBranch(dir, "toggle/adapters-dev", "flip", []string{rel}, exists, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	show := func(file string) string {
+		out, _ := exec.Command("git", "-C", origin, "show", "toggle/adapters-dev:"+file).Output()
+		return string(out)
+	}
+
+	run(branchReuse, "billing=1\nsearch=1\n")
+	if got := show("env/dev/parameters.properties"); got != "billing=1\nsearch=1\n" || show("NOTES.md") != "keep\n" {
+		t.Errorf("after reuse: %q, NOTES.md %q", got, show("NOTES.md"))
+	}
+	run(branchForce, "billing=0\nsearch=1\n")
+	if got := show("env/dev/parameters.properties"); got != "billing=0\nsearch=1\n" || show("NOTES.md") != "" {
+		t.Errorf("after force: %q, NOTES.md %q", got, show("NOTES.md"))
+	}
+}
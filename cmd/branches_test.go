@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initTestRepo creates a local repository with one commit per branch, each
+// holding the given files, and returns its path for use as a clone source.
+func initTestRepo(t *testing.T, branches map[string]map[string]string, order ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	git("init", "--quiet", "--initial-branch", order[0])
+	for i, branch := range order {
+		if i > 0 {
+			git("checkout", "--quiet", "-b", branch, order[0])
+		}
+		for name, body := range branches[branch] {
+			path := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(body), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		git("add", "-A")
+		git("commit", "--quiet", "--allow-empty", "-m", branch)
+	}
+	git("checkout", "--quiet", order[0])
+	return dir
+}
+
+func TestScanAllBranches(t *testing.T) {
+	repo := initTestRepo(t, map[string]map[string]string{
+		"main":    {"app.properties": "db.host=10.0.0.1\n"},
+		"feature": {"app.properties": "db.host=10.0.0.2\n", "extra.env": "CACHE_HOST=10.0.0.3\n"},
+		"release": {"svc/app.yml": "host: 10.0.0.4\n", ".acaignore-values": "10.0.0.1\n"},
+	}, "main", "feature", "release")
+	// A symbolic link's blob is its target, not a file to scan.
+	runGit(t, repo, "checkout", "--quiet", "feature")
+	if err := os.Symlink("10.0.0.5", filepath.Join(repo, "link.properties")); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, "add", "link.properties")
+	runGit(t, repo, "commit", "--quiet", "-m", "link")
+	runGit(t, repo, "checkout", "--quiet", "main")
+
+	opts := scanOptions{includes: []string{"**/*"}, excludes: []string{"**/.git/**"}, jobs: 3}
+	rows, err := scanAllBranches(repo, opts)
+	if err != nil {
+		t.Fatalf("scanAllBranches failed: %v", err)
+	}
+
+	var got []string
+	for _, r := range rows {
+		got = append(got, r.location()+"="+r.IPValue)
+	}
+	want := []string{
+		"feature:app.properties=10.0.0.2",
+		"feature:extra.env=10.0.0.3",
+		"main:app.properties=10.0.0.1",
+		"release:svc/app.yml=10.0.0.4", // its .acaignore-values suppresses 10.0.0.1
+	}
+	for _, r := range rows {
+		if strings.Contains(r.RelPath, "[") || r.Branch == "" {
+			t.Errorf("Expected branch in its own field, got %+v", r)
+		}
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("scanAllBranches() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
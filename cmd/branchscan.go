@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// --all-branches reads every branch straight from the objects of a bare
+// clone: each branch's files are listed with `git ls-tree` and read with
+// `git cat-file`, so nothing is checked out and branches are scanned in
+// parallel without sharing a working tree.
+
+func scanAllBranches(repo string, opts scanOptions) ([]matchRow, error) {
+	repoDir, cleanup, err := bareClone(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	branches, err := listBranches(repoDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branches: %w", err)
+	}
+
+	// Branches finish out of order; results are emitted per branch in
+	// branch order, each branch's own scan being unstreamed.
+	results := newOrderedEmitter(len(branches), opts.emit)
+	opts.cache, opts.emit = newBlobCache(), nil
+	index := make(map[string]int, len(branches))
+	for i, b := range branches {
+		index[b] = i
+	}
+	forEachLimit(branches, opts.jobs, func(branch string) {
+		rows, err := scanBranch(repoDir, branch, opts)
+		if err != nil {
+			warnf("failed to scan branch %s: %v", branch, err)
+		}
+		results.set(index[branch], rows)
+	})
+	return results.rows(), nil
+}
+
+// bareClone clones every branch of repo without a working tree.
+func bareClone(repo string) (string, func(), error) {
+	tmp, cleanup, err := makeTemp("gh-aca-utils-")
+	if err != nil {
+		return "", nil, err
+	}
+
+	o := cloneOptions{bare: true, quiet: true}
+	cached, cloneErr := cachedClone(repo, repoURL(repo), tmp, o)
+	if !cached && cloneErr == nil {
+		cloneErr = gitClone(repoURL(repo), tmp, o)
+	}
+	if cloneErr != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to clone repository: %w", cloneErr)
+	}
+	return tmp, cleanup, nil
+}
+
+// listBranches returns the sorted branch names of the bare clone at dir.
+func listBranches(dir string) ([]string, error) {
+	if useGoGit() {
+		return goGitBranches(dir)
+	}
+	out, err := gitOutput(dir, "for-each-ref", "--format=%(refname:short)", "refs/heads/")
+	if err != nil {
+		return nil, err
+	}
+	var branches []string
+	for _, b := range strings.Split(out, "\n") {
+		if b = strings.TrimSpace(b); b != "" {
+			branches = append(branches, b)
+		}
+	}
+	sort.Strings(branches)
+	return branches, nil
+}
+
+// branchBlobs maps the paths of the files on branch to their blob SHAs.
+func branchBlobs(dir, branch string) (map[string]string, error) {
+	if useGoGit() {
+		return goGitBranchBlobs(dir, branch)
+	}
+	out, err := gitOutput(dir, "ls-tree", "-r", "-z", "refs/heads/"+branch)
+	if err != nil {
+		return nil, err
+	}
+	return parseLsTree(out), nil
+}
+
+// blobReader reads the contents of blobs by SHA.
+type blobReader interface {
+	read(sha string) ([]byte, error)
+	close() error
+}
+
+// openBlobs returns a reader for the blobs of the repository at dir.
+func openBlobs(dir string) (blobReader, error) {
+	if useGoGit() {
+		return openGoGitBlobs(dir)
+	}
+	return openCatFile(dir)
+}
+
+// catFile reads blobs through one long-running `git cat-file --batch`.
+type catFile struct {
+	mu   sync.Mutex
+	in   io.WriteCloser
+	out  *bufio.Reader
+	wait func() error
+}
+
+func openCatFile(dir string) (*catFile, error) {
+	cmd := command(dir, "git", "cat-file", "--batch")
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &catFile{in: in, out: bufio.NewReader(out), wait: cmd.Wait}, nil
+}
+
+func (c *catFile) read(sha string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintln(c.in, sha); err != nil {
+		return nil, err
+	}
+	// "<sha> <type> <size>\n<contents>\n", or "<sha> missing\n".
+	header, err := c.out.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("cat-file %s: %s", sha, strings.TrimSpace(header))
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("cat-file %s: %s", sha, strings.TrimSpace(header))
+	}
+	data := make([]byte, size+1)
+	if _, err := io.ReadFull(c.out, data); err != nil {
+		return nil, err
+	}
+	return data[:size], nil
+}
+
+func (c *catFile) close() error {
+	_ = c.in.Close()
+	return c.wait()
+}
+
+// scanBranch scans the files on branch in the bare clone at dir, with the
+// scan config and value suppressions of that branch.
+func scanBranch(dir, branch string, opts scanOptions) ([]matchRow, error) {
+	blobs, err := branchBlobs(dir, branch)
+	if err != nil {
+		return nil, err
+	}
+	r, err := openBlobs(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.close() }()
+
+	filter, err := branchScanConfig(r, blobs, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for rel := range blobs {
+		if matchAny(rel, opts.excludes) {
+			tracef("skip %s:%s: excluded", branch, rel)
+			continue
+		}
+		if matchAny(rel, opts.includes) {
+			paths = append(paths, rel)
+		}
+	}
+	sort.Strings(paths)
+	debugf("%d file(s) on %s match the include patterns", len(paths), branch)
+
+	var rows []matchRow
+	for _, rel := range paths {
+		if canceled() {
+			break
+		}
+		tracef("scan %s:%s", branch, rel)
+		rows = append(rows, opts.cache.scan(blobs[rel], rel, func() []matchRow {
+			data, err := r.read(blobs[rel])
+			if err != nil {
+				warnf("failed to read %s on %s: %v", rel, branch, err)
+				return nil
+			}
+			return scanData(data, rel, opts)
+		})...)
+	}
+	rows = filter(rows)
+	for i := range rows {
+		rows[i].Branch = branch
+	}
+	return rows, nil
+}
+
+// branchScanConfig applies the repository config found at the top of a
+// branch to opts, and returns the filter of the branch's value
+// suppressions.
+func branchScanConfig(r blobReader, blobs map[string]string, opts *scanOptions) (func([]matchRow) []matchRow, error) {
+	files := map[string][]byte{}
+	for _, name := range append([]string{valueIgnoreFile}, repoConfigFiles...) {
+		sha, ok := blobs[name]
+		if !ok {
+			continue
+		}
+		data, err := r.read(sha)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		files[name] = data
+	}
+	if err := applyRepoScanConfigFiles(files, opts); err != nil {
+		return nil, err
+	}
+	ignores, err := parseValueIgnores(bytes.NewReader(files[valueIgnoreFile]))
+	if err != nil {
+		return nil, err
+	}
+	return newRowFilter(ignores, *opts), nil
+}
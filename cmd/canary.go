@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+)
+
+// rolloutStages returns the --env value of each stage of a canary
+// rollout: the canary environments, then the rest.
+func rolloutStages(canary, then string) ([]string, error) {
+	if then == "" {
+		return nil, usageErrorf("--canary needs --then with the environments to flip once the canary is done (e.g., --then prod)")
+	}
+	first := map[string]bool{}
+	for _, env := range splitCSV(canary, nil) {
+		first[env] = true
+	}
+	for _, env := range splitCSV(then, nil) {
+		if first[env] {
+			return nil, usageErrorf("environment %q is in both --canary and --then", env)
+		}
+	}
+	return []string{canary, then}, nil
+}
+
+// stageGate runs between two rollout stages: it waits, then asks whether
+// to go on unless yes is set or nobody is at a terminal to answer.
+func stageGate(done, next string, wait time.Duration, yes bool, p *prompter, sleep func(time.Duration)) error {
+	if wait > 0 {
+		infof("Flipped %s; waiting %s before %s (Ctrl-C to stop the rollout)", done, wait, next)
+		sleep(wait)
+	}
+	if yes || !p.interactive {
+		return nil
+	}
+	ok, err := p.confirm(fmt.Sprintf("Flipped %s. Continue with %s? [y/N]", done, next), "y")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("rollout stopped after %s; %s was not flipped", done, next)
+	}
+	return nil
+}
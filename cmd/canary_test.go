@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRolloutStages(t *testing.T) {
+	stages, err := rolloutStages("dev,qa", "prod")
+	if err != nil || strings.Join(stages, "|") != "dev,qa|prod" {
+		t.Errorf("rolloutStages = %v, %v", stages, err)
+	}
+	if _, err := rolloutStages("dev", ""); err == nil {
+		t.Error("Expected an error without --then")
+	}
+	if _, err := rolloutStages("dev,qa", "qa,prod"); err == nil || !strings.Contains(err.Error(), `"qa"`) {
+		t.Errorf("Expected an overlap error, got %v", err)
+	}
+}
+
+func TestStageGate(t *testing.T) {
+	var slept time.Duration
+	sleep := func(d time.Duration) { slept += d }
+	prompt := func(answer string) *prompter {
+		return &prompter{in: bufio.NewReader(strings.NewReader(answer)), out: io.Discard, interactive: true}
+	}
+
+	tests := []struct {
+		name    string
+		wait    time.Duration
+		yes     bool
+		p       *prompter
+		wantErr bool
+	}{
+		{"confirmed", 0, false, prompt("y\n"), false},
+		{"declined", 0, false, prompt("n\n"), true},
+		{"yes flag", 30 * time.Minute, true, prompt(""), false},
+		{"not a terminal", 0, false, &prompter{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slept = 0
+			err := stageGate("dev,qa", "prod", tt.wait, tt.yes, tt.p, sleep)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("stageGate = %v, wantErr %t", err, tt.wantErr)
+			}
+			if slept != tt.wait {
+				t.Errorf("slept %s, want %s", slept, tt.wait)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// opTimeout is the global --timeout flag; 0 means no limit.
+var opTimeout time.Duration
+
+// runCtx is the context of the running command. It is done when the user
+// presses Ctrl-C, the process gets SIGTERM or --timeout passes; clones,
+// git and gh commands, API calls and scans then stop, and the command
+// returns through its deferred cleanups, removing its temporary
+// directories.
+var runCtx = context.Background()
+
+// processWaitDelay is how long a git or gh process gets to exit after it
+// was interrupted before it is killed.
+const processWaitDelay = 5 * time.Second
+
+// withRunContext runs fn with runCtx cancelled on SIGINT and SIGTERM and,
+// if timeout is positive, after timeout. A second Ctrl-C kills the
+// process at once. If runCtx is done when fn returns, fn was cut short:
+// whatever partial results it printed stand, and the error says why.
+func withRunContext(timeout time.Duration, fn func() error) error {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	if timeout > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeoutCause(ctx, timeout, withExitCode(exitStopped, fmt.Errorf("timed out after %s (--timeout)", timeout)))
+		defer stop()
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	go func() {
+		select {
+		case s := <-sig:
+			signal.Stop(sig)
+			warnf("%s: stopping and cleaning up (press Ctrl-C again to quit now)", s)
+			cancel(withExitCode(exitStopped, fmt.Errorf("interrupted")))
+		case <-ctx.Done():
+		}
+	}()
+
+	old := runCtx
+	runCtx = ctx
+	defer func() { runCtx = old }()
+
+	err := fn()
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	return err
+}
+
+// cleanupTimeout bounds the cleanup a command does on its way out, such as
+// releasing a lock, once runCtx is done.
+const cleanupTimeout = 30 * time.Second
+
+// cleanupContext returns the context for that cleanup: it isn't cancelled
+// with runCtx, so an interrupted run still undoes what it did, but gives
+// up after cleanupTimeout.
+func cleanupContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(runCtx), cleanupTimeout)
+}
+
+// canceled reports whether the command was interrupted or timed out.
+func canceled() bool {
+	return runCtx.Err() != nil
+}
+
+// sleepCtx waits for d, or until runCtx is done.
+func sleepCtx(d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-runCtx.Done():
+	}
+}
+
+// command returns a command for name that is interrupted, then killed
+// after processWaitDelay, when runCtx is done.
+func command(dir, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(runCtx, name, args...)
+	cmd.Dir = dir
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = processWaitDelay
+	return cmd
+}
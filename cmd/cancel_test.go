@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withCanceledRun runs the rest of the test as if the user had pressed
+// Ctrl-C.
+func withCanceledRun(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errors.New("interrupted"))
+	old := runCtx
+	runCtx = ctx
+	t.Cleanup(func() { runCtx = old })
+}
+
+func TestWithRunContextTimeout(t *testing.T) {
+	tmp := t.TempDir()
+	start := time.Now()
+	err := withRunContext(200*time.Millisecond, func() error {
+		dir, err := os.MkdirTemp(tmp, "clone-")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = os.RemoveAll(dir) }()
+		return command("", "sleep", "30").Run()
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("took %s to stop a hung process", d)
+	}
+	if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+		t.Errorf("temp dirs left behind: %v", entries)
+	}
+	if canceled() {
+		t.Error("runCtx still done after the command returned")
+	}
+}
+
+func TestWithRunContext(t *testing.T) {
+	want := errors.New("boom")
+	if err := withRunContext(time.Minute, func() error { return want }); err != want {
+		t.Errorf("err = %v, want the command's own error", err)
+	}
+	if err := withRunContext(0, func() error { return nil }); err != nil {
+		t.Errorf("err = %v, want nil", err)
+	}
+}
+
+func TestCanceledScan(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "app.properties"), []byte("db.host=10.0.0.1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	withCanceledRun(t)
+	if rows := scanForIPPort(root, scanOptions{includes: []string{"**/*"}, jobs: 2}); len(rows) != 0 {
+		t.Errorf("scan after Ctrl-C = %v, want nothing more scanned", rows)
+	}
+
+	withRetries(t, 3)
+	calls := 0
+	_ = withRetry("test", func() error {
+		calls++
+		return errors.New("connection reset by peer")
+	})
+	if calls != 1 {
+		t.Errorf("retried %d times after Ctrl-C, want no retries", calls-1)
+	}
+}
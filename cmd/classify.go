@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Built-in finding labels.
+const (
+	labelPublicIP       = "public-ip"
+	labelPrivateIP      = "private-ip"
+	labelLoopback       = "loopback"
+	labelPrivilegedPort = "privileged-port"
+	labelTestFixture    = "test-fixture"
+)
+
+var severityColumn = rowColumn{Name: "severity", Header: "Severity",
+	Value: func(r matchRow) string { return strings.Join(r.Severity, ",") },
+	json:  func(r matchRow) any { return nonNil(r.Severity) }}
+
+// testDirs are path segments that mark a file as test data.
+var testDirs = map[string]bool{
+	"test": true, "tests": true, "testdata": true, "fixture": true, "fixtures": true,
+	"__tests__": true, "spec": true, "mocks": true,
+}
+
+// classifyRule is one line of a --classify-rules file: a label followed
+// by FIELD=VALUE conditions that must all hold for the label to apply.
+type classifyRule struct {
+	Label string
+	Line  int
+
+	cidr  netip.Prefix
+	ports portFilter
+	path  string // doublestar glob over the file path
+	key   string // case-insensitive glob over the IP or port key
+}
+
+func (c classifyRule) matches(r matchRow) bool {
+	if c.cidr.IsValid() {
+		addr, ok := rowAddr(r)
+		if !ok || !c.cidr.Contains(addr) {
+			return false
+		}
+	}
+	if len(c.ports) > 0 && (r.PortValue == "" || !c.ports.allows(r.PortValue)) {
+		return false
+	}
+	if c.path != "" {
+		if ok, _ := doublestar.Match(c.path, r.RelPath); !ok {
+			return false
+		}
+	}
+	if c.key != "" && !keyMatches(c.key, r.IPKey) && !keyMatches(c.key, r.PortKey) {
+		return false
+	}
+	return true
+}
+
+func keyMatches(pattern, key string) bool {
+	if key == "" {
+		return false
+	}
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(key))
+	return ok
+}
+
+// parseClassifyRules reads "LABEL FIELD=VALUE..." lines, where FIELD is
+// cidr, port (a LOW-HIGH range or single port), path or key.
+func parseClassifyRules(r io.Reader) ([]classifyRule, error) {
+	var out []classifyRule
+	s := bufio.NewScanner(r)
+	lineNo := 0
+	for s.Scan() {
+		lineNo++
+		line := s.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected LABEL FIELD=VALUE...", lineNo)
+		}
+		rule := classifyRule{Label: fields[0], Line: lineNo}
+		for _, cond := range fields[1:] {
+			field, value, ok := strings.Cut(cond, "=")
+			if !ok || value == "" {
+				return nil, fmt.Errorf("line %d: expected FIELD=VALUE, got %q", lineNo, cond)
+			}
+			switch strings.ToLower(field) {
+			case "cidr":
+				p, err := netip.ParsePrefix(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid cidr %q: %w", lineNo, value, err)
+				}
+				rule.cidr = p.Masked()
+			case "port":
+				ranges, ports := value, ""
+				if !strings.Contains(value, "-") {
+					ranges, ports = "", value
+				}
+				pf, err := parsePortFilter(ranges, ports)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNo, err)
+				}
+				rule.ports = append(rule.ports, pf...)
+			case "path":
+				if !doublestar.ValidatePattern(value) {
+					return nil, fmt.Errorf("line %d: invalid path pattern %q", lineNo, value)
+				}
+				rule.path = value
+			case "key":
+				if _, err := path.Match(value, ""); err != nil {
+					return nil, fmt.Errorf("line %d: invalid key pattern %q: %w", lineNo, value, err)
+				}
+				rule.key = value
+			default:
+				return nil, fmt.Errorf("line %d: unknown field %q (valid: cidr, port, path, key)", lineNo, field)
+			}
+		}
+		out = append(out, rule)
+	}
+	return out, s.Err()
+}
+
+func loadClassifyRules(file string) ([]classifyRule, error) {
+	if file == "" {
+		return nil, nil
+	}
+	fh, err := os.Open(file) // #nosec G304 - user-supplied rules file
+	if err != nil {
+		return nil, fmt.Errorf("failed to open classify rules: %w", err)
+	}
+	defer fh.Close()
+	rules, err := parseClassifyRules(fh)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return rules, nil
+}
+
+// builtinLabels applies the heuristics that need no configuration.
+func builtinLabels(r matchRow) []string {
+	var labels []string
+	if addr, ok := rowAddr(r); ok {
+		switch {
+		case addr.IsLoopback():
+			labels = append(labels, labelLoopback)
+		case isPublicAddr(addr):
+			labels = append(labels, labelPublicIP)
+		case addr.IsPrivate() || netip.MustParsePrefix("100.64.0.0/10").Contains(addr):
+			labels = append(labels, labelPrivateIP)
+		}
+	}
+	if n, err := strconv.Atoi(stripQuotes(r.PortValue)); err == nil && n > 0 && n < 1024 {
+		labels = append(labels, labelPrivilegedPort)
+	}
+	if isTestPath(r.RelPath) {
+		labels = append(labels, labelTestFixture)
+	}
+	return labels
+}
+
+// isTestPath reports whether a file lives in a test directory or is named
+// like a test file.
+func isTestPath(p string) bool {
+	segs := strings.Split(strings.ToLower(p), "/")
+	for _, s := range segs[:len(segs)-1] {
+		if testDirs[s] {
+			return true
+		}
+	}
+	base := segs[len(segs)-1]
+	return strings.Contains(base, "_test.") || strings.Contains(base, ".test.") || strings.Contains(base, ".spec.")
+}
+
+// classifyRows sets the Severity labels of each row: the built-in labels
+// followed by those of every matching user rule, without duplicates.
+func classifyRows(rows []matchRow, rules []classifyRule) {
+	for i := range rows {
+		labels := builtinLabels(rows[i])
+		for _, rule := range rules {
+			if rule.matches(rows[i]) && !containsLabel(labels, rule.Label) {
+				labels = append(labels, rule.Label)
+			}
+		}
+		rows[i].Severity = labels
+	}
+}
+
+func containsLabel(labels []string, want string) bool {
+	for _, l := range labels {
+		if strings.EqualFold(l, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterSeverity keeps rows carrying at least one of the wanted labels. An
+// empty list keeps every row.
+func filterSeverity(rows []matchRow, want []string) []matchRow {
+	if len(want) == 0 {
+		return rows
+	}
+	out := rows[:0]
+	for _, r := range rows {
+		for _, w := range want {
+			if containsLabel(r.Severity, w) {
+				out = append(out, r)
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuiltinLabels(t *testing.T) {
+	tests := []struct {
+		row  matchRow
+		want string
+	}{
+		{matchRow{IPValue: "8.8.8.8", PortValue: "53", RelPath: "app.env"}, "public-ip,privileged-port"},
+		{matchRow{IPValue: "10.0.0.5", PortValue: "5432", RelPath: "src/test/app.env"}, "private-ip,test-fixture"},
+		{matchRow{IPValue: "127.0.0.1", RelPath: "config.test.yml"}, "loopback,test-fixture"},
+		{matchRow{IPValue: "100.64.1.1", RelPath: "a.env"}, "private-ip"},
+		{matchRow{PortValue: "\"8080\"", RelPath: "testing/a.env"}, ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(builtinLabels(tt.row), ","); got != tt.want {
+			t.Errorf("builtinLabels(%+v) = %q, want %q", tt.row, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyRules(t *testing.T) {
+	rules, err := parseClassifyRules(strings.NewReader(`
+# label conditions
+legacy-dc cidr=10.20.0.0/16
+db-port port=5432 key=*db*
+public-ip path=legacy/**   # duplicate labels are dropped
+`))
+	if err != nil {
+		t.Fatalf("parseClassifyRules failed: %v", err)
+	}
+	rows := []matchRow{
+		{IPKey: "DB_HOST", IPValue: "10.20.1.1", PortKey: "DB_PORT", PortValue: "5432", RelPath: "app.env"},
+		{IPKey: "CACHE", IPValue: "10.30.1.1", PortValue: "5432", RelPath: "app.env"},
+		{IPValue: "8.8.8.8", RelPath: "legacy/x.env"},
+	}
+	classifyRows(rows, rules)
+	want := []string{"private-ip,legacy-dc,db-port", "private-ip", "public-ip"}
+	for i, r := range rows {
+		if got := strings.Join(r.Severity, ","); got != want[i] {
+			t.Errorf("row %d labels = %q, want %q", i, got, want[i])
+		}
+	}
+
+	if got := filterSeverity(rows, []string{"DB-PORT", "public-ip"}); len(got) != 2 || got[1].IPValue != "8.8.8.8" {
+		t.Errorf("Unexpected filtered rows: %+v", got)
+	}
+
+	for _, bad := range []string{"onlylabel", "x cidr=bogus", "x port=70000", "x color=red", "x key=["} {
+		if _, err := parseClassifyRules(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
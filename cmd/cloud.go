@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cloudCacheMaxAge is how long a downloaded IP range feed is reused before
+// it is fetched again.
+const cloudCacheMaxAge = 24 * time.Hour
+
+var cloudColumn = rowColumn{Name: "cloud", Header: "Cloud", Value: func(r matchRow) string { return r.Cloud }}
+
+// cloudRange is one published address block of a cloud provider.
+type cloudRange struct {
+	Prefix   netip.Prefix
+	Provider string
+	Service  string
+	Region   string
+}
+
+func (c cloudRange) label() string {
+	parts := []string{c.Provider}
+	if c.Service != "" {
+		parts = append(parts, c.Service)
+	}
+	if c.Region != "" {
+		parts = append(parts, c.Region)
+	}
+	return strings.Join(parts, "/")
+}
+
+// cloudFeed describes where a provider publishes its ranges and how to
+// parse them. Azure rotates its download URL weekly, so it has no default
+// and must be given with --cloud-feed azure=URL.
+type cloudFeed struct {
+	Provider string
+	URL      string
+	parse    func(data []byte) ([]cloudRange, error)
+}
+
+func defaultCloudFeeds() []cloudFeed {
+	return []cloudFeed{
+		{Provider: "aws", URL: "https://ip-ranges.amazonaws.com/ip-ranges.json", parse: parseAWSRanges},
+		{Provider: "gcp", URL: "https://www.gstatic.com/ipranges/cloud.json", parse: parseGCPRanges},
+		{Provider: "azure", parse: parseAzureRanges},
+	}
+}
+
+// parseCloudFeeds applies provider=URL overrides to the default feeds. A
+// URL may also be a local file path.
+func parseCloudFeeds(overrides []string) ([]cloudFeed, error) {
+	feeds := defaultCloudFeeds()
+	for _, o := range overrides {
+		provider, url, ok := strings.Cut(o, "=")
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		found := false
+		for i := range feeds {
+			if feeds[i].Provider == provider {
+				feeds[i].URL = strings.TrimSpace(url)
+				found = true
+			}
+		}
+		if !ok || !found {
+			return nil, usageErrorf("invalid --cloud-feed %q (want aws|gcp|azure=URL)", o)
+		}
+	}
+	return feeds, nil
+}
+
+func parseAWSRanges(data []byte) ([]cloudRange, error) {
+	var doc struct {
+		Prefixes []struct {
+			IPPrefix string `json:"ip_prefix"`
+			Region   string `json:"region"`
+			Service  string `json:"service"`
+		} `json:"prefixes"`
+		IPv6Prefixes []struct {
+			IPv6Prefix string `json:"ipv6_prefix"`
+			Region     string `json:"region"`
+			Service    string `json:"service"`
+		} `json:"ipv6_prefixes"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var out []cloudRange
+	add := func(prefix, service, region string) {
+		if p, err := netip.ParsePrefix(prefix); err == nil {
+			out = append(out, cloudRange{Prefix: p, Provider: "aws", Service: service, Region: region})
+		}
+	}
+	for _, p := range doc.Prefixes {
+		add(p.IPPrefix, p.Service, p.Region)
+	}
+	for _, p := range doc.IPv6Prefixes {
+		add(p.IPv6Prefix, p.Service, p.Region)
+	}
+	return out, nil
+}
+
+func parseGCPRanges(data []byte) ([]cloudRange, error) {
+	var doc struct {
+		Prefixes []struct {
+			IPv4Prefix string `json:"ipv4Prefix"`
+			IPv6Prefix string `json:"ipv6Prefix"`
+			Service    string `json:"service"`
+			Scope      string `json:"scope"`
+		} `json:"prefixes"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var out []cloudRange
+	for _, p := range doc.Prefixes {
+		prefix := p.IPv4Prefix
+		if prefix == "" {
+			prefix = p.IPv6Prefix
+		}
+		if pp, err := netip.ParsePrefix(prefix); err == nil {
+			out = append(out, cloudRange{Prefix: pp, Provider: "gcp", Service: p.Service, Region: p.Scope})
+		}
+	}
+	return out, nil
+}
+
+func parseAzureRanges(data []byte) ([]cloudRange, error) {
+	var doc struct {
+		Values []struct {
+			Name       string `json:"name"`
+			Properties struct {
+				Region          string   `json:"region"`
+				SystemService   string   `json:"systemService"`
+				AddressPrefixes []string `json:"addressPrefixes"`
+			} `json:"properties"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var out []cloudRange
+	for _, v := range doc.Values {
+		service := v.Properties.SystemService
+		if service == "" {
+			service = v.Name
+		}
+		for _, prefix := range v.Properties.AddressPrefixes {
+			if p, err := netip.ParsePrefix(prefix); err == nil {
+				out = append(out, cloudRange{Prefix: p, Provider: "azure", Service: service, Region: v.Properties.Region})
+			}
+		}
+	}
+	return out, nil
+}
+
+// fetchURL downloads a feed, or reads it from disk when url is a path.
+func fetchURL(url string) ([]byte, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return os.ReadFile(url)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// loadCloudRanges returns the ranges of all configured feeds. Each feed is
+// cached under cacheDir and refetched once older than maxAge; if a refresh
+// fails, a stale cache is used with a warning rather than failing the scan.
+func loadCloudRanges(feeds []cloudFeed, cacheDir string, maxAge time.Duration, fetch func(string) ([]byte, error)) []cloudRange {
+	var all []cloudRange
+	for _, f := range feeds {
+		if f.URL == "" {
+			continue
+		}
+		cachePath := filepath.Join(cacheDir, "cloud-"+f.Provider+".json")
+		data, err := os.ReadFile(cachePath)
+		fresh := false
+		if info, statErr := os.Stat(cachePath); err == nil && statErr == nil {
+			fresh = time.Since(info.ModTime()) < maxAge
+		}
+
+		if !fresh {
+			fetched, fetchErr := fetch(f.URL)
+			switch {
+			case fetchErr == nil:
+				data, err = fetched, nil
+				if mkErr := os.MkdirAll(cacheDir, 0750); mkErr == nil {
+					_ = os.WriteFile(cachePath, data, 0600)
+				}
+			case err == nil:
+				warnf("%s ranges: %v (using cached copy)", f.Provider, fetchErr)
+			default:
+				warnf("%s ranges: %v", f.Provider, fetchErr)
+				continue
+			}
+		}
+
+		ranges, err := f.parse(data)
+		if err != nil {
+			warnf("%s ranges: %v", f.Provider, err)
+			continue
+		}
+		all = append(all, ranges...)
+	}
+	return all
+}
+
+// isPublicAddr reports whether addr is globally routable.
+func isPublicAddr(addr netip.Addr) bool {
+	if !addr.IsValid() || addr.IsPrivate() || addr.IsLoopback() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	// Carrier-grade NAT (RFC 6598) is not covered by IsPrivate.
+	return !netip.MustParsePrefix("100.64.0.0/10").Contains(addr)
+}
+
+// attributeCloud sets the Cloud field of rows with a public IP to the most
+// specific matching provider range, or to "public" when no provider
+// publishes the address. Private addresses are left untouched.
+func attributeCloud(rows []matchRow, ranges []cloudRange) {
+	// Longest prefix first so the first match is the most specific; AWS
+	// "AMAZON" umbrella entries sort after their service-specific twins.
+	sorted := append([]cloudRange(nil), ranges...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Prefix.Bits() != sorted[j].Prefix.Bits() {
+			return sorted[i].Prefix.Bits() > sorted[j].Prefix.Bits()
+		}
+		return sorted[i].Service != "AMAZON" && sorted[j].Service == "AMAZON"
+	})
+
+	labels := map[netip.Addr]string{}
+	for i := range rows {
+		addr, ok := rowAddr(rows[i])
+		if !ok || !isPublicAddr(addr) {
+			continue
+		}
+		label, seen := labels[addr]
+		if !seen {
+			label = "public"
+			for _, r := range sorted {
+				if r.Prefix.Contains(addr) {
+					label = r.label()
+					break
+				}
+			}
+			labels[addr] = label
+		}
+		rows[i].Cloud = label
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	awsFeed   = `{"prefixes":[{"ip_prefix":"3.5.0.0/16","region":"us-east-1","service":"AMAZON"},{"ip_prefix":"3.5.0.0/19","region":"us-east-1","service":"S3"}],"ipv6_prefixes":[{"ipv6_prefix":"2600:1f00::/24","region":"GLOBAL","service":"AMAZON"}]}`
+	gcpFeed   = `{"prefixes":[{"ipv4Prefix":"34.1.0.0/20","service":"Google Cloud","scope":"europe-west1"},{"ipv6Prefix":"2600:1900::/28","service":"Google Cloud","scope":"us-east1"}]}`
+	azureFeed = `{"values":[{"name":"AzureCloud.westeurope","properties":{"region":"westeurope","systemService":"","addressPrefixes":["13.69.0.0/17","bad"]}}]}`
+)
+
+func TestParseCloudFeeds(t *testing.T) {
+	tests := []struct {
+		name   string
+		parse  func([]byte) ([]cloudRange, error)
+		data   string
+		want   int
+		sample string
+	}{
+		{"aws", parseAWSRanges, awsFeed, 3, "aws/AMAZON/us-east-1"},
+		{"gcp", parseGCPRanges, gcpFeed, 2, "gcp/Google Cloud/europe-west1"},
+		{"azure", parseAzureRanges, azureFeed, 1, "azure/AzureCloud.westeurope/westeurope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("Expected %d ranges, got %d: %+v", tt.want, len(got), got)
+			}
+			if got[0].label() != tt.sample {
+				t.Errorf("label() = %q, want %q", got[0].label(), tt.sample)
+			}
+		})
+	}
+
+	if _, err := parseCloudFeeds([]string{"aws=/tmp/aws.json", "azure=https://example.com/tags.json"}); err != nil {
+		t.Errorf("Unexpected error for valid overrides: %v", err)
+	}
+	for _, bad := range []string{"oracle=x", "aws"} {
+		if _, err := parseCloudFeeds([]string{bad}); err == nil {
+			t.Errorf("Expected error for --cloud-feed %q", bad)
+		}
+	}
+}
+
+func TestAttributeCloud(t *testing.T) {
+	aws, _ := parseAWSRanges([]byte(awsFeed))
+	gcp, _ := parseGCPRanges([]byte(gcpFeed))
+	rows := []matchRow{
+		{IPValue: "3.5.1.10"},
+		{IPValue: "3.5.200.1"},
+		{IPValue: "https://34.1.2.3/api"},
+		{IPValue: "8.8.8.8"},
+		{IPValue: "10.0.0.5"},
+		{IPValue: "100.64.1.1"},
+		{PortValue: "443"},
+	}
+
+	attributeCloud(rows, append(aws, gcp...))
+
+	want := []string{"aws/S3/us-east-1", "aws/AMAZON/us-east-1", "gcp/Google Cloud/europe-west1", "public", "", "", ""}
+	for i, w := range want {
+		if rows[i].Cloud != w {
+			t.Errorf("row %d (%q) Cloud = %q, want %q", i, rows[i].IPValue, rows[i].Cloud, w)
+		}
+	}
+}
+
+func TestLoadCloudRanges_Cache(t *testing.T) {
+	cacheDir := t.TempDir()
+	feeds := []cloudFeed{
+		{Provider: "aws", URL: "https://aws.example/ranges.json", parse: parseAWSRanges},
+		{Provider: "azure", parse: parseAzureRanges},
+	}
+
+	fetches := 0
+	fetch := func(url string) ([]byte, error) {
+		fetches++
+		return []byte(awsFeed), nil
+	}
+	if got := loadCloudRanges(feeds, cacheDir, time.Hour, fetch); len(got) != 3 {
+		t.Fatalf("Expected 3 ranges, got %d", len(got))
+	}
+	if got := loadCloudRanges(feeds, cacheDir, time.Hour, fetch); len(got) != 3 {
+		t.Fatalf("Expected 3 cached ranges, got %d", len(got))
+	}
+	if fetches != 1 {
+		t.Errorf("Expected feed to be fetched once and then cached, got %d fetches", fetches)
+	}
+
+	// A failed refresh falls back to the stale cache.
+	failing := func(string) ([]byte, error) { return nil, errors.New("offline") }
+	if got := loadCloudRanges(feeds, cacheDir, 0, failing); len(got) != 3 {
+		t.Errorf("Expected stale cache to be used, got %d ranges", len(got))
+	}
+
+	os.Remove(filepath.Join(cacheDir, "cloud-aws.json"))
+	if got := loadCloudRanges(feeds, cacheDir, 0, failing); len(got) != 0 {
+		t.Errorf("Expected no ranges without cache or network, got %d", len(got))
+	}
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// noCodeowners is the global --no-codeowners flag.
+var noCodeowners bool
+
+// codeownersFiles are where GitHub looks for CODEOWNERS, in its order.
+var codeownersFiles = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// codeownersRule is one line of a CODEOWNERS file.
+type codeownersRule struct {
+	pattern string
+	owners  []string
+}
+
+// parseCodeowners reads the rules of a CODEOWNERS file in file order.
+func parseCodeowners(content string) []codeownersRule {
+	var rules []codeownersRule
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		rules = append(rules, codeownersRule{pattern: fields[0], owners: fields[1:]})
+	}
+	return rules
+}
+
+// matches reports whether the rule's pattern, with gitignore semantics,
+// matches the slash-separated path rel.
+func (r codeownersRule) matches(rel string) bool {
+	p := r.pattern
+	dir := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	if !strings.HasPrefix(p, "/") && !strings.Contains(p, "/") {
+		p = "**/" + p // a bare name matches at any depth
+	}
+	p = strings.TrimPrefix(p, "/")
+	if ok, _ := doublestar.Match(p+"/**", rel); ok {
+		return true
+	}
+	ok, _ := doublestar.Match(p, rel)
+	return ok && !dir
+}
+
+// codeowners returns the reviewers CODEOWNERS assigns to files: for each
+// file the owners of the last matching rule, as user logins and ORG/TEAM
+// slugs. Owners given by email can't be requested and are left out.
+func codeowners(rules []codeownersRule, files []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, f := range files {
+		f = filepath.ToSlash(f)
+		var owners []string
+		for _, r := range rules {
+			if r.matches(f) {
+				owners = r.owners
+			}
+		}
+		for _, o := range owners {
+			login, ok := strings.CutPrefix(o, "@")
+			if !ok || seen[strings.ToLower(login)] {
+				continue
+			}
+			seen[strings.ToLower(login)] = true
+			out = append(out, login)
+		}
+	}
+	return out
+}
+
+// codeownersReviewers returns the reviewers to request for a pull request
+// changing files, reading CODEOWNERS with read. It is empty with
+// --no-codeowners, without a CODEOWNERS file, and never names the pull
+// request's author, as returned by login, whom GitHub refuses as a
+// reviewer.
+func codeownersReviewers(read func(rel string) ([]byte, error), files []string, login func() string) []string {
+	if noCodeowners {
+		return nil
+	}
+	for _, rel := range codeownersFiles {
+		b, err := read(rel)
+		if err != nil {
+			continue
+		}
+		owners := codeowners(parseCodeowners(string(b)), files)
+		if len(owners) == 0 {
+			return nil
+		}
+		author := login()
+		var out []string
+		for _, r := range owners {
+			if !strings.EqualFold(r, author) {
+				out = append(out, r)
+			}
+		}
+		if len(out) > 0 {
+			infof("Requesting review from code owners: %s", strings.Join(out, ", "))
+		}
+		return out
+	}
+	return nil
+}
+
+// readIn returns a reader of files under dir for codeownersReviewers.
+func readIn(dir string) func(rel string) ([]byte, error) {
+	return func(rel string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel))) // #nosec G304 - rel is one of codeownersFiles
+	}
+}
+
+// ghLogin returns the login gh is authenticated as, or "" if unknown.
+func ghLogin() string {
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := ghAPI("GET", "user", nil, &user); err != nil {
+		return ""
+	}
+	return user.Login
+}
+
+// withReviewers adds extra to reviewers, skipping ones already there.
+func withReviewers(reviewers, extra []string) []string {
+	out := append([]string(nil), reviewers...)
+	for _, r := range extra {
+		dup := false
+		for _, have := range out {
+			if strings.EqualFold(have, r) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCodeowners(t *testing.T) {
+	rules := parseCodeowners(`# Owners
+*                       @org/platform
+env/                    @org/config-team   # everything under env/
+/env/prod/              @org/sre @alice ops@example.com
+parameters.properties   @bob
+/env/*/parameters.json  @carol
+docs/**/*.md            @writer
+`)
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{[]string{"env/dev/parameters.properties"}, "bob"},
+		{[]string{"env/prod/app.yaml"}, "org/sre,alice"},
+		{[]string{"env/dev/parameters.json"}, "carol"},
+		{[]string{"env/dev/other.txt"}, "org/config-team"},
+		{[]string{"README.md"}, "org/platform"},
+		{[]string{"docs/a/b.md"}, "writer"},
+		{[]string{"env/prod/app.yaml", "env/dev/other.txt", "env/prod/x"}, "org/sre,alice,org/config-team"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(codeowners(rules, tt.files), ","); got != tt.want {
+			t.Errorf("codeowners(%v) = %q, want %q", tt.files, got, tt.want)
+		}
+	}
+}
+
+func TestCodeownersReviewers(t *testing.T) {
+	dir := t.TempDir()
+	login := func() string { return "alice" }
+	files := []string{"env/prod/parameters.properties"}
+	if got := codeownersReviewers(readIn(dir), files, login); got != nil {
+		t.Errorf("without CODEOWNERS = %v", got)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, ".github"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".github", "CODEOWNERS"), []byte("/env/prod/ @org/sre @Alice\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "CODEOWNERS"), []byte("* @ignored\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(codeownersReviewers(readIn(dir), files, login), ","); got != "org/sre" {
+		t.Errorf("reviewers = %q, want org/sre (the author is left out)", got)
+	}
+
+	noCodeowners = true
+	defer func() { noCodeowners = false }()
+	if got := codeownersReviewers(readIn(dir), files, login); got != nil {
+		t.Errorf("with --no-codeowners = %v", got)
+	}
+}
+
+func TestWithReviewers(t *testing.T) {
+	if got := strings.Join(withReviewers([]string{"bob"}, []string{"Bob", "org/sre"}), ","); got != "bob,org/sre" {
+		t.Errorf("withReviewers = %q", got)
+	}
+}
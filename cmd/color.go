@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+)
+
+// noColor is set by the global --no-color flag.
+var noColor bool
+
+// colorEnabled reports whether table output to w should be colorized: only
+// for terminals, and never with --no-color, NO_COLOR (https://no-color.org)
+// or TERM=dumb.
+func colorEnabled(w io.Writer) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && isTerminal(f)
+}
+
+// paint returns cell wrapped in its color: bold for the header row, or the
+// table's style for body cells.
+func (t *table) paint(color bool, row, col int, cell string) string {
+	if !color || cell == "" {
+		return cell
+	}
+	code := ansiBold
+	if row > 0 {
+		if t.style == nil {
+			return cell
+		}
+		if code = t.style(row, col, cell); code == "" {
+			return cell
+		}
+	}
+	return code + cell + ansiReset
+}
+
+// findingStyle highlights public IPs in red and privileged ports (below
+// 1024) in yellow.
+func findingStyle(cols []rowColumn) func(row, col int, cell string) string {
+	return func(_, col int, cell string) string {
+		if col >= len(cols) {
+			return ""
+		}
+		switch cols[col].Name {
+		case "ipValue":
+			if addr, err := netip.ParseAddr(firstIP(cell)); err == nil && isPublicAddr(addr.Unmap()) {
+				return ansiRed
+			}
+		case "portValue":
+			if n, err := strconv.Atoi(cell); err == nil && n < 1024 {
+				return ansiYellow
+			}
+		}
+		return ""
+	}
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestColorEnabled(t *testing.T) {
+	if colorEnabled(&bytes.Buffer{}) {
+		t.Error("Expected no color for non-terminal writers")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if colorEnabled(&bytes.Buffer{}) {
+		t.Error("Expected NO_COLOR to disable color")
+	}
+}
+
+func TestTablePaint(t *testing.T) {
+	cols := defaultColumns()
+	tbl := newTable()
+	tbl.style = findingStyle(cols)
+
+	tests := []struct {
+		row, col int
+		cell     string
+		want     string
+	}{
+		{0, 1, "IP Value", ansiBold + "IP Value" + ansiReset},
+		{1, 1, "8.8.8.8", ansiRed + "8.8.8.8" + ansiReset},
+		{1, 1, "http://8.8.4.4:80/", ansiRed + "http://8.8.4.4:80/" + ansiReset},
+		{1, 1, "10.0.0.5", "10.0.0.5"},
+		{1, 3, "443", ansiYellow + "443" + ansiReset},
+		{1, 3, "8443", "8443"},
+		{1, 0, "db.host", "db.host"},
+		{1, 1, "", ""},
+	}
+	for _, tt := range tests {
+		if got := tbl.paint(true, tt.row, tt.col, tt.cell); got != tt.want {
+			t.Errorf("paint(%d, %d, %q) = %q, want %q", tt.row, tt.col, tt.cell, got, tt.want)
+		}
+	}
+	if got := tbl.paint(false, 1, 1, "8.8.8.8"); got != "8.8.8.8" {
+		t.Errorf("Expected no color when disabled, got %q", got)
+	}
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// rowColumn is one printable field of a matchRow. Name is the --columns
+// and JSON key; Header is used for CSV, table and spreadsheet output.
+type rowColumn struct {
+	Name   string
+	Header string
+	Value  func(matchRow) string
+
+	short  string // table header if different from Header
+	number bool   // encode as a number in JSON and spreadsheets
+
+	// json, if set, gives the JSON value for columns that are not plain
+	// strings or numbers.
+	json func(matchRow) any
+}
+
+func (c rowColumn) tableHeader() string {
+	if c.short != "" {
+		return c.short
+	}
+	return c.Header
+}
+
+// defaultColumns are printed when --columns is not given; enrichment
+// columns are appended after them when their flag is set.
+func defaultColumns() []rowColumn {
+	return []rowColumn{
+		{Name: "ipKey", Header: "IP Key", Value: func(r matchRow) string { return r.IPKey }},
+		{Name: "ipValue", Header: "IP Value", Value: func(r matchRow) string { return r.IPValue }},
+		{Name: "portKey", Header: "Port Key", Value: func(r matchRow) string { return r.PortKey }},
+		{Name: "portValue", Header: "Port Value", Value: func(r matchRow) string { return r.PortValue }},
+		{Name: "filePath", Header: "File Path", Value: func(r matchRow) string { return r.RelPath }},
+		{Name: "lineNumber", Header: "Line Number", short: "Line", number: true,
+			Value: func(r matchRow) string { return strconv.Itoa(r.LineNumber) }},
+	}
+}
+
+var branchColumn = rowColumn{Name: "branch", Header: "Branch", Value: func(r matchRow) string { return r.Branch }}
+
+// allColumns lists every column --columns accepts.
+func allColumns() []rowColumn {
+	return append(defaultColumns(), branchColumn, ptrColumn, cloudColumn, hostnameColumn, dnsColumn, probeColumn, severityColumn,
+		lineColumn, beforeColumn, afterColumn)
+}
+
+// parseColumns resolves a comma-separated --columns list, matching names
+// case-insensitively. An empty spec returns nil, meaning the defaults.
+func parseColumns(spec string) ([]rowColumn, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	known := allColumns()
+	var out []rowColumn
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, c := range known {
+			if strings.EqualFold(c.Name, name) {
+				out = append(out, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			names := make([]string, len(known))
+			for i, c := range known {
+				names[i] = c.Name
+			}
+			return nil, usageErrorf("unknown column %q (valid: %s)", name, strings.Join(names, ", "))
+		}
+	}
+	if len(out) == 0 {
+		return nil, usageErrorf("--columns must name at least one column")
+	}
+	return out, nil
+}
+
+// pickColumns returns the user's selection, or the default columns followed
+// by the enrichment columns in extra.
+func pickColumns(selected, extra []rowColumn) []rowColumn {
+	if selected != nil {
+		return selected
+	}
+	return append(defaultColumns(), extra...)
+}
+
+// columnsJSON encodes rows as an indented JSON array of objects holding
+// only cols, with keys in column order.
+func columnsJSON(rows []matchRow, cols []rowColumn) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('[')
+	for i, r := range rows {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(columnsObject(r, cols))
+	}
+	b.WriteByte(']')
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, b.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// columnsObject encodes one row as a compact JSON object of cols.
+func columnsObject(r matchRow, cols []rowColumn) []byte {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for j, c := range cols {
+		if j > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(c.Name)
+		b.Write(key)
+		b.WriteByte(':')
+		if c.json != nil {
+			val, _ := json.Marshal(c.json(r))
+			b.Write(val)
+			continue
+		}
+		v := c.Value(r)
+		if c.number {
+			b.WriteString(v)
+		} else {
+			val, _ := json.Marshal(v)
+			b.Write(val)
+		}
+	}
+	b.WriteByte('}')
+	return b.Bytes()
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseColumns(t *testing.T) {
+	cols, err := parseColumns(" ipValue, PORTVALUE ,filePath,ptr")
+	if err != nil {
+		t.Fatalf("parseColumns failed: %v", err)
+	}
+	var names []string
+	for _, c := range cols {
+		names = append(names, c.Name)
+	}
+	if strings.Join(names, ",") != "ipValue,portValue,filePath,ptr" {
+		t.Errorf("Unexpected columns: %v", names)
+	}
+
+	if cols, err := parseColumns(""); err != nil || cols != nil {
+		t.Errorf("Expected nil columns for empty spec, got %v, %v", cols, err)
+	}
+	for _, bad := range []string{"ipValue,bogus", " , "} {
+		if _, err := parseColumns(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestColumnsJSON(t *testing.T) {
+	rows := []matchRow{
+		{IPKey: "db.host", IPValue: "10.0.0.5", PortValue: "5432", RelPath: "a \"quoted\".env", LineNumber: 7},
+	}
+
+	// The default columns encode exactly like the struct itself.
+	got, err := columnsJSON(rows, defaultColumns())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.MarshalIndent(rows, "", "  ")
+	if string(got) != string(want)+"\n" {
+		t.Errorf("Default columns JSON =\n%s\nwant\n%s", got, want)
+	}
+
+	cols, _ := parseColumns("lineNumber,ipValue")
+	got, err = columnsJSON(rows, cols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compact := strings.Join(strings.Fields(string(got)), ""); compact != `[{"lineNumber":7,"ipValue":"10.0.0.5"}]` {
+		t.Errorf("Selected columns JSON = %s", compact)
+	}
+
+	if got, _ := columnsJSON(nil, cols); strings.TrimSpace(string(got)) != "[]" {
+		t.Errorf("Expected empty array for no rows, got %s", got)
+	}
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/spf13/cobra"
+)
+
+// envMatrix holds adapter values per environment; a nil value means the
+// adapter is not in that environment's file.
+type envMatrix struct {
+	Envs     []string    `json:"envs"`
+	Adapters []matrixRow `json:"adapters"`
+}
+
+// matrixRow is one adapter across the environments of an envMatrix.
+type matrixRow struct {
+	Adapter string             `json:"adapter"`
+	Values  map[string]*string `json:"values"`
+	Differs bool               `json:"differs"`
+}
+
+// buildEnvMatrix lines up values read per environment. Without want, the
+// rows are the union of all keys in order of first appearance.
+func buildEnvMatrix(envs []string, values [][]adapterValue, want []string) envMatrix {
+	m := envMatrix{Envs: envs, Adapters: make([]matrixRow, 0)}
+	byEnv := make([]map[string]string, len(envs))
+	seen := map[string]bool{}
+	for _, a := range want {
+		seen[a] = true
+	}
+	for i, vs := range values {
+		byEnv[i] = map[string]string{}
+		for _, v := range vs {
+			if v.Missing {
+				continue
+			}
+			byEnv[i][v.Adapter] = v.Value
+			if !seen[v.Adapter] {
+				seen[v.Adapter] = true
+				want = append(want, v.Adapter)
+			}
+		}
+	}
+	for _, a := range want {
+		row := matrixRow{Adapter: a, Values: map[string]*string{}}
+		for i, env := range envs {
+			var value *string
+			if v, ok := byEnv[i][a]; ok {
+				value = &v
+			}
+			row.Values[env] = value
+			if first := row.Values[envs[0]]; (first == nil) != (value == nil) || (value != nil && *first != *value) {
+				row.Differs = true
+			}
+		}
+		m.Adapters = append(m.Adapters, row)
+	}
+	return m
+}
+
+// differing returns m with only the rows whose values are not the same in
+// every environment.
+func (m envMatrix) differing() envMatrix {
+	out := envMatrix{Envs: m.Envs, Adapters: make([]matrixRow, 0)}
+	for _, r := range m.Adapters {
+		if r.Differs {
+			out.Adapters = append(out.Adapters, r)
+		}
+	}
+	return out
+}
+
+// resolveEnvList resolves each environment name or glob in patterns,
+// keeping the first occurrence of every environment.
+func resolveEnvList(root string, patterns []string) ([]string, error) {
+	var envs []string
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		matched, err := resolveEnvs(root, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, env := range matched {
+			if !seen[env] {
+				seen[env] = true
+				envs = append(envs, env)
+			}
+		}
+	}
+	return envs, nil
+}
+
+func cmdCompareEnvs() *cobra.Command {
+	var repo, ref, envsCSV, adaptersCSV, paramFile, mode string
+	var diffOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "compare-envs",
+		Short: "Show adapter values side by side for each environment and highlight differences",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if repo == "" {
+				return usageErrorf("--repo ORG/REPO is required")
+			}
+			if err := checkParamFile(paramFile); err != nil {
+				return err
+			}
+			modeVal := parseMode(mode, outTable)
+
+			tmpDir, cleanup, err := cloneOrDownload(repo, ref)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			envs, err := resolveEnvList(tmpDir, splitCSV(envsCSV, []string{"*"}))
+			if err != nil {
+				return err
+			}
+
+			want := splitCSV(adaptersCSV, nil)
+			values := make([][]adapterValue, len(envs))
+			for i, env := range envs {
+				values[i], err = readEnvValues(tmpDir, env, paramFile, want)
+				if errors.Is(err, fs.ErrNotExist) {
+					warnf("env/%s has no %s", env, paramFile)
+					continue
+				}
+				if err != nil {
+					return err
+				}
+			}
+			matrix := buildEnvMatrix(envs, values, want)
+			if diffOnly {
+				matrix = matrix.differing()
+			}
+			return printEnvMatrix(matrix, modeVal)
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Target repo as ORG/REPO (required)")
+	cmd.Flags().StringVar(&ref, "ref", "", "Branch or tag (default: default branch)")
+	cmd.Flags().StringVar(&envsCSV, "env", "*", "Comma-separated environments or globs, in column order (default: all under env/)")
+	cmd.Flags().StringVar(&adaptersCSV, "adapters", "", "Comma-separated adapter keys (default: every key in any environment)")
+	cmd.Flags().StringVar(&paramFile, "file", "parameters.properties", "Parameters file in each env directory; .json files take JSON pointers as adapters")
+	cmd.Flags().BoolVar(&diffOnly, "diff-only", false, "Only show adapters whose values differ between environments")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table|json|csv")
+	setOutputModes(cmd, "table", "json", "csv")
+	return cmd
+}
+
+func printEnvMatrix(m envMatrix, mode outputMode) error {
+	cell := func(v *string) string {
+		if v == nil {
+			return "-"
+		}
+		return *v
+	}
+	switch mode {
+	case outJSON:
+		enc := json.NewEncoder(stdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(m)
+	case outCSV:
+		fmt.Fprint(stdout(), "adapter")
+		for _, env := range m.Envs {
+			fmt.Fprint(stdout(), ","+csvEsc(env))
+		}
+		fmt.Fprintln(stdout(), ",differs")
+		for _, r := range m.Adapters {
+			fmt.Fprint(stdout(), csvEsc(r.Adapter))
+			for _, env := range m.Envs {
+				fmt.Fprint(stdout(), ","+csvEsc(cell(r.Values[env])))
+			}
+			fmt.Fprintf(stdout(), ",%t\n", r.Differs)
+		}
+		return nil
+	}
+
+	w := newTable()
+	w.AddRow(append([]string{"", "Adapter"}, m.Envs...)...)
+	differs := 0
+	for _, r := range m.Adapters {
+		mark := ""
+		if r.Differs {
+			mark = "*"
+			differs++
+		}
+		cols := []string{mark, r.Adapter}
+		for _, env := range m.Envs {
+			cols = append(cols, cell(r.Values[env]))
+		}
+		w.AddRow(cols...)
+	}
+	// Differing rows are marked with * and, on a terminal, their values
+	// are shown in yellow.
+	w.style = func(row, col int, _ string) string {
+		if col >= 2 && m.Adapters[row-1].Differs {
+			return ansiYellow
+		}
+		return ""
+	}
+	w.Render()
+	fmt.Fprintf(stdout(), "\n%d of %d adapter(s) differ across %d environment(s).\n", differs, len(m.Adapters), len(m.Envs))
+	return nil
+}
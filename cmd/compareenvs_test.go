@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildEnvMatrix(t *testing.T) {
+	envs := []string{"dev", "qa", "prod"}
+	values := [][]adapterValue{
+		{{Adapter: "billing", Value: "1"}, {Adapter: "search", Value: "0"}},
+		{{Adapter: "billing", Value: "1"}, {Adapter: "search", Value: "1"}, {Adapter: "crm", Value: "on"}},
+		nil, // prod has no parameters file
+	}
+
+	render := func(m envMatrix) string {
+		var rows []string
+		for _, r := range m.Adapters {
+			cells := []string{r.Adapter}
+			for _, env := range m.Envs {
+				if v := r.Values[env]; v != nil {
+					cells = append(cells, *v)
+				} else {
+					cells = append(cells, "-")
+				}
+			}
+			if r.Differs {
+				cells = append(cells, "*")
+			}
+			rows = append(rows, strings.Join(cells, " "))
+		}
+		return strings.Join(rows, "; ")
+	}
+
+	m := buildEnvMatrix(envs, values, nil)
+	if got := render(m); got != "billing 1 1 - *; search 0 1 - *; crm - on - *" {
+		t.Errorf("matrix = %s", got)
+	}
+	if got := render(m.differing()); got != render(m) {
+		t.Errorf("differing = %s", got)
+	}
+
+	wanted := [][]adapterValue{
+		{{Adapter: "search", Value: "1"}, {Adapter: "billing", Value: "1"}, {Adapter: "gone", Missing: true}},
+		{{Adapter: "search", Value: "0"}, {Adapter: "billing", Value: "1"}, {Adapter: "gone", Missing: true}},
+	}
+	m = buildEnvMatrix(envs[:2], wanted, []string{"search", "billing", "gone"})
+	if got := render(m); got != "search 1 0 *; billing 1 1; gone - -" {
+		t.Errorf("matrix with --adapters = %s", got)
+	}
+	if got := render(m.differing()); got != "search 1 0 *" {
+		t.Errorf("differing = %s", got)
+	}
+}
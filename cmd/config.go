@@ -0,0 +1,560 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// globalConfigFile lives in the tool's config directory; repoConfigFiles are
+// looked up in the root of a repository, in order of precedence.
+const globalConfigFile = "config.yml"
+
+var repoConfigFiles = []string{".aca.yaml", ".aca.yml"}
+
+type configScope int
+
+const (
+	scopeGlobal configScope = 1 << iota
+	scopeRepo
+	scopeAll = scopeGlobal | scopeRepo
+)
+
+func (s configScope) String() string {
+	switch s {
+	case scopeGlobal:
+		return "global config"
+	case scopeRepo:
+		return "repository config"
+	}
+	return "any config"
+}
+
+// configKey describes one supported setting. Nested YAML mappings address
+// keys by their dotted path, e.g. scan.include.
+type configKey struct {
+	Path       string
+	Type       string // string, bool, int, list or enum
+	Enum       []string
+	Default    any
+	Scope      configScope
+	Deprecated string // replacement key for deprecated settings
+}
+
+var configSchema = []configKey{
+	{Path: "output", Type: "enum", Enum: []string{"csv", "table", "json", "xlsx", "junit", "ndjson", "jsonl"}, Default: "csv", Scope: scopeGlobal},
+	{Path: "scan.include", Type: "list", Default: splitCSV(defaultIncludes, nil), Scope: scopeAll},
+	{Path: "scan.exclude", Type: "list", Default: splitCSV(defaultExcludes, nil), Scope: scopeAll},
+	{Path: "scan.strictIP", Type: "bool", Default: false, Scope: scopeAll},
+	{Path: "protect.environments", Type: "list", Default: []string{}, Scope: scopeAll},
+	{Path: "protect.typeName", Type: "bool", Default: false, Scope: scopeAll},
+	{Path: "protect.approval", Type: "enum", Enum: []string{approvalNone, approvalToken, approvalEnvironment}, Default: approvalNone, Scope: scopeAll},
+	{Path: "protect.approvalEnvironment", Type: "string", Default: "", Scope: scopeAll},
+	{Path: "toggle.pairs", Type: "pairs", Default: []togglePair{}, Scope: scopeAll},
+	{Path: "toggle.adapters", Type: "adapterPairs", Default: map[string]togglePair{}, Scope: scopeAll},
+	{Path: "adapters.constraints", Type: "constraints", Default: map[string]adapterConstraint{}, Scope: scopeRepo},
+	{Path: "adapters.remote", Type: "string", Default: "", Scope: scopeGlobal},
+	{Path: "adapters.file", Type: "string", Default: "", Scope: scopeRepo},
+	{Path: "policy.file", Type: "string", Default: "", Scope: scopeRepo},
+	{Path: "defaults.org", Type: "string", Default: "", Scope: scopeGlobal},
+	{Path: "concurrency", Type: "int", Default: 0, Scope: scopeGlobal},
+}
+
+// configConflicts report settings that are individually valid but contradict
+// each other in the effective configuration.
+var configConflicts = []func(eff map[string]configValue) []string{
+	func(eff map[string]configValue) []string {
+		inc, _ := eff["scan.include"].Value.([]string)
+		exc, _ := eff["scan.exclude"].Value.([]string)
+		var out []string
+		for _, p := range inc {
+			for _, e := range exc {
+				if p == e {
+					out = append(out, fmt.Sprintf("pattern %q is both included (%s) and excluded (%s)",
+						p, eff["scan.include"].Source, eff["scan.exclude"].Source))
+				}
+			}
+		}
+		return out
+	},
+}
+
+type configValue struct {
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Source string `json:"source"`
+	Line   int    `json:"line,omitempty"`
+}
+
+type lintIssue struct {
+	Severity string `json:"severity"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (i lintIssue) String() string {
+	loc := i.File
+	if i.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", i.File, i.Line)
+	}
+	if loc == "" {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Severity, loc, i.Message)
+}
+
+// configLayer is one parsed config file.
+type configLayer struct {
+	Path   string
+	Values map[string]configValue
+}
+
+func lookupConfigKey(path string) (configKey, bool) {
+	for _, k := range configSchema {
+		if k.Path == path {
+			return k, true
+		}
+	}
+	return configKey{}, false
+}
+
+func isConfigSection(path string) bool {
+	for _, k := range configSchema {
+		if strings.HasPrefix(k.Path, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseConfig validates data against the schema for the given scope and
+// returns the recognised values along with any lint issues.
+func parseConfig(path string, data []byte, scope configScope) (configLayer, []lintIssue) {
+	layer := configLayer{Path: path, Values: map[string]configValue{}}
+	var issues []lintIssue
+	issue := func(sev string, line int, format string, args ...any) {
+		issues = append(issues, lintIssue{Severity: sev, File: path, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		issue("error", 0, "invalid YAML: %v", err)
+		return layer, issues
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		return layer, issues // empty file
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		issue("error", root.Line, "top level must be a mapping")
+		return layer, issues
+	}
+
+	var walk func(n *yaml.Node, prefix string)
+	walk = func(n *yaml.Node, prefix string) {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			keyNode, valNode := n.Content[i], n.Content[i+1]
+			p := prefix + keyNode.Value
+			key, known := lookupConfigKey(p)
+			if !known {
+				if valNode.Kind == yaml.MappingNode && isConfigSection(p) {
+					walk(valNode, p+".")
+					continue
+				}
+				msg := fmt.Sprintf("unknown key %q", p)
+				if s := suggestConfigKey(p); s != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", s)
+				}
+				issue("error", keyNode.Line, "%s", msg)
+				continue
+			}
+			if _, dup := layer.Values[p]; dup {
+				issue("error", keyNode.Line, "key %q is set more than once", p)
+			}
+			if key.Scope&scope == 0 {
+				issue("warning", keyNode.Line, "key %q is only honoured in the %s", p, key.Scope)
+			}
+			if key.Deprecated != "" {
+				issue("warning", keyNode.Line, "key %q is deprecated; use %q instead", p, key.Deprecated)
+			}
+			v, err := decodeConfigValue(key, valNode)
+			if err != nil {
+				issue("error", valNode.Line, "%s: %v", p, err)
+				continue
+			}
+			layer.Values[p] = configValue{Key: p, Value: v, Source: path, Line: keyNode.Line}
+		}
+	}
+	walk(root, "")
+	return layer, issues
+}
+
+func decodeConfigValue(key configKey, n *yaml.Node) (any, error) {
+	switch key.Type {
+	case "bool":
+		var b bool
+		if n.Kind != yaml.ScalarNode || n.Decode(&b) != nil {
+			return nil, fmt.Errorf("expected true or false")
+		}
+		return b, nil
+	case "int":
+		var i int
+		if n.Kind != yaml.ScalarNode || n.Decode(&i) != nil {
+			return nil, fmt.Errorf("expected an integer")
+		}
+		return i, nil
+	case "list":
+		switch n.Kind {
+		case yaml.SequenceNode:
+			var l []string
+			if err := n.Decode(&l); err != nil {
+				return nil, fmt.Errorf("expected a list of strings")
+			}
+			return l, nil
+		case yaml.ScalarNode:
+			return splitCSV(n.Value, []string{}), nil
+		}
+		return nil, fmt.Errorf("expected a list or comma-separated string")
+	case "constraints":
+		return decodeConstraints(n)
+	case "pairs":
+		var specs []string
+		if n.Kind != yaml.SequenceNode || n.Decode(&specs) != nil {
+			return nil, fmt.Errorf("expected a list of A:B pairs")
+		}
+		pairs := make([]togglePair, 0, len(specs))
+		for _, spec := range specs {
+			p, err := parseTogglePair(spec)
+			if err != nil {
+				return nil, fmt.Errorf("%q: %w", spec, err)
+			}
+			pairs = append(pairs, p)
+		}
+		return pairs, nil
+	case "adapterPairs":
+		var specs map[string]string
+		if n.Kind != yaml.MappingNode || n.Decode(&specs) != nil {
+			return nil, fmt.Errorf("expected a mapping of adapter keys to A:B pairs")
+		}
+		pairs := make(map[string]togglePair, len(specs))
+		for adapter, spec := range specs {
+			p, err := parseTogglePair(spec)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", adapter, err)
+			}
+			pairs[adapter] = p
+		}
+		return pairs, nil
+	case "enum":
+		if n.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("expected one of %s", strings.Join(key.Enum, ", "))
+		}
+		v := strings.ToLower(n.Value)
+		for _, e := range key.Enum {
+			if v == e {
+				return v, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid value %q: expected one of %s", n.Value, strings.Join(key.Enum, ", "))
+	default:
+		if n.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("expected a string")
+		}
+		return n.Value, nil
+	}
+}
+
+// suggestConfigKey returns the closest known key to a misspelt one.
+func suggestConfigKey(p string) string {
+	best, bestDist := "", 4
+	for _, k := range configSchema {
+		if d := levenshtein(strings.ToLower(p), strings.ToLower(k.Path)); d < bestDist {
+			best, bestDist = k.Path, d
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// mergeConfig layers the given configs over the schema defaults, later layers
+// winning, and returns every key with the source it came from.
+func mergeConfig(layers ...configLayer) []configValue {
+	out := make([]configValue, 0, len(configSchema))
+	for _, k := range configSchema {
+		v := configValue{Key: k.Path, Value: k.Default, Source: "default"}
+		for _, l := range layers {
+			if lv, ok := l.Values[k.Path]; ok {
+				v = lv
+			}
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func configConflictIssues(eff []configValue) []lintIssue {
+	byKey := make(map[string]configValue, len(eff))
+	for _, v := range eff {
+		byKey[v.Key] = v
+	}
+	var issues []lintIssue
+	for _, check := range configConflicts {
+		for _, msg := range check(byKey) {
+			issues = append(issues, lintIssue{Severity: "error", Message: "conflicting settings: " + msg})
+		}
+	}
+	return issues
+}
+
+func globalConfigPath() (string, error) {
+	dir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, globalConfigFile), nil
+}
+
+// findRepoConfig returns the repository config file in dir, warning when
+// more than one candidate exists.
+func findRepoConfig(dir string) (string, []lintIssue) {
+	var found []string
+	for _, name := range repoConfigFiles {
+		p := filepath.Join(dir, name)
+		if _, err := os.Stat(p); err == nil {
+			found = append(found, p)
+		}
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	var issues []lintIssue
+	if len(found) > 1 {
+		issues = append(issues, lintIssue{Severity: "warning", File: found[1],
+			Message: fmt.Sprintf("ignored because %s takes precedence", filepath.Base(found[0]))})
+	}
+	return found[0], issues
+}
+
+// readConfigLayer parses path if it exists; a missing file yields an empty layer.
+func readConfigLayer(path string, scope configScope) (configLayer, []lintIssue, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - config paths are chosen by the operator
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return configLayer{Path: path, Values: map[string]configValue{}}, nil, nil
+		}
+		return configLayer{}, nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	layer, issues := parseConfig(path, data, scope)
+	return layer, issues, nil
+}
+
+// loadConfig returns the effective settings for a run against the
+// repository checked out at repoDir: the global config overlaid with
+// ACA_* environment variables and the repository's .aca.yaml or .aca.yml.
+// Lint errors are reported as warnings and the affected keys keep their
+// defaults.
+func loadConfig(repoDir string) map[string]configValue {
+	var layers []configLayer
+	var issues []lintIssue
+	if p, err := globalConfigPath(); err == nil {
+		l, is, err := readConfigLayer(p, scopeGlobal)
+		if err != nil {
+			warnf("%v", err)
+		}
+		layers, issues = append(layers, l), append(issues, is...)
+	}
+	env, is := envConfigLayer()
+	layers, issues = append(layers, env), append(issues, is...)
+	if repoDir != "" {
+		p, is := findRepoConfig(repoDir)
+		issues = append(issues, is...)
+		if p != "" {
+			l, is, err := readConfigLayer(p, scopeRepo)
+			if err != nil {
+				warnf("%v", err)
+			}
+			layers, issues = append(layers, l), append(issues, is...)
+		}
+	}
+	for _, i := range issues {
+		if i.Severity == "error" {
+			warnf("config: %s", i)
+		}
+	}
+	eff := map[string]configValue{}
+	for _, v := range mergeConfig(layers...) {
+		eff[v.Key] = v
+	}
+	return eff
+}
+
+func cmdConfig() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and validate gh-aca-utils configuration",
+	}
+	cmd.AddCommand(cmdConfigLint())
+	return cmd
+}
+
+func cmdConfigLint() *cobra.Command {
+	var repoFile, mode string
+
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Validate the global and repository config files and show the effective configuration",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			modeVal := parseMode(mode, outTable)
+			var issues []lintIssue
+
+			globalPath, err := globalConfigPath()
+			if err != nil {
+				return err
+			}
+			global, globalIssues, err := readConfigLayer(globalPath, scopeGlobal)
+			if err != nil {
+				return err
+			}
+			issues = append(issues, globalIssues...)
+			env, envIssues := envConfigLayer()
+			issues = append(issues, envIssues...)
+
+			if repoFile == "" {
+				var findIssues []lintIssue
+				repoFile, findIssues = findRepoConfig(".")
+				issues = append(issues, findIssues...)
+			}
+			layers := []configLayer{global, env}
+			if repoFile != "" {
+				repo, repoIssues, err := readConfigLayer(repoFile, scopeRepo)
+				if err != nil {
+					return err
+				}
+				issues = append(issues, repoIssues...)
+				layers = append(layers, repo)
+			}
+
+			eff := mergeConfig(layers...)
+			issues = append(issues, configConflictIssues(eff)...)
+			sort.SliceStable(issues, func(i, j int) bool {
+				return issues[i].File < issues[j].File || (issues[i].File == issues[j].File && issues[i].Line < issues[j].Line)
+			})
+
+			if err := printConfigLint(issues, eff, modeVal); err != nil {
+				return err
+			}
+			errCount := 0
+			for _, i := range issues {
+				if i.Severity == "error" {
+					errCount++
+				}
+			}
+			if errCount > 0 {
+				return fmt.Errorf("config lint found %d error(s)", errCount)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&repoFile, "file", "", "Repository config file to lint (default: .aca.yaml or .aca.yml in the current directory)")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table|json")
+	setOutputModes(cmd, "table", "json")
+	return cmd
+}
+
+func formatConfigValue(v any) string {
+	switch v := v.(type) {
+	case []string:
+		return strings.Join(v, ",")
+	case []togglePair:
+		parts := make([]string, len(v))
+		for i, p := range v {
+			parts[i] = p[0] + ":" + p[1]
+		}
+		return strings.Join(parts, ",")
+	case map[string]togglePair:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = fmt.Sprintf("%s: %s:%s", k, v[k][0], v[k][1])
+		}
+		return strings.Join(parts, "; ")
+	case map[string]adapterConstraint:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = fmt.Sprintf("%s: %s", k, v[k])
+		}
+		return strings.Join(parts, "; ")
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func printConfigLint(issues []lintIssue, eff []configValue, mode outputMode) error {
+	if mode == outJSON {
+		if issues == nil {
+			issues = []lintIssue{}
+		}
+		enc := json.NewEncoder(stdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Issues    []lintIssue   `json:"issues"`
+			Effective []configValue `json:"effective"`
+		}{issues, eff})
+	}
+
+	if len(issues) == 0 {
+		fmt.Fprintln(stdout(), "No problems found.")
+	}
+	for _, i := range issues {
+		fmt.Fprintln(stdout(), i)
+	}
+	fmt.Fprintln(stdout())
+	w := newTable()
+	w.AddRow("Key", "Value", "Source")
+	for _, v := range eff {
+		src := v.Source
+		if v.Line > 0 {
+			src = fmt.Sprintf("%s:%d", v.Source, v.Line)
+		}
+		w.AddRow(v.Key, formatConfigValue(v.Value), src)
+	}
+	w.Render()
+	return nil
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	data := []byte(`output: table
+scan:
+  include:
+    - "**/*.properties"
+  exclde: "**/dist/**"
+  strictIP: maybe
+`)
+	layer, issues := parseConfig(".aca.yaml", data, scopeRepo)
+
+	want := []string{
+		`warning: .aca.yaml:1: key "output" is only honoured in the global config`,
+		`error: .aca.yaml:5: unknown key "scan.exclde" (did you mean "scan.exclude"?)`,
+		`error: .aca.yaml:6: scan.strictIP: expected true or false`,
+	}
+	got := make([]string, 0, len(issues))
+	for _, i := range issues {
+		got = append(got, i.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseConfig issues =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if v := layer.Values["scan.include"]; !reflect.DeepEqual(v.Value, []string{"**/*.properties"}) || v.Line != 3 {
+		t.Errorf("Unexpected scan.include value: %+v", v)
+	}
+	if _, ok := layer.Values["scan.strictIP"]; ok {
+		t.Error("Invalid values must not be recorded")
+	}
+}
+
+func TestParseConfig_Invalid(t *testing.T) {
+	tests := []string{"output: [unclosed", "- just\n- a list\n"}
+	for _, data := range tests {
+		t.Run(data, func(t *testing.T) {
+			_, issues := parseConfig("config.yml", []byte(data), scopeGlobal)
+			if len(issues) != 1 || issues[0].Severity != "error" {
+				t.Errorf("Expected one error, got %+v", issues)
+			}
+		})
+	}
+}
+
+func TestParseConfig_Deprecated(t *testing.T) {
+	saved := configSchema
+	defer func() { configSchema = saved }()
+	configSchema = append(append([]configKey(nil), saved...),
+		configKey{Path: "includes", Type: "list", Scope: scopeAll, Deprecated: "scan.include"})
+
+	_, issues := parseConfig("config.yml", []byte("includes: a,b\n"), scopeGlobal)
+	if len(issues) != 1 || !strings.Contains(issues[0].Message, `use "scan.include"`) {
+		t.Errorf("Expected deprecation warning, got %+v", issues)
+	}
+}
+
+func TestMergeConfig(t *testing.T) {
+	global, _ := parseConfig("config.yml", []byte("output: json\nscan:\n  strictIP: true\n"), scopeGlobal)
+	repo, _ := parseConfig(".aca.yml", []byte("scan:\n  strictIP: false\n  exclude: [\"**/*.properties\"]\n"), scopeRepo)
+
+	eff := mergeConfig(global, repo)
+	byKey := map[string]configValue{}
+	for _, v := range eff {
+		byKey[v.Key] = v
+	}
+
+	tests := []struct {
+		key    string
+		value  any
+		source string
+	}{
+		{"output", "json", "config.yml"},
+		{"scan.strictIP", false, ".aca.yml"},
+		{"scan.include", splitCSV(defaultIncludes, nil), "default"},
+		{"scan.exclude", []string{"**/*.properties"}, ".aca.yml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			v := byKey[tt.key]
+			if !reflect.DeepEqual(v.Value, tt.value) || v.Source != tt.source {
+				t.Errorf("%s = %v from %s, want %v from %s", tt.key, v.Value, v.Source, tt.value, tt.source)
+			}
+		})
+	}
+
+	conflicts := configConflictIssues(eff)
+	if len(conflicts) != 1 || !strings.Contains(conflicts[0].Message, "**/*.properties") {
+		t.Errorf("Expected include/exclude conflict, got %+v", conflicts)
+	}
+}
+
+func TestFindRepoConfig(t *testing.T) {
+	dir := t.TempDir()
+	if got, _ := findRepoConfig(dir); got != "" {
+		t.Errorf("Expected no config, got %q", got)
+	}
+
+	for _, name := range repoConfigFiles {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}\n"), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	got, issues := findRepoConfig(dir)
+	if filepath.Base(got) != ".aca.yaml" {
+		t.Errorf("Expected .aca.yaml to take precedence, got %q", got)
+	}
+	if len(issues) != 1 || issues[0].Severity != "warning" {
+		t.Errorf("Expected a warning about the ignored file, got %+v", issues)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"scan.exclde", "scan.exclude", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
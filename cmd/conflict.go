@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/greenstevester/gh-aca-utils/pkg/props"
+)
+
+// flipPlan is the dry-run result saved with --plan. It records the exact line
+// each flip was computed from so an apply can detect upstream edits.
+type flipPlan struct {
+	Repo      string        `json:"repo"`
+	Env       string        `json:"env"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Changes   []plannedFlip `json:"changes"`
+}
+
+type plannedFlip struct {
+	Adapter  string `json:"adapter"`
+	OldValue string `json:"old"`
+	NewValue string `json:"new"`
+	Line     string `json:"line"`
+}
+
+func saveFlipPlan(path, repo, env string, changes []change) error {
+	plan := flipPlan{Repo: repo, Env: env, CreatedAt: time.Now().UTC()}
+	for _, c := range changes {
+		plan.Changes = append(plan.Changes, plannedFlip{Adapter: c.Adapter, OldValue: c.OldValue, NewValue: c.NewValue, Line: c.line})
+	}
+	b, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0600); err != nil {
+		return fmt.Errorf("write plan %s: %w", path, err)
+	}
+	return nil
+}
+
+func loadFlipPlan(path string) (*flipPlan, error) {
+	b, err := os.ReadFile(path) // #nosec G304 - path is supplied by the operator
+	if err != nil {
+		return nil, fmt.Errorf("read plan %s: %w", path, err)
+	}
+	var plan flipPlan
+	if err := json.Unmarshal(b, &plan); err != nil {
+		return nil, fmt.Errorf("parse plan %s: %w", path, err)
+	}
+	return &plan, nil
+}
+
+// reconcilePlan compares the freshly computed flips with the saved plan. Flips
+// whose source line is unchanged are applied as planned; for changed lines the
+// operator picks a resolution, or the apply fails when not interactive.
+// Adapters absent from the plan are never applied.
+func reconcilePlan(plan *flipPlan, current []change, lines []string, index map[string]props.Entry, propPath string, p *prompter) ([]change, error) {
+	byAdapter := make(map[string]change, len(current))
+	for _, c := range current {
+		byAdapter[c.Adapter] = c
+	}
+	planned := make(map[string]bool, len(plan.Changes))
+
+	out := make([]change, 0, len(plan.Changes))
+	for _, pf := range plan.Changes {
+		planned[pf.Adapter] = true
+		cur, flippable := byAdapter[pf.Adapter]
+		if flippable && cur.line == pf.Line {
+			out = append(out, cur)
+			continue
+		}
+
+		e, found := index[pf.Adapter]
+		curLine := "(missing)"
+		if found {
+			curLine = entryText(lines, e)
+		}
+		if !p.interactive {
+			return nil, fmt.Errorf("adapter %q changed since the plan was captured (planned against %q, now %q); re-run the dry-run or resolve interactively",
+				pf.Adapter, pf.Line, curLine)
+		}
+
+		fmt.Fprintf(p.out, "\nAdapter %q changed since the plan was captured:\n  planned against: %s\n  current line:    %s\n", pf.Adapter, pf.Line, curLine)
+		options := [][2]string{{"s", "skip this adapter and keep the current line"}}
+		if flippable {
+			options = append(options, [2]string{"f", fmt.Sprintf("flip the current value (%s -> %s)", cur.OldValue, cur.NewValue)})
+		}
+		if found {
+			options = append(options, [2]string{"p", fmt.Sprintf("set the planned value (%s)", pf.NewValue)})
+		}
+		options = append(options, [2]string{"a", "abort without writing anything"})
+
+		choice, err := p.choose("How should this conflict be resolved?", options)
+		if err != nil {
+			return nil, err
+		}
+		switch choice {
+		case "s":
+			continue
+		case "f":
+			out = append(out, cur)
+		case "p":
+			out = append(out, change{
+				Adapter: pf.Adapter, OldValue: valueWithoutComment(e.Value), NewValue: pf.NewValue, FilePath: propPath,
+				line: curLine, lineIdx: e.Line - 1, lineEnd: e.EndLine - 1,
+			})
+		case "a":
+			return nil, fmt.Errorf("aborted by operator")
+		}
+	}
+
+	for _, c := range current {
+		if !planned[c.Adapter] {
+			warnf("adapter %q is not in the plan; skipping", c.Adapter)
+		}
+	}
+	return out, nil
+}
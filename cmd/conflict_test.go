@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/greenstevester/gh-aca-utils/pkg/props"
+)
+
+func TestPlanAndApplyFlips(t *testing.T) {
+	lines := props.SplitLinesEOL([]byte("# adapters\nbilling=0\nsearch=1\nmode=auto\n"))
+	index := indexKeys(lines)
+
+	changes := planValues(lines, index, []string{"billing", "search", "mode", "missing"}, "parameters.properties", toggleNext(defaultTogglePairs))
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %d: %+v", len(changes), changes)
+	}
+	if lines[1] != "billing=0\n" {
+		t.Errorf("planValues must not modify lines, got %q", lines[1])
+	}
+
+	lines = applyFlips(lines, changes)
+	if lines[1] != "billing=1\n" || lines[2] != "search=0\n" {
+		t.Errorf("Unexpected lines after applyFlips: %q", lines)
+	}
+}
+
+func TestFlipPlan_RoundTrip(t *testing.T) {
+	lines := props.SplitLinesEOL([]byte("billing=0\nsearch=1\n"))
+	changes := planValues(lines, indexKeys(lines), []string{"billing", "search"}, "p", toggleNext(defaultTogglePairs))
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := saveFlipPlan(path, "org/repo", "dev", changes); err != nil {
+		t.Fatalf("saveFlipPlan failed: %v", err)
+	}
+	plan, err := loadFlipPlan(path)
+	if err != nil {
+		t.Fatalf("loadFlipPlan failed: %v", err)
+	}
+	if plan.Repo != "org/repo" || plan.Env != "dev" || len(plan.Changes) != 2 {
+		t.Fatalf("Unexpected plan: %+v", plan)
+	}
+	if plan.Changes[0].Line != "billing=0" {
+		t.Errorf("Expected captured line %q, got %q", "billing=0", plan.Changes[0].Line)
+	}
+}
+
+func TestReconcilePlan(t *testing.T) {
+	plan := &flipPlan{Repo: "org/repo", Env: "dev", Changes: []plannedFlip{
+		{Adapter: "billing", OldValue: "0", NewValue: "1", Line: "billing=0"},
+		{Adapter: "search", OldValue: "1", NewValue: "0", Line: "search=1"},
+	}}
+
+	// search was flipped upstream after the plan was captured.
+	lines := props.SplitLinesEOL([]byte("billing=0\nsearch=0\nextra=1\n"))
+	index := indexKeys(lines)
+	current := planValues(lines, index, []string{"billing", "search", "extra"}, "p", toggleNext(defaultTogglePairs))
+
+	t.Run("non-interactive fails", func(t *testing.T) {
+		p := &prompter{in: bufio.NewReader(strings.NewReader("")), out: &bytes.Buffer{}}
+		_, err := reconcilePlan(plan, current, lines, index, "p", p)
+		if err == nil || !strings.Contains(err.Error(), "search") {
+			t.Errorf("Expected conflict error mentioning search, got %v", err)
+		}
+	})
+
+	tests := []struct {
+		answer   string
+		wantLen  int
+		wantNew  string
+		wantErr  bool
+		wantSkip bool
+	}{
+		{"s\n", 1, "", false, true},
+		{"f\n", 2, "1", false, false},
+		{"p\n", 2, "0", false, false},
+		{"x\na\n", 0, "", true, false},
+	}
+	for _, tt := range tests {
+		t.Run("answer "+strings.TrimSpace(tt.answer), func(t *testing.T) {
+			out := &bytes.Buffer{}
+			p := &prompter{in: bufio.NewReader(strings.NewReader(tt.answer)), out: out, interactive: true}
+			got, err := reconcilePlan(plan, current, lines, index, "p", p)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("reconcilePlan error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.wantLen {
+				t.Fatalf("Expected %d changes, got %d: %+v", tt.wantLen, len(got), got)
+			}
+			if !tt.wantErr && !tt.wantSkip && got[1].NewValue != tt.wantNew {
+				t.Errorf("Expected search to be set to %q, got %q", tt.wantNew, got[1].NewValue)
+			}
+			if !strings.Contains(out.String(), "search=0") {
+				t.Errorf("Expected prompt to show the current line, got %q", out.String())
+			}
+		})
+	}
+}
+
+func TestPlanFlips_PropertiesSyntax(t *testing.T) {
+	lines := props.SplitLinesEOL([]byte("billing : 0\nsearch \\\n    1\nnext=0\n"))
+	index := indexKeys(lines)
+
+	changes := planValues(lines, index, []string{"billing", "search", "billing"}, "p", toggleNext(defaultTogglePairs))
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %d: %+v", len(changes), changes)
+	}
+	if changes[1].line != "search \\\n    1" {
+		t.Errorf("Expected continued entry text to be captured, got %q", changes[1].line)
+	}
+
+	// Values are replaced where they are written, keeping the separator
+	// style and the continuation.
+	got := applyFlips(lines, changes)
+	want := []string{"billing : 1\n", "search \\\n", "    0\n", "next=0\n"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("applyFlips() = %q, want %q", got, want)
+	}
+}
+
+func TestApplyFlips_PreservesFormatting(t *testing.T) {
+	input := "# adapters\r\nbilling = 0   # off until Q3\r\nsearch:TRUE\r\n\temail\tyes\r\nsplit = o\\\r\n  n\r\n"
+	lines := props.SplitLinesEOL([]byte(input))
+	changes := planValues(lines, indexKeys(lines), []string{"billing", "search", "email", "split"}, "p", toggleNext(defaultTogglePairs))
+	if len(changes) != 4 {
+		t.Fatalf("Expected 4 changes, got %+v", changes)
+	}
+
+	got := strings.Join(applyFlips(lines, changes), "")
+	want := "# adapters\r\nbilling = 1   # off until Q3\r\nsearch:FALSE\r\n\temail\tno\r\nsplit=off\r\n"
+	if got != want {
+		t.Errorf("applyFlips() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestApplyFlips_CROnly(t *testing.T) {
+	input := "a=1\rb=0\rsplit = o\\\r  n\r"
+	lines := props.SplitLinesEOL([]byte(input))
+	changes := planValues(lines, indexKeys(lines), []string{"b", "split"}, "p", toggleNext(defaultTogglePairs))
+	if len(changes) != 2 || changes[1].line != "split = o\\\n  n" {
+		t.Fatalf("Expected 2 changes, got %+v", changes)
+	}
+	if got, want := strings.Join(applyFlips(lines, changes), ""), "a=1\rb=1\rsplit=off\r"; got != want {
+		t.Errorf("applyFlips() = %q, want %q", got, want)
+	}
+}
+
+// FuzzApplyFlips plans a change to every key of a properties file and
+// applies it; the planned values must be what the result parses to.
+func FuzzApplyFlips(f *testing.F) {
+	for _, s := range []string{"billing=0\nsearch=1\n", "a=1\rb=0\r", "split = o\\\r\n  n\r\n", "k : v # note\r\nk\\\r"} {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		lines := props.SplitLinesEOL(data)
+		index := indexKeys(lines)
+		changes := planValues(lines, index, mapKeys(index), "p", func(_, v string) (string, bool) { return v + "x", true })
+		out := strings.Join(applyFlips(lines, changes), "")
+		got := indexKeys(props.SplitLinesEOL([]byte(out)))
+		for _, c := range changes {
+			if v := valueWithoutComment(got[c.Adapter].Value); v != c.NewValue {
+				t.Fatalf("%q: %s = %q after applying, want %q (got %q)", data, c.Adapter, v, c.NewValue, out)
+			}
+		}
+	})
+}
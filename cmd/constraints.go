@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// adapterConstraint is the set of values an adapter may take, declared
+// under adapters.constraints in the repository config:
+//
+//	billing: binary            # a value flip-adapters can toggle (0/1, true/false, ...)
+//	mode: [ACTIVE, PASSIVE]    # one of a list
+//	retries: {min: 0, max: 5}  # a number in a range
+type adapterConstraint struct {
+	Values   []string // enum; empty for binary and ranges
+	Binary   bool
+	Min, Max *float64
+}
+
+func (c adapterConstraint) String() string {
+	switch {
+	case c.Binary:
+		return "binary"
+	case c.Values != nil:
+		return "one of " + strings.Join(c.Values, ", ")
+	case c.Min != nil && c.Max != nil:
+		return fmt.Sprintf("a number from %g to %g", *c.Min, *c.Max)
+	case c.Min != nil:
+		return fmt.Sprintf("a number of at least %g", *c.Min)
+	}
+	return fmt.Sprintf("a number of at most %g", *c.Max)
+}
+
+// allows reports whether v satisfies c.
+func (c adapterConstraint) allows(v string) bool {
+	switch {
+	case c.Binary:
+		for _, p := range defaultTogglePairs {
+			if strings.EqualFold(v, p[0]) || strings.EqualFold(v, p[1]) {
+				return true
+			}
+		}
+		return false
+	case c.Values != nil:
+		for _, a := range c.Values {
+			if v == a {
+				return true
+			}
+		}
+		return false
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	return err == nil && (c.Min == nil || n >= *c.Min) && (c.Max == nil || n <= *c.Max)
+}
+
+// decodeConstraints reads the adapters.constraints mapping.
+func decodeConstraints(n *yaml.Node) (map[string]adapterConstraint, error) {
+	if n.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping of adapter keys to constraints")
+	}
+	out := map[string]adapterConstraint{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i].Value, n.Content[i+1]
+		var c adapterConstraint
+		switch val.Kind {
+		case yaml.ScalarNode:
+			if val.Value != "binary" {
+				return nil, fmt.Errorf("%s: expected binary, a list of values or {min, max}", key)
+			}
+			c.Binary = true
+		case yaml.SequenceNode:
+			if err := val.Decode(&c.Values); err != nil || len(c.Values) == 0 {
+				return nil, fmt.Errorf("%s: expected a non-empty list of values", key)
+			}
+		case yaml.MappingNode:
+			var r struct {
+				Min *float64 `yaml:"min"`
+				Max *float64 `yaml:"max"`
+			}
+			if err := val.Decode(&r); err != nil || (r.Min == nil && r.Max == nil) {
+				return nil, fmt.Errorf("%s: expected a range with min and/or max", key)
+			}
+			if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+				return nil, fmt.Errorf("%s: min %g is greater than max %g", key, *r.Min, *r.Max)
+			}
+			c.Min, c.Max = r.Min, r.Max
+		default:
+			return nil, fmt.Errorf("%s: expected binary, a list of values or {min, max}", key)
+		}
+		out[key] = c
+	}
+	return out, nil
+}
+
+// checkConstraints refuses changes whose new value breaks the adapter's
+// constraint in cfg, listing every violation.
+func checkConstraints(cfg map[string]configValue, changes []change) error {
+	constraints, _ := cfg["adapters.constraints"].Value.(map[string]adapterConstraint)
+	if len(constraints) == 0 {
+		return nil
+	}
+	var bad []string
+	for _, c := range changes {
+		if con, ok := constraints[c.Adapter]; ok && !con.allows(c.NewValue) {
+			bad = append(bad, fmt.Sprintf("  %s: %s = %q, but it must be %s", c.FilePath, c.Adapter, c.NewValue, con))
+		}
+	}
+	if len(bad) == 0 {
+		return nil
+	}
+	sort.Strings(bad)
+	return fmt.Errorf("refusing values not allowed by adapters.constraints (%s):\n%s", cfg["adapters.constraints"].Source, strings.Join(bad, "\n"))
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAdapterConstraints(t *testing.T) {
+	layer, issues := parseConfig(".aca.yml", []byte(`adapters:
+  constraints:
+    billing: binary
+    mode: [ACTIVE, PASSIVE]
+    retries: {min: 0, max: 5}
+    timeout: {min: 1}
+`), scopeRepo)
+	if len(issues) > 0 {
+		t.Fatalf("issues: %v", issues)
+	}
+	cfg := map[string]configValue{"adapters.constraints": layer.Values["adapters.constraints"]}
+
+	tests := []struct {
+		adapter, value string
+		ok             bool
+	}{
+		{"billing", "1", true},
+		{"billing", "TRUE", true},
+		{"billing", "2", false},
+		{"mode", "PASSIVE", true},
+		{"mode", "passive", false},
+		{"retries", "5", true},
+		{"retries", "6", false},
+		{"retries", "many", false},
+		{"timeout", "30.5", true},
+		{"timeout", "0", false},
+		{"unconstrained", "anything", true},
+	}
+	for _, tt := range tests {
+		err := checkConstraints(cfg, []change{{Adapter: tt.adapter, NewValue: tt.value, FilePath: "env/dev/parameters.properties"}})
+		if (err == nil) != tt.ok {
+			t.Errorf("%s=%s: err = %v", tt.adapter, tt.value, err)
+		}
+	}
+	err := checkConstraints(cfg, []change{{Adapter: "retries", NewValue: "9", FilePath: "env/dev/p"}})
+	if err == nil || !strings.Contains(err.Error(), `env/dev/p: retries = "9", but it must be a number from 0 to 5`) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestAdapterConstraintsLint(t *testing.T) {
+	for _, doc := range []string{
+		"adapters:\n  constraints: [billing]\n",
+		"adapters:\n  constraints:\n    billing: toggle\n",
+		"adapters:\n  constraints:\n    mode: []\n",
+		"adapters:\n  constraints:\n    retries: {min: 5, max: 1}\n",
+		"adapters:\n  constraints:\n    retries: {lo: 1}\n",
+	} {
+		if _, issues := parseConfig(".aca.yml", []byte(doc), scopeRepo); len(issues) != 1 || issues[0].Severity != "error" {
+			t.Errorf("%q: issues = %v", doc, issues)
+		}
+	}
+	if _, issues := parseConfig("config.yml", []byte("adapters:\n  constraints:\n    billing: binary\n"), scopeGlobal); len(issues) != 1 || issues[0].Severity != "warning" {
+		t.Errorf("global scope: issues = %v", issues)
+	}
+}
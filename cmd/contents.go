@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// apiCheckout stands in for a clone when flip-adapters runs with
+// --no-clone: the few files it needs are fetched through the contents API
+// into a scratch directory with the same layout, and the result is
+// committed with the Git Data API.
+type apiCheckout struct {
+	repo    string
+	base    string // default branch
+	baseSHA string // its head commit, which every read is pinned to
+}
+
+// newAPICheckout resolves the default branch of repo and its head commit.
+func newAPICheckout(repo string) (*apiCheckout, error) {
+	var info struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := ghAPI("GET", "repos/"+repo, nil, &info); err != nil {
+		return nil, err
+	}
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/git/ref/heads/%s", repo, info.DefaultBranch), nil, &ref); err != nil {
+		return nil, err
+	}
+	return &apiCheckout{repo: repo, base: info.DefaultBranch, baseSHA: ref.Object.SHA}, nil
+}
+
+// contentsPath is the contents API path for a file or directory.
+func (c *apiCheckout) contentsPath(rel string) string {
+	return fmt.Sprintf("repos/%s/contents/%s?ref=%s", c.repo, escapePath(rel), url.QueryEscape(c.baseSHA))
+}
+
+// escapePath escapes each segment of a slash-separated path for a URL.
+func escapePath(rel string) string {
+	parts := strings.Split(rel, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
+
+// checkout creates a scratch directory holding an empty directory for
+// every environment under env/, so environment patterns resolve as they
+// do in a clone.
+func (c *apiCheckout) checkout() (string, func(), error) {
+	var entries []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	if err := ghAPI("GET", c.contentsPath("env"), nil, &entries); err != nil {
+		return "", nil, fmt.Errorf("list environments: %w", err)
+	}
+	dir, cleanup, err := makeTemp("aca-api-*")
+	if err != nil {
+		return "", nil, err
+	}
+	for _, e := range entries {
+		if e.Type != "dir" {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Join(dir, "env", e.Name), 0750); err != nil {
+			cleanup()
+			return "", nil, err
+		}
+	}
+	return dir, cleanup, nil
+}
+
+// fetch downloads the file rel into the same place under dir.
+func (c *apiCheckout) fetch(dir, rel string) error {
+	b, err := c.read(rel)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, filepath.FromSlash(rel)), b, 0600)
+}
+
+// fetchConfig downloads the repository config file, if any, into dir so
+// loadConfig finds it as in a clone.
+func (c *apiCheckout) fetchConfig(dir string) {
+	for _, name := range repoConfigFiles {
+		if b, err := c.read(name); err == nil {
+			_ = os.WriteFile(filepath.Join(dir, name), b, 0600)
+		}
+	}
+}
+
+// read returns the content of the file rel.
+func (c *apiCheckout) read(rel string) ([]byte, error) {
+	var file struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	if err := ghAPI("GET", c.contentsPath(rel), nil, &file); err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", rel, err)
+	}
+	if file.Encoding != "base64" {
+		return nil, fmt.Errorf("fetch %s: file is too large for the contents API; run without --no-clone", rel)
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", rel, err)
+	}
+	return b, nil
+}
+
+// branchSHA returns the head commit of branch, or "" when it doesn't exist.
+func (c *apiCheckout) branchSHA(branch string) (string, error) {
+	var refs []struct {
+		Ref    string `json:"ref"`
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/git/matching-refs/heads/%s", c.repo, escapePath(branch)), nil, &refs); err != nil {
+		return "", err
+	}
+	for _, r := range refs {
+		if r.Ref == "refs/heads/"+branch {
+			return r.Object.SHA, nil
+		}
+	}
+	return "", nil
+}
+
+// commit creates one new commit holding files and points branch at it,
+// and returns the commit SHA. The commit goes on top of the default
+// branch, or on top of existing (the branch's current head) with
+// branchReuse; an existing branch is moved to it, forcibly with
+// branchForce.
+func (c *apiCheckout) commit(branch, msg string, files map[string][]byte, existing string, p branchPolicy) (string, error) {
+	type treeEntry struct {
+		Path    string `json:"path"`
+		Mode    string `json:"mode"`
+		Type    string `json:"type"`
+		Content string `json:"content"`
+	}
+	var entries []treeEntry
+	for rel, b := range files {
+		entries = append(entries, treeEntry{Path: path.Clean(rel), Mode: "100644", Type: "blob", Content: string(b)})
+	}
+	parent := c.baseSHA
+	if existing != "" && p == branchReuse {
+		parent = existing
+	}
+	var tree, commit struct {
+		SHA string `json:"sha"`
+	}
+	var base struct {
+		Tree struct {
+			SHA string `json:"sha"`
+		} `json:"tree"`
+	}
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/git/commits/%s", c.repo, parent), nil, &base); err != nil {
+		return "", err
+	}
+	if err := ghAPI("POST", fmt.Sprintf("repos/%s/git/trees", c.repo), map[string]any{"base_tree": base.Tree.SHA, "tree": entries}, &tree); err != nil {
+		return "", fmt.Errorf("create tree: %w", err)
+	}
+	if parent == existing && tree.SHA == base.Tree.SHA {
+		infof("Branch %s already has these changes", branch)
+		return parent, nil
+	}
+	if err := ghAPI("POST", fmt.Sprintf("repos/%s/git/commits", c.repo), map[string]any{"message": msg, "tree": tree.SHA, "parents": []string{parent}}, &commit); err != nil {
+		return "", fmt.Errorf("create commit: %w", err)
+	}
+	if existing == "" {
+		if err := ghAPI("POST", fmt.Sprintf("repos/%s/git/refs", c.repo), map[string]string{"ref": "refs/heads/" + branch, "sha": commit.SHA}, nil); err != nil {
+			return "", fmt.Errorf("create branch %s: %w", branch, err)
+		}
+	} else {
+		req := map[string]any{"sha": commit.SHA, "force": p == branchForce}
+		if err := ghAPI("PATCH", fmt.Sprintf("repos/%s/git/refs/heads/%s", c.repo, escapePath(branch)), req, nil); err != nil {
+			return "", fmt.Errorf("update branch %s: %w", branch, err)
+		}
+	}
+	infof("Committed %s to %s", commit.SHA[:min(7, len(commit.SHA))], branch)
+	return commit.SHA, nil
+}
+
+// openPR opens a pull request from branch into the default branch, routes
+// it per opts and returns its URL.
+func (c *apiCheckout) openPR(branch, title, body string, opts prOptions) (string, error) {
+	var pr struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	req := map[string]any{"title": title, "body": body, "head": branch, "base": c.base, "draft": opts.draft}
+	if err := ghAPI("POST", fmt.Sprintf("repos/%s/pulls", c.repo), req, &pr); err != nil {
+		return "", fmt.Errorf("create pull request: %w", err)
+	}
+	if err := opts.applyAPI(c.repo, pr.Number); err != nil {
+		return pr.HTMLURL, err
+	}
+	return pr.HTMLURL, nil
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeGH serves the API calls of an --no-clone flip of org/svc, whose
+// default branch main holds env/dev and env/prod, and logs them as
+// fakeGitHub does.
+func fakeGH(t *testing.T) string {
+	t.Helper()
+	return fakeGitHub(t, func(_ *http.Request, call string) (int, string) {
+		_, path, _ := strings.Cut(call, " ")
+		switch path {
+		case "repos/org/svc":
+			return 200, `{"default_branch":"main"}`
+		case "repos/org/svc/git/ref/heads/main":
+			return 200, `{"object":{"sha":"base123"}}`
+		case "repos/org/svc/contents/env?ref=base123":
+			return 200, `[{"name":"dev","type":"dir"},{"name":"prod","type":"dir"},{"name":"README.md","type":"file"}]`
+		case "repos/org/svc/contents/env/dev/parameters.properties?ref=base123":
+			return 200, `{"encoding":"base64","content":"YmlsbGluZz0w\nCg=="}`
+		case "repos/org/svc/git/commits/base123":
+			return 200, `{"tree":{"sha":"tree0"}}`
+		case "repos/org/svc/git/trees":
+			return 200, `{"sha":"tree1"}`
+		case "repos/org/svc/git/commits":
+			return 200, `{"sha":"commit1234567"}`
+		case "repos/org/svc/git/refs", "repos/org/svc/issues/7", "repos/org/svc/pulls/7/requested_reviewers":
+			return 200, `{}`
+		case "repos/org/svc/pulls":
+			return 200, `{"number":7,"html_url":"https://github.com/org/svc/pull/7"}`
+		case "repos/org/svc/milestones?state=open&per_page=100":
+			return 200, `[{"number":3,"title":"Q3"}]`
+		case "repos/org/svc/commits/commit1234567/check-runs?per_page=100":
+			return 200, `{"check_runs":[{"name":"build","status":"completed","conclusion":"success"},{"name":"lint","status":"completed","conclusion":"failure"}]}`
+		}
+		return 404, ""
+	})
+}
+
+func TestAPICheckout(t *testing.T) {
+	log := fakeGH(t)
+
+	api, err := newAPICheckout("org/svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, cleanup, err := api.checkout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	envs, err := resolveEnvs(dir, "*")
+	if err != nil || strings.Join(envs, ",") != "dev,prod" {
+		t.Fatalf("resolveEnvs = %v, %v", envs, err)
+	}
+	if err := api.fetch(dir, "env/dev/parameters.properties"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "env", "dev", "parameters.properties"))
+	if err != nil || string(b) != "billing=0\n" {
+		t.Fatalf("fetched %q, %v", b, err)
+	}
+
+	sha, err := api.commit("toggle/adapters-dev", "flip billing", map[string][]byte{"env/dev/parameters.properties": []byte("billing=1\n")}, "", branchFail)
+	if err != nil || sha != "commit1234567" {
+		t.Fatalf("commit = %q, %v", sha, err)
+	}
+	opts := prOptions{labels: []string{"flags"}, reviewers: []string{"alice", "org/platform"}, draft: true, milestone: "Q3"}
+	url, err := api.openPR("toggle/adapters-dev", "Flip", "body", opts)
+	if err != nil || url != "https://github.com/org/svc/pull/7" {
+		t.Fatalf("openPR = %q, %v", url, err)
+	}
+
+	calls, _ := os.ReadFile(log)
+	for _, want := range []string{
+		`"base_tree":"tree0"`,
+		`"content":"billing=1\n"`,
+		`"parents":["base123"]`,
+		`"ref":"refs/heads/toggle/adapters-dev","sha":"commit1234567"`,
+		`"base":"main"`,
+		`"draft":true`,
+		"PATCH repos/org/svc/issues/7\n" + `{"labels":["flags"],"milestone":3}`,
+		`{"reviewers":["alice"],"team_reviewers":["platform"]}`,
+	} {
+		if !strings.Contains(string(calls), want) {
+			t.Errorf("Expected a request containing %s in:\n%s", want, calls)
+		}
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	if got := escapePath("env/my env/app#1.json"); got != "env/my%20env/app%231.json" {
+		t.Errorf("escapePath = %q", got)
+	}
+}
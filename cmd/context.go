@@ -0,0 +1,37 @@
+package cmd
+
+import "strings"
+
+// Context columns are included in JSON output when --context is given.
+var (
+	lineColumn = rowColumn{Name: "line", Header: "Line Text",
+		Value: func(r matchRow) string { return r.Line }}
+	beforeColumn = rowColumn{Name: "before", Header: "Before",
+		Value: func(r matchRow) string { return strings.Join(r.Before, "\n") },
+		json:  func(r matchRow) any { return nonNil(r.Before) }}
+	afterColumn = rowColumn{Name: "after", Header: "After",
+		Value: func(r matchRow) string { return strings.Join(r.After, "\n") },
+		json:  func(r matchRow) any { return nonNil(r.After) }}
+)
+
+// attachContext sets the matched line and up to n lines on either side of
+// it on each row. lines holds the whole file, indexed from line 1.
+func attachContext(rows []matchRow, lines []string, n int) {
+	for i := range rows {
+		idx := rows[i].LineNumber - 1
+		if idx < 0 || idx >= len(lines) {
+			continue
+		}
+		rows[i].Line = lines[idx]
+		rows[i].Before = append([]string(nil), lines[max(0, idx-n):idx]...)
+		rows[i].After = append([]string(nil), lines[idx+1:min(len(lines), idx+1+n)]...)
+	}
+}
+
+// nonNil keeps empty context as [] rather than null in JSON.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
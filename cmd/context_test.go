@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanFileContext(t *testing.T) {
+	dir := t.TempDir()
+	env := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(env, []byte("# db\n\nDB_HOST=10.0.0.5\nDB_NAME=app\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	props := filepath.Join(dir, "app.properties")
+	if err := os.WriteFile(props, []byte("db.host=10.0.0.6\nname=x\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := scanOptions{withContext: true, context: 2}
+	rows := scanFile(env, "app.env", opts)
+	if len(rows) != 1 {
+		t.Fatalf("Expected 1 row, got %+v", rows)
+	}
+	r := rows[0]
+	if r.Line != "DB_HOST=10.0.0.5" || strings.Join(r.Before, "|") != "# db|" || strings.Join(r.After, "|") != "DB_NAME=app" {
+		t.Errorf("Unexpected context: %q %q %q", r.Line, r.Before, r.After)
+	}
+
+	rows = scanFile(props, "app.properties", scanOptions{withContext: true})
+	if len(rows) != 1 || rows[0].Line != "db.host=10.0.0.6" || len(rows[0].Before) != 0 || len(rows[0].After) != 0 {
+		t.Errorf("Expected only the matched line with --context 0, got %+v", rows)
+	}
+
+	if rows := scanFile(env, "app.env", scanOptions{}); len(rows) != 1 || rows[0].Line != "" {
+		t.Errorf("Expected no context without --context, got %+v", rows)
+	}
+}
+
+func TestContextColumnsJSON(t *testing.T) {
+	rows := []matchRow{{IPValue: "10.0.0.5", Line: "a=10.0.0.5", After: []string{"b=1"}}}
+	cols := []rowColumn{lineColumn, beforeColumn, afterColumn}
+	got := string(columnsObject(rows[0], cols))
+	if got != `{"line":"a=10.0.0.5","before":[],"after":["b=1"]}` {
+		t.Errorf("Context JSON = %s", got)
+	}
+}
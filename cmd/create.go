@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/greenstevester/gh-aca-utils/pkg/jsonptr"
+	"github.com/greenstevester/gh-aca-utils/pkg/props"
+)
+
+// splitMissing separates the wanted adapters that has finds from the ones
+// it doesn't, dropping duplicates.
+func splitMissing(want []string, has func(adapter string) bool) (existing, missing []string) {
+	seen := map[string]bool{}
+	for _, a := range want {
+		if seen[a] {
+			continue
+		}
+		seen[a] = true
+		if has(a) {
+			existing = append(existing, a)
+		} else {
+			missing = append(missing, a)
+		}
+	}
+	return existing, missing
+}
+
+// planCreates plans adding each missing adapter with value.
+func planCreates(missing []string, value, path string) []change {
+	changes := make([]change, 0, len(missing))
+	for _, a := range missing {
+		changes = append(changes, change{Adapter: a, NewValue: value, FilePath: path, Created: true, newRaw: jsonRaw(value, false)})
+	}
+	return changes
+}
+
+// appendProperties adds a key=value line for each created change after
+// the last line of the file, matching its line endings.
+func appendProperties(lines []string, creates []change) []string {
+	if len(creates) == 0 {
+		return lines
+	}
+	eol := "\n"
+	for _, l := range lines {
+		if e := l[len(trimEOL(l)):]; e != "" {
+			eol = e
+			break
+		}
+	}
+	// A file without a final line terminator keeps ending without one.
+	final := len(lines) == 0 || trimEOL(lines[len(lines)-1]) != lines[len(lines)-1]
+	if !final {
+		lines[len(lines)-1] += eol
+	}
+	for _, c := range creates {
+		lines = append(lines, props.EscapeKey(c.Adapter)+"="+props.EscapeValue(c.NewValue)+eol)
+	}
+	if !final {
+		lines[len(lines)-1] = trimEOL(lines[len(lines)-1])
+	}
+	return lines
+}
+
+// jsonCreatable reports whether a member can be added at ptr: its parent
+// must be an existing object.
+func jsonCreatable(data []byte, ptr string) bool {
+	parent := ptr[:strings.LastIndex(ptr, "/")]
+	span, err := jsonptr.Find(data, parent)
+	return err == nil && data[span.Start] == '{'
+}
+
+// insertJSONMember adds the member at ptr to its parent object, after the
+// last existing member and with the same indentation.
+func insertJSONMember(data []byte, ptr, raw string) ([]byte, error) {
+	i := strings.LastIndex(ptr, "/")
+	tokens, err := jsonptr.Parse(ptr[i:])
+	if err != nil {
+		return nil, err
+	}
+	span, err := jsonptr.Find(data, ptr[:i])
+	if err != nil || data[span.Start] != '{' {
+		return nil, fmt.Errorf("cannot add %s: parent is not an object", ptr)
+	}
+	key, _ := json.Marshal(tokens[0])
+	member := string(key) + ": " + raw
+
+	obj := string(data[span.Start:span.End])
+	last := strings.TrimRight(obj[:len(obj)-1], " \t\r\n")
+	at := span.Start + len(last)
+	var insert string
+	switch {
+	case last == "{":
+		insert = member
+	case !strings.Contains(obj, "\n"):
+		insert = ", " + member
+	default:
+		line := last[strings.LastIndex(last, "\n")+1:]
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		eol := "\n"
+		if strings.Contains(obj, "\r\n") {
+			eol = "\r\n"
+		}
+		insert = "," + eol + indent + member
+	}
+	out := append([]byte(nil), data[:at]...)
+	out = append(out, insert...)
+	return append(out, data[at:]...), nil
+}
+
+// createMissingNote reports adapters that will be created rather than
+// flipped.
+func createMissingNote(env string, missing []string, value string) {
+	if len(missing) > 0 {
+		infof("Creating %s in env/%s with value %q", strings.Join(missing, ", "), env, value)
+	}
+}
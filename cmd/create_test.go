@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/greenstevester/gh-aca-utils/pkg/props"
+)
+
+func TestCreateMissing_Properties(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"trailing newline", "billing=1\n", "billing=0\nsearch=1\nnew\\ key=1\n"},
+		{"no trailing newline", "billing=1", "billing=0\nsearch=1\nnew\\ key=1"},
+		{"crlf", "# flags\r\nbilling=1\r\n", "# flags\r\nbilling=0\r\nsearch=1\r\nnew\\ key=1\r\n"},
+		{"crlf without trailing newline", "billing=1\r\nx=y", "billing=0\r\nx=y\r\nsearch=1\r\nnew\\ key=1"},
+		{"cr only", "# flags\rbilling=1\r", "# flags\rbilling=0\rsearch=1\rnew\\ key=1\r"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := props.SplitLinesEOL([]byte(tt.in))
+			index := indexKeys(lines)
+			existing, missing := splitMissing([]string{"billing", "search", "new key", "search"}, func(a string) bool {
+				_, ok := index[a]
+				return ok
+			})
+			if strings.Join(existing, ",") != "billing" || strings.Join(missing, ",") != "search,new key" {
+				t.Fatalf("splitMissing = %v, %v", existing, missing)
+			}
+			changes := append(planValues(lines, index, existing, "p", toggleNext(defaultTogglePairs)), planCreates(missing, "1", "p")...)
+			if got := strings.Join(applyFlips(lines, changes), ""); got != tt.want {
+				t.Errorf("applyFlips = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateMissing_JSON(t *testing.T) {
+	tests := []struct {
+		name, in, ptr, value, want string
+	}{
+		{"indented", "{\n  \"Features\": {\n    \"Billing\": true\n  }\n}\n", "/Features/Search", "false",
+			"{\n  \"Features\": {\n    \"Billing\": true,\n    \"Search\": false\n  }\n}\n"},
+		{"crlf nested object last", "{\r\n\t\"A\": {\r\n\t\t\"x\": 1\r\n\t}\r\n}", "/B", "on",
+			"{\r\n\t\"A\": {\r\n\t\t\"x\": 1\r\n\t},\r\n\t\"B\": \"on\"\r\n}"},
+		{"single line", `{"a": 1}`, "/b~1c", "0", `{"a": 1, "b/c": 0}`},
+		{"empty object", `{"F": {}}`, "/F/x", "1", `{"F": {"x": 1}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !jsonCreatable([]byte(tt.in), tt.ptr) {
+				t.Fatalf("jsonCreatable(%s) = false", tt.ptr)
+			}
+			got := string(applyJSONFlips([]byte(tt.in), planCreates([]string{tt.ptr}, tt.value, "p.json")))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+	for _, ptr := range []string{"/Missing/x", "/a/x"} {
+		if jsonCreatable([]byte(`{"a": 1}`), ptr) {
+			t.Errorf("jsonCreatable(%s) = true, want false", ptr)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// configEnvPrefix starts the environment variable of every config key that
+// takes a single value: scan.include is ACA_SCAN_INCLUDE, scan.strictIP
+// ACA_SCAN_STRICT_IP.
+const configEnvPrefix = "ACA_"
+
+// configEnvVar returns the environment variable for a config key.
+func configEnvVar(key string) string {
+	var b strings.Builder
+	b.WriteString(configEnvPrefix)
+	prev := rune(0)
+	for _, r := range key {
+		switch {
+		case r == '.':
+			b.WriteByte('_')
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			b.WriteByte('_')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
+		prev = r
+	}
+	return b.String()
+}
+
+// envConfigLayer reads the config keys set in the environment. It sits
+// between the global config file and the repository's, so a variable
+// overrides the user's file for one invocation.
+func envConfigLayer() (configLayer, []lintIssue) {
+	layer := configLayer{Path: "environment", Values: map[string]configValue{}}
+	var issues []lintIssue
+	for _, k := range configSchema {
+		switch k.Type {
+		case "string", "bool", "int", "list", "enum":
+		default:
+			continue
+		}
+		name := configEnvVar(k.Path)
+		s, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		v, err := decodeConfigValue(k, &yaml.Node{Kind: yaml.ScalarNode, Value: s})
+		if err != nil {
+			issues = append(issues, lintIssue{Severity: "error", File: "environment", Message: fmt.Sprintf("%s: %v", name, err)})
+			continue
+		}
+		layer.Values[k.Path] = configValue{Key: k.Path, Value: v, Source: "env " + name}
+	}
+	return layer, issues
+}
+
+// flagDefaults maps command flags to the config keys that give their
+// defaults.
+var flagDefaults = map[string]string{
+	"include": "scan.include",
+	"exclude": "scan.exclude",
+	"output":  "output",
+	"jobs":    "concurrency",
+}
+
+// outputModesAnnotation holds, on a command's --output flag, the modes the
+// command accepts.
+const outputModesAnnotation = "aca_output_modes"
+
+// setOutputModes records the modes cmd's --output flag accepts; the output
+// config key only sets that flag to one of them.
+func setOutputModes(cmd *cobra.Command, modes ...string) {
+	_ = cmd.Flags().SetAnnotation("output", outputModesAnnotation, modes)
+}
+
+// applyConfigDefaults sets the flags of cmd the user didn't give from the
+// global config and environment, and qualifies bare repository names
+// with defaults.org.
+func applyConfigDefaults(cmd *cobra.Command) error {
+	cfg := loadConfig("")
+	var errs []string
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err := applyFlagDefault(f, cfg); err != nil {
+			errs = append(errs, err.Error())
+		}
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func applyFlagDefault(f *pflag.Flag, cfg map[string]configValue) error {
+	if f.Name == "repo" {
+		org, _ := cfg["defaults.org"].Value.(string)
+		return qualifyRepoFlag(f, org)
+	}
+	key, ok := flagDefaults[f.Name]
+	if !ok || f.Changed {
+		return nil
+	}
+	v := cfg[key]
+	if v.Source == "default" || v.Source == "" {
+		return nil
+	}
+	var s string
+	switch val := v.Value.(type) {
+	case []string:
+		s = strings.Join(val, ",")
+	case int:
+		if val <= 0 {
+			return nil
+		}
+		s = strconv.Itoa(val)
+	case string:
+		// An output mode a command doesn't offer keeps its own default.
+		if key == "output" && !slices.Contains(f.Annotations[outputModesAnnotation], val) {
+			return nil
+		}
+		s = val
+	default:
+		return nil
+	}
+	if err := f.Value.Set(s); err != nil {
+		return fmt.Errorf("%s from %s: %w", key, v.Source, err)
+	}
+	return nil
+}
+
+// qualifyRepoFlag prefixes org/ to the values of a --repo flag that name a
+// repository without its owner.
+func qualifyRepoFlag(f *pflag.Flag, org string) error {
+	if org == "" {
+		return nil
+	}
+	qualify := func(r string) string {
+		if r != "" && !strings.Contains(r, "/") {
+			return org + "/" + r
+		}
+		return r
+	}
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		list := sv.GetSlice()
+		for i, r := range list {
+			list[i] = qualify(r)
+		}
+		return sv.Replace(list)
+	}
+	if r := f.Value.String(); qualify(r) != r {
+		return f.Value.Set(qualify(r))
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func TestConfigEnvVar(t *testing.T) {
+	tests := map[string]string{
+		"output":               "ACA_OUTPUT",
+		"scan.include":         "ACA_SCAN_INCLUDE",
+		"scan.strictIP":        "ACA_SCAN_STRICT_IP",
+		"protect.environments": "ACA_PROTECT_ENVIRONMENTS",
+		"defaults.org":         "ACA_DEFAULTS_ORG",
+	}
+	for key, want := range tests {
+		if got := configEnvVar(key); got != want {
+			t.Errorf("configEnvVar(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestApplyConfigDefaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		env     map[string]string
+		args    []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "config file",
+			config: "output: json\nscan:\n  include: ['**/*.yml']\nconcurrency: 2\ndefaults:\n  org: myorg\n",
+			args:   []string{"--repo", "svc"},
+			want:   map[string]string{"output": "json", "include": "**/*.yml", "jobs": "2", "repo": "myorg/svc"},
+		},
+		{
+			name:   "env overrides file",
+			config: "output: json\n",
+			env:    map[string]string{"ACA_OUTPUT": "table", "ACA_DEFAULTS_ORG": "envorg"},
+			args:   []string{"--repo", "svc"},
+			want:   map[string]string{"output": "table", "repo": "envorg/svc"},
+		},
+		{
+			name:   "flags win",
+			config: "output: json\nconcurrency: 2\n",
+			args:   []string{"--output", "table", "--jobs", "8", "--repo", "org/svc"},
+			want:   map[string]string{"output": "table", "jobs": "8", "repo": "org/svc"},
+		},
+		{
+			name:   "output the command lacks",
+			config: "output: csv\n",
+			want:   map[string]string{"output": "table"},
+		},
+		{
+			name: "nothing set",
+			want: map[string]string{"output": "table", "include": "**/*.properties", "jobs": "4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("ACA_CONFIG_DIR", dir)
+			for _, k := range []string{"ACA_OUTPUT", "ACA_DEFAULTS_ORG", "ACA_CONCURRENCY", "ACA_SCAN_INCLUDE"} {
+				t.Setenv(k, "")
+				os.Unsetenv(k)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if tt.config != "" {
+				if err := os.WriteFile(filepath.Join(dir, globalConfigFile), []byte(tt.config), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cmd := &cobra.Command{Use: "test", RunE: func(*cobra.Command, []string) error { return nil }}
+			cmd.Flags().String("output", "table", "Output: table|json")
+			setOutputModes(cmd, "table", "json")
+			cmd.Flags().String("include", "**/*.properties", "")
+			cmd.Flags().Int("jobs", 4, "")
+			cmd.Flags().String("repo", "", "")
+			cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error { return applyConfigDefaults(cmd) }
+			cmd.SetArgs(tt.args)
+			if err := cmd.Execute(); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v", err)
+			}
+			for name, want := range tt.want {
+				if got := cmd.Flags().Lookup(name).Value.String(); got != want {
+					t.Errorf("--%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyFlagDefaultOutputModes(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String("output", "table", "Output: table|ndjson")
+	setOutputModes(cmd, "table", "ndjson")
+	f := cmd.Flags().Lookup("output")
+	for val, want := range map[string]string{"json": "table", "ndjson": "ndjson"} {
+		cfg := map[string]configValue{"output": {Key: "output", Value: val, Source: "env ACA_OUTPUT"}}
+		if err := applyFlagDefault(f, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if got := f.Value.String(); got != want {
+			t.Errorf("output %s: --output = %q, want %q", val, got, want)
+		}
+		_ = f.Value.Set("table")
+	}
+}
+
+func TestQualifyRepoFlag(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.StringSlice("repo", nil, "")
+	if err := fs.Parse([]string{"--repo", "a,other/b", "--repo", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	f := fs.Lookup("repo")
+	if err := qualifyRepoFlag(f, "myorg"); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Value.String(); got != "[myorg/a,other/b,myorg/c]" {
+		t.Errorf("--repo = %s", got)
+	}
+}
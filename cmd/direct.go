@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// directBlockingRules are the ruleset rule types that keep a plain push
+// from landing on a branch.
+var directBlockingRules = map[string]string{
+	"pull_request":           "changes must go through a pull request",
+	"update":                 "updates are restricted",
+	"required_status_checks": "status checks must pass first",
+	"required_signatures":    "commits must be signed",
+	"required_deployments":   "deployments must succeed first",
+	"merge_queue":            "changes must go through the merge queue",
+}
+
+// checkDirectPush returns repo's default branch if a commit may be pushed
+// straight to it, and otherwise an error naming the branch protection or
+// ruleset that forbids it.
+func checkDirectPush(repo string) (string, error) {
+	var info struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := ghAPI("GET", "repos/"+repo, nil, &info); err != nil {
+		return "", err
+	}
+	branch := info.DefaultBranch
+	var b struct {
+		Protected bool `json:"protected"`
+	}
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/branches/%s", repo, escapePath(branch)), nil, &b); err != nil {
+		return "", err
+	}
+	if b.Protected {
+		return "", usageErrorf("--direct: %s's default branch %s is protected; drop --direct to open a pull request instead", repo, branch)
+	}
+	var rules []struct {
+		Type string `json:"type"`
+	}
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/rules/branches/%s", repo, escapePath(branch)), nil, &rules); err != nil && !isNotFound(err) {
+		return "", err
+	}
+	var reasons []string
+	for _, r := range rules {
+		if why, ok := directBlockingRules[r.Type]; ok {
+			reasons = append(reasons, why)
+		}
+	}
+	if len(reasons) > 0 {
+		return "", usageErrorf("--direct: rulesets on %s's default branch %s forbid direct pushes (%s); drop --direct to open a pull request instead", repo, branch, strings.Join(reasons, "; "))
+	}
+	return branch, nil
+}
+
+// gitCommitDirect commits paths in the clone at dir on top of the checked
+// out default branch and pushes it there. The push is refused, rather than
+// forced, if the branch moved on the remote in the meantime.
+func gitCommitDirect(dir, branch, msg string, paths []string) error {
+	if useGoGit() {
+		return goGitCommitDirect(dir, branch, msg, paths)
+	}
+	if err := gitIn(dir, append([]string{"add", "--"}, paths...)...); err != nil {
+		return err
+	}
+	if err := gitIn(dir, "commit", "-m", msg); err != nil {
+		return err
+	}
+	return gitPush(dir, "origin", "HEAD:refs/heads/"+branch)
+}
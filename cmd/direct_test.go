@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckDirectPush(t *testing.T) {
+	fakeGitHub(t, func(_ *http.Request, call string) (int, string) {
+		_, path, _ := strings.Cut(call, " ")
+		owner, rest, _ := strings.Cut(strings.TrimPrefix(path, "repos/org/"), "/")
+		switch {
+		case path == "repos/org/ruled/rules/branches/main":
+			return 200, `[{"type":"non_fast_forward"},{"type":"pull_request"}]`
+		case path == "repos/org/open/rules/branches/main":
+			return 404, ""
+		case rest == "rules/branches/main":
+			return 200, `[{"type":"non_fast_forward"}]`
+		case path == "repos/org/protected/branches/main":
+			return 200, `{"protected":true}`
+		case rest == "branches/main":
+			return 200, `{"protected":false}`
+		case rest == "" && owner != "":
+			return 200, `{"default_branch":"main"}`
+		}
+		return 404, ""
+	})
+
+	tests := []struct {
+		repo string
+		want string // substring of the error; "" for success
+	}{
+		{"org/open", ""},
+		{"org/linear", ""},
+		{"org/protected", "is protected"},
+		{"org/ruled", "must go through a pull request"},
+	}
+	for _, tt := range tests {
+		branch, err := checkDirectPush(tt.repo)
+		if tt.want == "" {
+			if err != nil || branch != "main" {
+				t.Errorf("checkDirectPush(%s) = %q, %v", tt.repo, branch, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("checkDirectPush(%s) = %v, want %q", tt.repo, err, tt.want)
+		}
+	}
+}
+
+func TestGitCommitDirect(t *testing.T) {
+	origin := initTestRepo(t, map[string]map[string]string{
+		"main": {"env/dev/parameters.properties": "billing=0\n"},
+	}, "main", "other")
+	for _, kv := range []string{"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1"} {
+		k, v, _ := strings.Cut(kv, "=")
+		t.Setenv(k, v)
+	}
+	// A checked-out branch can't be pushed to.
+	if out, err := exec.Command("git", "-C", origin, "checkout", "--quiet", "other").CombinedOutput(); err != nil {
+		t.Fatalf("checkout: %v\n%s", err, out)
+	}
+	dir := filepath.Join(t.TempDir(), "clone")
+	if out, err := exec.Command("git", "clone", "--quiet", "--depth", "1", "--branch", "main", "file://"+origin, dir).CombinedOutput(); err != nil {
+		t.Fatalf("clone: %v\n%s", err, out)
+	}
+	rel := filepath.Join("env", "dev", "parameters.properties")
+	if err := os.WriteFile(filepath.Join(dir, rel), []byte("billing=1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitCommitDirect(dir, "main", "flip billing", []string{rel}); err != nil {
+		t.Fatal(err)
+	}
+	out, _ := exec.Command("git", "-C", origin, "log", "-1", "--format=%s", "main").Output()
+	if got := strings.TrimSpace(string(out)); got != "flip billing" {
+		t.Errorf("main head = %q", got)
+	}
+}
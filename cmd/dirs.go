@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// configDirFlag is the global --config-dir flag.
+var configDirFlag string
+
+// configDirOverride returns --config-dir or ACA_CONFIG_DIR. Both hold the
+// config and, under cache/, the caches, for CI runners whose home
+// directory is read-only.
+func configDirOverride() string {
+	if configDirFlag != "" {
+		return configDirFlag
+	}
+	return os.Getenv("ACA_CONFIG_DIR")
+}
+
+// xdgDir returns $env/gh-aca-utils, or fallback()/gh-aca-utils when env is
+// unset. os.UserConfigDir and os.UserCacheDir follow XDG on Linux but not
+// on macOS, so the variable is checked here first.
+func xdgDir(env string, fallback func() (string, error)) (string, error) {
+	base := os.Getenv(env)
+	if !filepath.IsAbs(base) {
+		var err error
+		if base, err = fallback(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(base, "gh-aca-utils"), nil
+}
+
+// getConfigDir returns the tool's config directory, creating it if needed:
+// --config-dir, ACA_CONFIG_DIR or $XDG_CONFIG_HOME/gh-aca-utils (by
+// default ~/.config/gh-aca-utils on Linux). A ~/.gh-aca-utils left by older
+// versions is moved there the first time.
+func getConfigDir() (string, error) {
+	configDir := configDirOverride()
+	if configDir == "" {
+		var err error
+		if configDir, err = xdgDir("XDG_CONFIG_HOME", os.UserConfigDir); err != nil {
+			return "", fmt.Errorf("failed to find the config directory: %w", err)
+		}
+		if home, err := os.UserHomeDir(); err == nil {
+			if dir, ok := migrateLegacyConfigDir(filepath.Join(home, ".gh-aca-utils"), configDir); !ok {
+				configDir = dir
+			}
+		}
+	}
+
+	if err := os.MkdirAll(configDir, 0750); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	return configDir, nil
+}
+
+// migrateLegacyConfigDir moves legacy to configDir when only legacy
+// exists. If the move fails legacy stays in use: it returns legacy and
+// false.
+func migrateLegacyConfigDir(legacy, configDir string) (string, bool) {
+	if _, err := os.Stat(legacy); err != nil {
+		return configDir, true
+	}
+	if _, err := os.Stat(configDir); !errors.Is(err, os.ErrNotExist) {
+		warnf("both %s and %s exist; using %s (remove the old one)", legacy, configDir, configDir)
+		return configDir, true
+	}
+	if err := os.MkdirAll(filepath.Dir(configDir), 0750); err == nil {
+		err = os.Rename(legacy, configDir)
+		if err == nil {
+			// Downloaded feeds now live in the cache directory.
+			_ = os.RemoveAll(filepath.Join(configDir, "cache"))
+			infof("Moved %s to %s", legacy, configDir)
+			return configDir, true
+		}
+		warnf("could not move %s to %s: %v; still using it", legacy, configDir, err)
+	}
+	return legacy, false
+}
+
+// getCacheDir returns the cache directory: cache/ under --config-dir or
+// ACA_CONFIG_DIR, otherwise $XDG_CACHE_HOME/gh-aca-utils (by default
+// ~/.cache/gh-aca-utils on Linux). It is not created.
+func getCacheDir() (string, error) {
+	if dir := configDirOverride(); dir != "" {
+		return filepath.Join(dir, "cache"), nil
+	}
+	return xdgDir("XDG_CACHE_HOME", os.UserCacheDir)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigDirs(t *testing.T) {
+	home, xdg := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(xdg, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(xdg, "cache"))
+	t.Setenv("ACA_CONFIG_DIR", "")
+
+	legacy := filepath.Join(home, ".gh-aca-utils")
+	if err := os.MkdirAll(filepath.Join(legacy, "cache"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(legacy, "journal.jsonl"), []byte("{}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := getConfigDir()
+	want := filepath.Join(xdg, "config", "gh-aca-utils")
+	if err != nil || dir != want {
+		t.Fatalf("getConfigDir = %s, %v; want %s", dir, err, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "journal.jsonl")); err != nil {
+		t.Errorf("journal not migrated: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cache")); !os.IsNotExist(err) {
+		t.Errorf("legacy cache was moved along: %v", err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("legacy directory still exists: %v", err)
+	}
+	if cache, err := getCacheDir(); err != nil || cache != filepath.Join(xdg, "cache", "gh-aca-utils") {
+		t.Errorf("getCacheDir = %s, %v", cache, err)
+	}
+
+	override := filepath.Join(t.TempDir(), "aca")
+	t.Setenv("ACA_CONFIG_DIR", override)
+	if dir, err := getConfigDir(); err != nil || dir != override {
+		t.Errorf("getConfigDir with ACA_CONFIG_DIR = %s, %v", dir, err)
+	}
+	configDirFlag = filepath.Join(override, "flag")
+	defer func() { configDirFlag = "" }()
+	if cache, err := getCacheDir(); err != nil || cache != filepath.Join(override, "flag", "cache") {
+		t.Errorf("getCacheDir with --config-dir = %s, %v", cache, err)
+	}
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dnsMatch      = "ok"
+	dnsUnresolved = "unresolved"
+)
+
+var (
+	hostnameColumn = rowColumn{Name: "hostname", Header: "Hostname", Value: func(r matchRow) string { return r.Hostname }}
+	dnsColumn      = rowColumn{Name: "dns", Header: "DNS", Value: func(r matchRow) string { return r.DNS }}
+
+	// hostnameRe matches a dotted DNS name with an alphabetic TLD, which
+	// keeps IPv4 literals and version strings out.
+	hostnameRe = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}\b`)
+)
+
+// lookupHostFunc matches net.Resolver.LookupHost.
+type lookupHostFunc func(ctx context.Context, host string) ([]string, error)
+
+// lineHostname returns the first hostname on a line that also holds an IP,
+// e.g. hosts-file entries or "10.0.0.5 # db.example.com". For key/value
+// lines only the text after the key is considered, since dotted keys look
+// like hostnames too.
+func lineHostname(line, key string) string {
+	if key != "" {
+		if i := strings.Index(line, key); i >= 0 {
+			line = line[i+len(key):]
+		}
+	}
+	return hostnameRe.FindString(line)
+}
+
+// dnsMapping is one line of a --dns-map file: an IP literal or a key glob,
+// followed by the hostname the matching IPs are expected to resolve from.
+type dnsMapping struct {
+	Pattern  string
+	Hostname string
+	addr     netip.Addr
+}
+
+// parseDNSMap reads "PATTERN HOSTNAME" lines. Because an IP literal is a
+// valid pattern, an /etc/hosts file can be used as-is; extra aliases after
+// the hostname are ignored.
+func parseDNSMap(r io.Reader) ([]dnsMapping, error) {
+	var out []dnsMapping
+	s := bufio.NewScanner(r)
+	lineNo := 0
+	for s.Scan() {
+		lineNo++
+		line := s.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected PATTERN HOSTNAME", lineNo)
+		}
+		m := dnsMapping{Pattern: fields[0], Hostname: fields[1]}
+		if a, err := netip.ParseAddr(m.Pattern); err == nil {
+			m.addr = a.Unmap()
+		} else if _, err := path.Match(m.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("line %d: invalid key pattern %q: %w", lineNo, m.Pattern, err)
+		}
+		out = append(out, m)
+	}
+	return out, s.Err()
+}
+
+func loadDNSMap(file string) ([]dnsMapping, error) {
+	if file == "" {
+		return nil, nil
+	}
+	fh, err := os.Open(file) // #nosec G304 - user-supplied mapping file
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DNS map: %w", err)
+	}
+	defer fh.Close()
+	m, err := parseDNSMap(fh)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return m, nil
+}
+
+// expectedHostname picks the hostname an IP row should match: an exact IP
+// mapping wins over a key glob, which wins over a hostname on the same line.
+func expectedHostname(r matchRow, addr netip.Addr, mapping []dnsMapping) string {
+	for _, m := range mapping {
+		if m.addr.IsValid() && m.addr == addr {
+			return m.Hostname
+		}
+	}
+	for _, m := range mapping {
+		if !m.addr.IsValid() && r.IPKey != "" {
+			if ok, _ := path.Match(m.Pattern, r.IPKey); ok {
+				return m.Hostname
+			}
+		}
+	}
+	return r.host
+}
+
+// checkDNS resolves the expected hostname of every IP row and records in
+// the DNS field whether the hardcoded IP is still among its addresses.
+func checkDNS(rows []matchRow, mapping []dnsMapping, jobs int, timeout time.Duration, lookup lookupHostFunc) {
+	var hosts []string
+	seen := map[string]bool{}
+	for i := range rows {
+		addr, ok := rowAddr(rows[i])
+		if !ok {
+			continue
+		}
+		rows[i].Hostname = expectedHostname(rows[i], addr, mapping)
+		if h := strings.ToLower(rows[i].Hostname); h != "" && !seen[h] {
+			seen[h] = true
+			hosts = append(hosts, h)
+		}
+	}
+
+	resolved := make(map[string][]netip.Addr, len(hosts))
+	var mu sync.Mutex
+	forEachLimit(hosts, jobs, func(host string) {
+		ctx, cancel := context.WithTimeout(runCtx, timeout)
+		defer cancel()
+		addrs, err := lookup(ctx, host)
+		if err != nil {
+			return
+		}
+		var parsed []netip.Addr
+		for _, a := range addrs {
+			if p, err := netip.ParseAddr(a); err == nil {
+				parsed = append(parsed, p.Unmap())
+			}
+		}
+		sort.Slice(parsed, func(i, j int) bool { return parsed[i].Less(parsed[j]) })
+		mu.Lock()
+		resolved[host] = parsed
+		mu.Unlock()
+	})
+
+	for i := range rows {
+		if rows[i].Hostname == "" {
+			continue
+		}
+		addr, _ := rowAddr(rows[i])
+		current, ok := resolved[strings.ToLower(rows[i].Hostname)]
+		if !ok || len(current) == 0 {
+			rows[i].DNS = dnsUnresolved
+			continue
+		}
+		rows[i].DNS = dnsStatus(addr, current)
+	}
+}
+
+func dnsStatus(addr netip.Addr, current []netip.Addr) string {
+	names := make([]string, len(current))
+	for i, a := range current {
+		if a == addr {
+			return dnsMatch
+		}
+		names[i] = a.String()
+	}
+	return "mismatch (now " + strings.Join(names, " ") + ")"
+}
+
+// warnDNSMismatches reports stale IPs on stderr so they stand out even in
+// CSV or JSON output.
+func warnDNSMismatches(rows []matchRow) {
+	for _, r := range rows {
+		if r.DNS != "" && r.DNS != dnsMatch {
+			warnf("%s:%d: %s for %s is %s", r.location(), r.LineNumber, r.IPValue, r.Hostname, r.DNS)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineHostname(t *testing.T) {
+	tests := []struct {
+		line, key, want string
+	}{
+		{"10.0.0.5   db.example.com db", "", "db.example.com"},
+		{"db.host=10.0.0.5 # db-1.internal.example.com", "db.host", "db-1.internal.example.com"},
+		{"db.host=10.0.0.5", "db.host", ""},
+		{"version=1.2.3.4", "version", ""},
+	}
+	for _, tt := range tests {
+		if got := lineHostname(tt.line, tt.key); got != tt.want {
+			t.Errorf("lineHostname(%q, %q) = %q, want %q", tt.line, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestParseDNSMap(t *testing.T) {
+	m, err := parseDNSMap(strings.NewReader("# hosts\n10.0.0.5 db.example.com db\n\ncache.* cache.example.com # glob\n"))
+	if err != nil {
+		t.Fatalf("parseDNSMap failed: %v", err)
+	}
+	if len(m) != 2 || m[0].Hostname != "db.example.com" || !m[0].addr.IsValid() || m[1].Pattern != "cache.*" {
+		t.Errorf("Unexpected mapping: %+v", m)
+	}
+
+	for _, bad := range []string{"lonely\n", "[bad example.com\n"} {
+		if _, err := parseDNSMap(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestCheckDNS(t *testing.T) {
+	rows := []matchRow{
+		{IPKey: "db.host", IPValue: "10.0.0.5", host: "db.example.com"},
+		{IPKey: "cache.primary", IPValue: "10.0.1.7"},
+		{IPValue: "10.0.2.1"},
+		{IPKey: "legacy", IPValue: "10.0.3.3", host: "gone.example.com"},
+		{IPKey: "other", IPValue: "10.0.4.4"},
+	}
+	mapping := []dnsMapping{{Pattern: "cache.*", Hostname: "cache.example.com"}}
+	mapping = append(mapping, mustDNSMap(t, "10.0.2.1 api.example.com")...)
+
+	lookup := func(ctx context.Context, host string) ([]string, error) {
+		switch host {
+		case "db.example.com":
+			return []string{"10.0.0.6", "10.0.0.5"}, nil
+		case "cache.example.com":
+			return []string{"10.0.1.9", "10.0.1.8"}, nil
+		case "api.example.com":
+			return []string{"10.0.2.1"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	checkDNS(rows, mapping, 2, time.Second, lookup)
+
+	want := []struct{ host, status string }{
+		{"db.example.com", dnsMatch},
+		{"cache.example.com", "mismatch (now 10.0.1.8 10.0.1.9)"},
+		{"api.example.com", dnsMatch},
+		{"gone.example.com", dnsUnresolved},
+		{"", ""},
+	}
+	for i, w := range want {
+		if rows[i].Hostname != w.host || rows[i].DNS != w.status {
+			t.Errorf("row %d = (%q, %q), want (%q, %q)", i, rows[i].Hostname, rows[i].DNS, w.host, w.status)
+		}
+	}
+}
+
+func TestScanFile_LineHostname(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hosts.txt")
+	if err := os.WriteFile(path, []byte("10.0.0.5 db.example.com\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	rows := scanFile(path, "hosts.txt", scanOptions{})
+	if len(rows) != 1 || rows[0].host != "db.example.com" {
+		t.Errorf("Expected hostname to be captured from the line, got %+v", rows)
+	}
+}
+
+func mustDNSMap(t *testing.T, s string) []dnsMapping {
+	t.Helper()
+	m, err := parseDNSMap(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/greenstevester/gh-aca-utils/pkg/jsonptr"
+	"github.com/greenstevester/gh-aca-utils/pkg/props"
+)
+
+// checkDriftMode validates --on-drift.
+func checkDriftMode(mode string) error {
+	if mode != "abort" && mode != "rebase" {
+		return usageErrorf("invalid --on-drift %q: want abort or rebase", mode)
+	}
+	return nil
+}
+
+// rebaseChanges replans changes against b, the upstream content of the
+// parameters file rel. Every adapter must still hold the value its change
+// was planned from, and adapters planned for creation must still be
+// absent; anything else is a conflict.
+func rebaseChanges(b []byte, rel string, changes []change) ([]change, error) {
+	planned := map[string]change{}
+	var want, conflicts []string
+	var creates []change
+	for _, c := range changes {
+		if c.Created {
+			creates = append(creates, c)
+			continue
+		}
+		planned[c.Adapter] = c
+		want = append(want, c.Adapter)
+	}
+	seen := map[string]bool{}
+	next := func(adapter, v string) (string, bool) {
+		seen[adapter] = true
+		c := planned[adapter]
+		if v != c.OldValue {
+			conflicts = append(conflicts, fmt.Sprintf("%s is now %q (planned from %q)", adapter, v, c.OldValue))
+			return "", false
+		}
+		return c.NewValue, true
+	}
+
+	var rebased []change
+	var has func(adapter string) bool
+	if isJSONParams(rel) {
+		var err error
+		if rebased, err = planJSONValues(b, want, rel, next); err != nil {
+			return nil, err
+		}
+		has = func(a string) bool {
+			_, err := jsonptr.Find(b, adapterPointer(a))
+			return err == nil || !jsonCreatable(b, adapterPointer(a))
+		}
+	} else {
+		lines := props.SplitLinesEOL(b)
+		index := indexKeys(lines)
+		rebased = planValues(lines, index, want, rel, next)
+		has = func(a string) bool {
+			_, ok := index[a]
+			return ok
+		}
+	}
+	for _, a := range want {
+		if !seen[a] {
+			conflicts = append(conflicts, a+" was removed")
+		}
+	}
+	for _, c := range creates {
+		if has(c.Adapter) {
+			conflicts = append(conflicts, c.Adapter+" was added")
+			continue
+		}
+		rebased = append(rebased, c)
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%s changed upstream and the flips no longer apply: %s", rel, strings.Join(conflicts, "; "))
+	}
+	return rebased, nil
+}
+
+// checkDrift makes sure nobody changed the files upstream since they were
+// read before committing over them. With --on-drift rebase, changed files
+// have their flips reapplied on top of the upstream content instead.
+func (r *flipRun) checkDrift() error {
+	head, err := newAPICheckout(r.repo)
+	if err != nil {
+		return err
+	}
+	base := ""
+	if r.api != nil {
+		base = r.api.baseSHA
+	} else if base, err = gitHead(r.dir); err != nil {
+		return err
+	}
+	if head.baseSHA == base {
+		return nil
+	}
+	drifted := false
+	for i := range r.flips {
+		f := &r.flips[i]
+		rel := path.Join("env", f.env, r.file)
+		up, err := head.read(rel)
+		if err != nil {
+			return err
+		}
+		orig := f.data
+		if !r.jsonParams {
+			orig = []byte(strings.Join(f.lines, ""))
+		}
+		if bytes.Equal(up, orig) {
+			continue
+		}
+		if r.onDrift != "rebase" {
+			return fmt.Errorf("%s changed upstream since it was read (%s is now at %.7s); nothing was written. Re-run, or use --on-drift rebase to reapply the flips on top", rel, head.base, head.baseSHA)
+		}
+		if f.changes, err = rebaseChanges(up, rel, f.changes); err != nil {
+			return err
+		}
+		if r.jsonParams {
+			f.data = up
+		} else {
+			f.lines = props.SplitLinesEOL(up)
+		}
+		infof("%s changed upstream; reapplied %d change(s) on top", rel, len(f.changes))
+		drifted = true
+	}
+	if !drifted {
+		return nil
+	}
+	if r.api != nil {
+		r.api.baseSHA = head.baseSHA
+	} else if sha, err := gitResetToRemote(r.dir, head.base); err != nil {
+		return err
+	} else if sha != head.baseSHA {
+		return fmt.Errorf("%s moved again while rebasing; re-run", head.base)
+	}
+	r.changes = r.changes[:0]
+	for _, f := range r.flips {
+		r.changes = append(r.changes, f.changes...)
+	}
+	r.res.Changes = r.changes
+	return nil
+}
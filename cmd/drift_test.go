@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/greenstevester/gh-aca-utils/pkg/props"
+)
+
+func TestRebaseChanges(t *testing.T) {
+	orig := "billing=0\nsearch=1\n"
+	lines := props.SplitLinesEOL([]byte(orig))
+	planned := append(planValues(lines, indexKeys(lines), []string{"billing", "search"}, "p", toggleNext(defaultTogglePairs)),
+		planCreates([]string{"crm"}, "0", "p")...)
+
+	tests := []struct {
+		name, upstream, want, err string
+	}{
+		{"unrelated edit", "# owner: team-a\nbilling=0\nsearch=1\ncache=on\n", "# owner: team-a\nbilling=1\nsearch=0\ncache=on\ncrm=0\n", ""},
+		{"value changed", "billing=1\nsearch=1\n", "", "billing is now \"1\" (planned from \"0\")"},
+		{"removed", "billing=0\n", "", "search was removed"},
+		{"created upstream", "billing=0\nsearch=1\ncrm=1\n", "", "crm was added"},
+	}
+	for _, tt := range tests {
+		got, err := rebaseChanges([]byte(tt.upstream), "env/dev/parameters.properties", planned)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		out := strings.Join(applyFlips(props.SplitLinesEOL([]byte(tt.upstream)), got), "")
+		if out != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, out, tt.want)
+		}
+	}
+}
+
+func TestRebaseChangesJSON(t *testing.T) {
+	orig := []byte(`{"Features": {"Billing": false}}`)
+	planned, err := planJSONValues(orig, []string{"/Features/Billing"}, "p", toggleNext(defaultTogglePairs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream := []byte("{\n  \"Features\": {\"Search\": true, \"Billing\": false}\n}\n")
+	got, err := rebaseChanges(upstream, "env/dev/appsettings.json", planned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := string(applyJSONFlips(upstream, got)); out != "{\n  \"Features\": {\"Search\": true, \"Billing\": true}\n}\n" {
+		t.Errorf("got %s", out)
+	}
+}
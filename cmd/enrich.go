@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ptrColumn = rowColumn{Name: "ptr", Header: "PTR", Value: func(r matchRow) string { return r.PTR }}
+
+// lookupAddrFunc matches net.Resolver.LookupAddr.
+type lookupAddrFunc func(ctx context.Context, addr string) ([]string, error)
+
+// rowAddr extracts the first parseable IP address from a row's IP value,
+// which may be a bare IP or a longer string containing one.
+func rowAddr(r matchRow) (netip.Addr, bool) {
+	candidate := firstIP(r.IPValue)
+	if candidate == "" {
+		return netip.Addr{}, false
+	}
+	addr, err := netip.ParseAddr(candidate)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// resolvePTRs fills in the PTR field of each row with an IP. Every distinct
+// address is looked up once, with at most jobs lookups in flight; failed
+// lookups leave the field empty.
+func resolvePTRs(rows []matchRow, jobs int, timeout time.Duration, lookup lookupAddrFunc) {
+	var addrs []string
+	seen := map[string]bool{}
+	for _, r := range rows {
+		if a, ok := rowAddr(r); ok && !seen[a.String()] {
+			seen[a.String()] = true
+			addrs = append(addrs, a.String())
+		}
+	}
+
+	names := make(map[string]string, len(addrs))
+	var mu sync.Mutex
+	forEachLimit(addrs, jobs, func(addr string) {
+		ctx, cancel := context.WithTimeout(runCtx, timeout)
+		defer cancel()
+		ptrs, err := lookup(ctx, addr)
+		if err != nil || len(ptrs) == 0 {
+			return
+		}
+		mu.Lock()
+		names[addr] = strings.TrimSuffix(ptrs[0], ".")
+		mu.Unlock()
+	})
+
+	for i := range rows {
+		if a, ok := rowAddr(rows[i]); ok {
+			rows[i].PTR = names[a.String()]
+		}
+	}
+}
+
+// forEachLimit calls fn for every item with at most jobs calls running
+// concurrently, and returns once all calls have finished.
+func forEachLimit(items []string, jobs int, fn func(string)) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, jobs)
+	for _, item := range items {
+		if canceled() {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(item string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(item)
+		}(item)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResolvePTRs(t *testing.T) {
+	rows := []matchRow{
+		{IPKey: "db.host", IPValue: "10.0.0.5"},
+		{IPKey: "db.replica", IPValue: "\"10.0.0.5\""},
+		{IPKey: "api.url", IPValue: "http://192.168.1.10:8080"},
+		{IPKey: "unknown", IPValue: "172.16.0.1"},
+		{PortKey: "server.port", PortValue: "8080"},
+	}
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	lookup := func(ctx context.Context, addr string) ([]string, error) {
+		mu.Lock()
+		calls[addr]++
+		mu.Unlock()
+		switch addr {
+		case "10.0.0.5":
+			return []string{"db-1.internal.example.", "db.example."}, nil
+		case "192.168.1.10":
+			return []string{"api.example."}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	resolvePTRs(rows, 2, time.Second, lookup)
+
+	want := []string{"db-1.internal.example", "db-1.internal.example", "api.example", "", ""}
+	for i, w := range want {
+		if rows[i].PTR != w {
+			t.Errorf("row %d PTR = %q, want %q", i, rows[i].PTR, w)
+		}
+	}
+	if calls["10.0.0.5"] != 1 {
+		t.Errorf("Expected one lookup per distinct address, got %d for 10.0.0.5", calls["10.0.0.5"])
+	}
+}
+
+func TestResolvePTRs_Timeout(t *testing.T) {
+	rows := []matchRow{{IPValue: "10.0.0.1"}}
+	lookup := func(ctx context.Context, addr string) ([]string, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	start := time.Now()
+	resolvePTRs(rows, 1, 20*time.Millisecond, lookup)
+	if time.Since(start) > time.Second {
+		t.Error("Expected lookup to be cut off by the timeout")
+	}
+	if rows[0].PTR != "" {
+		t.Errorf("Expected empty PTR after timeout, got %q", rows[0].PTR)
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// isEnvPattern reports whether --env holds glob metacharacters.
+func isEnvPattern(env string) bool {
+	return strings.ContainsAny(env, "*?[")
+}
+
+// resolveEnvs expands --env against the directories under root/env. A
+// plain name is returned as-is after validation; a pattern must match at
+// least one directory. Names are returned sorted.
+func resolveEnvs(root, env string) ([]string, error) {
+	if strings.Contains(env, "/") || strings.Contains(env, "\\") || strings.Contains(env, "..") {
+		return nil, usageErrorf("invalid environment name: %q", env)
+	}
+	if !isEnvPattern(env) {
+		if filepath.Clean(env) != env {
+			return nil, usageErrorf("invalid environment name: %q", env)
+		}
+		return []string{env}, nil
+	}
+	if _, err := path.Match(env, ""); err != nil {
+		return nil, usageErrorf("invalid environment pattern %q: %w", env, err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(root, "env"))
+	if err != nil {
+		return nil, fmt.Errorf("list environments: %w", err)
+	}
+	var envs []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if ok, _ := path.Match(env, e.Name()); ok {
+			envs = append(envs, e.Name())
+		}
+	}
+	if len(envs) == 0 {
+		return nil, fmt.Errorf("no environment under env/ matches %q", env)
+	}
+	sort.Strings(envs)
+	return envs, nil
+}
+
+var branchUnsafeRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// envBranchName derives the default flip branch from --env, which may be
+// a pattern.
+func envBranchName(env string) string {
+	return "toggle/adapters-" + branchSlug(env)
+}
+
+// branchSlug reduces s to characters that are safe in a branch name.
+func branchSlug(s string) string {
+	return strings.Trim(branchUnsafeRe.ReplaceAllString(s, "_"), "_.-")
+}
+
+// checkParamFile validates --file: a plain file name inside each env
+// directory.
+func checkParamFile(name string) error {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return usageErrorf("invalid --file %q: expected a file name such as parameters.json", name)
+	}
+	return nil
+}
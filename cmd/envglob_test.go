@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveEnvs(t *testing.T) {
+	root := t.TempDir()
+	for _, env := range []string{"dev", "dev2", "prod-eu", "prod-us", "prod-east"} {
+		if err := os.MkdirAll(filepath.Join(root, "env", env), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "env", "devnotes"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		env, want string
+	}{
+		{"dev", "dev"},
+		{"missing", "missing"}, // plain names are read directly and fail there
+		{"dev*", "dev,dev2"},
+		{"prod-??", "prod-eu,prod-us"},
+		{"prod-[e]*", "prod-east,prod-eu"},
+	}
+	for _, tt := range tests {
+		envs, err := resolveEnvs(root, tt.env)
+		if err != nil || strings.Join(envs, ",") != tt.want {
+			t.Errorf("resolveEnvs(%q) = %v, %v; want %s", tt.env, envs, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"qa*", "../dev", "a/b", "dev[", "./dev"} {
+		if _, err := resolveEnvs(root, bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+
+	if got := envBranchName("prod-??"); got != "toggle/adapters-prod" {
+		t.Errorf("envBranchName = %q", got)
+	}
+}
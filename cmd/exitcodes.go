@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/spf13/cobra"
+)
+
+// Exit statuses. Scripts tell "nothing found" from "could not look" by
+// them; `exit-codes` lists them. Keep exitCodes in step.
+const (
+	exitOK       = 0
+	exitFindings = 1
+	exitUsage    = 2
+	exitAuth     = 3
+	exitNetwork  = 4
+	exitNotFound = 5
+	exitStopped  = 6
+	exitFailure  = 7
+)
+
+// exitCodeDoc documents an exit status.
+type exitCodeDoc struct {
+	Code        int    `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+var exitCodes = []exitCodeDoc{
+	{exitOK, "ok", "Success; scans and checks found nothing that fails the run"},
+	{exitFindings, "findings", "Findings, policy violations or drift that fail the run (--fail-if-found, --fail-on, check, drift, verify)"},
+	{exitUsage, "usage", "Unknown command, or invalid flags or arguments"},
+	{exitAuth, "auth", "Not authenticated, or the token lacks access or scopes (HTTP 401/403, no push access)"},
+	{exitNetwork, "network", "Network failure, or GitHub unavailable or rate limiting (HTTP 429/5xx), after retries"},
+	{exitNotFound, "not-found", "Repository, branch or ref not found (HTTP 404)"},
+	{exitStopped, "stopped", "Interrupted by Ctrl-C or SIGTERM, or stopped by --timeout"},
+	{exitFailure, "error", "Any other failure, including some of several repositories failing"},
+}
+
+// codedError makes the command fail with a given exit status.
+type codedError struct {
+	err  error
+	code int
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+// withExitCode returns err as an error that exits with code.
+func withExitCode(code int, err error) error {
+	return &codedError{err: err, code: code}
+}
+
+// usageErrorf formats the error of an invalid flag or argument, which
+// exits with exitUsage.
+func usageErrorf(format string, args ...any) error {
+	return withExitCode(exitUsage, fmt.Errorf(format, args...))
+}
+
+// authOutput and notFoundOutput match what git, gh and go-gh report when a
+// clone, fetch, push or API call fails on credentials, or because the
+// repository or ref doesn't exist. GitHub answers "not found" for private
+// repositories the token can't see.
+var (
+	authOutput     = regexp.MustCompile(`(?i)authentication (failed|required)|could not read username|invalid username or password|bad credentials|permission denied \(publickey|permission to \S+ denied|authentication token not found|gh auth login`)
+	notFoundOutput = regexp.MustCompile(`(?i)repository not found|remote branch \S+ not found|couldn't find remote ref|does not appear to be a git repository`)
+)
+
+// classifyOutput gives err, a failed git or gh command, the exit status
+// its output stderr calls for.
+func classifyOutput(err error, stderr []byte) error {
+	switch {
+	case authOutput.Match(stderr):
+		return withExitCode(exitAuth, err)
+	case notFoundOutput.Match(stderr):
+		return withExitCode(exitNotFound, err)
+	}
+	return err
+}
+
+// exitCode returns the exit status for err, what a command returned.
+func exitCode(err error) int {
+	var fe *findingsError
+	var ce *codedError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &ce):
+		return ce.code
+	case errors.As(err, &fe):
+		return exitFindings
+	case isTransient(err):
+		return exitNetwork
+	}
+	switch httpStatus(err) {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return exitAuth
+	case http.StatusNotFound:
+		return exitNotFound
+	}
+	msg := err.Error()
+	switch {
+	case errors.Is(err, transport.ErrAuthenticationRequired), errors.Is(err, transport.ErrAuthorizationFailed), authOutput.MatchString(msg):
+		return exitAuth
+	case errors.Is(err, transport.ErrRepositoryNotFound), notFoundOutput.MatchString(msg):
+		return exitNotFound
+	}
+	return exitFailure
+}
+
+func cmdExitCodes() *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "exit-codes",
+		Short: "List the exit statuses of the commands and what they mean",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if asJSON {
+				enc := json.NewEncoder(stdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(exitCodes)
+			}
+			w := newTable()
+			w.AddRow("CODE", "NAME", "MEANING")
+			for _, c := range exitCodes {
+				w.AddRow(fmt.Sprint(c.Code), c.Name, c.Description)
+			}
+			w.Render()
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the exit statuses as JSON")
+	return cmd
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, exitOK},
+		{"findings", &findingsError{"2 finding(s) reported (--fail-if-found)"}, exitFindings},
+		{"quiet findings", &findingsError{}, exitFindings},
+		{"flag validation", usageErrorf("--repo ORG/REPO is required"), exitUsage},
+		{"wrapped flag validation", fmt.Errorf("org/svc: %w", usageErrorf("invalid --port-range %q", "x")), exitUsage},
+		{"message naming a flag", errors.New("--strict: 1 adapter(s) cannot be flipped"), exitFailure},
+		{"unauthorized", fmt.Errorf("GET repos/org/svc: %w", &api.HTTPError{StatusCode: 401}), exitAuth},
+		{"forbidden", &api.HTTPError{StatusCode: 403}, exitAuth},
+		{"no push access", withExitCode(exitAuth, errors.New("preflight: you don't have push access")), exitAuth},
+		{"git auth", errors.New("fatal: Authentication failed for 'https://github.com/org/svc.git/'"), exitAuth},
+		{"go-git auth", fmt.Errorf("clone: %w", transport.ErrAuthenticationRequired), exitAuth},
+		{"server error", &api.HTTPError{StatusCode: 502}, exitNetwork},
+		{"network", transientError{errors.New("exit status 128")}, exitNetwork},
+		{"not found", fmt.Errorf("clone org/svc: exit status 1; download: %w", &api.HTTPError{StatusCode: 404}), exitNotFound},
+		{"go-git not found", transport.ErrRepositoryNotFound, exitNotFound},
+		{"timeout", withExitCode(exitStopped, errors.New("timed out after 1m0s (--timeout)")), exitStopped},
+		{"other", errors.New("2 of 3 repositories failed"), exitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	_, colErr := parseColumns("ip,nope")
+	_, stageErr := rolloutStages("dev", "dev,prod")
+	for _, err := range []error{colErr, stageErr, checkMergeMethod("ff")} {
+		if got := exitCode(err); got != exitUsage {
+			t.Errorf("exitCode(%v) = %d, want %d", err, got, exitUsage)
+		}
+	}
+}
+
+func TestNetCommandExitCode(t *testing.T) {
+	withRetries(t, 0)
+	tests := []struct {
+		stderr string
+		want   int
+	}{
+		{"remote: Repository not found.\nfatal: repository 'https://github.com/org/nope.git/' not found", exitNotFound},
+		{"remote: Permission to org/svc.git denied to someone.", exitAuth},
+		{"fatal: could not read Username for 'https://github.com': terminal prompts disabled", exitAuth},
+		{"fatal: unable to access: Could not resolve host: github.com", exitNetwork},
+		{"error: pathspec 'x' did not match any file(s) known to git", exitFailure},
+	}
+	for _, tt := range tests {
+		err := netCommand("", "sh", "-c", fmt.Sprintf("printf '%%s\\n' %q >&2; exit 128", tt.stderr))
+		if got := exitCode(err); got != tt.want {
+			t.Errorf("%q: exit code %d, want %d", tt.stderr, got, tt.want)
+		}
+	}
+}
+
+func TestExitCodesJSON(t *testing.T) {
+	var out bytes.Buffer
+	resultOutput = &out
+	defer func() { resultOutput = nil }()
+	cmd := cmdExitCodes()
+	cmd.SetArgs([]string{"--json"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	var got []exitCodeDoc
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	seen := map[int]bool{}
+	for i, c := range got {
+		if c.Code != i || c.Name == "" || c.Description == "" || seen[c.Code] {
+			t.Errorf("entry %d = %+v", i, c)
+		}
+		seen[c.Code] = true
+	}
+	if len(got) != exitFailure+1 {
+		t.Errorf("%d exit codes documented, want %d", len(got), exitFailure+1)
+	}
+}
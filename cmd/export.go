@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// adapterExport is a snapshot of every adapter value in a repository, one
+// entry per environment.
+type adapterExport struct {
+	Repo         string      `json:"repo"`
+	Ref          string      `json:"ref,omitempty"`
+	File         string      `json:"file"`
+	ExportedAt   time.Time   `json:"exportedAt"`
+	Environments []envExport `json:"environments"`
+}
+
+type envExport struct {
+	Env      string            `json:"env"`
+	File     string            `json:"file"`
+	Adapters map[string]string `json:"adapters"`
+}
+
+// buildExport groups values read per environment; environments without
+// the parameters file are left out.
+func buildExport(repo, ref, paramFile string, envs []string, values [][]adapterValue, now time.Time) adapterExport {
+	out := adapterExport{Repo: repo, Ref: ref, File: paramFile, ExportedAt: now.UTC(), Environments: make([]envExport, 0, len(envs))}
+	for i, env := range envs {
+		if values[i] == nil {
+			continue
+		}
+		e := envExport{Env: env, Adapters: map[string]string{}}
+		for _, v := range values[i] {
+			e.File = v.File
+			if !v.Missing {
+				e.Adapters[v.Adapter] = v.Value
+			}
+		}
+		out.Environments = append(out.Environments, e)
+	}
+	return out
+}
+
+func printExport(x adapterExport, values [][]adapterValue, mode outputMode) error {
+	if mode == outCSV {
+		fmt.Fprintln(stdout(), "env,adapter,value,file")
+		for _, vs := range values {
+			for _, v := range vs {
+				if !v.Missing {
+					fmt.Fprintf(stdout(), "%s,%s,%s,%s\n", csvEsc(v.Env), csvEsc(v.Adapter), csvEsc(v.Value), csvEsc(v.File))
+				}
+			}
+		}
+		return nil
+	}
+	enc := json.NewEncoder(stdout())
+	enc.SetIndent("", "  ")
+	return enc.Encode(x)
+}
+
+func cmdExportAdapters() *cobra.Command {
+	var repo, ref, envsCSV, adaptersCSV, paramFile, mode string
+
+	cmd := &cobra.Command{
+		Use:   "export-adapters",
+		Short: "Dump every adapter value of every environment as one JSON or CSV document",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if repo == "" {
+				return usageErrorf("--repo ORG/REPO is required")
+			}
+			if err := checkParamFile(paramFile); err != nil {
+				return err
+			}
+			modeVal := parseMode(mode, outJSON)
+			if modeVal != outJSON && modeVal != outCSV {
+				return usageErrorf("invalid --output %q: want json or csv", mode)
+			}
+
+			tmpDir, cleanup, err := cloneOrDownload(repo, ref)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			envs, err := resolveEnvList(tmpDir, splitCSV(envsCSV, []string{"*"}))
+			if err != nil {
+				return err
+			}
+
+			want := splitCSV(adaptersCSV, nil)
+			values := make([][]adapterValue, len(envs))
+			for i, env := range envs {
+				values[i], err = readEnvValues(tmpDir, env, paramFile, want)
+				if errors.Is(err, fs.ErrNotExist) {
+					warnf("env/%s has no %s", env, paramFile)
+					continue
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return printExport(buildExport(repo, ref, paramFile, envs, values, time.Now()), values, modeVal)
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Target repo as ORG/REPO (required)")
+	cmd.Flags().StringVar(&ref, "ref", "", "Branch or tag (default: default branch)")
+	cmd.Flags().StringVar(&envsCSV, "env", "*", "Comma-separated environments or globs (default: all under env/)")
+	cmd.Flags().StringVar(&adaptersCSV, "adapters", "", "Comma-separated adapter keys (default: every key)")
+	cmd.Flags().StringVar(&paramFile, "file", "parameters.properties", "Parameters file in each env directory; .json files export every scalar by JSON pointer")
+	cmd.Flags().StringVar(&mode, "output", "json", "Output: json|csv")
+	setOutputModes(cmd, "json", "csv")
+	return cmd
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestBuildExport(t *testing.T) {
+	now := time.Date(2025, 6, 1, 2, 0, 0, 0, time.UTC)
+	values := [][]adapterValue{
+		{{Env: "dev", Adapter: "billing", Value: "1", File: "env/dev/parameters.properties"}, {Env: "dev", Adapter: "search", Value: "0", File: "env/dev/parameters.properties"}},
+		nil,
+		{{Env: "prod", Adapter: "billing", Value: "0", File: "env/prod/parameters.properties"}, {Env: "prod", Adapter: "search", Missing: true, File: "env/prod/parameters.properties"}},
+	}
+	x := buildExport("org/svc", "", "parameters.properties", []string{"dev", "empty", "prod"}, values, now)
+	b, err := json.Marshal(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"repo":"org/svc","file":"parameters.properties","exportedAt":"2025-06-01T02:00:00Z","environments":[` +
+		`{"env":"dev","file":"env/dev/parameters.properties","adapters":{"billing":"1","search":"0"}},` +
+		`{"env":"prod","file":"env/prod/parameters.properties","adapters":{"billing":"0"}}]}`
+	if string(b) != want {
+		t.Errorf("buildExport =\n%s\nwant\n%s", b, want)
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// findingsError reports that a command ran to completion but its findings
+// call for a non-zero exit status. An empty message exits silently.
+type findingsError struct {
+	msg string
+}
+
+func (e *findingsError) Error() string { return e.msg }
+
+// failPolicy counts findings that should make ip-port exit non-zero, for
+// use as a CI gate. Output is still printed in full.
+type failPolicy struct {
+	labels []string // --fail-on severity labels
+	any    bool     // --fail-if-found
+	quiet  bool     // --quiet: fail without a message
+	hits   int
+}
+
+func (p *failPolicy) count(rows []matchRow) {
+	for _, r := range rows {
+		if p.fails(r) {
+			p.hits++
+		}
+	}
+}
+
+// fails reports whether r violates the policy. A nil policy allows
+// everything.
+func (p *failPolicy) fails(r matchRow) bool {
+	if p == nil {
+		return false
+	}
+	if p.any {
+		return true
+	}
+	for _, l := range p.labels {
+		if containsLabel(r.Severity, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns a findingsError if any counted finding violates the
+// policy. The usage text is suppressed since the command itself succeeded;
+// with quiet so is the message.
+func (p *failPolicy) check(cmd *cobra.Command) error {
+	if p.hits == 0 {
+		return nil
+	}
+	cmd.SilenceUsage = true
+	switch {
+	case p.quiet:
+		cmd.SilenceErrors = true
+		return &findingsError{}
+	case p.any:
+		return &findingsError{fmt.Sprintf("%d finding(s) reported (--fail-if-found)", p.hits)}
+	}
+	return &findingsError{fmt.Sprintf("%d finding(s) labelled %s (--fail-on)", p.hits, strings.Join(p.labels, ","))}
+}
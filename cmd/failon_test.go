@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestFailPolicy(t *testing.T) {
+	rows := []matchRow{
+		{IPValue: "8.8.8.8", Severity: []string{"public-ip"}},
+		{IPValue: "10.0.0.1", PortValue: "22", Severity: []string{"private-ip", "privileged-port"}},
+		{IPValue: "10.0.0.2", Severity: []string{"private-ip"}},
+	}
+	tests := []struct {
+		policy failPolicy
+		hits   int
+	}{
+		{failPolicy{}, 0},
+		{failPolicy{labels: []string{"public-ip", "PRIVILEGED-PORT"}}, 2},
+		{failPolicy{labels: []string{"loopback"}}, 0},
+		{failPolicy{any: true}, 3},
+		{failPolicy{any: true, quiet: true}, 3},
+	}
+	for _, tt := range tests {
+		p := tt.policy
+		p.count(rows[:1])
+		p.count(rows[1:])
+		if p.hits != tt.hits {
+			t.Errorf("%+v: hits = %d, want %d", tt.policy, p.hits, tt.hits)
+		}
+		cmd := &cobra.Command{}
+		err := p.check(cmd)
+		if (err != nil) != (tt.hits > 0) {
+			t.Errorf("%+v: check() = %v", tt.policy, err)
+		}
+		if err == nil {
+			continue
+		}
+		if _, ok := err.(*findingsError); !ok || !cmd.SilenceUsage {
+			t.Errorf("Unexpected policy error %#v (usage silenced: %t)", err, cmd.SilenceUsage)
+		}
+		if quiet := tt.policy.quiet; quiet != (err.Error() == "") || quiet != cmd.SilenceErrors {
+			t.Errorf("Expected a silent error only with quiet, got %q", err)
+		}
+		if !tt.policy.quiet && !strings.Contains(err.Error(), "finding(s)") {
+			t.Errorf("Unexpected policy error message %q", err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type portRange struct {
+	lo, hi int
+}
+
+// portFilter restricts findings to a set of port ranges. An empty filter
+// keeps every row.
+type portFilter []portRange
+
+// parsePortFilter builds a filter from --port-range ("1024-9999,30000-32767")
+// and --ports ("8080,8443"). Both flags contribute to the same allow set.
+func parsePortFilter(ranges, ports string) (portFilter, error) {
+	var pf portFilter
+	for _, r := range splitCSV(ranges, nil) {
+		loStr, hiStr, ok := strings.Cut(r, "-")
+		if !ok {
+			return nil, usageErrorf("invalid --port-range %q: expected LOW-HIGH", r)
+		}
+		lo, err := parsePort(loStr)
+		if err != nil {
+			return nil, usageErrorf("invalid --port-range %q: %w", r, err)
+		}
+		hi, err := parsePort(hiStr)
+		if err != nil {
+			return nil, usageErrorf("invalid --port-range %q: %w", r, err)
+		}
+		if lo > hi {
+			return nil, usageErrorf("invalid --port-range %q: low port is greater than high port", r)
+		}
+		pf = append(pf, portRange{lo, hi})
+	}
+	for _, p := range splitCSV(ports, nil) {
+		n, err := parsePort(p)
+		if err != nil {
+			return nil, usageErrorf("invalid --ports value %q: %w", p, err)
+		}
+		pf = append(pf, portRange{n, n})
+	}
+	return pf, nil
+}
+
+func parsePort(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 || n > 65535 {
+		return 0, fmt.Errorf("port must be a number between 1 and 65535")
+	}
+	return n, nil
+}
+
+func (pf portFilter) allows(port string) bool {
+	n, err := strconv.Atoi(stripQuotes(port))
+	if err != nil {
+		return false
+	}
+	for _, r := range pf {
+		if n >= r.lo && n <= r.hi {
+			return true
+		}
+	}
+	return false
+}
+
+// apply keeps only rows whose port falls inside the filter. Rows without a
+// port are dropped while a filter is active, since they can't match.
+func (pf portFilter) apply(rows []matchRow) []matchRow {
+	if len(pf) == 0 {
+		return rows
+	}
+	out := rows[:0]
+	for _, r := range rows {
+		if r.PortValue != "" && pf.allows(r.PortValue) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestParsePortFilter(t *testing.T) {
+	tests := []struct {
+		ranges  string
+		ports   string
+		wantLen int
+		wantErr bool
+	}{
+		{"", "", 0, false},
+		{"1024-9999", "", 1, false},
+		{"1024-9999, 30000-32767", "", 2, false},
+		{"", "8080,8443", 2, false},
+		{"1-1023", "8080", 2, false},
+		{"9999-1024", "", 0, true},
+		{"1024", "", 0, true},
+		{"0-80", "", 0, true},
+		{"", "70000", 0, true},
+		{"", "http", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ranges+"|"+tt.ports, func(t *testing.T) {
+			got, err := parsePortFilter(tt.ranges, tt.ports)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePortFilter(%q, %q) error = %v, wantErr %v", tt.ranges, tt.ports, err, tt.wantErr)
+			}
+			if len(got) != tt.wantLen {
+				t.Errorf("parsePortFilter(%q, %q) returned %d ranges, want %d", tt.ranges, tt.ports, len(got), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestPortFilter_Apply(t *testing.T) {
+	rows := []matchRow{
+		{PortKey: "server.port", PortValue: "8080"},
+		{PortKey: "admin.port", PortValue: "443"},
+		{PortKey: "grpc.port", PortValue: "\"9090\""},
+		{IPKey: "db.host", IPValue: "10.0.0.1"},
+	}
+
+	pf, err := parsePortFilter("9000-9999", "8080")
+	if err != nil {
+		t.Fatalf("parsePortFilter failed: %v", err)
+	}
+	got := pf.apply(append([]matchRow(nil), rows...))
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 rows, got %d: %+v", len(got), got)
+	}
+	if got[0].PortValue != "8080" || got[1].PortKey != "grpc.port" {
+		t.Errorf("Unexpected rows kept: %+v", got)
+	}
+
+	var empty portFilter
+	if got := empty.apply(append([]matchRow(nil), rows...)); len(got) != len(rows) {
+		t.Errorf("Expected empty filter to keep all %d rows, got %d", len(rows), len(got))
+	}
+}
@@ -0,0 +1,702 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/browser"
+	"github.com/greenstevester/gh-aca-utils/pkg/jsonptr"
+	"github.com/greenstevester/gh-aca-utils/pkg/props"
+)
+
+// flipOptions are the checked flip-adapters flags every repository is
+// flipped with.
+type flipOptions struct {
+	env, adapters, file, branch string
+	fileChanged, branchChanged  bool // --file and --branch were given
+
+	revert, strict, fixTypos bool
+	planFile, createMissing  string
+	pairs                    []togglePair
+
+	dryRun, doCommit, doPR, direct bool
+	noClone, noSparse              bool
+	yes, lock, forceUnlock         bool
+	onDrift                        string
+	onExisting                     branchPolicy
+	tmpls                          flipTemplates
+	prOpts                         prOptions
+	web, autoMerge                 bool
+	mergeMethod                    string
+	issueRef                       string
+	newIssue                       bool
+	deployment                     bool
+	deploymentEnv                  string
+	mergeTimeout                   time.Duration
+	waitForChecks                  bool
+	checksTimeout                  time.Duration
+
+	// report prints the changes of one repository when perRepoReport is
+	// set; structured is set for JSON and CSV output.
+	mode                      outputMode
+	structured, perRepoReport bool
+	report                    func(repo string, changes []change) error
+}
+
+// envFlip is the planned change to one environment's parameters file.
+type envFlip struct {
+	env, propPath string
+	data          []byte   // a JSON file's content
+	lines         []string // a .properties file's lines
+	changes       []change
+}
+
+// flipRun is flip-adapters in one repository. Its phases run in order, each
+// filling in the state the next ones read.
+type flipRun struct {
+	flipOptions // env, adapters, file and branch are this repository's
+	repo        string
+	res         *flipResult
+
+	// prepare
+	reverting     journalEntry
+	want          []string
+	jsonParams    bool
+	access        pushAccess
+	defaultBranch string // the branch --direct commits to
+
+	// checkout and selectEnvs
+	api    *apiCheckout // set with --no-clone
+	dir    string
+	sparse bool
+	envs   []string
+	cfg    map[string]configValue
+
+	// plan
+	flips   []envFlip
+	changes []change
+	skipped []string
+	issue   trackingIssue
+
+	// gate
+	flipped  []string // the environments with changes
+	approved approval
+
+	// commit and openPR
+	verb, sha, prURL string
+	exists           bool // the branch is already on the remote
+	data             flipMessageData
+}
+
+// flipRepo flips the adapters o selects in repo and fills in res.
+func (o flipOptions) flipRepo(repo string, res *flipResult) error {
+	r := &flipRun{flipOptions: o, repo: repo, res: res}
+	if err := r.prepare(); err != nil {
+		return err
+	}
+	cleanup, err := r.checkout()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if err := r.selectEnvs(); err != nil {
+		return err
+	}
+	if err := r.plan(); err != nil {
+		return err
+	}
+	if len(r.changes) == 0 {
+		return r.reportUnchanged()
+	}
+	if r.dryRun {
+		return r.reportDryRun()
+	}
+	release, err := r.gate()
+	if err != nil {
+		return err
+	}
+	defer release()
+	if r.doCommit {
+		if err := r.checkDrift(); err != nil {
+			return err
+		}
+	}
+	if err := r.write(); err != nil {
+		return err
+	}
+	if !r.doCommit {
+		return nil
+	}
+	if err := r.commit(); err != nil {
+		return err
+	}
+	if r.doPR {
+		if err := r.openPR(); err != nil {
+			return err
+		}
+	}
+	return r.finish()
+}
+
+// prepare works out what to flip, from the flags or from the journaled run
+// --revert undoes, and checks the user can push it.
+func (r *flipRun) prepare() error {
+	if r.revert {
+		journal, err := journalPath()
+		if err != nil {
+			return err
+		}
+		entries, err := readJournal(journal)
+		if err != nil {
+			return err
+		}
+		var ok bool
+		if r.reverting, ok = lastRevertible(entries, r.repo); !ok {
+			return fmt.Errorf("no applied flip-adapters run for %s to revert in %s", r.repo, journal)
+		}
+		infof("Reverting run %s from %s (%s)", r.reverting.ID, r.reverting.Time.Local().Format(time.RFC1123), strings.Join(r.reverting.envs(), ", "))
+		r.env = strings.Join(r.reverting.envs(), ",")
+		r.adapters = strings.Join(r.reverting.adapters(), ",")
+		r.file = r.reverting.File
+	}
+	var err error
+	if r.want, err = adaptersOrStored(r.adapters, r.repo, r.env); err != nil {
+		return err
+	}
+	if err := checkParamFile(r.file); err != nil {
+		return err
+	}
+	if r.jsonParams = isJSONParams(r.file); r.jsonParams && r.planFile != "" {
+		return usageErrorf("--plan is only supported for .properties files")
+	}
+
+	if r.doCommit && !r.dryRun {
+		if r.access, err = preflight(r.repo, false); err != nil {
+			return err
+		}
+		if r.access.fork && r.noClone {
+			return withExitCode(exitAuth, fmt.Errorf("--no-clone needs push access to %s; run without it to open the pull request from your fork", r.repo))
+		}
+	}
+	if r.direct && !r.dryRun {
+		if r.access.fork {
+			return withExitCode(exitAuth, fmt.Errorf("--direct needs push access to %s", r.repo))
+		}
+		if r.defaultBranch, err = checkDirectPush(r.repo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkout gets the repository through the contents API with --no-clone,
+// else as a sparse clone, falling back to a whole one.
+func (r *flipRun) checkout() (func(), error) {
+	var cleanup func()
+	var err error
+	switch {
+	case r.noClone:
+		if r.api, err = newAPICheckout(r.repo); err != nil {
+			return nil, err
+		}
+		r.dir, cleanup, err = r.api.checkout()
+	case !r.noSparse && !useGoGit():
+		if r.dir, cleanup, err = sparseClone(r.repo); err == nil {
+			r.sparse = true
+			break
+		}
+		if canceled() {
+			return nil, err
+		}
+		debugf("sparse clone of %s failed (%v); cloning it whole", r.repo, err)
+		fallthrough
+	default:
+		r.dir, cleanup, err = cloneOrDownload(r.repo, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cleanup, nil
+}
+
+// selectEnvs resolves the environments to flip and fetches their files
+// along with the repository's config, which may name the parameters file.
+func (r *flipRun) selectEnvs() error {
+	r.envs = r.reverting.envs()
+	var err error
+	if !r.revert {
+		if r.envs, err = resolveEnvList(r.dir, splitCSV(r.env, nil)); err != nil {
+			return err
+		}
+	}
+	if r.sparse {
+		if err := sparseCheckout(r.dir, r.envs); err != nil {
+			return err
+		}
+	}
+	if r.api != nil {
+		r.api.fetchConfig(r.dir)
+	}
+	r.cfg = loadConfig(r.dir)
+	if !r.revert {
+		if r.file, err = repoParamFile(r.file, r.fileChanged, r.cfg); err != nil {
+			return err
+		}
+		if r.jsonParams = isJSONParams(r.file); r.jsonParams && r.planFile != "" {
+			return usageErrorf("--plan is only supported for .properties files")
+		}
+	}
+	if r.api != nil {
+		for _, env := range r.envs {
+			if err := r.api.fetch(r.dir, path.Join("env", env, r.file)); err != nil {
+				return err
+			}
+		}
+	}
+	if isEnvPattern(r.env) {
+		infof("Environments matching %q: %s", r.env, strings.Join(r.envs, ", "))
+	}
+	if len(r.envs) > 1 && r.planFile != "" {
+		return usageErrorf("--plan needs a single environment; %q matches %d", r.env, len(r.envs))
+	}
+	if len(r.envs) > 1 && r.deploymentEnv != "" {
+		return usageErrorf("--deployment-environment needs a single environment; %q matches %d", r.env, len(r.envs))
+	}
+	return nil
+}
+
+// plan works out the changes to each environment's file on its own; they
+// are reported and committed together.
+func (r *flipRun) plan() error {
+	cfgPairs, _ := r.cfg["toggle.pairs"].Value.([]togglePair)
+	byAdapter, _ := r.cfg["toggle.adapters"].Value.(map[string]togglePair)
+	pairs := withConfigPairs(r.pairs, cfgPairs)
+	typoFixes, typoPrompter := map[string]string{}, newPrompter()
+	for _, env := range r.envs {
+		want, next := r.want, toggleNextFor(pairs, byAdapter)
+		if r.revert {
+			want, next = r.reverting.revertPlan(env)
+		}
+		if err := r.planEnv(env, want, next, typoFixes, typoPrompter); err != nil {
+			return err
+		}
+	}
+
+	if err := checkConstraints(r.cfg, r.changes); err != nil {
+		return err
+	}
+	if r.strict && len(r.skipped) > 0 {
+		return fmt.Errorf("--strict: %d adapter(s) cannot be flipped, nothing was changed: %s", len(r.skipped), strings.Join(r.skipped, ", "))
+	}
+	if r.issueRef != "" && len(r.changes) > 0 {
+		var err error
+		if r.issue, err = lookupIssue(r.issueRef, r.repo); err != nil {
+			return err
+		}
+		r.linkIssue()
+	}
+	r.res.Changes = r.changes
+	return nil
+}
+
+// planEnv plans the flips of want in env's parameters file. typoFixes
+// carries the corrections --fix-typos made in earlier environments.
+func (r *flipRun) planEnv(env string, want []string, next func(adapter, v string) (string, bool), typoFixes map[string]string, typoPrompter *prompter) error {
+	relPath := path.Join("env", env, r.file)
+	propPath := filepath.Join(r.dir, filepath.FromSlash(relPath))
+	// Double-check path is within expected directory
+	if !strings.HasPrefix(propPath, filepath.Join(r.dir, "env")+string(os.PathSeparator)) {
+		return fmt.Errorf("invalid file path")
+	}
+	b, err := os.ReadFile(propPath) // #nosec G304 - path is validated above
+	if err != nil {
+		return fmt.Errorf("read %s: %w", propPath, err)
+	}
+	if r.fixTypos {
+		var has func(a string) bool
+		var keys []string
+		if r.jsonParams {
+			has = func(a string) bool {
+				_, err := jsonptr.Find(b, adapterPointer(a))
+				return err == nil
+			}
+			keys = jsonAdapterKeys(b)
+		} else {
+			index := indexKeys(props.SplitLinesEOL(b))
+			has = func(a string) bool {
+				_, ok := index[a]
+				return ok
+			}
+			keys = mapKeys(index)
+		}
+		if want, err = fixTypos(want, has, keys, typoFixes, relPath, typoPrompter); err != nil {
+			return err
+		}
+	}
+
+	if r.jsonParams {
+		var missing []string
+		if r.createMissing != "" {
+			// Adapters that can't be added (no parent object) go
+			// through planning and are reported as not found.
+			want, missing = splitMissing(want, func(a string) bool {
+				_, err := jsonptr.Find(b, adapterPointer(a))
+				return err == nil || !jsonCreatable(b, adapterPointer(a))
+			})
+			createMissingNote(env, missing, r.createMissing)
+		}
+		envChanges, err := planJSONValues(b, want, relPath, next)
+		if err != nil {
+			return err
+		}
+		envChanges = append(envChanges, planCreates(missing, r.createMissing, relPath)...)
+		for _, a := range unplanned(want, envChanges) {
+			r.skipped = append(r.skipped, env+"/"+a)
+		}
+		if len(envChanges) > 0 {
+			r.flips = append(r.flips, envFlip{env: env, propPath: propPath, data: b, changes: envChanges})
+			r.changes = append(r.changes, envChanges...)
+		}
+		return nil
+	}
+
+	lines := props.SplitLinesEOL(b)
+	index := indexKeys(lines)
+	var missing []string
+	if r.createMissing != "" {
+		want, missing = splitMissing(want, func(a string) bool {
+			_, ok := index[a]
+			return ok
+		})
+		createMissingNote(env, missing, r.createMissing)
+	}
+	envChanges := planValues(lines, index, want, relPath, next)
+	envChanges = append(envChanges, planCreates(missing, r.createMissing, relPath)...)
+	for _, a := range unplanned(want, envChanges) {
+		r.skipped = append(r.skipped, env+"/"+a)
+	}
+
+	if r.planFile != "" && !r.dryRun {
+		plan, err := loadFlipPlan(r.planFile)
+		if err != nil {
+			return err
+		}
+		if plan.Repo != r.repo || plan.Env != env {
+			return fmt.Errorf("plan %s was captured for %s env %q, not %s env %q", r.planFile, plan.Repo, plan.Env, r.repo, env)
+		}
+		envChanges, err = reconcilePlan(plan, envChanges, lines, index, relPath, newPrompter())
+		if err != nil {
+			return err
+		}
+	}
+	if len(envChanges) > 0 {
+		r.flips = append(r.flips, envFlip{env: env, propPath: propPath, lines: lines, changes: envChanges})
+		r.changes = append(r.changes, envChanges...)
+	}
+	return nil
+}
+
+// linkIssue links the changes to the tracking issue.
+func (r *flipRun) linkIssue() {
+	for i := range r.changes {
+		r.changes[i].IssueURL = r.issue.URL
+	}
+	r.res.IssueURL = r.issue.URL
+}
+
+// reportUnchanged reports that there was nothing to flip.
+func (r *flipRun) reportUnchanged() error {
+	if !r.perRepoReport {
+		return nil
+	}
+	if r.mode == outJSON {
+		if err := json.NewEncoder(stdout()).Encode([]change{}); err != nil {
+			return fmt.Errorf("encode JSON: %w", err)
+		}
+		return nil
+	}
+	if r.mode == outCSV {
+		printChangeCSV(r.repo, nil, true)
+		return nil
+	}
+	fmt.Fprintln(stdout(), "No changes made.")
+	return nil
+}
+
+// reportDryRun saves --plan and reports the changes without making them.
+func (r *flipRun) reportDryRun() error {
+	if r.planFile != "" {
+		if err := saveFlipPlan(r.planFile, r.repo, r.envs[0], r.changes); err != nil {
+			return err
+		}
+		infof("Plan written to %s", r.planFile)
+	}
+	return r.report(r.repo, r.changes)
+}
+
+// gate asks for the confirmation and approval protected environments need
+// and takes the --lock, returning its release.
+func (r *flipRun) gate() (func(), error) {
+	patterns, _ := r.cfg["protect.environments"].Value.([]string)
+	typeName, _ := r.cfg["protect.typeName"].Value.(bool)
+	r.flipped = make([]string, len(r.flips))
+	for i, f := range r.flips {
+		r.flipped[i] = f.env
+	}
+	protected := protectedEnvs(r.flipped, patterns)
+	if err := confirmProtected(r.repo, protected, typeName, r.yes, newPrompter()); err != nil {
+		return nil, err
+	}
+	var err error
+	if r.approved, err = requireApproval(r.repo, protected, r.cfg); err != nil {
+		return nil, err
+	}
+	if r.doCommit && (r.lock || r.forceUnlock) {
+		return acquireFlipLock(r.repo, r.flipped, r.forceUnlock)
+	}
+	return func() {}, nil
+}
+
+// reportPR is set when the JSON and CSV reports wait for the pull request
+// so they can carry its URL.
+func (r *flipRun) reportPR() bool {
+	return r.doPR && r.structured
+}
+
+// write saves the flipped files, opens the --create-issue issue and
+// reports the changes unless the report waits for the pull request.
+func (r *flipRun) write() error {
+	for _, f := range r.flips {
+		var out []byte
+		if r.jsonParams {
+			out = applyJSONFlips(f.data, f.changes)
+		} else {
+			out = []byte(strings.Join(applyFlips(f.lines, f.changes), ""))
+		}
+		if err := os.WriteFile(f.propPath, out, 0600); err != nil {
+			return fmt.Errorf("write %s: %w", f.propPath, err)
+		}
+	}
+
+	if r.newIssue {
+		title := fmt.Sprintf("Flip adapters in %s: %s", strings.Join(r.flipped, ", "), strings.Join(r.want, ", "))
+		body := fmt.Sprintf("Tracks this change to %s, made with gh aca-utils flip-adapters:\n\n%s", r.repo, changeList(r.changes))
+		var err error
+		if r.issue, err = createIssue(r.repo, title, body); err != nil {
+			return err
+		}
+		infof("Created tracking issue %s", r.issue.URL)
+		r.linkIssue()
+	}
+
+	if !r.reportPR() {
+		return r.report(r.repo, r.changes)
+	}
+	return nil
+}
+
+// commit commits the flipped files to the branch, or to the default branch
+// with --direct, and pushes them.
+func (r *flipRun) commit() error {
+	r.verb = "flip"
+	if r.direct {
+		r.branch = r.defaultBranch
+	} else if r.branch == "" {
+		r.branch = envBranchName(r.env)
+	}
+	if r.revert {
+		r.verb = "revert"
+		if !r.branchChanged {
+			r.branch = "revert/adapters-" + branchSlug(strings.Join(r.flipped, "-"))
+		}
+	}
+	if err := r.access.prepare(r.dir); err != nil {
+		return err
+	}
+	var existing string // head of the branch on the remote, for --no-clone
+	var err error
+	switch {
+	case r.direct:
+		// The commit goes on the default branch as cloned; the drift
+		// check made sure it is current.
+	case r.api != nil:
+		existing, err = r.api.branchSHA(r.branch)
+		r.exists = existing != ""
+	default:
+		r.exists, err = remoteBranchExists(r.dir, r.branch)
+	}
+	if err != nil {
+		return err
+	}
+	if !r.direct {
+		if resolved, err := r.onExisting.resolve(r.branch, r.exists, time.Now()); err != nil {
+			return err
+		} else if resolved != r.branch {
+			r.branch, r.exists, existing = resolved, false, ""
+		}
+	}
+	r.data = flipMessageData{Repo: r.repo, Env: strings.Join(r.flipped, ","), Envs: r.flipped, Adapters: strings.Join(r.want, ","), Branch: r.branch, Verb: r.verb, Changes: r.changes}
+	if r.issue.URL != "" {
+		r.data.Issue = r.issue.ref(r.repo)
+	}
+	msg, err := renderFlipTemplate(r.tmpls.commit, fmt.Sprintf("chore(env:%s): %s adapters %s", r.data.Env, r.verb, r.data.Adapters), r.data)
+	if err != nil {
+		return err
+	}
+	msg = withIssueRef(msg, r.data.Issue)
+
+	if r.api != nil {
+		files := map[string][]byte{}
+		for _, env := range r.flipped {
+			rel := path.Join("env", env, r.file)
+			b, err := os.ReadFile(filepath.Join(r.dir, filepath.FromSlash(rel))) // #nosec G304 - written above
+			if err != nil {
+				return err
+			}
+			files[rel] = b
+		}
+		policy := r.onExisting
+		if r.direct {
+			// Fast-forward the default branch from the commit the
+			// files were read at.
+			existing, policy = r.api.baseSHA, branchReuse
+		}
+		r.sha, err = r.api.commit(r.branch, msg, files, existing, policy)
+		return err
+	}
+	paths := make([]string, len(r.flipped))
+	for i, env := range r.flipped {
+		paths[i] = filepath.Join("env", env, r.file)
+	}
+	if r.direct {
+		return gitCommitDirect(r.dir, r.branch, msg, paths)
+	}
+	return gitCommitBranch(r.dir, r.branch, msg, paths, r.exists, r.onExisting)
+}
+
+// openPR opens the pull request for the branch, or finds the one already
+// open, and queues it for auto-merge.
+func (r *flipRun) openPR() error {
+	title := fmt.Sprintf("Flip adapters in %s: %s", strings.Join(r.flipped, ", "), strings.Join(r.want, ", "))
+	body := "Automated via gh aca-utils flip-adapters."
+	if r.revert {
+		title = fmt.Sprintf("Revert adapter flips in %s: %s", strings.Join(r.flipped, ", "), strings.Join(r.want, ", "))
+		body = fmt.Sprintf("Automated via gh aca-utils flip-adapters --revert of run %s.", r.reverting.ID)
+	}
+	var err error
+	if body, err = renderFlipTemplate(r.tmpls.body, body, r.data); err != nil {
+		return err
+	}
+	body = withIssueRef(body, r.data.Issue)
+	if r.exists {
+		if r.prURL, err = existingPR(r.repo, r.access.headOwner(r.repo), r.branch); err != nil {
+			return err
+		}
+	}
+	opts := r.prOpts
+	if r.prURL == "" {
+		read := readIn(r.dir)
+		switch {
+		case r.api != nil:
+			read = r.api.read
+		case r.sparse:
+			read = sparseRead(r.dir)
+		}
+		paths := make([]string, len(r.flipped))
+		for i, env := range r.flipped {
+			paths[i] = path.Join("env", env, r.file)
+		}
+		opts.reviewers = withReviewers(opts.reviewers, codeownersReviewers(read, paths, ghLogin))
+	}
+	switch {
+	case r.prURL != "":
+		infof("Updated existing pull request")
+	case r.api != nil:
+		r.prURL, err = r.api.openPR(r.branch, title, body, opts)
+	default:
+		r.prURL, err = createPR(r.dir, title, body, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	if r.reportPR() {
+		infof("Pull request: %s", r.prURL)
+		withPR := append([]change(nil), r.changes...)
+		for i := range withPR {
+			withPR[i].PRURL = r.prURL
+		}
+		if err := r.report(r.repo, withPR); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintln(stdout(), r.prURL)
+	}
+	if r.web {
+		// The URL is already printed above, so a missing browser only warns.
+		if err := browser.New("", os.Stderr, os.Stderr).Browse(r.prURL); err != nil {
+			warnf("open %s in browser: %v", r.prURL, err)
+		}
+	}
+	if r.autoMerge {
+		return enableAutoMerge(r.prURL, r.mergeMethod)
+	}
+	return nil
+}
+
+// finish records the deployment and the journal entry for the pushed
+// commit, and waits for its checks with --wait-checks.
+func (r *flipRun) finish() error {
+	var err error
+	if r.sha == "" && (r.deployment || r.waitForChecks) {
+		if r.sha, err = gitHead(r.dir); err != nil {
+			return err
+		}
+	}
+	if r.deployment {
+		if err := r.deploy(); err != nil {
+			return err
+		}
+	}
+	entry := journalEntry{Command: r.verb, Repo: r.repo, File: r.file, Branch: r.branch, PRURL: r.prURL, Reverts: r.reverting.ID, Approver: r.approved.By, ApprovalRun: r.approved.Run, Issue: r.issue.URL}
+	for _, f := range r.flips {
+		for _, c := range f.changes {
+			entry.Changes = append(entry.Changes, journalChange{Env: f.env, Adapter: c.Adapter, Old: c.OldValue, New: c.NewValue, Created: c.Created})
+		}
+	}
+	recordJournal(entry)
+	r.res.Branch, r.res.PRURL = r.branch, r.prURL
+	if r.waitForChecks {
+		r.res.Checks, err = waitChecks(r.repo, r.sha, checksInterval, r.checksTimeout)
+		return err
+	}
+	return nil
+}
+
+// deploy records a GitHub Deployment of each flipped environment. The flip
+// is deployed once it is on the default branch: pushed there with
+// --direct, or merged.
+func (r *flipRun) deploy() error {
+	deployed := r.sha
+	if !r.direct {
+		var err error
+		if deployed, err = waitMerged(r.prURL, checksInterval, r.mergeTimeout); err != nil {
+			return fmt.Errorf("--deployment: %w", err)
+		}
+	}
+	for _, f := range r.flips {
+		env := r.deploymentEnv
+		if env == "" {
+			env = f.env
+		}
+		if err := recordDeployment(r.repo, deployed, env, f.changes, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// formatFuncs are available in --format templates.
+var formatFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+}
+
+// parseRowFormat compiles a --format template. Escapes such as \t are
+// interpreted so tab-separated formats can be written on the command line.
+func parseRowFormat(format string) (*template.Template, error) {
+	if format == "" {
+		return nil, nil
+	}
+	format = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(format)
+	t, err := template.New("format").Funcs(formatFuncs).Option("missingkey=error").Parse(format)
+	if err != nil {
+		return nil, usageErrorf("invalid --format: %w", err)
+	}
+	return t, nil
+}
+
+// writeTemplateRows executes tmpl once per row, each on its own line.
+func writeTemplateRows(w io.Writer, tmpl *template.Template, rows []matchRow) error {
+	for _, r := range rows {
+		if err := tmpl.Execute(w, r); err != nil {
+			return fmt.Errorf("--format: %w", err)
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteTemplateRows(t *testing.T) {
+	rows := []matchRow{
+		{IPKey: "db.host", IPValue: "10.0.0.5", PortValue: "5432", RelPath: "a.env", LineNumber: 2},
+		{IPKey: "gw", IPValue: "10.0.0.1", RelPath: "b.env", LineNumber: 7},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"{{.IPValue}}:{{.PortValue}}", "10.0.0.5:5432\n10.0.0.1:\n"},
+		{`{{.RelPath}}\t{{.LineNumber}}`, "a.env\t2\nb.env\t7\n"},
+		{"{{upper .IPKey}} {{json .IPValue}}", "DB.HOST \"10.0.0.5\"\nGW \"10.0.0.1\"\n"},
+		{"{{if .PortValue}}{{.IPValue}}{{end}}", "10.0.0.5\n\n"},
+	}
+	for _, tt := range tests {
+		tmpl, err := parseRowFormat(tt.format)
+		if err != nil {
+			t.Fatalf("parseRowFormat(%q) failed: %v", tt.format, err)
+		}
+		var buf bytes.Buffer
+		if err := writeTemplateRows(&buf, tmpl, rows); err != nil {
+			t.Fatalf("writeTemplateRows(%q) failed: %v", tt.format, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("format %q = %q, want %q", tt.format, buf.String(), tt.want)
+		}
+	}
+
+	if _, err := parseRowFormat("{{.IPValue"); err == nil {
+		t.Error("Expected parse error for unterminated action")
+	}
+	tmpl, _ := parseRowFormat("{{.NoSuchField}}")
+	if err := writeTemplateRows(&bytes.Buffer{}, tmpl, rows); err == nil {
+		t.Error("Expected execution error for unknown field")
+	}
+}
+
+func TestRowStream_Template(t *testing.T) {
+	tmpl, _ := parseRowFormat("{{.IPValue}}")
+	var buf bytes.Buffer
+	s := newRowStream(outTable, nil, tmpl)
+	s.w = &buf
+	s.write([]matchRow{{IPValue: "10.0.0.1"}})
+	s.write([]matchRow{{IPValue: "10.0.0.2"}})
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "10.0.0.1\n10.0.0.2\n" {
+		t.Errorf("Unexpected streamed template output %q", buf.String())
+	}
+}
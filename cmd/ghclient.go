@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/auth"
+)
+
+// apiTransport replaces the HTTP transport of the API clients. Tests point
+// it at a fake server.
+var apiTransport http.RoundTripper
+
+// clientOptions configures a go-gh client for githubHost(). An empty token
+// is resolved as gh resolves it: GH_TOKEN or GITHUB_TOKEN, gh's config,
+// then its keyring.
+func clientOptions(token string) (api.ClientOptions, error) {
+	host := githubHost()
+	if token == "" {
+		token, _ = auth.TokenForHost(host)
+	}
+	if token == "" {
+		return api.ClientOptions{}, fmt.Errorf("not logged in to %s: run `gh auth login` or set GH_TOKEN", host)
+	}
+	return api.ClientOptions{
+		Host:         host,
+		AuthToken:    token,
+		Transport:    apiTransport,
+		LogIgnoreEnv: true,
+		Headers: map[string]string{
+			"Accept":               "application/vnd.github+json",
+			"X-GitHub-Api-Version": "2022-11-28",
+		},
+	}, nil
+}
+
+// restClient returns a REST client authenticated with token, or with the
+// user's own token when it is "".
+func restClient(token string) (*api.RESTClient, error) {
+	opts, err := clientOptions(token)
+	if err != nil {
+		return nil, err
+	}
+	return api.NewRESTClient(opts)
+}
+
+// apiRequest sends method path with body encoded as JSON, when non-nil,
+// and returns the successful response for the caller to read and close.
+// Failures are *api.HTTPError, whose message reads "HTTP <status>: ...".
+// Requests that are safe to repeat are retried on transient failures.
+func apiRequest(token, method, path string, body any) (*http.Response, error) {
+	return apiRequestContext(runCtx, token, method, path, body)
+}
+
+// apiRequestContext is apiRequest, sent on ctx instead of runCtx.
+func apiRequestContext(ctx context.Context, token, method, path string, body any) (*http.Response, error) {
+	client, err := restClient(token)
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	if body != nil {
+		if b, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("encode request for %s: %w", path, err)
+		}
+	}
+	var resp *http.Response
+	send := func() error {
+		var r io.Reader
+		if b != nil {
+			r = bytes.NewReader(b)
+		}
+		debugf("api %s %s", method, path)
+		resp, err = client.RequestWithContext(ctx, method, path, r)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", method, path, err)
+		}
+		return nil
+	}
+	if idempotent(method) {
+		err = withRetry(method+" "+path, send)
+	} else {
+		err = send()
+	}
+	if err != nil {
+		return nil, err
+	}
+	noteRateLimit(resp.Header)
+	return resp, nil
+}
+
+// idempotent reports whether a request with method can be sent again
+// without doing twice what it does: a POST that failed with a 502 may
+// still have opened its pull request.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// decodeResponse reads resp into out, if non-nil, and closes it.
+func decodeResponse(resp *http.Response, path string, out any) error {
+	defer func() { _ = resp.Body.Close() }()
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response from %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode response from %s: %w", path, err)
+	}
+	return nil
+}
+
+// graphQL runs query with vars as the user and decodes its data into out.
+// It retries on transient failures, so the query must be safe to repeat.
+func graphQL(query string, vars map[string]any, out any) error {
+	opts, err := clientOptions("")
+	if err != nil {
+		return err
+	}
+	client, err := api.NewGraphQLClient(opts)
+	if err != nil {
+		return err
+	}
+	return withRetry("graphql", func() error {
+		debugf("api graphql")
+		return client.DoWithContext(runCtx, query, vars, out)
+	})
+}
+
+// httpStatus returns the HTTP status of a failed API call, or 0.
+func httpStatus(err error) int {
+	var he *api.HTTPError
+	if errors.As(err, &he) {
+		return he.StatusCode
+	}
+	return 0
+}
+
+// rateLimitWarned keeps the low rate limit warning to once per run.
+var rateLimitWarned sync.Once
+
+// noteRateLimit logs the API rate limit left after a response, warning
+// once when less than a tenth of it remains.
+func noteRateLimit(h http.Header) {
+	left, err1 := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	limit, err2 := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	if err1 != nil || err2 != nil || limit == 0 {
+		return
+	}
+	tracef("rate limit: %d of %d requests left", left, limit)
+	if left >= limit/10 {
+		return
+	}
+	rateLimitWarned.Do(func() {
+		reset := "soon"
+		if s, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			reset = "at " + time.Unix(s, 0).Format(time.Kitchen)
+		}
+		warnf("only %d of %d GitHub API requests left; the limit resets %s", left, limit, reset)
+	})
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGitHub points the API clients at a server that answers each call
+// with respond, given the call as "METHOD PATH" with PATH as it would be
+// passed to ghAPI. Calls are logged to the returned file as "METHOD PATH",
+// each followed by its JSON body, if any, on a line of its own. The user's
+// token is "alice-token".
+func fakeGitHub(t *testing.T, respond func(r *http.Request, call string) (int, string)) string {
+	t.Helper()
+	log := filepath.Join(t.TempDir(), "calls.log")
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := strings.TrimPrefix(r.URL.EscapedPath(), "/")
+		if r.URL.RawQuery != "" {
+			p += "?" + r.URL.RawQuery
+		}
+		call := r.Method + " " + p
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		entry := call + "\n"
+		if len(body) > 0 {
+			entry += string(body) + "\n"
+		}
+		f, err := os.OpenFile(log, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err == nil {
+			_, _ = f.WriteString(entry)
+			_ = f.Close()
+		}
+		mu.Unlock()
+		status, resp := respond(r, call)
+		if resp == "" && status >= 400 {
+			resp = fmt.Sprintf(`{"message":%q}`, http.StatusText(status))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, resp)
+	}))
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	old := apiTransport
+	apiTransport = redirectTransport{target}
+	t.Cleanup(func() { apiTransport = old })
+	t.Setenv("GH_HOST", "github.com")
+	t.Setenv("GH_TOKEN", "alice-token")
+	return log
+}
+
+// redirectTransport sends every request to target.
+type redirectTransport struct{ target *url.URL }
+
+func (rt redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme, r.URL.Host = rt.target.Scheme, rt.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// requestToken returns the token a request to the fake was sent with.
+func requestToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "token ")
+}
+
+func TestAPIErrors(t *testing.T) {
+	fakeGitHub(t, func(r *http.Request, call string) (int, string) {
+		switch call {
+		case "GET user":
+			return 200, `{"login":"alice"}`
+		case "GET limited":
+			return 403, `{"message":"API rate limit exceeded"}`
+		}
+		return 404, ""
+	})
+	if got := ghLogin(); got != "alice" {
+		t.Errorf("ghLogin() = %q, want alice", got)
+	}
+	if err := ghAPI("GET", "repos/org/missing", nil, nil); !isNotFound(err) {
+		t.Errorf("missing repository: err = %v, want HTTP 404", err)
+	}
+	err := ghAPI("GET", "limited", nil, nil)
+	if httpStatus(err) != 403 || !strings.Contains(err.Error(), "API rate limit exceeded") {
+		t.Errorf("rate limited: err = %v", err)
+	}
+}
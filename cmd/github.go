@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ghAPI calls the GitHub REST API as the user gh is logged in as, or with
+// GH_TOKEN. A non-nil body is sent as JSON and a non-nil out receives the
+// decoded response.
+func ghAPI(method, path string, body, out any) error {
+	return ghAPIContext(runCtx, method, path, body, out)
+}
+
+// ghAPIContext is ghAPI, sent on ctx instead of runCtx.
+func ghAPIContext(ctx context.Context, method, path string, body, out any) error {
+	resp, err := apiRequestContext(ctx, "", method, path, body)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, path, out)
+}
+
+// --- deployments
+
+type deploymentRequest struct {
+	Ref              string         `json:"ref"`
+	Environment      string         `json:"environment"`
+	Task             string         `json:"task"`
+	Description      string         `json:"description"`
+	AutoMerge        bool           `json:"auto_merge"`
+	RequiredContexts []string       `json:"required_contexts"`
+	Payload          map[string]any `json:"payload"`
+}
+
+type deploymentStatusRequest struct {
+	State       string `json:"state"`
+	Environment string `json:"environment"`
+	Description string `json:"description"`
+	LogURL      string `json:"log_url,omitempty"`
+}
+
+func newFlipDeployment(ref, environment string, changes []change) deploymentRequest {
+	flips := make([]string, 0, len(changes))
+	for _, c := range changes {
+		flips = append(flips, fmt.Sprintf("%s %s->%s", c.Adapter, c.OldValue, c.NewValue))
+	}
+	return deploymentRequest{
+		Ref:         ref,
+		Environment: environment,
+		Task:        "deploy:flip-adapters",
+		Description: "Flip adapters: " + strings.Join(flips, ", "),
+		// The flip itself is the change being recorded; don't merge the
+		// default branch in or wait on status checks.
+		AutoMerge:        false,
+		RequiredContexts: []string{},
+		Payload:          map[string]any{"changes": changes},
+	}
+}
+
+// recordDeployment creates a deployment for ref in the target environment and
+// marks it successful, so the flip appears in the repo's Environments timeline.
+func recordDeployment(repo, ref, environment string, changes []change, logURL string) error {
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	if err := ghAPI("POST", fmt.Sprintf("repos/%s/deployments", repo), newFlipDeployment(ref, environment, changes), &created); err != nil {
+		return fmt.Errorf("create deployment: %w", err)
+	}
+	status := deploymentStatusRequest{
+		State:       "success",
+		Environment: environment,
+		Description: fmt.Sprintf("%d adapter(s) flipped by gh aca-utils", len(changes)),
+		LogURL:      logURL,
+	}
+	if err := ghAPI("POST", fmt.Sprintf("repos/%s/deployments/%d/statuses", repo, created.ID), status, nil); err != nil {
+		return fmt.Errorf("create deployment status: %w", err)
+	}
+	infof("Recorded deployment %d for environment %q", created.ID, environment)
+	return nil
+}
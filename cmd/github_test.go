@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewFlipDeployment(t *testing.T) {
+	changes := []change{
+		{Adapter: "billing", OldValue: "0", NewValue: "1", FilePath: "env/prod/parameters.properties"},
+		{Adapter: "search", OldValue: "1", NewValue: "0", FilePath: "env/prod/parameters.properties"},
+	}
+	req := newFlipDeployment("abc123", "production", changes)
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+
+	if decoded["ref"] != "abc123" || decoded["environment"] != "production" {
+		t.Errorf("Unexpected ref/environment: %v", decoded)
+	}
+	if decoded["auto_merge"] != false {
+		t.Errorf("Expected auto_merge=false, got %v", decoded["auto_merge"])
+	}
+	if ctx, ok := decoded["required_contexts"].([]any); !ok || len(ctx) != 0 {
+		t.Errorf("Expected empty required_contexts array, got %v", decoded["required_contexts"])
+	}
+	if !strings.Contains(req.Description, "billing 0->1") || !strings.Contains(req.Description, "search 1->0") {
+		t.Errorf("Expected description to list flips, got %q", req.Description)
+	}
+}
+
+func TestCmdFlipAdapters_DeploymentRequiresMerge(t *testing.T) {
+	for _, args := range [][]string{
+		{"--deployment"},
+		{"--commit", "--deployment"},
+		{"--pr", "--deployment"},
+	} {
+		cmd := cmdFlipAdapters()
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetErr(&buf)
+
+		cmd.SetArgs(append([]string{"--repo", "org/repo", "--env", "dev", "--adapters", "billing"}, args...))
+		err := cmd.Execute()
+		if err == nil || !strings.Contains(err.Error(), "on the default branch") {
+			t.Errorf("%v: expected an error asking for --direct or --auto-merge, got %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Git backends for --git-backend: the git binary, go-git in-process, or
+// the binary when it is installed and go-git otherwise.
+const (
+	gitBackendAuto  = "auto"
+	gitBackendExec  = "git"
+	gitBackendGoGit = "go-git"
+)
+
+// gitBackend is the global --git-backend flag.
+var gitBackend = gitBackendAuto
+
+func checkGitBackend(s string) error {
+	switch s {
+	case gitBackendAuto, gitBackendExec, gitBackendGoGit:
+		return nil
+	}
+	return usageErrorf("invalid --git-backend %q: want %s, %s or %s", s, gitBackendAuto, gitBackendExec, gitBackendGoGit)
+}
+
+// useGoGit reports whether clones, branch listings and commits run in
+// process with go-git instead of the git binary.
+func useGoGit() bool {
+	switch gitBackend {
+	case gitBackendExec:
+		return false
+	case gitBackendGoGit:
+		return true
+	}
+	_, err := exec.LookPath("git")
+	return err != nil
+}
+
+// cloneOptions narrow a clone.
+type cloneOptions struct {
+	depth      int
+	branch     string
+	noCheckout bool
+	bare       bool
+	partial    bool // fetch file contents on demand; the git binary only
+	quiet      bool
+}
+
+// args returns o as git clone flags.
+func (o cloneOptions) args() []string {
+	var args []string
+	if o.depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(o.depth))
+	}
+	if o.branch != "" {
+		args = append(args, "--branch", o.branch)
+	}
+	if o.partial {
+		args = append(args, "--filter=blob:none")
+	}
+	if o.noCheckout {
+		args = append(args, "--no-checkout")
+	}
+	if o.bare {
+		args = append(args, "--bare")
+	}
+	if o.quiet {
+		args = append(args, "--quiet")
+	}
+	return args
+}
+
+// repoURL returns where to clone repo from: repo itself when it is a URL
+// or a local repository, else its HTTPS URL on GitHub.
+func repoURL(repo string) string {
+	if strings.Contains(repo, "://") {
+		return repo
+	}
+	if info, err := os.Stat(repo); err == nil && info.IsDir() {
+		return repo
+	}
+	return gitBaseURL() + "/" + repo + ".git"
+}
+
+// gitClone clones url into dir.
+func gitClone(url, dir string, o cloneOptions) error {
+	return retryInto("clone "+url, dir, func() error {
+		if useGoGit() {
+			debugf("cloning %s with go-git", url)
+			return goGitClone(url, dir, o)
+		}
+		return netCommand("", "git", append(append([]string{"clone"}, o.args()...), url, dir)...)
+	})
+}
+
+// retryInto runs fill, which writes into dir, with withRetry, emptying
+// dir of what a failed attempt left before the next.
+func retryInto(what, dir string, fill func() error) error {
+	first := true
+	return withRetry(what, func() error {
+		if !first {
+			if err := emptyDir(dir); err != nil {
+				return err
+			}
+		}
+		first = false
+		return fill()
+	})
+}
+
+// gitHead returns the commit checked out in the clone at dir.
+func gitHead(dir string) (string, error) {
+	if useGoGit() {
+		return goGitHead(dir)
+	}
+	return gitOutput(dir, "rev-parse", "HEAD")
+}
+
+// gitCurrentBranch returns the branch checked out in the clone at dir.
+func gitCurrentBranch(dir string) (string, error) {
+	if useGoGit() {
+		return goGitCurrentBranch(dir)
+	}
+	return gitOutput(dir, "rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// gitRemoteURL returns the URL of remote in the clone at dir, or "" when
+// there is no such remote.
+func gitRemoteURL(dir, remote string) string {
+	if useGoGit() {
+		return goGitRemoteURL(dir, remote)
+	}
+	u, err := gitOutput(dir, "config", "--get", "remote."+remote+".url")
+	if err != nil {
+		return ""
+	}
+	return u
+}
+
+// gitResetToRemote fetches branch from origin into the clone at dir,
+// resets the checkout to it and returns its commit.
+func gitResetToRemote(dir, branch string) (string, error) {
+	if useGoGit() {
+		return goGitResetToRemote(dir, branch)
+	}
+	if err := gitFetchBranch(dir, branch); err != nil {
+		return "", err
+	}
+	sha, err := gitOutput(dir, "rev-parse", "FETCH_HEAD")
+	if err != nil {
+		return "", err
+	}
+	return sha, gitIn(dir, "reset", "--quiet", "--hard", "FETCH_HEAD")
+}
+
+// gitUseFork makes url the origin of the clone at dir and the old origin
+// its upstream.
+func gitUseFork(dir, url string) error {
+	if useGoGit() {
+		return goGitUseFork(dir, url)
+	}
+	if err := gitIn(dir, "remote", "rename", "origin", "upstream"); err != nil {
+		return err
+	}
+	return gitIn(dir, "remote", "add", "origin", url)
+}
+
+// gitFetchBranch fetches branch from origin, one commit deep, into
+// FETCH_HEAD of the clone at dir.
+func gitFetchBranch(dir, branch string) error {
+	return withRetry("fetch "+branch, func() error {
+		return netCommand(dir, "git", "fetch", "--depth", "1", "origin", branch)
+	})
+}
+
+// gitPush runs git push with args in the clone at dir.
+func gitPush(dir string, args ...string) error {
+	return withRetry("push", func() error {
+		return netCommand(dir, "git", append([]string{"push"}, args...)...)
+	})
+}
@@ -0,0 +1,381 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// The go-git side of the git layer, for hosts without the git binary. It
+// covers what the commands do in a clone: clone, list branches, commit
+// and push.
+
+// goGitAuth returns the credentials for url: the user's token, found as
+// gh finds it, sent to GitHub over HTTPS as git does with gh as its credential
+// helper.
+func goGitAuth(url string) transport.AuthMethod {
+	if !strings.HasPrefix(url, "https://") {
+		return nil
+	}
+	token, _ := auth.TokenForHost(githubHost())
+	if token == "" {
+		return nil
+	}
+	return &githttp.BasicAuth{Username: "x-access-token", Password: token}
+}
+
+// originAuth returns the credentials for the origin of r.
+func originAuth(r *git.Repository) transport.AuthMethod {
+	remote, err := r.Remote("origin")
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return nil
+	}
+	return goGitAuth(remote.Config().URLs[0])
+}
+
+func goGitClone(url, dir string, o cloneOptions) error {
+	opts := &git.CloneOptions{URL: url, Depth: o.depth, NoCheckout: o.noCheckout, Auth: goGitAuth(url)}
+	if !o.quiet {
+		opts.Progress = os.Stderr
+	}
+	if o.branch != "" {
+		opts.ReferenceName = plumbing.NewBranchReferenceName(o.branch)
+		opts.SingleBranch = true
+	}
+	_, err := git.PlainCloneContext(runCtx, dir, o.bare, opts)
+	return err
+}
+
+func goGitHead(dir string) (string, error) {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return "", err
+	}
+	head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+	return head.Hash().String(), nil
+}
+
+func goGitCurrentBranch(dir string) (string, error) {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return "", err
+	}
+	head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+	return head.Name().Short(), nil
+}
+
+func goGitRemoteURL(dir, remote string) string {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return ""
+	}
+	rem, err := r.Remote(remote)
+	if err != nil || len(rem.Config().URLs) == 0 {
+		return ""
+	}
+	return rem.Config().URLs[0]
+}
+
+// goGitBranches lists the branches the clone at dir has, locally or as
+// branches of origin, sorted.
+func goGitBranches(dir string) ([]string, error) {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := r.References()
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var branches []string
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name, ok := strings.CutPrefix(ref.Name().String(), "refs/remotes/origin/")
+		if !ok {
+			name, ok = strings.CutPrefix(ref.Name().String(), "refs/heads/")
+		}
+		if ok && name != "HEAD" && !seen[name] {
+			seen[name] = true
+			branches = append(branches, name)
+		}
+		return nil
+	})
+	sort.Strings(branches)
+	return branches, err
+}
+
+func goGitRemoteBranchExists(dir, branch string) (bool, error) {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return false, err
+	}
+	remote, err := r.Remote("origin")
+	if err != nil {
+		return false, err
+	}
+	var refs []*plumbing.Reference
+	err = withRetry("list branches", func() (err error) {
+		refs, err = remote.ListContext(runCtx, &git.ListOptions{Auth: originAuth(r)})
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, ref := range refs {
+		if ref.Name() == plumbing.NewBranchReferenceName(branch) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// goGitFetch fetches branch from origin, one commit deep, and returns its
+// head.
+func goGitFetch(r *git.Repository, branch string) (plumbing.Hash, error) {
+	remoteRef := plumbing.NewRemoteReferenceName("origin", branch)
+	spec := config.RefSpec(fmt.Sprintf("+%s:%s", plumbing.NewBranchReferenceName(branch), remoteRef))
+	err := withRetry("fetch "+branch, func() error {
+		err := r.FetchContext(runCtx, &git.FetchOptions{RemoteName: "origin", RefSpecs: []config.RefSpec{spec}, Depth: 1, Auth: originAuth(r)})
+		if errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("fetch %s: %w", branch, err)
+	}
+	ref, err := r.Reference(remoteRef, true)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return ref.Hash(), nil
+}
+
+func goGitResetToRemote(dir, branch string) (string, error) {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return "", err
+	}
+	hash, err := goGitFetch(r, branch)
+	if err != nil {
+		return "", err
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return "", err
+	}
+	return hash.String(), w.Reset(&git.ResetOptions{Commit: hash, Mode: git.HardReset})
+}
+
+func goGitUseFork(dir, url string) error {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return err
+	}
+	origin, err := r.Remote("origin")
+	if err != nil {
+		return err
+	}
+	if _, err := r.CreateRemote(&config.RemoteConfig{Name: "upstream", URLs: origin.Config().URLs}); err != nil {
+		return err
+	}
+	if err := r.DeleteRemote("origin"); err != nil {
+		return err
+	}
+	_, err = r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{url}})
+	return err
+}
+
+// gitSignature returns who commits: GIT_AUTHOR_NAME and GIT_AUTHOR_EMAIL,
+// else user.name and user.email from git's config, else the
+// authenticated GitHub user.
+func gitSignature(r *git.Repository) *object.Signature {
+	sig := &object.Signature{Name: os.Getenv("GIT_AUTHOR_NAME"), Email: os.Getenv("GIT_AUTHOR_EMAIL"), When: time.Now()}
+	if sig.Name == "" || sig.Email == "" {
+		if cfg, err := r.ConfigScoped(config.GlobalScope); err == nil && cfg.User.Name != "" {
+			sig.Name, sig.Email = cfg.User.Name, cfg.User.Email
+		}
+	}
+	if sig.Name == "" {
+		sig.Name = ghLogin()
+		if sig.Name == "" {
+			sig.Name = "gh-aca-utils"
+		}
+	}
+	if sig.Email == "" {
+		sig.Email = sig.Name + "@users.noreply.github.com"
+	}
+	return sig
+}
+
+// goGitCommit stages paths, commits them with msg and pushes spec to
+// origin.
+func goGitCommit(r *git.Repository, w *git.Worktree, msg string, paths []string, spec config.RefSpec) error {
+	for _, p := range paths {
+		if _, err := w.Add(filepath.ToSlash(p)); err != nil {
+			return fmt.Errorf("add %s: %w", p, err)
+		}
+	}
+	if _, err := w.Commit(msg, &git.CommitOptions{Author: gitSignature(r)}); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	err := withRetry("push", func() error {
+		err := r.PushContext(runCtx, &git.PushOptions{RemoteName: "origin", RefSpecs: []config.RefSpec{spec}, Auth: originAuth(r)})
+		if errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("push: %w", err)
+	}
+	return nil
+}
+
+// goGitCommitBranch is gitCommitBranch with go-git.
+func goGitCommitBranch(dir, branch, msg string, paths []string, exists bool, p branchPolicy) error {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return err
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	ref := plumbing.NewBranchReferenceName(branch)
+	if err := w.Checkout(&git.CheckoutOptions{Branch: ref, Create: true, Keep: true}); err != nil {
+		return fmt.Errorf("create branch %s: %w", branch, err)
+	}
+	if exists && p == branchReuse {
+		hash, err := goGitFetch(r, branch)
+		if err != nil {
+			return err
+		}
+		// Point the branch at the remote tip and keep the edited files.
+		if err := w.Reset(&git.ResetOptions{Commit: hash, Mode: git.MixedReset}); err != nil {
+			return err
+		}
+		if same, err := goGitUnchanged(w, paths); err != nil {
+			return err
+		} else if same {
+			infof("Branch %s already has these changes", branch)
+			return nil
+		}
+	}
+	spec := config.RefSpec(fmt.Sprintf("%s:%s", ref, ref))
+	if exists && p == branchForce {
+		spec = "+" + spec
+	}
+	return goGitCommit(r, w, msg, paths, spec)
+}
+
+// goGitUnchanged reports whether paths match the index of w.
+func goGitUnchanged(w *git.Worktree, paths []string) (bool, error) {
+	status, err := w.Status()
+	if err != nil {
+		return false, err
+	}
+	for _, p := range paths {
+		if s := status.File(filepath.ToSlash(p)); s.Worktree != git.Unmodified || s.Staging != git.Unmodified {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// goGitCommitDirect is gitCommitDirect with go-git.
+func goGitCommitDirect(dir, branch, msg string, paths []string) error {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return err
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	return goGitCommit(r, w, msg, paths, config.RefSpec(fmt.Sprintf("%s:%s", head.Name(), plumbing.NewBranchReferenceName(branch))))
+}
+
+// goGitBranchBlobs maps the paths of the files on branch in the clone at
+// dir to their blob SHAs, as parseLsTree does for the git binary.
+func goGitBranchBlobs(dir, branch string) (map[string]string, error) {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := r.Reference(plumbing.NewRemoteReferenceName("origin", branch), true)
+	if err != nil {
+		ref, err = r.Reference(plumbing.NewBranchReferenceName(branch), true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	commit, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, err
+	}
+	files, err := commit.Files()
+	if err != nil {
+		return nil, err
+	}
+	blobs := map[string]string{}
+	err = files.ForEach(func(f *object.File) error {
+		if f.Mode.IsFile() && f.Mode != filemode.Symlink {
+			blobs[f.Name] = f.Hash.String()
+		}
+		return nil
+	})
+	return blobs, err
+}
+
+// goGitBlobs reads blobs with go-git.
+type goGitBlobs struct {
+	r *git.Repository
+}
+
+func openGoGitBlobs(dir string) (*goGitBlobs, error) {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &goGitBlobs{r: r}, nil
+}
+
+func (g *goGitBlobs) read(sha string) ([]byte, error) {
+	blob, err := g.r.BlobObject(plumbing.NewHash(sha))
+	if err != nil {
+		return nil, err
+	}
+	rd, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rd.Close() }()
+	return io.ReadAll(rd)
+}
+
+func (g *goGitBlobs) close() error { return nil }
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// withGoGit runs the rest of the test with the go-git backend.
+func withGoGit(t *testing.T) {
+	t.Helper()
+	old := gitBackend
+	gitBackend = gitBackendGoGit
+	t.Cleanup(func() { gitBackend = old })
+	for _, kv := range []string{"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1"} {
+		k, v, _ := strings.Cut(kv, "=")
+		t.Setenv(k, v)
+	}
+}
+
+func TestGoGitScanAllBranches(t *testing.T) {
+	repo := initTestRepo(t, map[string]map[string]string{
+		"main":    {"app.properties": "db.host=10.0.0.1\n"},
+		"feature": {"app.properties": "db.host=10.0.0.2\n"},
+	}, "main", "feature")
+	withGoGit(t)
+
+	rows, err := scanAllBranches(repo, scanOptions{includes: []string{"**/*"}, excludes: []string{"**/.git/**"}, jobs: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range rows {
+		got = append(got, r.location()+"="+r.IPValue)
+	}
+	want := []string{"feature:app.properties=10.0.0.2", "main:app.properties=10.0.0.1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGoGitCommitBranch(t *testing.T) {
+	origin := initTestRepo(t, map[string]map[string]string{
+		"main":                {"env/dev/parameters.properties": "billing=0\nsearch=0\n"},
+		"toggle/adapters-dev": {"env/dev/parameters.properties": "billing=1\nsearch=0\n", "NOTES.md": "keep\n"},
+		"other":               {},
+	}, "main", "toggle/adapters-dev", "other")
+	// A checked-out branch can't be pushed to.
+	if out, err := exec.Command("git", "-C", origin, "checkout", "--quiet", "other").CombinedOutput(); err != nil {
+		t.Fatalf("checkout: %v\n%s", err, out)
+	}
+	withGoGit(t)
+	rel := filepath.Join("env", "dev", "parameters.properties")
+	clone := func(content string) string {
+		t.Helper()
+		dir := filepath.Join(t.TempDir(), "clone")
+		if err := gitClone("file://"+origin, dir, cloneOptions{branch: "main", quiet: true}); err != nil {
+			t.Fatalf("clone: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, rel), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	show := func(rev, file string) string {
+		out, _ := exec.Command("git", "-C", origin, "show", rev+":"+file).Output()
+		return string(out)
+	}
+
+	dir := clone("billing=1\nsearch=1\n")
+	exists, err := remoteBranchExists(dir, "toggle/adapters-dev")
+	if err != nil || !exists {
+		t.Fatalf("remoteBranchExists = %v, %v", exists, err)
+	}
+	if err := gitCommitBranch(dir, "toggle/adapters-dev", "flip", []string{rel}, exists, branchReuse); err != nil {
+		t.Fatal(err)
+	}
+	if got := show("toggle/adapters-dev", rel); got != "billing=1\nsearch=1\n" || show("toggle/adapters-dev", "NOTES.md") != "keep\n" {
+		t.Errorf("after reuse: %q, NOTES.md %q", got, show("toggle/adapters-dev", "NOTES.md"))
+	}
+
+	dir = clone("billing=0\nsearch=1\n")
+	if err := gitCommitBranch(dir, "toggle/adapters-dev", "flip", []string{rel}, true, branchForce); err != nil {
+		t.Fatal(err)
+	}
+	if got := show("toggle/adapters-dev", rel); got != "billing=0\nsearch=1\n" || show("toggle/adapters-dev", "NOTES.md") != "" {
+		t.Errorf("after force: %q, NOTES.md %q", got, show("toggle/adapters-dev", "NOTES.md"))
+	}
+
+	dir = clone("billing=1\nsearch=0\n")
+	if err := gitCommitDirect(dir, "main", "flip billing", []string{rel}); err != nil {
+		t.Fatal(err)
+	}
+	if got := show("main", rel); got != "billing=1\nsearch=0\n" {
+		t.Errorf("after direct: %q", got)
+	}
+	if head, err := gitHead(dir); err != nil || len(head) != 40 {
+		t.Errorf("gitHead = %q, %v", head, err)
+	}
+}
+
+func TestCheckGitBackend(t *testing.T) {
+	for _, s := range []string{"auto", "git", "go-git"} {
+		if err := checkGitBackend(s); err != nil {
+			t.Errorf("checkGitBackend(%q) = %v", s, err)
+		}
+	}
+	if err := checkGitBackend("libgit2"); err == nil {
+		t.Error("checkGitBackend(libgit2) succeeded")
+	}
+}
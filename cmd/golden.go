@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// deviation is an adapter whose live value differs from the golden
+// configuration.
+type deviation struct {
+	Env      string `json:"env"`
+	Adapter  string `json:"adapter"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Missing  bool   `json:"missing,omitempty"`
+}
+
+// compareGolden lists the golden values that env's live values don't
+// match. Keys only in the live file are not deviations: the golden file
+// names the adapters under control.
+func compareGolden(env string, golden, live []adapterValue) []deviation {
+	actual := map[string]string{}
+	for _, v := range live {
+		if !v.Missing {
+			actual[v.Adapter] = v.Value
+		}
+	}
+	out := make([]deviation, 0)
+	for _, g := range golden {
+		if g.Missing {
+			continue
+		}
+		v, ok := actual[g.Adapter]
+		if ok && v == g.Value {
+			continue
+		}
+		out = append(out, deviation{Env: env, Adapter: g.Adapter, Expected: g.Value, Actual: v, Missing: !ok})
+	}
+	return out
+}
+
+func printDeviations(devs []deviation, checked int, mode outputMode) error {
+	switch mode {
+	case outJSON:
+		enc := json.NewEncoder(stdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(devs)
+	case outCSV:
+		fmt.Fprintln(stdout(), "env,adapter,expected,actual,missing")
+		for _, d := range devs {
+			fmt.Fprintf(stdout(), "%s,%s,%s,%s,%t\n", csvEsc(d.Env), csvEsc(d.Adapter), csvEsc(d.Expected), csvEsc(d.Actual), d.Missing)
+		}
+		return nil
+	}
+	if len(devs) == 0 {
+		fmt.Fprintf(stdout(), "No drift: %d environment(s) match the golden configuration.\n", checked)
+		return nil
+	}
+	w := newTable()
+	w.AddRow("Env", "Adapter", "Expected", "Actual")
+	for _, d := range devs {
+		actual := d.Actual
+		if d.Missing {
+			actual = "(missing)"
+		}
+		w.AddRow(d.Env, d.Adapter, d.Expected, actual)
+	}
+	w.style = func(row, col int, _ string) string {
+		if row > 0 && col == 3 {
+			return ansiYellow
+		}
+		return ""
+	}
+	w.Render()
+	return nil
+}
+
+func cmdDrift() *cobra.Command {
+	var repo, ref, envName, goldenFile, adaptersCSV, paramFile, mode string
+
+	cmd := &cobra.Command{
+		Use:   "drift",
+		Short: "Compare live adapter values against a golden configuration; exit non-zero on drift",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if repo == "" {
+				return usageErrorf("--repo ORG/REPO is required")
+			}
+			if goldenFile == "" {
+				return usageErrorf("--golden FILE is required")
+			}
+			if envName == "" {
+				return usageErrorf("--env is required (e.g., prod)")
+			}
+			if err := checkParamFile(paramFile); err != nil {
+				return err
+			}
+			modeVal := parseMode(mode, outTable)
+
+			b, err := os.ReadFile(goldenFile) // #nosec G304 - user-supplied golden file
+			if err != nil {
+				return fmt.Errorf("read golden file: %w", err)
+			}
+			golden, err := parseEnvValues(b, "golden", filepath.Base(goldenFile), splitCSV(adaptersCSV, nil))
+			if err != nil {
+				return err
+			}
+			var want []string
+			for _, g := range golden {
+				want = append(want, g.Adapter)
+			}
+			if len(want) == 0 {
+				return fmt.Errorf("%s has no adapter values", goldenFile)
+			}
+
+			tmpDir, cleanup, err := cloneOrDownload(repo, ref)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			envs, err := resolveEnvs(tmpDir, envName)
+			if err != nil {
+				return err
+			}
+
+			devs := make([]deviation, 0)
+			for _, env := range envs {
+				live, err := readEnvValues(tmpDir, env, paramFile, want)
+				if errors.Is(err, fs.ErrNotExist) {
+					warnf("env/%s has no %s", env, paramFile)
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				devs = append(devs, compareGolden(env, golden, live)...)
+			}
+			if err := printDeviations(devs, len(envs), modeVal); err != nil {
+				return err
+			}
+			if len(devs) > 0 {
+				cmd.SilenceUsage = true
+				return &findingsError{fmt.Sprintf("%d adapter value(s) drifted from %s", len(devs), goldenFile)}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Target repo as ORG/REPO (required)")
+	cmd.Flags().StringVar(&ref, "ref", "", "Branch or tag (default: default branch)")
+	cmd.Flags().StringVar(&envName, "env", "", "Environment name or glob (required)")
+	cmd.Flags().StringVar(&goldenFile, "golden", "", "Approved configuration, as .properties or .json (required)")
+	cmd.Flags().StringVar(&adaptersCSV, "adapters", "", "Only check these comma-separated adapter keys (default: every key in the golden file)")
+	cmd.Flags().StringVar(&paramFile, "file", "parameters.properties", "Parameters file in each env directory")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table|json|csv")
+	setOutputModes(cmd, "table", "json", "csv")
+	return cmd
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCompareGolden(t *testing.T) {
+	golden := []adapterValue{{Adapter: "billing", Value: "1"}, {Adapter: "search", Value: "0"}, {Adapter: "crm", Value: "on"}, {Adapter: "absent", Missing: true}}
+	tests := []struct {
+		name string
+		live []adapterValue
+		want string
+	}{
+		{"match", []adapterValue{{Adapter: "billing", Value: "1"}, {Adapter: "search", Value: "0"}, {Adapter: "crm", Value: "on"}, {Adapter: "extra", Value: "x"}}, "[]"},
+		{"differs", []adapterValue{{Adapter: "billing", Value: "0"}, {Adapter: "search", Value: "0"}, {Adapter: "crm", Value: "on"}}, "[{prod billing 1 0 false}]"},
+		{"missing", []adapterValue{{Adapter: "billing", Value: "1"}, {Adapter: "search", Value: "0"}, {Adapter: "crm", Missing: true}}, "[{prod crm on  true}]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(compareGolden("prod", golden, tt.live)); got != tt.want {
+			t.Errorf("%s: compareGolden = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
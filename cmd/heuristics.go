@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Dotted version strings such as 1.2.3.4 are valid IPv4 literals, so keys and
+// lines that clearly talk about versions are excluded from IP detection. Keys
+// are matched per word, after splitting camelCase, so "appVersion" is a
+// version key but "conversion.host" isn't; a key that also names a network
+// setting ("build.host", "buildAgentIp") is still checked.
+var (
+	versionKeyRe  = regexp.MustCompile(`(?i)(^|[._\- ])(version|ver|rev|revision|release|build)([._\- ]|$)`)
+	versionLineRe = regexp.MustCompile(`(?i)\b(version|ver|release|revision|rev)\b`)
+	versionSufRe  = regexp.MustCompile(`(?i)^[0-9.]+[-+](snapshot|release|final|rc\d*|beta\d*|alpha\d*|ga|build\S*)$`)
+	tokenSplitRe  = regexp.MustCompile(`[^A-Za-z]+|([a-z])([A-Z])|([A-Z])([A-Z][a-z])`)
+)
+
+var networkHints = []string{
+	"host", "addr", "ip", "server", "url", "uri", "endpoint", "gateway", "dns", "proxy",
+	"cidr", "subnet", "listen", "bind", "upstream", "remote", "peer", "nameserver",
+}
+
+// hasNetworkHint reports whether s mentions a network setting, matching hint
+// prefixes per word so "dbHost" and "ip_address" count but "shipped" doesn't.
+func hasNetworkHint(s string) bool {
+	if strings.Contains(s, "://") || strings.Contains(s, "@") {
+		return true
+	}
+	for _, tok := range keyWords(s) {
+		tok = strings.ToLower(tok)
+		for _, h := range networkHints {
+			if strings.HasPrefix(tok, h) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// keyWords splits s into its words: at anything but letters, and where
+// camelCase starts a new word ("dbHost", "APIVersion").
+func keyWords(s string) []string {
+	return strings.Fields(tokenSplitRe.ReplaceAllString(s, "$1$3 $2$4"))
+}
+
+func isVersionKey(k string) bool {
+	return versionKeyRe.MatchString(strings.Join(keyWords(k), " "))
+}
+
+// acceptIPValue decides whether an IP-looking key/value pair is reported. In
+// strict mode the value must be an IP literal on its own (optionally with a
+// port, prefix length or URL around it) or the key must name a network setting.
+func acceptIPValue(k, v string, strict bool) bool {
+	if isVersionKey(k) && !hasNetworkHint(k) {
+		return false
+	}
+	vv := stripQuotes(v)
+	if versionSufRe.MatchString(vv) {
+		return false
+	}
+	if !strict {
+		return true
+	}
+	return isIPLiteral(vv) || hasNetworkHint(k)
+}
+
+// acceptInlineIP is the free-text counterpart of acceptIPValue.
+func acceptInlineIP(line, ip string, strict bool) bool {
+	if versionLineRe.MatchString(line) {
+		return false
+	}
+	if i := strings.Index(line, ip); i > 0 && (line[i-1] == 'v' || line[i-1] == 'V') {
+		return false
+	}
+	if !strict {
+		return true
+	}
+	return hasNetworkHint(line)
+}
+
+func isIPLiteral(v string) bool {
+	if _, err := netip.ParseAddr(v); err == nil {
+		return true
+	}
+	if _, err := netip.ParsePrefix(v); err == nil {
+		return true
+	}
+	if _, err := netip.ParseAddrPort(v); err == nil {
+		return true
+	}
+	if u, err := url.Parse(v); err == nil && u.Host != "" {
+		_, err := netip.ParseAddr(u.Hostname())
+		return err == nil
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcceptIPValue(t *testing.T) {
+	tests := []struct {
+		key    string
+		value  string
+		strict bool
+		want   bool
+	}{
+		{"db.host", "10.0.0.1", false, true},
+		{"version", "1.2.3.4", false, false},
+		{"app.version", "2.0.0.1", false, false},
+		{"appVersion", "1.2.3.4", false, false},
+		{"build", "1.2.3.4", false, false},
+		{"lib.rev", "1.0.0.9", false, false},
+		{"APIVersion", "1.2.3.4", false, false},
+		{"conversion.host", "10.0.0.1", false, true},
+		{"subversion.server.ip", "10.0.0.1", false, true},
+		{"inversionService.addr", "10.0.0.1", false, true},
+		{"server", "10.0.0.1", false, true},
+		{"reverse.proxy", "10.0.0.1", false, true},
+		{"build.host", "10.1.2.3", false, true},
+		{"rev.proxy.ip", "10.1.2.3", false, true},
+		{"release.server.addr", "10.1.2.3", false, true},
+		{"buildAgentIp", "10.1.2.3", false, true},
+		{"artifact", "1.2.3.4-SNAPSHOT", false, false},
+		{"artifact", "2.0.0.1", false, true},
+		{"artifact", "2.0.0.1", true, true},
+		{"artifact", "built from 2.0.0.1 sources", true, false},
+		{"db.host", "primary at 10.0.0.1", true, true},
+		{"endpoint", "http://10.0.0.1:8080/api", true, true},
+		{"peers", "10.0.0.0/24", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			if got := acceptIPValue(tt.key, tt.value, tt.strict); got != tt.want {
+				t.Errorf("acceptIPValue(%q, %q, %v) = %v, want %v", tt.key, tt.value, tt.strict, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcceptInlineIP(t *testing.T) {
+	tests := []struct {
+		line   string
+		ip     string
+		strict bool
+		want   bool
+	}{
+		{"upstream backend 10.0.0.1", "10.0.0.1", false, true},
+		{"Release 2.0.0.1 notes", "2.0.0.1", false, false},
+		{"tool v1.2.3.4 installed", "1.2.3.4", false, false},
+		{"shipped 2.0.0.1 last week", "2.0.0.1", false, true},
+		{"shipped 2.0.0.1 last week", "2.0.0.1", true, false},
+		{"server 10.0.0.1:8080;", "10.0.0.1", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := acceptInlineIP(tt.line, tt.ip, tt.strict); got != tt.want {
+				t.Errorf("acceptInlineIP(%q, %q, %v) = %v, want %v", tt.line, tt.ip, tt.strict, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasNetworkHint(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"dbHost", true},
+		{"ip_address", true},
+		{"primary.server", true},
+		{"https://example.com", true},
+		{"shipped last week", false},
+		{"artifact", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := hasNetworkHint(tt.input); got != tt.want {
+				t.Errorf("hasNetworkHint(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanForIPPort_VersionStrings(t *testing.T) {
+	root := t.TempDir()
+	content := "app.version=1.2.3.4\ndb.host=10.0.0.5\n"
+	if err := os.WriteFile(filepath.Join(root, "app.properties"), []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	rows := scanForIPPort(root, scanOptions{includes: []string{"**/*"}})
+	if len(rows) != 1 || rows[0].IPValue != "10.0.0.5" {
+		t.Errorf("Expected only db.host to be reported, got %+v", rows)
+	}
+}
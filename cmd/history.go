@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// historyFilter selects journal entries and their changes for aca history.
+type historyFilter struct {
+	repo, env, adapter string
+	since              time.Time
+}
+
+// apply returns the matching entries newest first, keeping only the changes
+// that match --env and --adapter.
+func (f historyFilter) apply(entries []journalEntry) []journalEntry {
+	out := make([]journalEntry, 0)
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if (f.repo != "" && !strings.EqualFold(e.Repo, f.repo)) || e.Time.Before(f.since) {
+			continue
+		}
+		var changes []journalChange
+		for _, c := range e.Changes {
+			if (f.env == "" || c.Env == f.env) && (f.adapter == "" || c.Adapter == f.adapter) {
+				changes = append(changes, c)
+			}
+		}
+		if len(changes) == 0 && (f.env != "" || f.adapter != "") {
+			continue
+		}
+		e.Changes = changes
+		out = append(out, e)
+	}
+	return out
+}
+
+// parseSince accepts a date (2006-01-02), an RFC 3339 time or a duration
+// before now such as 36h or 7d.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, usageErrorf("invalid --since %q: expected a date (2006-01-02), an RFC 3339 time or a duration such as 36h or 7d", s)
+}
+
+func cmdHistory() *cobra.Command {
+	var repo, env, adapter, since, mode string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List adapter changes applied by this tool, newest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			modeVal := parseMode(mode, outTable)
+			from, err := parseSince(since, time.Now())
+			if err != nil {
+				return err
+			}
+			path, err := journalPath()
+			if err != nil {
+				return err
+			}
+			entries, err := readJournal(path)
+			if err != nil {
+				return err
+			}
+			entries = historyFilter{repo: repo, env: env, adapter: adapter, since: from}.apply(entries)
+			if limit > 0 && len(entries) > limit {
+				entries = entries[:limit]
+			}
+			return printHistory(entries, modeVal)
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Only show operations on ORG/REPO")
+	cmd.Flags().StringVar(&env, "env", "", "Only show changes in this environment")
+	cmd.Flags().StringVar(&adapter, "adapter", "", "Only show changes to this adapter")
+	cmd.Flags().StringVar(&since, "since", "", "Only show operations since a date (2006-01-02) or duration ago (36h, 7d)")
+	cmd.Flags().IntVar(&limit, "limit", 0, "Show at most N operations (0 = all)")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table|json")
+	setOutputModes(cmd, "table", "json")
+	return cmd
+}
+
+func printHistory(entries []journalEntry, mode outputMode) error {
+	if mode == outJSON {
+		enc := json.NewEncoder(stdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(stdout(), "No recorded operations.")
+		return nil
+	}
+	w := newTable()
+	w.AddRow("Time", "ID", "Command", "Repo", "Env", "Adapter", "Old", "New", "Branch", "PR")
+	for _, e := range entries {
+		command := e.Command
+		if e.Reverts != "" {
+			command += " (" + e.Reverts + ")"
+		}
+		for _, c := range e.Changes {
+			w.AddRow(e.Time.Local().Format("2006-01-02 15:04"), e.ID, command, e.Repo, c.Env, c.Adapter, c.Old, c.New, e.Branch, e.PRURL)
+		}
+	}
+	w.Render()
+	return nil
+}
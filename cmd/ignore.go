@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// valueIgnoreFile is read from the root of the scanned tree. Each non-comment
+// line holds a regex or CIDR; an optional trailing comment may carry an
+// expiry date in the form "# until 2025-01-01".
+const valueIgnoreFile = ".acaignore-values"
+
+var untilRe = regexp.MustCompile(`(?i)\buntil\s+(\d{4}-\d{2}-\d{2})\b`)
+
+type valueIgnore struct {
+	Pattern string
+	Line    int
+	Until   time.Time // zero means no expiry
+	prefix  netip.Prefix
+	re      *regexp.Regexp
+}
+
+// expired reports whether the entry's expiry date has passed. The date itself
+// is still covered by the suppression.
+func (v valueIgnore) expired(now time.Time) bool {
+	return !v.Until.IsZero() && !now.Before(v.Until.AddDate(0, 0, 1))
+}
+
+func (v valueIgnore) matches(value string) bool {
+	value = stripQuotes(value)
+	if value == "" {
+		return false
+	}
+	if v.prefix.IsValid() {
+		addr, err := netip.ParseAddr(value)
+		return err == nil && v.prefix.Contains(addr.Unmap())
+	}
+	return v.re.MatchString(value)
+}
+
+// loadValueIgnores reads the suppression file from root. A missing file is not
+// an error.
+func loadValueIgnores(root string) ([]valueIgnore, error) {
+	f, err := os.Open(filepath.Join(root, valueIgnoreFile)) // #nosec G304 - fixed name under the scan root
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil {
+			warnf("failed to close %s: %v", valueIgnoreFile, closeErr)
+		}
+	}()
+	return parseValueIgnores(f)
+}
+
+func parseValueIgnores(r io.Reader) ([]valueIgnore, error) {
+	var out []valueIgnore
+	s := bufio.NewScanner(r)
+	lineNo := 0
+	for s.Scan() {
+		lineNo++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pattern, comment := line, ""
+		if i := strings.Index(line, " #"); i >= 0 {
+			pattern, comment = strings.TrimSpace(line[:i]), line[i+2:]
+		}
+
+		entry := valueIgnore{Pattern: pattern, Line: lineNo}
+		if m := untilRe.FindStringSubmatch(comment); m != nil {
+			until, err := time.Parse("2006-01-02", m[1])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid expiry date %q: %w", valueIgnoreFile, lineNo, m[1], err)
+			}
+			entry.Until = until
+		}
+
+		if p, err := netip.ParsePrefix(pattern); err == nil {
+			entry.prefix = p.Masked()
+		} else if a, err := netip.ParseAddr(pattern); err == nil {
+			entry.prefix = netip.PrefixFrom(a.Unmap(), a.Unmap().BitLen())
+		} else {
+			re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid pattern %q: %w", valueIgnoreFile, lineNo, pattern, err)
+			}
+			entry.re = re
+		}
+		out = append(out, entry)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", valueIgnoreFile, err)
+	}
+	return out, nil
+}
+
+// applyValueIgnores blanks out IP and port values matched by an active
+// suppression and drops rows left with nothing to report. Expired entries are
+// not applied and are returned so the caller can report them as stale.
+func applyValueIgnores(rows []matchRow, ignores []valueIgnore, now time.Time) ([]matchRow, []valueIgnore) {
+	if len(ignores) == 0 {
+		return rows, nil
+	}
+	active, stale := splitExpiredIgnores(ignores, now)
+
+	suppressed := func(v string) bool {
+		for _, ig := range active {
+			if ig.matches(v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	out := rows[:0]
+	for _, r := range rows {
+		if suppressed(r.IPValue) {
+			r.IPKey, r.IPValue = "", ""
+		}
+		if suppressed(r.PortValue) {
+			r.PortKey, r.PortValue = "", ""
+		}
+		if r.IPKey == "" && r.IPValue == "" && r.PortKey == "" && r.PortValue == "" {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out, stale
+}
+
+// splitExpiredIgnores separates entries still in force from expired ones.
+func splitExpiredIgnores(ignores []valueIgnore, now time.Time) (active, stale []valueIgnore) {
+	for _, ig := range ignores {
+		if ig.expired(now) {
+			stale = append(stale, ig)
+		} else {
+			active = append(active, ig)
+		}
+	}
+	return active, stale
+}
+
+func warnStaleIgnores(stale []valueIgnore) {
+	for _, ig := range stale {
+		warnf("%s:%d: suppression %q expired on %s and is stale; remove or renew it",
+			valueIgnoreFile, ig.Line, ig.Pattern, ig.Until.Format("2006-01-02"))
+	}
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseValueIgnores(t *testing.T) {
+	input := `# suppressions for shared infrastructure
+10.0.0.0/8          # internal range
+192.168.1.1
+127\.0\.0\.\d+      # loopback, until 2025-01-01
+8080
+
+`
+	ignores, err := parseValueIgnores(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseValueIgnores failed: %v", err)
+	}
+	if len(ignores) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(ignores))
+	}
+
+	if ignores[2].Until.IsZero() || ignores[2].Until.Format("2006-01-02") != "2025-01-01" {
+		t.Errorf("Expected expiry 2025-01-01 on line %d, got %v", ignores[2].Line, ignores[2].Until)
+	}
+	if ignores[2].Line != 4 {
+		t.Errorf("Expected line 4, got %d", ignores[2].Line)
+	}
+
+	tests := []struct {
+		entry int
+		value string
+		want  bool
+	}{
+		{0, "10.1.2.3", true},
+		{0, "11.1.2.3", false},
+		{1, "192.168.1.1", true},
+		{1, "192.168.1.10", false},
+		{2, "127.0.0.1", true},
+		{3, "8080", true},
+		{3, "18080", false},
+	}
+	for _, tt := range tests {
+		t.Run(ignores[tt.entry].Pattern+"_"+tt.value, func(t *testing.T) {
+			if got := ignores[tt.entry].matches(tt.value); got != tt.want {
+				t.Errorf("matches(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseValueIgnores_Invalid(t *testing.T) {
+	inputs := []string{
+		"[unclosed",
+		"10.0.0.1 # until 2025-13-45",
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			if _, err := parseValueIgnores(strings.NewReader(input)); err == nil {
+				t.Errorf("Expected error for %q", input)
+			}
+		})
+	}
+}
+
+func TestApplyValueIgnores(t *testing.T) {
+	ignores, err := parseValueIgnores(strings.NewReader("10.0.0.0/8\n8080 # until 2025-01-01\n"))
+	if err != nil {
+		t.Fatalf("parseValueIgnores failed: %v", err)
+	}
+
+	rows := []matchRow{
+		{IPKey: "db.host", IPValue: "10.0.0.5", RelPath: "a.properties", LineNumber: 1},
+		{IPKey: "api.host", IPValue: "172.16.0.1", RelPath: "a.properties", LineNumber: 2},
+		{IPKey: "svc.host", IPValue: "10.1.1.1", PortKey: "svc.port", PortValue: "9090", RelPath: "b.yml", LineNumber: 3},
+		{PortKey: "server.port", PortValue: "8080", RelPath: "b.yml", LineNumber: 4},
+	}
+
+	// Before expiry: the port suppression still applies.
+	got, stale := applyValueIgnores(append([]matchRow(nil), rows...), ignores, time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	if len(stale) != 0 {
+		t.Errorf("Expected no stale entries, got %d", len(stale))
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 rows, got %d: %+v", len(got), got)
+	}
+	if got[1].IPValue != "" || got[1].PortValue != "9090" {
+		t.Errorf("Expected IP blanked and port kept, got %+v", got[1])
+	}
+
+	// After expiry: the port suppression is stale and no longer applies.
+	got, stale = applyValueIgnores(append([]matchRow(nil), rows...), ignores, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+	if len(stale) != 1 || stale[0].Pattern != "8080" {
+		t.Errorf("Expected 8080 to be stale, got %+v", stale)
+	}
+	if len(got) != 3 {
+		t.Errorf("Expected 3 rows after expiry, got %d", len(got))
+	}
+}
+
+func TestScanTree_ValueIgnores(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"app.properties": "db.host=10.0.0.5\napi.host=172.16.0.1\n",
+		valueIgnoreFile:  "10.0.0.0/8\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	rows, err := scanTree(root, scanOptions{includes: []string{"**/*.properties"}})
+	if err != nil {
+		t.Fatalf("scanTree failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0].IPValue != "172.16.0.1" {
+		t.Errorf("Expected only 172.16.0.1 to remain, got %+v", rows)
+	}
+}
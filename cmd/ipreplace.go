@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// ipMapping rewrites addresses in From to the same host in To. Single
+// addresses are full-length prefixes.
+type ipMapping struct {
+	From, To netip.Prefix
+}
+
+// parseIPMap parses "old=new" pairs where both sides are addresses or
+// prefixes of the same family and length.
+func parseIPMap(pairs []string) ([]ipMapping, error) {
+	var out []ipMapping
+	for _, pair := range pairs {
+		oldStr, newStr, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, usageErrorf("invalid --map %q: expected OLD=NEW", pair)
+		}
+		from, err := parsePolicyCIDR(strings.TrimSpace(oldStr))
+		if err != nil {
+			return nil, usageErrorf("invalid --map %q: %w", pair, err)
+		}
+		to, err := parsePolicyCIDR(strings.TrimSpace(newStr))
+		if err != nil {
+			return nil, usageErrorf("invalid --map %q: %w", pair, err)
+		}
+		if from.Addr().Is4() != to.Addr().Is4() || from.Bits() != to.Bits() {
+			return nil, usageErrorf("invalid --map %q: both sides must be the same address family and prefix length", pair)
+		}
+		for _, m := range out {
+			if m.From == from {
+				return nil, usageErrorf("invalid --map %q: %s is mapped twice", pair, from)
+			}
+		}
+		out = append(out, ipMapping{From: from, To: to})
+	}
+	// Most specific mapping first, so a single address can be carved out
+	// of a mapped range.
+	sort.SliceStable(out, func(i, j int) bool { return out[i].From.Bits() > out[j].From.Bits() })
+	return out, nil
+}
+
+// translate returns addr moved into the To prefix, keeping its host bits.
+func (m ipMapping) translate(addr netip.Addr) netip.Addr {
+	src, dst := addr.AsSlice(), m.To.Addr().AsSlice()
+	bits := m.To.Bits()
+	for i := range dst {
+		switch {
+		case bits >= 8:
+			bits -= 8
+		case bits > 0:
+			mask := byte(0xff) << (8 - bits)
+			dst[i] = dst[i]&mask | src[i]&^mask
+			bits = 0
+		default:
+			dst[i] = src[i]
+		}
+	}
+	out, _ := netip.AddrFromSlice(dst)
+	return out
+}
+
+// ipRewriter replaces every address token on a line that falls into one
+// of the mappings. Tokens are found the same way ip-port detects them.
+func ipRewriter(mappings []ipMapping) lineRewriter {
+	return func(_, line string) (string, string, string, bool) {
+		var b strings.Builder
+		var olds, news []string
+		last := 0
+		for _, loc := range ipTokens(line) {
+			addr, err := netip.ParseAddr(line[loc[0]:loc[1]])
+			if err != nil {
+				continue
+			}
+			for _, m := range mappings {
+				if m.From.Contains(addr.Unmap()) {
+					repl := m.translate(addr.Unmap()).String()
+					b.WriteString(line[last:loc[0]])
+					b.WriteString(repl)
+					last = loc[1]
+					olds, news = append(olds, line[loc[0]:loc[1]]), append(news, repl)
+					break
+				}
+			}
+		}
+		if len(olds) == 0 {
+			return line, "", "", false
+		}
+		b.WriteString(line[last:])
+		return b.String(), strings.Join(olds, ","), strings.Join(news, ","), true
+	}
+}
+
+// ipTokens returns the non-overlapping IPv4 and IPv6 literals on a line
+// in order.
+func ipTokens(line string) [][]int {
+	locs := ipv4.FindAllStringIndex(line, -1)
+	for _, l := range ipv6.FindAllStringIndex(line, -1) {
+		overlap := false
+		for _, v4 := range locs {
+			if l[0] < v4[1] && v4[0] < l[1] {
+				overlap = true
+				break
+			}
+		}
+		if !overlap {
+			locs = append(locs, l)
+		}
+	}
+	sort.Slice(locs, func(i, j int) bool { return locs[i][0] < locs[j][0] })
+	return locs
+}
+
+func cmdIPReplace() *cobra.Command {
+	var repo, branch, includes, excludes, mode string
+	var pairs []string
+	var doCommit, doPR, dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "ip-replace",
+		Short: "Rewrite IP addresses across a repository and optionally open a PR",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if repo == "" {
+				return usageErrorf("--repo ORG/REPO is required")
+			}
+			mappings, err := parseIPMap(pairs)
+			if err != nil {
+				return err
+			}
+			if len(mappings) == 0 {
+				return usageErrorf("--map OLD=NEW is required")
+			}
+			modeVal := parseMode(mode, outTable)
+			doCommit = doCommit || doPR
+			var access pushAccess
+			if doCommit && !dryRun {
+				if access, err = preflight(repo, false); err != nil {
+					return err
+				}
+			}
+
+			tmpDir, cleanup, err := cloneOrDownload(repo, "")
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			files := listFiles(tmpDir, splitCSV(includes, []string{"**/*"}), splitCSV(excludes, []string{"**/.git/**"}))
+			edits, err := planRewrites(tmpDir, files, ipRewriter(mappings))
+			if err != nil {
+				return err
+			}
+			if dryRun || len(edits) == 0 {
+				return printRewrites(edits, modeVal)
+			}
+
+			changed, err := applyRewrites(tmpDir, edits)
+			if err != nil {
+				return err
+			}
+			if err := printRewrites(edits, modeVal); err != nil {
+				return err
+			}
+			if !doCommit {
+				return nil
+			}
+			if branch == "" {
+				branch = "replace/ips"
+			}
+			summary := strings.Join(pairs, ", ")
+			msg := fmt.Sprintf("chore: replace IPs %s", summary)
+			body := fmt.Sprintf("Automated via gh aca-utils ip-replace.\n\nMappings: %s\nChanged %d line(s) in %d file(s).", summary, len(edits), len(changed))
+			if err := access.prepare(tmpDir); err != nil {
+				return err
+			}
+			_, err = commitRewrites(tmpDir, branch, changed, msg, "Replace IPs: "+summary, body, doPR)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Target repo as ORG/REPO (required)")
+	cmd.Flags().StringSliceVar(&pairs, "map", nil, "OLD=NEW address or same-size CIDR pairs, comma-separated (e.g. 10.0.0.5=10.1.0.5,10.2.0.0/16=10.3.0.0/16)")
+	cmd.Flags().StringVar(&includes, "include", defaultIncludes, "Comma-separated glob patterns to include")
+	cmd.Flags().StringVar(&excludes, "exclude", defaultExcludes, "Comma-separated glob patterns to exclude")
+	cmd.Flags().StringVar(&branch, "branch", "", "Branch name to create (with --commit, default replace/ips)")
+	cmd.Flags().BoolVar(&doCommit, "commit", false, "Commit the change to a new branch and push")
+	cmd.Flags().BoolVar(&doPR, "pr", false, "Create a pull request (implies --commit)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", true, "Show a diff of the planned changes without writing")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table (diff)|json")
+	setOutputModes(cmd, "table", "json")
+	return cmd
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseIPMap(t *testing.T) {
+	m, err := parseIPMap([]string{"10.2.0.0/16=10.3.0.0/16", " 10.2.0.5 = 192.168.0.1 "})
+	if err != nil {
+		t.Fatalf("parseIPMap failed: %v", err)
+	}
+	if len(m) != 2 || m[0].From.String() != "10.2.0.5/32" {
+		t.Errorf("Expected the most specific mapping first, got %v", m)
+	}
+	for _, bad := range [][]string{
+		{"10.0.0.1"},
+		{"10.0.0.1=bogus"},
+		{"10.0.0.0/16=10.1.0.0/24"},
+		{"10.0.0.1=::1"},
+		{"10.0.0.0/8=11.0.0.0/8", "10.0.0.0/8=12.0.0.0/8"},
+	} {
+		if _, err := parseIPMap(bad); err == nil {
+			t.Errorf("Expected error for %v", bad)
+		}
+	}
+}
+
+func TestIPRewriter(t *testing.T) {
+	m, err := parseIPMap([]string{"10.2.0.0/12=172.16.0.0/12", "10.2.0.5=192.168.0.1", "fd00::1=fd00::2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw := ipRewriter(m)
+	tests := []struct {
+		line, want, old string
+	}{
+		{"db.host=10.2.0.5", "db.host=192.168.0.1", "10.2.0.5"},
+		{"hosts: [10.2.3.4, 10.2.0.50, 110.2.0.5]", "hosts: [172.18.3.4, 172.18.0.50, 110.2.0.5]", "10.2.3.4,10.2.0.50"},
+		{"url=http://[fd00::1]:80", "url=http://[fd00::2]:80", "fd00::1"},
+		{"version=1.2.3.4", "version=1.2.3.4", ""},
+	}
+	for _, tt := range tests {
+		got, old, _, ok := rw("a.env", tt.line)
+		if got != tt.want || old != tt.old || ok != (tt.old != "") {
+			t.Errorf("rewrite(%q) = %q, %q, %t; want %q, %q", tt.line, got, old, ok, tt.want, tt.old)
+		}
+	}
+}
+
+func TestPlanAndApplyRewrites(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "env"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	app := filepath.Join(root, "env", "app.env")
+	if err := os.WriteFile(app, []byte("A=10.0.0.5\r\nB=10.0.0.6\r\nC=10.0.0.5\r\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	m, _ := parseIPMap([]string{"10.0.0.5=10.9.9.9"})
+	edits, err := planRewrites(root, listFiles(root, []string{"**/*"}, nil), ipRewriter(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 2 || edits[1].Line != 3 || edits[1].File != "env/app.env" {
+		t.Fatalf("Unexpected edits: %+v", edits)
+	}
+
+	var out bytes.Buffer
+	resultOutput = &out
+	defer func() { resultOutput = nil }()
+	if err := printRewrites(edits, outTable); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "--- a/env/app.env\n+++ b/env/app.env\n@@ line 1 @@\n-A=10.0.0.5\n+A=10.9.9.9\n") {
+		t.Errorf("Unexpected diff:\n%s", out.String())
+	}
+
+	changed, err := applyRewrites(root, edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(app)
+	if len(changed) != 1 || string(b) != "A=10.9.9.9\r\nB=10.0.0.6\r\nC=10.9.9.9\r\n" {
+		t.Errorf("Unexpected result %v: %q", changed, b)
+	}
+	if info, _ := os.Stat(app); info.Mode().Perm() != 0o640 {
+		t.Errorf("File mode not preserved: %v", info.Mode())
+	}
+	if _, err := applyRewrites(root, edits); err == nil {
+		t.Error("Expected stale edits to be rejected")
+	}
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// trackingIssue is the ticket a change is linked to with --issue or
+// --create-issue.
+type trackingIssue struct {
+	Repo   string
+	Number int
+	URL    string
+}
+
+// ref is how commits and pull requests in repo refer to the issue: #N in
+// its own repository, ORG/REPO#N elsewhere.
+func (i trackingIssue) ref(repo string) string {
+	if strings.EqualFold(i.Repo, repo) {
+		return fmt.Sprintf("#%d", i.Number)
+	}
+	return fmt.Sprintf("%s#%d", i.Repo, i.Number)
+}
+
+// parseIssueRef reads an --issue value: N or #N for an issue in repo,
+// ORG/REPO#N, or the issue's URL.
+func parseIssueRef(s, repo string) (string, int, error) {
+	ref := strings.TrimSpace(s)
+	if u, ok := strings.CutPrefix(ref, "https://"); ok {
+		parts := strings.Split(strings.TrimSuffix(u, "/"), "/")
+		if len(parts) != 5 || parts[3] != "issues" {
+			return "", 0, usageErrorf("invalid --issue %q: want a URL like https://github.com/ORG/REPO/issues/N", s)
+		}
+		ref = parts[1] + "/" + parts[2] + "#" + parts[4]
+	}
+	issueRepo, num, ok := strings.Cut(ref, "#")
+	if !ok {
+		issueRepo, num = "", ref
+	}
+	if issueRepo == "" {
+		issueRepo = repo
+	} else if parts := strings.Split(issueRepo, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", 0, usageErrorf("invalid --issue %q: want N, ORG/REPO#N or an issue URL", s)
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil || n <= 0 {
+		return "", 0, usageErrorf("invalid --issue %q: want N, ORG/REPO#N or an issue URL", s)
+	}
+	return issueRepo, n, nil
+}
+
+// lookupIssue resolves an --issue value for a change to repo, failing if
+// the issue doesn't exist.
+func lookupIssue(s, repo string) (trackingIssue, error) {
+	issueRepo, n, err := parseIssueRef(s, repo)
+	if err != nil {
+		return trackingIssue{}, err
+	}
+	var issue struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/issues/%d", issueRepo, n), nil, &issue); err != nil {
+		return trackingIssue{}, fmt.Errorf("tracking issue %s#%d: %w", issueRepo, n, err)
+	}
+	return trackingIssue{Repo: issueRepo, Number: n, URL: issue.HTMLURL}, nil
+}
+
+// createIssue opens a tracking issue in repo.
+func createIssue(repo, title, body string) (trackingIssue, error) {
+	var issue struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := ghAPI("POST", fmt.Sprintf("repos/%s/issues", repo), map[string]string{"title": title, "body": body}, &issue); err != nil {
+		return trackingIssue{}, fmt.Errorf("create tracking issue: %w", err)
+	}
+	return trackingIssue{Repo: repo, Number: issue.Number, URL: issue.HTMLURL}, nil
+}
+
+// withIssueRef appends a reference to the issue to a commit message or
+// pull request body, unless a template already put it there.
+func withIssueRef(text, ref string) string {
+	if ref == "" || regexp.MustCompile(regexp.QuoteMeta(ref)+`\b`).MatchString(text) {
+		return text
+	}
+	return strings.TrimRight(text, "\n") + "\n\nRefs " + ref
+}
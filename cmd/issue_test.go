@@ -0,0 +1,61 @@
+package cmd
+
+import "testing"
+
+func TestParseIssueRef(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantRepo string
+		wantN    int
+		wantErr  bool
+	}{
+		{in: "1234", wantRepo: "org/svc", wantN: 1234},
+		{in: "#12", wantRepo: "org/svc", wantN: 12},
+		{in: "org/tickets#7", wantRepo: "org/tickets", wantN: 7},
+		{in: "https://github.com/org/tickets/issues/7", wantRepo: "org/tickets", wantN: 7},
+		{in: "https://github.com/org/tickets/pull/7", wantErr: true},
+		{in: "org#7", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			repo, n, err := parseIssueRef(tt.in, "org/svc")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if repo != tt.wantRepo || n != tt.wantN {
+				t.Errorf("parseIssueRef(%q) = %s, %d, want %s, %d", tt.in, repo, n, tt.wantRepo, tt.wantN)
+			}
+		})
+	}
+}
+
+func TestWithIssueRef(t *testing.T) {
+	tests := []struct {
+		name, text, ref, want string
+	}{
+		{"appended", "chore(env:dev): flip adapters billing", "#12", "chore(env:dev): flip adapters billing\n\nRefs #12"},
+		{"other repo", "Automated via gh aca-utils flip-adapters.\n", "org/tickets#7", "Automated via gh aca-utils flip-adapters.\n\nRefs org/tickets#7"},
+		{"already in template", "fix: flip billing (#12)", "#12", "fix: flip billing (#12)"},
+		{"prefix of another issue", "see #123", "#12", "see #123\n\nRefs #12"},
+		{"no issue", "flip", "", "flip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withIssueRef(tt.text, tt.ref); got != tt.want {
+				t.Errorf("withIssueRef = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackingIssueRef(t *testing.T) {
+	i := trackingIssue{Repo: "org/tickets", Number: 7}
+	if got := i.ref("org/tickets"); got != "#7" {
+		t.Errorf("same repo ref = %q", got)
+	}
+	if got := i.ref("org/svc"); got != "org/tickets#7" {
+		t.Errorf("other repo ref = %q", got)
+	}
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// journalEntry records one applied operation, such as a committed
+// flip-adapters run, for aca history and --revert.
+type journalEntry struct {
+	ID          string          `json:"id"`
+	Time        time.Time       `json:"time"`
+	Command     string          `json:"command"`
+	Repo        string          `json:"repo"`
+	File        string          `json:"file"`
+	Branch      string          `json:"branch,omitempty"`
+	PRURL       string          `json:"pr_url,omitempty"`
+	Reverts     string          `json:"reverts,omitempty"`
+	Approver    string          `json:"approver,omitempty"`
+	ApprovalRun string          `json:"approval_run,omitempty"` // workflow run whose environment approval the change used up
+	Issue       string          `json:"issue,omitempty"`        // tracking issue URL
+	Changes     []journalChange `json:"changes"`
+}
+
+// journalChange is one adapter value changed by a run.
+type journalChange struct {
+	Env     string `json:"env"`
+	Adapter string `json:"adapter"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Created bool   `json:"created,omitempty"`
+}
+
+// journalPath is the change journal, one JSON entry per line.
+func journalPath() (string, error) {
+	dir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "journal.jsonl"), nil
+}
+
+// appendJournal adds e to the journal at path, filling in its ID and time.
+func appendJournal(path string, e journalEntry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if e.ID == "" {
+		e.ID = strconv.FormatInt(e.Time.UnixNano(), 36)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encode journal entry: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600) // #nosec G304 - path is under the config dir
+	if err != nil {
+		return fmt.Errorf("open journal: %w", err)
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write journal: %w", err)
+	}
+	return f.Close()
+}
+
+// recordJournal appends e to the user's journal. The change has already
+// been pushed by then, so failures are only reported.
+func recordJournal(e journalEntry) {
+	path, err := journalPath()
+	if err == nil {
+		err = appendJournal(path, e)
+	}
+	if err != nil {
+		warnf("could not record the run in the change journal: %v", err)
+	}
+}
+
+// readJournal returns the entries at path, oldest first. A missing journal
+// is empty.
+func readJournal(path string) ([]journalEntry, error) {
+	f, err := os.Open(path) // #nosec G304 - path is under the config dir
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open journal: %w", err)
+	}
+	defer f.Close()
+
+	var entries []journalEntry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e journalEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+	return entries, nil
+}
+
+// lastRevertible returns the newest run for repo that is neither a revert
+// nor already reverted, so repeated --revert calls unwind runs in turn.
+func lastRevertible(entries []journalEntry, repo string) (journalEntry, bool) {
+	reverted := map[string]bool{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Repo != repo {
+			continue
+		}
+		if e.Reverts != "" {
+			reverted[e.Reverts] = true
+			continue
+		}
+		if !reverted[e.ID] {
+			return e, true
+		}
+	}
+	return journalEntry{}, false
+}
+
+// envs lists the environments e changed, in order of first appearance.
+func (e journalEntry) envs() []string {
+	var envs []string
+	seen := map[string]bool{}
+	for _, c := range e.Changes {
+		if !seen[c.Env] {
+			seen[c.Env] = true
+			envs = append(envs, c.Env)
+		}
+	}
+	return envs
+}
+
+// adapters lists the adapters e changed, in order of first appearance.
+func (e journalEntry) adapters() []string {
+	var adapters []string
+	seen := map[string]bool{}
+	for _, c := range e.Changes {
+		if !seen[c.Adapter] {
+			seen[c.Adapter] = true
+			adapters = append(adapters, c.Adapter)
+		}
+	}
+	return adapters
+}
+
+// revertPlan returns the adapters e changed in env and the planValues step
+// that restores their old values. Adapters edited since the run are
+// reported and left alone, as are adapters the run created.
+func (e journalEntry) revertPlan(env string) ([]string, func(adapter, v string) (string, bool)) {
+	var want []string
+	recorded := map[string]journalChange{}
+	for _, c := range e.Changes {
+		if c.Env == env && c.Created {
+			warnf("adapter %q was added to %s by the run; remove it by hand if needed", c.Adapter, env)
+			continue
+		}
+		if c.Env == env {
+			want = append(want, c.Adapter)
+			recorded[c.Adapter] = c
+		}
+	}
+	next := func(adapter, v string) (string, bool) {
+		c := recorded[adapter]
+		if v != c.New {
+			warnf("adapter %q in %s is %q, not %q as recorded; skipping", adapter, env, v, c.New)
+			return "", false
+		}
+		return c.Old, true
+	}
+	return want, next
+}
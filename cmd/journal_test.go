@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJournal_RoundTripAndLastRevertible(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	if entries, err := readJournal(path); err != nil || len(entries) != 0 {
+		t.Fatalf("missing journal = %v, %v", entries, err)
+	}
+	runs := []journalEntry{
+		{ID: "a", Command: "flip", Repo: "org/svc", File: "parameters.properties", Changes: []journalChange{{Env: "dev", Adapter: "billing", Old: "0", New: "1"}}},
+		{ID: "b", Command: "flip", Repo: "org/other", File: "parameters.properties"},
+		{ID: "c", Command: "flip", Repo: "org/svc", File: "parameters.properties", Changes: []journalChange{{Env: "prod", Adapter: "search", Old: "true", New: "false"}}},
+		{ID: "d", Command: "revert", Repo: "org/svc", Reverts: "c"},
+	}
+	for _, e := range runs {
+		if err := appendJournal(path, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := readJournal(path)
+	if err != nil || len(entries) != len(runs) {
+		t.Fatalf("readJournal = %d entries, %v", len(entries), err)
+	}
+	if entries[0].Time.IsZero() || entries[2].Changes[0].New != "false" {
+		t.Errorf("Unexpected entry: %+v", entries[2])
+	}
+
+	e, ok := lastRevertible(entries, "org/svc")
+	if !ok || e.ID != "a" {
+		t.Errorf("lastRevertible = %q, %v; want a (c is already reverted)", e.ID, ok)
+	}
+	if _, ok := lastRevertible(entries[3:], "org/svc"); ok {
+		t.Error("Expected nothing to revert when only reverts are recorded")
+	}
+}
+
+func TestJournal_RevertPlan(t *testing.T) {
+	e := journalEntry{Changes: []journalChange{
+		{Env: "dev", Adapter: "billing", Old: "0", New: "1"},
+		{Env: "dev", Adapter: "search", Old: "on", New: "off"},
+		{Env: "prod", Adapter: "billing", Old: "0", New: "1"},
+	}}
+	if got := strings.Join(e.envs(), ","); got != "dev,prod" {
+		t.Errorf("envs = %s", got)
+	}
+	if got := strings.Join(e.adapters(), ","); got != "billing,search" {
+		t.Errorf("adapters = %s", got)
+	}
+
+	lines := []string{"billing=1\n", "search=on\n", "other=0"}
+	want, next := e.revertPlan("dev")
+	changes := planValues(lines, indexKeys(lines), want, "parameters.properties", next)
+	// search was switched back by hand since the run, so only billing is restored.
+	if len(changes) != 1 || changes[0].Adapter != "billing" || changes[0].NewValue != "0" {
+		t.Fatalf("Unexpected changes: %+v", changes)
+	}
+	if got := strings.Join(applyFlips(lines, changes), ""); got != "billing=0\nsearch=on\nother=0" {
+		t.Errorf("applyFlips = %q", got)
+	}
+}
+
+func TestHistoryFilter(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	entries := []journalEntry{
+		{ID: "a", Time: now.AddDate(0, 0, -10), Repo: "org/svc", Changes: []journalChange{{Env: "dev", Adapter: "billing"}}},
+		{ID: "b", Time: now.AddDate(0, 0, -2), Repo: "org/svc", Changes: []journalChange{{Env: "dev", Adapter: "search"}, {Env: "prod", Adapter: "billing"}}},
+		{ID: "c", Time: now.AddDate(0, 0, -1), Repo: "org/other", Changes: []journalChange{{Env: "dev", Adapter: "billing"}}},
+	}
+	since, err := parseSince("7d", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter historyFilter
+		want   string
+	}{
+		{"all newest first", historyFilter{}, "c:1,b:2,a:1"},
+		{"repo", historyFilter{repo: "ORG/svc"}, "b:2,a:1"},
+		{"adapter keeps matching changes", historyFilter{adapter: "billing"}, "c:1,b:1,a:1"},
+		{"env and since", historyFilter{env: "prod", since: since}, "b:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range tt.filter.apply(entries) {
+				got = append(got, fmt.Sprintf("%s:%d", e.ID, len(e.Changes)))
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("apply = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	if got, err := parseSince("36h", now); err != nil || !got.Equal(now.Add(-36*time.Hour)) {
+		t.Errorf("36h = %v, %v", got, err)
+	}
+	if got, err := parseSince("2026-05-01T00:00:00Z", now); err != nil || got.Day() != 1 {
+		t.Errorf("RFC 3339 = %v, %v", got, err)
+	}
+	for _, bad := range []string{"yesterday", "-3d", "5 days"} {
+		if _, err := parseSince(bad, now); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/greenstevester/gh-aca-utils/pkg/jsonptr"
+)
+
+// isJSONParams reports whether a parameters file is JSON, such as
+// parameters.json or appsettings.json.
+func isJSONParams(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".json")
+}
+
+// adapterPointer maps an adapter name to a JSON pointer; names without a
+// leading slash address a top-level key.
+func adapterPointer(adapter string) string {
+	if strings.HasPrefix(adapter, "/") {
+		return adapter
+	}
+	return "/" + jsonptr.Escape(adapter)
+}
+
+// jsonAdapterKeys returns the adapter names of every scalar in data:
+// top-level keys by name, nested values by pointer.
+func jsonAdapterKeys(data []byte) []string {
+	ptrs, _ := jsonptr.Leaves(data)
+	keys := make([]string, 0, len(ptrs))
+	for _, p := range ptrs {
+		if p == "" {
+			continue
+		}
+		if name := p[1:]; !strings.Contains(name, "/") {
+			p = strings.NewReplacer("~1", "/", "~0", "~").Replace(name)
+		}
+		keys = append(keys, p)
+	}
+	return keys
+}
+
+// planJSONFlips computes the toggles for JSON documents: each adapter is a JSON
+// pointer whose boolean, number or string value is toggled.
+func planJSONFlips(data []byte, want []string, path string, pairs []togglePair) ([]change, error) {
+	return planJSONValues(data, want, path, toggleNext(pairs))
+}
+
+// planJSONValues is planValues for JSON documents. Objects and arrays are
+// never changed.
+func planJSONValues(data []byte, want []string, path string, next func(adapter, v string) (string, bool)) ([]change, error) {
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("%s: invalid JSON", path)
+	}
+	changes := make([]change, 0)
+	planned := map[string]bool{}
+	for _, a := range want {
+		ptr := adapterPointer(a)
+		if planned[ptr] {
+			continue
+		}
+		span, err := jsonptr.Find(data, ptr)
+		if err != nil {
+			warnf("adapter %q not found in %s%s", a, path, didYouMean(suggestKeys(a, jsonAdapterKeys(data))))
+			continue
+		}
+		raw := string(data[span.Start:span.End])
+		old, quoted := jsonValue(raw)
+		if !quoted && strings.ContainsAny(raw, "{[") {
+			warnf("adapter %q has non-scalar value %s; skipping", a, raw)
+			continue
+		}
+		newV, ok := next(a, old)
+		if !ok {
+			continue
+		}
+		planned[ptr] = true
+		changes = append(changes, change{
+			Adapter: a, OldValue: old, NewValue: newV, FilePath: path,
+			line: raw, span: span, newRaw: jsonRaw(newV, quoted),
+		})
+	}
+	return changes, nil
+}
+
+// applyJSONFlips splices the new values into data, leaving every other
+// byte, including indentation and key order, as it was. Created adapters
+// are added as the last member of their parent object.
+func applyJSONFlips(data []byte, changes []change) []byte {
+	var ordered, creates []change
+	for _, c := range changes {
+		if c.Created {
+			creates = append(creates, c)
+		} else {
+			ordered = append(ordered, c)
+		}
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].span.Start > ordered[j].span.Start })
+	out := append([]byte(nil), data...)
+	for _, c := range ordered {
+		out = append(out[:c.span.Start], append([]byte(c.newRaw), out[c.span.End:]...)...)
+	}
+	for _, c := range creates {
+		if b, err := insertJSONMember(out, adapterPointer(c.Adapter), c.newRaw); err == nil {
+			out = b
+		}
+	}
+	return out
+}
+
+// jsonRaw encodes a new value: as a string when it replaces one or isn't
+// a JSON literal, otherwise as written (true, 0, null).
+func jsonRaw(v string, quoted bool) string {
+	if quoted || !json.Valid([]byte(v)) {
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return v
+}
+
+// jsonValue returns the text of a raw JSON value, unquoting strings.
+func jsonValue(raw string) (string, bool) {
+	if !strings.HasPrefix(raw, `"`) {
+		return raw, false
+	}
+	var v string
+	_ = json.Unmarshal([]byte(raw), &v)
+	return v, true
+}
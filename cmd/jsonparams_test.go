@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONFlips(t *testing.T) {
+	doc := "{\r\n    \"Features\": {\r\n        \"Billing\": true,\r\n        \"Search\" : \"Off\",\r\n        \"Level\": 1,\r\n        \"Mode\": \"auto\"\r\n    },\r\n    \"TopLevel\": 0\r\n}\r\n"
+	want := []string{"/Features/Billing", "/Features/Search", "/Features/Level", "/Features/Mode", "TopLevel", "/Missing", "/Features/Billing"}
+	changes, err := planJSONFlips([]byte(doc), want, "appsettings.json", defaultTogglePairs)
+	if err != nil {
+		t.Fatalf("planJSONFlips failed: %v", err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Adapter+"="+c.OldValue+">"+c.NewValue)
+	}
+	if strings.Join(got, ",") != "/Features/Billing=true>false,/Features/Search=Off>On,/Features/Level=1>0,TopLevel=0>1" {
+		t.Errorf("Unexpected changes: %v", got)
+	}
+
+	out := string(applyJSONFlips([]byte(doc), changes))
+	expected := strings.NewReplacer(`"Billing": true`, `"Billing": false`, `"Search" : "Off"`, `"Search" : "On"`,
+		`"Level": 1`, `"Level": 0`, `"TopLevel": 0`, `"TopLevel": 1`).Replace(doc)
+	if out != expected {
+		t.Errorf("applyJSONFlips changed more than the values:\n%q\nwant\n%q", out, expected)
+	}
+
+	if _, err := planJSONFlips([]byte(`{"a":`), []string{"a"}, "bad.json", defaultTogglePairs); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+	if got := adapterPointer("a/b~c"); got != "/a~1b~0c" {
+		t.Errorf("adapterPointer = %q", got)
+	}
+}
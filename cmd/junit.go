@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// printJUnit writes rows as a JUnit XML report to the result output.
+func printJUnit(rows []matchRow, cols []rowColumn, policy *failPolicy) error {
+	return writeJUnit(stdout(), rows, cols, policy)
+}
+
+// writeJUnit maps findings onto a JUnit report: one test suite per file
+// (per branch in all-branches scans) and one test case per finding, so CI
+// test views list every hardcoded address at its file and line. Findings
+// that fail policy (--fail-on, --fail-if-found) fail their test case; the
+// others pass.
+func writeJUnit(w io.Writer, rows []matchRow, cols []rowColumn, policy *failPolicy) error {
+	report := junitTestSuites{Name: "aca ip-port"}
+	suiteIdx := map[string]int{}
+	for _, r := range rows {
+		i, ok := suiteIdx[r.location()]
+		if !ok {
+			i = len(report.Suites)
+			suiteIdx[r.location()] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: r.location()})
+		}
+		suite := &report.Suites[i]
+		tc := junitTestCase{
+			Name:      fmt.Sprintf("line %d: %s", r.LineNumber, findingSummary(r)),
+			ClassName: r.location(),
+		}
+		suite.Tests++
+		report.Tests++
+		if policy.fails(r) {
+			tc.Failure = &junitFailure{
+				Message: "hardcoded " + findingSummary(r),
+				Type:    "finding",
+				Body:    junitDetails(r, cols),
+			}
+			suite.Failures++
+			report.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// findingSummary describes a finding as "IP 10.0.0.5:5432" style text.
+func findingSummary(r matchRow) string {
+	switch {
+	case r.IPValue != "" && r.PortValue != "":
+		return fmt.Sprintf("IP %s port %s", r.IPValue, r.PortValue)
+	case r.IPValue != "":
+		return "IP " + r.IPValue
+	default:
+		return "port " + r.PortValue
+	}
+}
+
+// junitDetails lists the non-empty printed columns of a finding.
+func junitDetails(r matchRow, cols []rowColumn) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:%d\n", r.location(), r.LineNumber)
+	for _, c := range cols {
+		if c.Name == "filePath" || c.Name == "lineNumber" || c.Name == "branch" {
+			continue
+		}
+		if v := c.Value(r); v != "" {
+			fmt.Fprintf(&b, "%s: %s\n", c.Header, v)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	rows := []matchRow{
+		{IPKey: "db.host", IPValue: "10.0.0.5", PortKey: "db.port", PortValue: "5432", RelPath: "a.properties", LineNumber: 2, Severity: []string{"prod"}},
+		{PortKey: "server.port", PortValue: "8080", RelPath: "b.yml", LineNumber: 4},
+		{IPKey: "gw", IPValue: "10.0.0.1", RelPath: "a.properties", LineNumber: 9, PTR: "gw.example", Severity: []string{"prod"}},
+	}
+
+	var buf bytes.Buffer
+	policy := &failPolicy{labels: []string{"prod"}}
+	if err := writeJUnit(&buf, rows, append(defaultColumns(), ptrColumn), policy); err != nil {
+		t.Fatalf("writeJUnit failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Errorf("Expected XML header, got %q", buf.String()[:40])
+	}
+
+	var got junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Output is not valid XML: %v", err)
+	}
+	if got.Tests != 3 || got.Failures != 2 || len(got.Suites) != 2 {
+		t.Fatalf("Unexpected totals: tests=%d failures=%d suites=%d", got.Tests, got.Failures, len(got.Suites))
+	}
+
+	a := got.Suites[0]
+	if a.Name != "a.properties" || a.Tests != 2 {
+		t.Errorf("Unexpected first suite: %+v", a)
+	}
+	if a.TestCases[0].Name != "line 2: IP 10.0.0.5 port 5432" || a.TestCases[0].Failure == nil {
+		t.Errorf("Unexpected test case: %+v", a.TestCases[0])
+	}
+	if !strings.Contains(a.TestCases[1].Failure.Body, "PTR: gw.example") {
+		t.Errorf("Expected extra columns in failure body, got %q", a.TestCases[1].Failure.Body)
+	}
+	// Findings --fail-on doesn't reject pass.
+	if b := got.Suites[1]; b.TestCases[0].Name != "line 4: port 8080" || b.TestCases[0].Failure != nil || b.Failures != 0 {
+		t.Errorf("Unexpected passing test case: %+v", b)
+	}
+
+	buf.Reset()
+	if err := writeJUnit(&buf, rows, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Tests != 3 || got.Failures != 0 {
+		t.Errorf("Without a policy: tests=%d failures=%d, want 3 passing", got.Tests, got.Failures)
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/spf13/cobra"
+)
+
+// isToggle reports whether v is a value flip-adapters can toggle for
+// adapter, using the same pairs as a flip.
+func isToggle(adapter, v string, pairs []togglePair, byAdapter map[string]togglePair) bool {
+	if p, ok := byAdapter[adapter]; ok {
+		if _, ok := toggleValue(v, []togglePair{p}); ok {
+			return true
+		}
+	}
+	_, ok := toggleValue(v, pairs)
+	return ok
+}
+
+// toggleRows returns m with only the adapters whose value is a toggle value
+// in every environment that has them.
+func (m envMatrix) toggleRows(pairs []togglePair, byAdapter map[string]togglePair) envMatrix {
+	out := envMatrix{Envs: m.Envs, Adapters: make([]matrixRow, 0)}
+	for _, r := range m.Adapters {
+		toggle := true
+		for _, v := range r.Values {
+			if v != nil && !isToggle(r.Adapter, *v, pairs, byAdapter) {
+				toggle = false
+				break
+			}
+		}
+		if toggle {
+			out.Adapters = append(out.Adapters, r)
+		}
+	}
+	return out
+}
+
+func cmdListAdapters() *cobra.Command {
+	var repo, ref, envsCSV, paramFile, mode string
+	var togglePairs []string
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "list-adapters",
+		Short: "List the toggle-like adapter keys in a repository with their values per environment",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if repo == "" {
+				return usageErrorf("--repo ORG/REPO is required")
+			}
+			if err := checkParamFile(paramFile); err != nil {
+				return err
+			}
+			pairs, err := parseTogglePairs(togglePairs)
+			if err != nil {
+				return err
+			}
+			modeVal := parseMode(mode, outTable)
+
+			tmpDir, cleanup, err := cloneOrDownload(repo, ref)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			cfg := loadConfig(tmpDir)
+			if paramFile, err = repoParamFile(paramFile, cmd.Flags().Changed("file"), cfg); err != nil {
+				return err
+			}
+
+			envs, err := resolveEnvList(tmpDir, splitCSV(envsCSV, []string{"*"}))
+			if err != nil {
+				return err
+			}
+			values := make([][]adapterValue, len(envs))
+			for i, env := range envs {
+				values[i], err = readEnvValues(tmpDir, env, paramFile, nil)
+				if errors.Is(err, fs.ErrNotExist) {
+					warnf("env/%s has no %s", env, paramFile)
+					continue
+				}
+				if err != nil {
+					return err
+				}
+			}
+			matrix := buildEnvMatrix(envs, values, nil)
+			if !all {
+				cfgPairs, _ := cfg["toggle.pairs"].Value.([]togglePair)
+				byAdapter, _ := cfg["toggle.adapters"].Value.(map[string]togglePair)
+				matrix = matrix.toggleRows(withConfigPairs(pairs, cfgPairs), byAdapter)
+			}
+			if err := printEnvMatrix(matrix, modeVal); err != nil {
+				return err
+			}
+			if modeVal == outTable && len(matrix.Adapters) > 0 {
+				infof("Store the ones you flip with: gh aca-utils set-adapters --repo %s --adapters KEY,...", repo)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Target repo as ORG/REPO (required)")
+	cmd.Flags().StringVar(&ref, "ref", "", "Branch or tag (default: default branch)")
+	cmd.Flags().StringVar(&envsCSV, "env", "*", "Comma-separated environments or globs, in column order (default: all under env/)")
+	cmd.Flags().StringVar(&paramFile, "file", "parameters.properties", "Parameters file in each env directory; .json files list scalars by JSON pointer")
+	cmd.Flags().StringSliceVar(&togglePairs, "toggle-pair", nil, "Extra value pair that counts as a toggle, as A:B, in addition to 0:1, true:false, on:off, enabled:disabled and yes:no")
+	cmd.Flags().BoolVar(&all, "all", false, "List every key, not only those with toggle values")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table|json|csv")
+	setOutputModes(cmd, "table", "json", "csv")
+	return cmd
+}
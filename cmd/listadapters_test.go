@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToggleRows(t *testing.T) {
+	envs := []string{"dev", "prod"}
+	values := [][]adapterValue{
+		{{Adapter: "billing", Value: "1"}, {Adapter: "db.url", Value: "jdbc:pg://db"}, {Adapter: "mode", Value: "blue"}, {Adapter: "search", Value: "TRUE"}},
+		{{Adapter: "billing", Value: "0"}, {Adapter: "db.url", Value: "jdbc:pg://prod"}, {Adapter: "mode", Value: "green"}, {Adapter: "search", Value: "maybe"}, {Adapter: "crm", Value: "on"}},
+	}
+	m := buildEnvMatrix(envs, values, nil)
+
+	keys := func(m envMatrix) string {
+		var out []string
+		for _, r := range m.Adapters {
+			out = append(out, r.Adapter)
+		}
+		return strings.Join(out, ",")
+	}
+	if got := keys(m.toggleRows(defaultTogglePairs, nil)); got != "billing,crm" {
+		t.Errorf("toggleRows = %s, want billing,crm", got)
+	}
+	byAdapter := map[string]togglePair{"mode": {"blue", "green"}}
+	if got := keys(m.toggleRows(defaultTogglePairs, byAdapter)); got != "billing,mode,crm" {
+		t.Errorf("toggleRows with toggle.adapters = %s, want billing,mode,crm", got)
+	}
+}
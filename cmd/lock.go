@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// flipLockRef is the ref flip-adapters --lock holds in the target
+// repository while it edits. It lives outside refs/heads so it shows up
+// as neither a branch nor a tag, and creating it is atomic: of two runs,
+// exactly one creates it.
+const flipLockRef = "aca-locks/flip-adapters"
+
+// lockCommitTitle starts the message of the commit the lock ref points
+// at; the rest says who holds it.
+const lockCommitTitle = "gh aca-utils flip lock"
+
+// acquireFlipLock takes repo's flip lock for a run changing envs and
+// returns the function that releases it. If another run holds the lock it
+// fails with who that is, unless force removes their lock first.
+func acquireFlipLock(repo string, envs []string, force bool) (func(), error) {
+	api, err := newAPICheckout(repo)
+	if err != nil {
+		return nil, err
+	}
+	if force {
+		holder, err := readFlipLock(repo)
+		if err != nil {
+			return nil, err
+		}
+		if holder != "" {
+			if err := ghAPI("DELETE", fmt.Sprintf("repos/%s/git/refs/%s", repo, flipLockRef), nil, nil); err != nil {
+				return nil, fmt.Errorf("force-unlock %s: %w", repo, err)
+			}
+			warnf("removed the flip lock on %s held by %s", repo, holder)
+		}
+	}
+
+	var base struct {
+		Tree struct {
+			SHA string `json:"sha"`
+		} `json:"tree"`
+	}
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/git/commits/%s", repo, api.baseSHA), nil, &base); err != nil {
+		return nil, err
+	}
+	holder := ghLogin()
+	if holder == "" {
+		holder = "unknown user"
+	}
+	if host, err := os.Hostname(); err == nil {
+		holder += " on " + host
+	}
+	msg := fmt.Sprintf("%s\n\nHolder: %s\nSince: %s\nEnvironments: %s\n", lockCommitTitle, holder, time.Now().UTC().Format(time.RFC3339), strings.Join(envs, ","))
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	if err := ghAPI("POST", fmt.Sprintf("repos/%s/git/commits", repo), map[string]any{"message": msg, "tree": base.Tree.SHA, "parents": []string{api.baseSHA}}, &commit); err != nil {
+		return nil, fmt.Errorf("lock %s: %w", repo, err)
+	}
+	if err := ghAPI("POST", fmt.Sprintf("repos/%s/git/refs", repo), map[string]string{"ref": "refs/" + flipLockRef, "sha": commit.SHA}, nil); err != nil {
+		if !strings.Contains(err.Error(), "already exists") {
+			return nil, fmt.Errorf("lock %s: %w", repo, err)
+		}
+		other, _ := readFlipLock(repo)
+		if other == "" {
+			other = "another run"
+		}
+		return nil, fmt.Errorf("%s is locked by %s; wait for that flip to finish, or pass --force-unlock if it is gone", repo, other)
+	}
+	infof("Locked %s for flipping", repo)
+	return func() {
+		ctx, cancel := cleanupContext()
+		defer cancel()
+		if err := ghAPIContext(ctx, "DELETE", fmt.Sprintf("repos/%s/git/refs/%s", repo, flipLockRef), nil, nil); err != nil {
+			warnf("release the flip lock on %s: %v; remove it with --force-unlock", repo, err)
+		}
+	}, nil
+}
+
+// readFlipLock describes who holds repo's flip lock, or returns "" when
+// it is free.
+func readFlipLock(repo string) (string, error) {
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/git/ref/%s", repo, flipLockRef), nil, &ref); err != nil {
+		if isNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("read flip lock of %s: %w", repo, err)
+	}
+	var commit struct {
+		Message string `json:"message"`
+	}
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/git/commits/%s", repo, ref.Object.SHA), nil, &commit); err != nil {
+		return "", fmt.Errorf("read flip lock of %s: %w", repo, err)
+	}
+	return describeLock(commit.Message), nil
+}
+
+// describeLock summarizes a lock commit message as "HOLDER since TIME
+// (ENVS)".
+func describeLock(msg string) string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(msg, "\n") {
+		if k, v, ok := strings.Cut(line, ": "); ok {
+			fields[k] = strings.TrimSpace(v)
+		}
+	}
+	holder := fields["Holder"]
+	if holder == "" {
+		holder = "an unknown run"
+	}
+	if since := fields["Since"]; since != "" {
+		holder += " since " + since
+	}
+	if envs := fields["Environments"]; envs != "" {
+		holder += " (" + envs + ")"
+	}
+	return holder
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeLockGH serves org/svc, whose flip lock is held by bob when held is
+// true. Calls are logged as "METHOD PATH".
+func fakeLockGH(t *testing.T, held bool) string {
+	t.Helper()
+	var mu sync.Mutex
+	return fakeGitHub(t, func(_ *http.Request, call string) (int, string) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch call {
+		case "GET user":
+			return 200, `{"login":"alice"}`
+		case "GET repos/org/svc":
+			return 200, `{"default_branch":"main"}`
+		case "GET repos/org/svc/git/ref/heads/main":
+			return 200, `{"object":{"sha":"base123"}}`
+		case "GET repos/org/svc/git/commits/base123":
+			return 200, `{"tree":{"sha":"tree0"}}`
+		case "POST repos/org/svc/git/commits":
+			return 200, `{"sha":"lock1"}`
+		case "GET repos/org/svc/git/ref/aca-locks/flip-adapters":
+			if !held {
+				return 404, ""
+			}
+			return 200, `{"object":{"sha":"held1"}}`
+		case "GET repos/org/svc/git/commits/held1":
+			return 200, `{"message":"gh aca-utils flip lock\n\nHolder: bob on laptop\nSince: 2026-01-02T03:04:05Z\nEnvironments: prod\n"}`
+		case "POST repos/org/svc/git/refs":
+			if held {
+				return 422, `{"message":"Reference already exists"}`
+			}
+			held = true
+			return 201, `{}`
+		case "DELETE repos/org/svc/git/refs/aca-locks/flip-adapters":
+			held = false
+			return 204, ""
+		}
+		return 404, ""
+	})
+}
+
+func TestAcquireFlipLock(t *testing.T) {
+	tests := []struct {
+		name        string
+		held, force bool
+		wantErr     string
+	}{
+		{name: "free"},
+		{name: "held", held: true, wantErr: "locked by bob on laptop since 2026-01-02T03:04:05Z (prod)"},
+		{name: "force unlock", held: true, force: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := fakeLockGH(t, tt.held)
+			release, err := acquireFlipLock("org/svc", []string{"prod"}, tt.force)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			release()
+			calls, _ := os.ReadFile(log)
+			if !strings.HasSuffix(string(calls), "DELETE repos/org/svc/git/refs/aca-locks/flip-adapters\n") {
+				t.Errorf("lock not released; calls:\n%s", calls)
+			}
+		})
+	}
+}
+
+func TestReleaseFlipLockAfterInterrupt(t *testing.T) {
+	log := fakeLockGH(t, false)
+	release, err := acquireFlipLock("org/svc", []string{"prod"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withCanceledRun(t)
+	release()
+	calls, _ := os.ReadFile(log)
+	if !strings.HasSuffix(string(calls), "DELETE repos/org/svc/git/refs/aca-locks/flip-adapters\n") {
+		t.Errorf("lock not released after Ctrl-C; calls:\n%s", calls)
+	}
+}
+
+func TestDescribeLock(t *testing.T) {
+	tests := []struct {
+		msg, want string
+	}{
+		{"gh aca-utils flip lock\n\nHolder: alice\nSince: 2026-01-02T03:04:05Z\nEnvironments: dev,prod\n", "alice since 2026-01-02T03:04:05Z (dev,prod)"},
+		{"gh aca-utils flip lock\n", "an unknown run"},
+	}
+	for _, tt := range tests {
+		if got := describeLock(tt.msg); got != tt.want {
+			t.Errorf("describeLock(%q) = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+}
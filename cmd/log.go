@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// levelTrace is below debug: every subprocess and file, with -vv.
+const levelTrace = slog.LevelDebug - 4
+
+// Global --verbose and --log-format flags.
+var (
+	verbosity int
+	logFormat string
+)
+
+// logger receives the diagnostics of a run on stderr; results go to
+// stdout(). It is replaced by setupLogging once the flags are parsed.
+var logger = newLogger(os.Stderr, "text", 0)
+
+// setupLogging configures logger from --verbose and --log-format.
+func setupLogging(format string, verbose int) error {
+	if format != "text" && format != "json" {
+		return usageErrorf("invalid --log-format %q: want text or json", format)
+	}
+	logger = newLogger(os.Stderr, format, verbose)
+	return nil
+}
+
+// newLogger logs info and above, debug with one -v and everything with
+// two.
+func newLogger(w io.Writer, format string, verbose int) *slog.Logger {
+	level := slog.LevelInfo
+	switch {
+	case verbose >= 2:
+		level = levelTrace
+	case verbose == 1:
+		level = slog.LevelDebug
+	}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+			Level: level,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.LevelKey && a.Value.Any() == levelTrace {
+					a.Value = slog.StringValue("TRACE")
+				}
+				return a
+			},
+		}))
+	}
+	return slog.New(&textHandler{w: w, level: level, mu: &sync.Mutex{}})
+}
+
+// textHandler writes messages the way the commands always have: info as
+// is, other levels behind a lowercase prefix such as "warning: ".
+type textHandler struct {
+	w     io.Writer
+	level slog.Level
+	mu    *sync.Mutex
+	attrs []slog.Attr
+}
+
+func (h *textHandler) Enabled(_ context.Context, l slog.Level) bool { return l >= h.level }
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	switch {
+	case r.Level >= slog.LevelError:
+		b.WriteString("error: ")
+	case r.Level >= slog.LevelWarn:
+		b.WriteString("warning: ")
+	case r.Level >= slog.LevelInfo:
+	case r.Level >= slog.LevelDebug:
+		b.WriteString("debug: ")
+	default:
+		b.WriteString("trace: ")
+	}
+	b.WriteString(r.Message)
+	write := func(a slog.Attr) bool {
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+		return true
+	}
+	for _, a := range h.attrs {
+		write(a)
+	}
+	r.Attrs(write)
+	b.WriteByte('\n')
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &c
+}
+
+func (h *textHandler) WithGroup(string) slog.Handler { return h }
+
+func logf(level slog.Level, format string, args ...any) {
+	if !logger.Enabled(context.Background(), level) {
+		return
+	}
+	logger.Log(context.Background(), level, fmt.Sprintf(format, args...))
+}
+
+// errorf, warnf, infof, debugf and tracef log a formatted message at
+// their level.
+func errorf(format string, args ...any) { logf(slog.LevelError, format, args...) }
+func warnf(format string, args ...any)  { logf(slog.LevelWarn, format, args...) }
+func infof(format string, args ...any)  { logf(slog.LevelInfo, format, args...) }
+func debugf(format string, args ...any) { logf(slog.LevelDebug, format, args...) }
+func tracef(format string, args ...any) { logf(levelTrace, format, args...) }
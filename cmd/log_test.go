@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLoggerLevels(t *testing.T) {
+	tests := []struct {
+		verbose int
+		want    string
+	}{
+		{0, "warning: w 1\ni 2\n"},
+		{1, "warning: w 1\ni 2\ndebug: d 3\n"},
+		{2, "warning: w 1\ni 2\ndebug: d 3\ntrace: t 4\n"},
+	}
+	defer func(old *slog.Logger) { logger = old }(logger)
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		logger = newLogger(&buf, "text", tt.verbose)
+		warnf("w %d", 1)
+		infof("i %d", 2)
+		debugf("d %d", 3)
+		tracef("t %d", 4)
+		if buf.String() != tt.want {
+			t.Errorf("-v x%d logged %q, want %q", tt.verbose, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	defer func(old *slog.Logger) { logger = old }(logger)
+	var buf bytes.Buffer
+	logger = newLogger(&buf, "json", 2)
+	warnf("adapter %q not found", "billing")
+	tracef("run git status")
+
+	var levels, msgs []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var rec struct {
+			Level string `json:"level"`
+			Msg   string `json:"msg"`
+		}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		levels, msgs = append(levels, rec.Level), append(msgs, rec.Msg)
+	}
+	if strings.Join(levels, ",") != "WARN,TRACE" || msgs[0] != `adapter "billing" not found` {
+		t.Errorf("levels %v, messages %v", levels, msgs)
+	}
+}
+
+func TestSetupLoggingRejectsFormat(t *testing.T) {
+	defer func(old *slog.Logger) { logger = old }(logger)
+	if err := setupLogging("xml", 0); err == nil {
+		t.Error("setupLogging accepted --log-format xml")
+	}
+}
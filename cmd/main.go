@@ -2,29 +2,44 @@ package cmd
 
 import (
 	"archive/tar"
-	"bufio"
 	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/bmatcuk/doublestar/v4"
+	"github.com/greenstevester/gh-aca-utils/pkg/jsonptr"
+	"github.com/greenstevester/gh-aca-utils/pkg/props"
 	"github.com/spf13/cobra"
 )
 
+// Default ip-port scan globs.
+const (
+	defaultIncludes = "**/*.properties,**/*.yml,**/*.yaml,**/*.conf,**/*.ini,**/*.txt,**/*.env,**/*.json"
+	defaultExcludes = "**/.git/**,**/node_modules/**,**/dist/**"
+)
+
 type outputMode string
 
 const (
-	outCSV   outputMode = "csv"
-	outTable outputMode = "table"
-	outJSON  outputMode = "json"
+	outCSV    outputMode = "csv"
+	outTable  outputMode = "table"
+	outJSON   outputMode = "json"
+	outXLSX   outputMode = "xlsx"
+	outJUnit  outputMode = "junit"
+	outNDJSON outputMode = "ndjson"
 )
 
 type matchRow struct {
@@ -34,6 +49,34 @@ type matchRow struct {
 	PortValue  string `json:"portValue"`
 	RelPath    string `json:"filePath"`
 	LineNumber int    `json:"lineNumber"`
+	Branch     string `json:"branch,omitempty"`
+	PTR        string `json:"ptr,omitempty"`
+	Probe      string `json:"probe,omitempty"`
+	Hostname   string `json:"hostname,omitempty"`
+	DNS        string `json:"dns,omitempty"`
+	Cloud      string `json:"cloud,omitempty"`
+
+	// Severity holds the classification labels of the finding, e.g.
+	// public-ip or privileged-port.
+	Severity []string `json:"severity,omitempty"`
+
+	// Line, Before and After are the matched line and its surrounding
+	// lines, captured with --context.
+	Line   string   `json:"line,omitempty"`
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+
+	// host is a hostname seen on the same line, used by --check-dns.
+	host string
+}
+
+// location identifies the file of a finding for aggregated and human
+// output, using git's "branch:path" notation in all-branches scans.
+func (r matchRow) location() string {
+	if r.Branch == "" {
+		return r.RelPath
+	}
+	return r.Branch + ":" + r.RelPath
 }
 
 type change struct {
@@ -41,6 +84,17 @@ type change struct {
 	OldValue string `json:"old"`
 	NewValue string `json:"new"`
 	FilePath string `json:"filePath"`
+	Created  bool   `json:"created,omitempty"`
+	PRURL    string `json:"prUrl,omitempty"`    // pull request opened for the change
+	IssueURL string `json:"issueUrl,omitempty"` // tracking issue the change is linked to
+
+	line    string // original line text the change was planned against
+	lineIdx int    // first and last line index of the entry
+	lineEnd int
+
+	// span and newRaw locate and replace the value in JSON parameter files.
+	span   jsonptr.Span
+	newRaw string
 }
 
 func Execute() {
@@ -48,10 +102,80 @@ func Execute() {
 	root.AddCommand(cmdIPPort())
 	root.AddCommand(cmdFlipAdapters())
 	root.AddCommand(cmdSetAdapters())
+	root.AddCommand(cmdConfig())
+	root.AddCommand(cmdCheck())
+	root.AddCommand(cmdIPReplace())
+	root.AddCommand(cmdPortReplace())
+	root.AddCommand(cmdHistory())
+	root.AddCommand(cmdStatus())
+	root.AddCommand(cmdCompareEnvs())
+	root.AddCommand(cmdSyncEnv())
+	root.AddCommand(cmdScheduleFlip())
+	root.AddCommand(cmdRollback())
+	root.AddCommand(cmdAudit())
+	root.AddCommand(cmdDrift())
+	root.AddCommand(cmdExportAdapters())
+	root.AddCommand(cmdApplyAdapters())
+	root.AddCommand(cmdListAdapters())
+	root.AddCommand(cmdAdaptersReport())
+	root.AddCommand(cmdPlan())
+	root.AddCommand(cmdApplyPlan())
+	root.AddCommand(cmdVerify())
+	root.AddCommand(cmdCache())
+	root.AddCommand(cmdExitCodes())
+
+	var outputFile string
+	root.PersistentFlags().StringVar(&outputFile, "output-file", "", "Write results to PATH (atomically replaced) instead of stdout")
+	root.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored table output (also honors NO_COLOR)")
+	root.PersistentFlags().BoolVar(&noFork, "no-fork", false, "Fail instead of pushing to your fork when you lack push access to the repository")
+	root.PersistentFlags().BoolVar(&noCodeowners, "no-codeowners", false, "Don't request reviews from the CODEOWNERS of the changed files on pull requests")
+	root.PersistentFlags().StringVar(&configDirFlag, "config-dir", "", "Config and cache directory (also ACA_CONFIG_DIR; default: $XDG_CONFIG_HOME/gh-aca-utils)")
+	root.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "Log what the command does on stderr: -v for clone, API and scan decisions, -vv also for every command run and file scanned")
+	root.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format on stderr: text|json")
+	root.PersistentFlags().StringVar(&gitBackend, "git-backend", gitBackendAuto, "Run git operations with: auto (git if installed, else built-in go-git)|git|go-git")
+	root.PersistentFlags().BoolVar(&useRepoCache, "cache", false, "Keep clones under the cache directory (repos/ORG/REPO) and fetch into them on later runs instead of cloning again")
+	root.PersistentFlags().DurationVar(&opTimeout, "timeout", 0, "Stop the command after this long (e.g. 10m), cleaning up and printing the results so far; 0 means no limit")
+	root.PersistentFlags().StringVar(&proxyFlag, "proxy", "", "Send HTTP traffic through this proxy: http://, https://, socks5:// or socks5h:// URL (default: HTTPS_PROXY; NO_PROXY hosts bypass it)")
+	root.PersistentFlags().IntVar(&retries, "retries", retries, "Retry clones, downloads, API calls and pushes that fail on network errors or GitHub 429/5xx up to N times")
+	root.PersistentFlags().DurationVar(&retryDelay, "retry-delay", retryDelay, "Wait before the first retry, doubled (with jitter) for each further one")
+	root.PersistentFlags().StringVar(&workDir, "work-dir", "", "Make temporary clones and checkouts under this directory (default: the system temp directory)")
+	root.PersistentFlags().BoolVar(&keepTemp, "keep-temp", false, "Keep temporary clones and checkouts after the run, printing where, for debugging")
+
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return applyConfigDefaults(cmd)
+	}
+	// Errors before a command runs are cobra's: an unknown command or flag,
+	// a bad flag value or the wrong arguments.
+	started := false
+	wrapRunE(root, func(run func() error) error {
+		started = true
+		if err := setupLogging(logFormat, verbosity); err != nil {
+			return err
+		}
+		if err := checkGitBackend(gitBackend); err != nil {
+			return err
+		}
+		if err := checkRetries(retries, retryDelay); err != nil {
+			return err
+		}
+		if err := setupProxy(proxyFlag); err != nil {
+			return err
+		}
+		if err := checkWorkDir(workDir); err != nil {
+			return err
+		}
+		setupTokenAuth()
+		return runWithOutputFile(outputFile, func() error { return withRunContext(opTimeout, run) })
+	})
 
 	if err := root.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		if err.Error() != "" {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if !started {
+			os.Exit(exitUsage)
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -59,233 +183,582 @@ func cmdIPPort() *cobra.Command {
 	var repo, ref string
 	var includes, excludes string
 	var mode string
-	var allBranches bool
+	var portRange, portList, groupBy, columns, sortBy, format string
+	var noCache, desc bool
+	var allBranches, strictIP, uniqueValues, resolve, cloud, cloudRefresh, probe, checkDNSFlag bool
+	var dnsMap, classifyRulesFile, allowlist, maxLine string
+	var cloudFeeds, severity, failOn []string
+	var classify, failIfFound, reportUnseen, redact, count, quiet bool
+	var jobs, resolveJobs, probeJobs, contextLines int
+	var resolveTimeout, probeTimeout time.Duration
 
 	cmd := &cobra.Command{
 		Use:   "ip-port",
 		Short: "Scan repo for IP/Port key/value pairs across branches",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if repo == "" {
-				return fmt.Errorf("--repo ORG/REPO is required")
+				return usageErrorf("--repo ORG/REPO is required")
 			}
 			modeVal := parseMode(mode, outCSV)
 
-			if allBranches {
-				return scanAllBranches(repo, includes, excludes, modeVal)
+			ports, err := parsePortFilter(portRange, portList)
+			if err != nil {
+				return err
 			}
-
-			tmpDir, cleanup, err := cloneOrDownload(repo, ref)
+			feeds, err := parseCloudFeeds(cloudFeeds)
+			if err != nil {
+				return err
+			}
+			mapping, err := loadDNSMap(dnsMap)
+			if err != nil {
+				return err
+			}
+			rules, err := loadClassifyRules(classifyRulesFile)
+			if err != nil {
+				return err
+			}
+			inv, err := loadInventory(allowlist)
 			if err != nil {
 				return err
 			}
-			defer cleanup()
+			if reportUnseen && inv == nil {
+				return usageErrorf("--report-unseen requires --allowlist")
+			}
+			selected, err := parseColumns(columns)
+			if err != nil {
+				return err
+			}
+			tmpl, err := parseRowFormat(format)
+			if err != nil {
+				return err
+			}
+			sortKey, err := parseSortKey(sortBy)
+			if err != nil {
+				return err
+			}
+			groupByVal, err := parseGroupBy(groupBy)
+			if err != nil {
+				return err
+			}
+			if groupByVal != "" && uniqueValues {
+				return usageErrorf("--group-by and --unique-values cannot be combined")
+			}
+			if (modeVal == outXLSX || modeVal == outJUnit || modeVal == outNDJSON) && (groupByVal != "" || uniqueValues) {
+				return usageErrorf("--output %s cannot be combined with --group-by or --unique-values", modeVal)
+			}
+			if selected != nil && (groupByVal != "" || uniqueValues) {
+				return usageErrorf("--columns cannot be combined with --group-by or --unique-values")
+			}
+			if sortKey != "" && (groupByVal != "" || uniqueValues) {
+				return usageErrorf("--sort cannot be combined with --group-by or --unique-values")
+			}
+			if tmpl != nil && (groupByVal != "" || uniqueValues || selected != nil) {
+				return usageErrorf("--format cannot be combined with --group-by, --unique-values or --columns")
+			}
+			if count && quiet {
+				return usageErrorf("--count and --quiet cannot be combined")
+			}
+			if contextLines < 0 {
+				return usageErrorf("--context must not be negative")
+			}
+			maxLineLength, err := parseSize(maxLine)
+			if err != nil {
+				return usageErrorf("--max-line-length: %w", err)
+			}
+			opts := scanOptions{
+				includes: splitCSV(includes, []string{"**/*"}),
+				excludes: splitCSV(excludes, []string{"**/.git/**", "**/node_modules/**"}),
+				ports:    ports,
+				strictIP: strictIP,
+				jobs:     jobs,
+				cacheDir: defaultScanCacheDir(),
+				noCache:  noCache,
+
+				withContext:   cmd.Flags().Changed("context"),
+				context:       contextLines,
+				maxLineLength: maxLineLength,
+				flagged:       scanFlagsGiven(cmd),
+			}
+
+			var extra []rowColumn
+			if allBranches {
+				extra = append(extra, branchColumn)
+			}
+			if classify || classifyRulesFile != "" {
+				extra = append(extra, severityColumn)
+			}
+			if opts.withContext && (modeVal == outJSON || modeVal == outNDJSON) {
+				extra = append(extra, lineColumn, beforeColumn, afterColumn)
+			}
 
-			inc := splitCSV(includes, []string{"**/*"})
-			exc := splitCSV(excludes, []string{"**/.git/**", "**/node_modules/**"})
+			// Output that needs no look-ahead is printed as files are
+			// scanned, unless a later step needs the full result set.
+			var stream *rowStream
+			if !uniqueValues && groupByVal == "" && sortKey == "" && !count && !quiet && !resolve && !cloud && !probe && !checkDNSFlag && dnsMap == "" {
+				stream = newRowStream(modeVal, pickColumns(selected, extra), tmpl)
+			}
+			policy := &failPolicy{labels: failOn, any: failIfFound || quiet, quiet: quiet}
+			if stream != nil {
+				opts.emit = func(rows []matchRow) {
+					rows = inv.filter(rows)
+					classifyRows(rows, rules)
+					rows = filterSeverity(rows, severity)
+					policy.count(rows)
+					if redact {
+						redactRows(rows)
+					}
+					stream.write(rows)
+				}
+			}
 
-			rows := scanForIPPort(tmpDir, inc, exc)
-			return printRows(rows, modeVal)
+			var rows []matchRow
+			if allBranches {
+				rows, err = scanAllBranches(repo, opts)
+			} else {
+				rows, err = scanRef(repo, ref, opts)
+			}
+			if err != nil {
+				return err
+			}
+			if reportUnseen {
+				defer inv.warnUnseen()
+			}
+			if stream != nil {
+				if err := stream.close(); err != nil {
+					return err
+				}
+				return policy.check(cmd)
+			}
+			rows = inv.filter(rows)
+			classifyRows(rows, rules)
+			rows = filterSeverity(rows, severity)
+			policy.count(rows)
+
+			if resolve {
+				resolvePTRs(rows, resolveJobs, resolveTimeout, net.DefaultResolver.LookupAddr)
+				extra = append(extra, ptrColumn)
+			}
+			if cloud {
+				cacheDir, err := getCacheDir()
+				if err != nil {
+					return err
+				}
+				maxAge := cloudCacheMaxAge
+				if cloudRefresh {
+					maxAge = 0
+				}
+				ranges := loadCloudRanges(feeds, cacheDir, maxAge, fetchURL)
+				attributeCloud(rows, ranges)
+				extra = append(extra, cloudColumn)
+			}
+			if checkDNSFlag || dnsMap != "" {
+				checkDNS(rows, mapping, resolveJobs, resolveTimeout, net.DefaultResolver.LookupHost)
+				warnDNSMismatches(rows)
+				extra = append(extra, hostnameColumn, dnsColumn)
+			}
+			if probe {
+				probeRows(rows, probeJobs, probeTimeout, (&net.Dialer{}).DialContext)
+				extra = append(extra, probeColumn)
+			}
+			sortAndRedact(rows, sortKey, desc, redact)
+			switch {
+			case quiet:
+				// The exit status is the result.
+			case count:
+				n := len(rows)
+				if uniqueValues {
+					n = len(collapseUnique(rows))
+				} else if groupByVal != "" {
+					n = len(groupRows(rows, groupByVal))
+				}
+				_, err = fmt.Fprintln(stdout(), n)
+			case uniqueValues:
+				err = printUniqueRows(collapseUnique(rows), modeVal)
+			case groupByVal != "":
+				err = printGroups(groupRows(rows, groupByVal), groupByVal, modeVal)
+			case tmpl != nil:
+				err = writeTemplateRows(stdout(), tmpl, rows)
+			default:
+				err = printRows(rows, modeVal, pickColumns(selected, extra), policy)
+			}
+			if err != nil {
+				return err
+			}
+			return policy.check(cmd)
 		},
 	}
 
 	cmd.Flags().StringVar(&repo, "repo", "", "Target repo as ORG/REPO")
 	cmd.Flags().StringVar(&ref, "ref", "", "Branch or tag (default: default branch)")
 	cmd.Flags().BoolVar(&allBranches, "all-branches", false, "Scan all branches in the repository")
-	cmd.Flags().StringVar(&includes, "include",
-		"**/*.properties,**/*.yml,**/*.yaml,**/*.conf,**/*.ini,**/*.txt,**/*.env,**/*.json",
-		"Comma-separated glob patterns to include")
-	cmd.Flags().StringVar(&excludes, "exclude",
-		"**/.git/**,**/node_modules/**,**/dist/**",
-		"Comma-separated glob patterns to exclude")
-	cmd.Flags().StringVar(&mode, "output", "csv", "Output: csv|table|json")
+	cmd.Flags().StringVar(&includes, "include", defaultIncludes, "Comma-separated glob patterns to include")
+	cmd.Flags().StringVar(&excludes, "exclude", defaultExcludes, "Comma-separated glob patterns to exclude")
+	cmd.Flags().StringVar(&mode, "output", "csv", "Output: csv|table|json|xlsx|junit|ndjson (or jsonl)")
+	setOutputModes(cmd, "csv", "table", "json", "xlsx", "junit", "ndjson", "jsonl")
+	cmd.Flags().BoolVar(&noCache, "no-cache", false, "Rescan even if results for this commit are cached")
+	cmd.Flags().StringVar(&columns, "columns", "", "Comma-separated columns to print, in order (e.g. ipValue,portValue,filePath)")
+	cmd.Flags().StringVar(&format, "format", "", "Format each row with a Go template, e.g. '{{.IPValue}}:{{.PortValue}}' (overrides --output)")
+	cmd.Flags().StringVar(&sortBy, "sort", "", "Sort rows by ip|port|file|line (default: file walk order)")
+	cmd.Flags().BoolVar(&desc, "desc", false, "Reverse the --sort order")
+	cmd.Flags().BoolVar(&redact, "redact", false, "Mask the host part of IPs and the digits of ports in the output, for sharing reports")
+	cmd.Flags().IntVar(&contextLines, "context", 0, "Include the matched line and N lines before/after it in JSON output")
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", runtime.NumCPU(), "Number of files to scan concurrently")
+	cmd.Flags().StringVar(&maxLine, "max-line-length", "16MiB", "Scan at most this much of each line (e.g. 512KiB, 64MiB); longer lines are cut with a warning")
+	cmd.Flags().StringVar(&portRange, "port-range", "", "Only report ports within these ranges (e.g. 1024-9999,30000-32767)")
+	cmd.Flags().StringVar(&portList, "ports", "", "Only report these ports (comma-separated, e.g. 8080,8443)")
+	cmd.Flags().BoolVar(&uniqueValues, "unique-values", false, "Collapse identical IP/port values into one row with a count and file list")
+	cmd.Flags().StringVar(&groupBy, "group-by", "", "Aggregate findings by ip|port|file|key with counts and member lists")
+	cmd.Flags().BoolVar(&resolve, "resolve", false, "Reverse-resolve discovered IPs and add their PTR names")
+	cmd.Flags().IntVar(&resolveJobs, "resolve-jobs", 16, "Concurrent DNS lookups (with --resolve/--check-dns)")
+	cmd.Flags().DurationVar(&resolveTimeout, "resolve-timeout", 2*time.Second, "Timeout per DNS lookup (with --resolve/--check-dns)")
+	cmd.Flags().BoolVar(&checkDNSFlag, "check-dns", false, "Flag IPs that no longer match the current DNS record of their hostname")
+	cmd.Flags().StringVar(&dnsMap, "dns-map", "", "File of 'IP|KEY-GLOB HOSTNAME' lines for --check-dns (hosts-file compatible)")
+	cmd.Flags().BoolVar(&cloud, "cloud", false, "Tag public IPs with the cloud provider/service/region that owns them")
+	cmd.Flags().StringSliceVar(&cloudFeeds, "cloud-feed", nil, "Override a provider range feed as PROVIDER=URL|FILE (aws, gcp, azure)")
+	cmd.Flags().BoolVar(&cloudRefresh, "cloud-refresh", false, "Re-download cloud range feeds instead of using the 24h cache")
+	cmd.Flags().BoolVar(&probe, "probe", false, "Attempt TCP connections to each IP:port pair and report open|closed|filtered")
+	cmd.Flags().IntVar(&probeJobs, "probe-jobs", 16, "Concurrent TCP probes (with --probe)")
+	cmd.Flags().DurationVar(&probeTimeout, "probe-timeout", 2*time.Second, "Connect timeout per probe (with --probe)")
+	cmd.Flags().BoolVar(&classify, "classify", false, "Add a severity column labelling each finding (public-ip, private-ip, loopback, privileged-port, test-fixture)")
+	cmd.Flags().StringVar(&classifyRulesFile, "classify-rules", "", "File of 'LABEL FIELD=VALUE...' rules adding labels (fields: cidr, port, path, key); implies --classify")
+	cmd.Flags().StringSliceVar(&severity, "severity", nil, "Only report findings with one of these labels (e.g. public-ip,privileged-port)")
+	cmd.Flags().StringVar(&allowlist, "allowlist", "", "CSV inventory of approved ip,port pairs (CIDRs and * allowed); only unapproved findings are reported")
+	cmd.Flags().BoolVar(&reportUnseen, "report-unseen", false, "Warn about --allowlist entries not found in the scan")
+	cmd.Flags().BoolVar(&count, "count", false, "Print only the number of findings (or of unique values/groups)")
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Print nothing; exit 1 if there are findings, 0 otherwise")
+	cmd.Flags().StringSliceVar(&failOn, "fail-on", nil, "Exit non-zero if any finding has one of these labels (e.g. public-ip,privileged-port)")
+	cmd.Flags().BoolVar(&failIfFound, "fail-if-found", false, "Exit non-zero if any finding is reported")
+	cmd.Flags().BoolVar(&strictIP, "strict-ip", false, "Only report IPs that are whole values or appear in a network context")
 
 	return cmd
 }
 
 func cmdFlipAdapters() *cobra.Command {
-	var repo, envName, adaptersCSV, branch, mode string
-	var doCommit, doPR, dryRun bool
+	var envName, adaptersCSV, branch, mode, planFile, deploymentEnv, paramFile, createMissing, reposFile string
+	var commitTemplate, prBodyFile, mergeMethod string
+	var autoMerge, waitForChecks, branchSuffixTime, reuseBranch, forcePush, web bool
+	var onDrift string
+	var yes bool
+	var checksTimeout, mergeTimeout time.Duration
+	var repos, togglePairs []string
+	var doCommit, doPR, dryRun, deployment, revert, strict, noClone, noSparse, fixTyposFlag bool
+	var canary, then string
+	var direct, lock, forceUnlock bool
+	var issueRef string
+	var newIssue bool
+	var stageWait time.Duration
+	var prOpts prOptions
 
 	cmd := &cobra.Command{
 		Use:   "flip-adapters",
-		Short: "Toggle adapter values (0↔1) in env/<ENV>/parameters.properties",
+		Short: "Toggle adapter values (0↔1, true↔false, on↔off, ...) in env/<ENV>/parameters.properties",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if repo == "" {
-				return fmt.Errorf("--repo ORG/REPO is required")
+			repoList, err := loadRepos(repos, reposFile)
+			if err != nil {
+				return err
 			}
-			if envName == "" {
-				return fmt.Errorf("--env is required (e.g., dev)")
+			if len(repoList) == 0 {
+				return usageErrorf("--repo ORG/REPO or --repos-file is required")
 			}
-			if adaptersCSV == "" {
-				// Try to load from stored adapters
-				storedAdapters, err := loadStoredAdapters()
-				if err != nil {
-					return fmt.Errorf("--adapters is required (comma list) or run 'gh aca set-adapters' to store adapters first")
+			if revert && (envName != "" || adaptersCSV != "" || planFile != "" || cmd.Flags().Changed("file")) {
+				return usageErrorf("--revert replays the journal; it cannot be combined with --env, --adapters, --file or --plan")
+			}
+			var stages []string
+			if canary != "" {
+				if envName != "" || revert || planFile != "" {
+					return usageErrorf("--canary and --then choose the environments; they cannot be combined with --env, --revert or --plan")
 				}
-				if len(storedAdapters) == 0 {
-					return fmt.Errorf("--adapters is required (comma list) or run 'gh aca set-adapters' to store adapters first")
+				if len(repoList) > 1 {
+					return usageErrorf("--canary needs a single repository; got %d", len(repoList))
 				}
-				// Validate that no stored adapter names are empty
-				for _, adapter := range storedAdapters {
-					if strings.TrimSpace(adapter) == "" {
-						return fmt.Errorf("invalid empty adapter name found in stored adapters")
-					}
+				if stages, err = rolloutStages(canary, then); err != nil {
+					return err
 				}
-				adaptersCSV = strings.Join(storedAdapters, ",")
+			} else if then != "" || stageWait != 0 {
+				return usageErrorf("--then and --wait need --canary")
+			}
+			if !revert && envName == "" && canary == "" {
+				return usageErrorf("--env is required (e.g., dev)")
 			}
 			modeVal := parseMode(mode, outTable)
-
-			tmpDir, cleanup, err := cloneOrDownload(repo, "")
+			pairs, err := parseTogglePairs(togglePairs)
 			if err != nil {
 				return err
 			}
-			defer cleanup()
-
-			// Validate environment name to prevent path traversal
-			cleanEnvName := filepath.Clean(envName)
-			if strings.Contains(cleanEnvName, "..") || strings.Contains(cleanEnvName, "/") || strings.Contains(cleanEnvName, "\\") {
-				return fmt.Errorf("invalid environment name: %q", envName)
+			if direct && (doPR || cmd.Flags().Changed("branch") || branchSuffixTime || reuseBranch || forcePush) {
+				return usageErrorf("--direct commits to the default branch; it cannot be combined with --pr, --branch, --branch-suffix-timestamp, --reuse-branch or --force-push")
 			}
-
-			propPath := filepath.Join(tmpDir, "env", cleanEnvName, "parameters.properties")
-			// Double-check path is within expected directory
-			if !strings.HasPrefix(propPath, filepath.Join(tmpDir, "env")+string(os.PathSeparator)) {
-				return fmt.Errorf("invalid file path")
+			doCommit = doCommit || doPR || direct
+			if deployment && !direct && !(doPR && autoMerge) {
+				return usageErrorf("--deployment records the flip once it is on the default branch: use it with --direct, or with --pr --auto-merge")
 			}
-			b, err := os.ReadFile(propPath) // #nosec G304 - path is validated above
-			if err != nil {
-				return fmt.Errorf("read %s: %w", propPath, err)
+			if issueRef != "" && newIssue {
+				return usageErrorf("--issue and --create-issue cannot be combined")
 			}
-
-			lines := strings.Split(string(b), "\n")
-			want := splitCSV(adaptersCSV, nil)
-			changes := make([]change, 0)
-
-			m := map[string]int{} // adapter -> line index
-			for i, line := range lines {
-				if isCommentOrBlank(line) {
-					continue
-				}
-				k, v, ok := parseKV(line)
-				if !ok {
-					continue
-				}
-				m[k] = i
-				_ = v
+			if newIssue && !doCommit {
+				return usageErrorf("--create-issue requires --commit or --pr")
 			}
-
-			for _, a := range want {
-				idx, ok := m[a]
-				if !ok {
-					fmt.Fprintf(os.Stderr, "warning: adapter %q not found in %s\n", a, propPath)
-					continue
-				}
-				k, v, _ := parseKV(lines[idx])
-				var newV string
-				switch strings.TrimSpace(v) {
-				case "0":
-					newV = "1"
-				case "1":
-					newV = "0"
-				default:
-					fmt.Fprintf(os.Stderr, "warning: adapter %q has non-binary value %q; skipping\n", k, v)
-					continue
+			for _, name := range []string{"label", "reviewer", "assignee", "draft", "milestone", "web"} {
+				if cmd.Flags().Changed(name) && !doPR {
+					return usageErrorf("--%s requires --pr", name)
 				}
-				lines[idx] = fmt.Sprintf("%s=%s", k, newV)
-				changes = append(changes, change{Adapter: k, OldValue: strings.TrimSpace(v), NewValue: newV, FilePath: propPath})
 			}
-
-			if len(changes) == 0 {
-				if modeVal == outJSON {
-					if err := json.NewEncoder(os.Stdout).Encode([]change{}); err != nil {
-						return fmt.Errorf("encode JSON: %w", err)
-					}
-					return nil
-				}
-				fmt.Println("No changes made.")
-				return nil
+			if fixTyposFlag && revert {
+				return usageErrorf("--fix-typos cannot be combined with --revert")
 			}
-
-			if dryRun {
-				return printChangeReport(changes, modeVal)
+			if createMissing != "" && (planFile != "" || revert) {
+				return usageErrorf("--create-missing cannot be combined with --plan or --revert")
 			}
-
-			if err := os.WriteFile(propPath, []byte(strings.Join(lines, "\n")), 0600); err != nil {
-				return fmt.Errorf("write %s: %w", propPath, err)
+			if strings.ContainsAny(createMissing, "\r\n") {
+				return usageErrorf("invalid --create-missing value %q", createMissing)
 			}
-
-			if err := printChangeReport(changes, modeVal); err != nil {
+			if cmd.Flags().Changed("commit-message-template") && !doCommit {
+				return usageErrorf("--commit-message-template requires --commit or --pr")
+			}
+			if cmd.Flags().Changed("pr-body-file") && !doPR {
+				return usageErrorf("--pr-body-file requires --pr")
+			}
+			if autoMerge && !doPR {
+				return usageErrorf("--auto-merge requires --pr")
+			}
+			if cmd.Flags().Changed("merge-method") && !autoMerge {
+				return usageErrorf("--merge-method requires --auto-merge")
+			}
+			if err := checkMergeMethod(mergeMethod); err != nil {
 				return err
 			}
-
-			if doCommit {
-				if branch == "" {
-					branch = fmt.Sprintf("toggle/adapters-%s", envName)
-				}
-				if err := gitIn(tmpDir, "checkout", "-b", branch); err != nil {
-					return err
+			if waitForChecks && !doCommit {
+				return usageErrorf("--wait-checks requires --commit or --pr")
+			}
+			if err := checkDriftMode(onDrift); err != nil {
+				return err
+			}
+			onExisting, err := newBranchPolicy(branchSuffixTime, reuseBranch, forcePush)
+			if err != nil {
+				return err
+			}
+			if onExisting != branchFail && !doCommit {
+				return usageErrorf("--branch-suffix-timestamp, --reuse-branch and --force-push require --commit or --pr")
+			}
+			tmpls, err := loadFlipTemplates(commitTemplate, prBodyFile)
+			if err != nil {
+				return err
+			}
+			if len(repoList) > 1 && planFile != "" {
+				return usageErrorf("--plan needs a single repository; got %d", len(repoList))
+			}
+			// With several repositories, JSON and CSV output is one
+			// consolidated document instead of a change list per repository.
+			structured := modeVal == outJSON || modeVal == outCSV
+			perRepoReport := len(repoList) == 1 || !structured
+			report := func(repo string, changes []change) error {
+				if !perRepoReport {
+					return nil
 				}
-				if err := gitIn(tmpDir, "add", filepath.Join("env", envName, "parameters.properties")); err != nil {
-					return err
+				if modeVal == outCSV {
+					printChangeCSV(repo, changes, true)
+					return nil
 				}
-				msg := fmt.Sprintf("chore(env:%s): flip adapters %s", envName, strings.Join(want, ","))
-				if err := gitIn(tmpDir, "commit", "-m", msg); err != nil {
+				if err := printChangeReport(changes, modeVal); err != nil {
 					return err
 				}
-				if err := gitIn(tmpDir, "push", "-u", "origin", branch); err != nil {
-					return err
+				if modeVal == outTable && len(changes) > 0 && changes[0].IssueURL != "" {
+					fmt.Fprintf(stdout(), "Tracking issue: %s\n", changes[0].IssueURL)
 				}
-				if doPR {
-					prTitle := fmt.Sprintf("Flip adapters in %s: %s", envName, strings.Join(want, ", "))
-					prBody := "Automated via gh aca-utils flip-adapters."
-					if err := ghIn(tmpDir, "pr", "create", "--fill", "--title", prTitle, "--body", prBody); err != nil {
-						return err
+				return nil
+			}
+
+			opts := flipOptions{
+				env: envName, adapters: adaptersCSV, file: paramFile, branch: branch,
+				fileChanged: cmd.Flags().Changed("file"), branchChanged: cmd.Flags().Changed("branch"),
+				revert: revert, strict: strict, fixTypos: fixTyposFlag, planFile: planFile, createMissing: createMissing, pairs: pairs,
+				dryRun: dryRun, doCommit: doCommit, doPR: doPR, direct: direct, noClone: noClone, noSparse: noSparse,
+				yes: yes, lock: lock, forceUnlock: forceUnlock, onDrift: onDrift, onExisting: onExisting, tmpls: tmpls,
+				prOpts: prOpts, web: web, autoMerge: autoMerge, mergeMethod: mergeMethod, issueRef: issueRef, newIssue: newIssue,
+				deployment: deployment, deploymentEnv: deploymentEnv, mergeTimeout: mergeTimeout,
+				waitForChecks: waitForChecks, checksTimeout: checksTimeout,
+				mode: modeVal, structured: structured, perRepoReport: perRepoReport, report: report,
+			}
+
+			if len(stages) > 0 {
+				stageBranch := branch
+				for i, stage := range stages {
+					if i > 0 && !dryRun {
+						if err := stageGate(stages[i-1], stage, stageWait, yes, newPrompter(), sleepCtx); err != nil {
+							return err
+						}
+					}
+					infof("==> Stage %d of %d: %s", i+1, len(stages), stage)
+					opts.env = stage
+					if stageBranch != "" {
+						opts.branch = stageBranch + "-" + branchSlug(stage)
+					}
+					if err := opts.flipRepo(repoList[0], &flipResult{}); err != nil {
+						if i+1 < len(stages) {
+							return fmt.Errorf("%s: %w; the rollout stopped before %s", stage, err, stages[i+1])
+						}
+						return fmt.Errorf("%s: %w", stage, err)
 					}
 				}
+				return nil
 			}
-			return nil
+			if len(repoList) == 1 {
+				return opts.flipRepo(repoList[0], &flipResult{})
+			}
+			results := make([]flipResult, 0, len(repoList))
+			for _, repo := range repoList {
+				if canceled() {
+					break
+				}
+				infof("==> %s", repo)
+				res := flipResult{Repo: repo}
+				if err := opts.flipRepo(repo, &res); err != nil {
+					errorf("%s: %v", repo, err)
+					res.Error = err.Error()
+				}
+				results = append(results, res)
+			}
+			return printFlipResults(results, modeVal)
 		},
 	}
 
-	cmd.Flags().StringVar(&repo, "repo", "", "Target repo as ORG/REPO (required)")
-	cmd.Flags().StringVar(&envName, "env", "", "Environment directory under env/ (required)")
+	cmd.Flags().StringSliceVar(&repos, "repo", nil, "Target repo as ORG/REPO (required; repeat or comma-separate for several, one PR each)")
+	cmd.Flags().StringVar(&reposFile, "repos-file", "", "File listing target repos, one ORG/REPO per line (# comments allowed)")
+	cmd.Flags().StringVar(&envName, "env", "", "Environment directory under env/, or a glob such as 'dev*' or 'prod-??' (required)")
 	cmd.Flags().StringVar(&adaptersCSV, "adapters", "", "Comma-separated adapter keys (or use stored adapters from 'set-adapters')")
 	cmd.Flags().StringVar(&branch, "branch", "", "Branch name to create (with --commit)")
 	cmd.Flags().BoolVar(&doCommit, "commit", false, "Commit the change to a new branch and push")
 	cmd.Flags().BoolVar(&doPR, "pr", false, "Create a pull request (implies --commit)")
+	cmd.Flags().StringSliceVar(&prOpts.labels, "label", nil, "Add labels to the pull request (with --pr)")
+	cmd.Flags().StringSliceVar(&prOpts.reviewers, "reviewer", nil, "Request reviews from users or ORG/TEAM teams (with --pr)")
+	cmd.Flags().StringSliceVar(&prOpts.assignees, "assignee", nil, "Assign users to the pull request (with --pr)")
+	cmd.Flags().BoolVar(&prOpts.draft, "draft", false, "Open the pull request as a draft (with --pr)")
+	cmd.Flags().StringVar(&prOpts.milestone, "milestone", "", "Add the pull request to a milestone by title (with --pr)")
+	cmd.Flags().StringVar(&commitTemplate, "commit-message-template", "", "Go template for the commit message, e.g. 'feat(env:{{.Env}}): {{.Verb}} {{.Adapters}}' (fields: Repo, Env, Envs, Adapters, Branch, Verb, Changes)")
+	cmd.Flags().BoolVar(&branchSuffixTime, "branch-suffix-timestamp", false, "If the branch already exists, commit to <branch>-<UTC timestamp> instead")
+	cmd.Flags().BoolVar(&reuseBranch, "reuse-branch", false, "If the branch already exists, add the commit on top of it and update its open pull request")
+	cmd.Flags().BoolVar(&forcePush, "force-push", false, "If the branch already exists, replace it with a fresh commit on the default branch")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Confirm changes to protected environments (protect.environments in config) without prompting")
+	cmd.Flags().StringVar(&approvalRun, "approval-run", "", "Workflow run whose approved deployment review approves protected environments (protect.approval: environment)")
+	cmd.Flags().StringVar(&onDrift, "on-drift", "abort", "When a parameters file changed upstream since it was read: abort, or rebase the flips onto the new content")
+	cmd.Flags().BoolVar(&web, "web", false, "Open the pull request in the browser after creating it (with --pr)")
+	cmd.Flags().BoolVar(&autoMerge, "auto-merge", false, "Queue the pull request to merge once required checks and reviews pass (with --pr)")
+	cmd.Flags().StringVar(&mergeMethod, "merge-method", "squash", "Merge method for --auto-merge: squash|merge|rebase")
+	cmd.Flags().BoolVar(&direct, "direct", false, "Commit straight to the default branch instead of a new branch and PR, if no branch protection or ruleset forbids it")
+	cmd.Flags().StringVar(&issueRef, "issue", "", "Tracking issue to link the change to: N, ORG/REPO#N or its URL; referenced in the commit message and PR body and reported with the changes")
+	cmd.Flags().BoolVar(&newIssue, "create-issue", false, "Open a tracking issue for the change in the target repo and link it like --issue (with --commit or --pr)")
+	cmd.Flags().BoolVar(&lock, "lock", false, "Hold a lock in the target repo while committing, so concurrent flips of it fail instead of racing")
+	cmd.Flags().BoolVar(&forceUnlock, "force-unlock", false, "Remove a flip lock left by another run before taking it (implies --lock)")
+	cmd.Flags().StringVar(&canary, "canary", "", "Roll out in stages: flip these environments (comma-separated names or globs) first, then --then")
+	cmd.Flags().StringVar(&then, "then", "", "Environments to flip after the --canary stage")
+	cmd.Flags().DurationVar(&stageWait, "wait", 0, "With --canary, how long to wait after the canary stage before asking to continue (e.g., 30m)")
+	cmd.Flags().BoolVar(&waitForChecks, "wait-checks", false, "After pushing, wait for the commit's check runs and fail if any fail")
+	cmd.Flags().DurationVar(&checksTimeout, "checks-timeout", 30*time.Minute, "How long --wait-checks waits before giving up")
+	cmd.Flags().StringVar(&prBodyFile, "pr-body-file", "", "Go template file for the pull request body, with the same fields as --commit-message-template (with --pr)")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", true, "Show planned changes without writing")
-	cmd.Flags().StringVar(&mode, "output", "table", "Output: table|json")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table|json|csv")
+	setOutputModes(cmd, "table", "json", "csv")
+	cmd.Flags().BoolVar(&deployment, "deployment", false, "Record a GitHub Deployment for the environment once the flip is on the default branch (with --direct, or --pr --auto-merge after the merge)")
+	cmd.Flags().DurationVar(&mergeTimeout, "merge-timeout", 2*time.Hour, "How long --deployment waits for the auto-merge before giving up")
+	cmd.Flags().StringVar(&deploymentEnv, "deployment-environment", "", "GitHub environment name for --deployment (default: --env)")
+	cmd.Flags().StringVar(&paramFile, "file", "parameters.properties", "Parameters file in each env directory; .json files (e.g. appsettings.json) take JSON pointers such as /Features/Billing as adapters")
+	cmd.Flags().StringSliceVar(&togglePairs, "toggle-pair", nil, "Extra value pair to toggle between as A:B, in addition to 0:1, true:false, on:off, enabled:disabled and yes:no")
+	cmd.Flags().StringVar(&planFile, "plan", "", "Write the dry-run plan to FILE, or verify the apply against it")
+	cmd.Flags().StringVar(&createMissing, "create-missing", "", "Append adapters missing from the file with this initial value (--create-missing alone uses 0)")
+	cmd.Flags().Lookup("create-missing").NoOptDefVal = "0"
+	cmd.Flags().BoolVar(&noClone, "no-clone", false, "Read and commit the parameters files through the GitHub API instead of cloning the repo")
+	cmd.Flags().BoolVar(&noSparse, "no-sparse", false, "Clone and check out the whole repository instead of only env/ENV/ (without file contents until needed)")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail without changing anything if any adapter is missing or has a value outside the toggle pairs")
+	cmd.Flags().BoolVar(&fixTyposFlag, "fix-typos", false, "Ask which existing key was meant when an adapter is not found and a close match exists")
+	cmd.Flags().BoolVar(&revert, "revert", false, "Restore the values changed by the last committed run for --repo, as recorded in journal.jsonl in the config directory")
 
 	return cmd
 }
 
 func cmdSetAdapters() *cobra.Command {
-	var adapters string
-	var list, clear bool
+	var adapters, add, remove, repo, envName, fromRepo, paramFile, remote string
+	var filters []string
+	var list, clear, dedupe, push, pull bool
+	var meta adapterMeta
 
 	cmd := &cobra.Command{
 		Use:   "set-adapters",
 		Short: "Manage stored adapter lists for reuse in flip-adapters command",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if strings.ContainsAny(repo, " []") || strings.ContainsAny(envName, " []") || repo == "*" || envName == "*" {
+				return usageErrorf("invalid --repo or --env for a stored adapter list")
+			}
+			scope := adapterScope{Repo: repo, Env: envName}
+			if push || pull {
+				if push && pull {
+					return usageErrorf("--push and --pull are mutually exclusive")
+				}
+				r, err := resolveAdapterRemote(remote)
+				if err != nil {
+					return err
+				}
+				if push {
+					return pushAdapters(r)
+				}
+				return pullAdapters(r)
+			}
+			if fromRepo != "" {
+				if adapters != "" || add != "" || remove != "" {
+					return usageErrorf("--from-repo replaces the whole list; drop --adapters, --add and --remove")
+				}
+				// --env names the environment to read; the list is
+				// stored for --repo, or for all repos.
+				return seedStoredAdapters(fromRepo, envName, paramFile, filters, adapterScope{Repo: repo})
+			}
 			if list {
-				return listStoredAdapters()
+				return listStoredAdapters(scope)
 			}
 
 			if clear {
-				return clearStoredAdapters()
+				return clearStoredAdapters(scope)
+			}
+
+			if add != "" || remove != "" || (dedupe && adapters == "") {
+				if adapters != "" {
+					return usageErrorf("--adapters replaces the whole list; use it or --add/--remove, not both")
+				}
+				return editStoredAdapters(scope, add, remove, dedupe)
 			}
 
 			if adapters == "" {
-				return fmt.Errorf("--adapters is required (comma-separated list)")
+				return usageErrorf("--adapters is required (comma-separated list)")
+			}
+
+			// Documenting adapters leaves the stored lists alone.
+			if !meta.empty() {
+				if repo != "" || envName != "" {
+					return usageErrorf("adapter descriptions apply everywhere; drop --repo and --env")
+				}
+				return describeAdapters(adapters, meta)
 			}
 
-			return storeAdapters(adapters)
+			return storeAdapters(adapters, scope, dedupe)
 		},
 	}
 
 	cmd.Flags().StringVar(&adapters, "adapters", "", "Comma-separated list of adapter names to store")
-	cmd.Flags().BoolVar(&list, "list", false, "List currently stored adapters")
-	cmd.Flags().BoolVar(&clear, "clear", false, "Clear all stored adapters")
+	cmd.Flags().StringVar(&repo, "repo", "", "Store, list or clear the list for this ORG/REPO (default: all repos)")
+	cmd.Flags().StringVar(&envName, "env", "", "Store, list or clear the list for this environment (default: all environments)")
+	cmd.Flags().BoolVar(&list, "list", false, "List currently stored adapters (with --repo/--env: the list flip-adapters would use)")
+	cmd.Flags().BoolVar(&clear, "clear", false, "Clear stored adapters (with --repo/--env: only that list)")
+	cmd.Flags().StringVar(&fromRepo, "from-repo", "", "Store the keys of --env's parameters file in ORG/REPO as the list")
+	cmd.Flags().StringSliceVar(&filters, "filter", nil, "With --from-repo, only keys matching these globs (e.g. 'adapter.*')")
+	cmd.Flags().StringVar(&paramFile, "file", "parameters.properties", "With --from-repo, the parameters file in the env directory")
+	cmd.Flags().BoolVar(&push, "push", false, "Upload the stored adapters file to the shared location")
+	cmd.Flags().BoolVar(&pull, "pull", false, "Replace the stored adapters file with the shared one (the old one is kept as .bak)")
+	cmd.Flags().StringVar(&remote, "remote", "", "Shared location as ORG/REPO[:PATH] or gist:ID[/FILE] (default: adapters.remote from the config)")
+	cmd.Flags().StringVar(&add, "add", "", "Comma-separated adapters to append to the stored list")
+	cmd.Flags().StringVar(&remove, "remove", "", "Comma-separated adapters to drop from the stored list")
+	cmd.Flags().BoolVar(&dedupe, "dedupe", false, "Drop repeated adapters from the list, keeping the first (alone: clean up the stored list)")
+	cmd.Flags().StringVar(&meta.Description, "description", "", "Document the --adapters with a description instead of storing a list")
+	cmd.Flags().StringSliceVar(&meta.Owners, "owner", nil, "Document the owners of the --adapters (repeatable)")
+	cmd.Flags().StringVar(&meta.Default, "default", "", "Document the default value of the --adapters")
+	cmd.Flags().StringSliceVar(&meta.Tags, "tag", nil, "Document tags for the --adapters (repeatable)")
 
 	return cmd
 }
@@ -294,43 +767,26 @@ func cmdSetAdapters() *cobra.Command {
 
 // cloneOrDownload tries `gh repo clone`, then falls back to tarball download.
 func cloneOrDownload(repo, ref string) (string, func(), error) {
-	tmp, err := os.MkdirTemp("", "gh-aca-utils-")
-	if err != nil {
-		return "", nil, err
-	}
-	cleanup := func() { _ = os.RemoveAll(tmp) }
-
-	args := []string{"repo", "clone", repo, tmp, "--", "--depth", "1"}
-	if ref != "" {
-		args = append(args, "--branch", ref)
-	}
-	if cloneErr := execCommand("gh", args...); cloneErr == nil {
+	tmp, cleanup, cloneErr := cloneShallow(repo, ref)
+	if cloneErr == nil {
 		return tmp, cleanup, nil
 	}
+	if canceled() {
+		return "", nil, cloneErr
+	}
 
 	// fallback
-	tarURL := fmt.Sprintf("repos/%s/tarball", repo)
-	if ref != "" {
-		tarURL = fmt.Sprintf("repos/%s/tarball/%s", repo, ref)
-	}
-	// #nosec G204 - tarURL is constructed from validated repo parameter
-	cmd := exec.Command("gh", "api", "-H", "Accept: application/vnd.github+json", tarURL)
-	stdout, err := cmd.StdoutPipe()
+	debugf("clone of %s failed (%v); downloading the tarball instead", repo, cloneErr)
+	tmp, cleanup, err := makeTemp("gh-aca-utils-")
 	if err != nil {
-		cleanup()
 		return "", nil, err
 	}
-	if startErr := cmd.Start(); startErr != nil {
-		cleanup()
-		return "", nil, startErr
-	}
-	if untarErr := untarGz(stdout, tmp); untarErr != nil {
+	err = retryInto("download "+tarballPath(repo, ref), tmp, func() error {
+		return downloadTarball(repo, ref, cloneErr, func(r io.Reader) error { return untarGz(r, tmp) })
+	})
+	if err != nil {
 		cleanup()
-		return "", nil, untarErr
-	}
-	if waitErr := cmd.Wait(); waitErr != nil {
-		// Log but don't fail - tar extraction may have succeeded
-		fmt.Fprintf(os.Stderr, "warning: gh api command failed: %v\n", waitErr)
+		return "", nil, err
 	}
 
 	entries, err := os.ReadDir(tmp)
@@ -346,34 +802,52 @@ func cloneOrDownload(repo, ref string) (string, func(), error) {
 		}
 		if err := os.Remove(top); err != nil {
 			// Non-critical error, continue
-			fmt.Fprintf(os.Stderr, "warning: failed to remove temp dir: %v\n", err)
+			warnf("failed to remove temp dir: %v", err)
 		}
 	}
 	return tmp, cleanup, nil
 }
 
-func cloneAllBranches(repo string) (string, func(), error) {
-	tmp, err := os.MkdirTemp("", "gh-aca-utils-")
+// cloneShallow clones the tip of ref (default branch if empty) of repo
+// into a temporary directory.
+func cloneShallow(repo, ref string) (string, func(), error) {
+	tmp, cleanup, err := makeTemp("gh-aca-utils-")
 	if err != nil {
 		return "", nil, err
 	}
-	cleanup := func() { _ = os.RemoveAll(tmp) }
 
-	// Clone with all branches
-	args := []string{"clone", repo, tmp}
-	if cloneErr := execCommand("git", args...); cloneErr != nil {
+	debugf("cloning %s into %s", repo, tmp)
+	if err := cloneRepo(repo, tmp, cloneOptions{depth: 1, branch: ref}); err != nil {
 		cleanup()
-		return "", nil, fmt.Errorf("failed to clone repository: %w", cloneErr)
+		return "", nil, err
 	}
+	return tmp, cleanup, nil
+}
 
-	// Fetch all remote branches
-	if fetchErr := gitIn(tmp, "fetch", "--all"); fetchErr != nil {
-		fmt.Fprintf(os.Stderr, "warning: failed to fetch all branches: %v\n", fetchErr)
+// tarballPath is the API path of the tarball of repo at ref.
+func tarballPath(repo, ref string) string {
+	if ref != "" {
+		return fmt.Sprintf("repos/%s/tarball/%s", repo, ref)
 	}
+	return fmt.Sprintf("repos/%s/tarball", repo)
+}
 
-	return tmp, cleanup, nil
+// downloadTarball passes the gzipped tarball of repo at ref to read as
+// it downloads. cloneErr, why the repository wasn't cloned instead, is
+// part of the error when the download fails too.
+func downloadTarball(repo, ref string, cloneErr error, read func(io.Reader) error) error {
+	resp, err := apiRequest("", "GET", tarballPath(repo, ref), nil)
+	if err != nil {
+		return fmt.Errorf("clone %s: %v; download: %w", repo, cloneErr, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return read(resp.Body)
 }
 
+// maxTarFileSize caps how much of each file in a tarball is read, against
+// decompression bombs.
+const maxTarFileSize = 100 * 1024 * 1024 // 100MB limit
+
 func untarGz(r io.Reader, dest string) error {
 	gz, err := gzip.NewReader(r)
 	if err != nil {
@@ -381,7 +855,7 @@ func untarGz(r io.Reader, dest string) error {
 	}
 	defer func() {
 		if closeErr := gz.Close(); closeErr != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to close gzip reader: %v\n", closeErr)
+			warnf("failed to close gzip reader: %v", closeErr)
 		}
 	}()
 
@@ -424,12 +898,11 @@ func untarGz(r io.Reader, dest string) error {
 			}
 
 			// Limit file size to prevent decompression bombs
-			const maxFileSize = 100 * 1024 * 1024 // 100MB limit
-			limited := io.LimitReader(tr, maxFileSize)
+			limited := io.LimitReader(tr, maxTarFileSize)
 
 			if _, err := io.Copy(f, limited); err != nil {
 				if closeErr := f.Close(); closeErr != nil {
-					fmt.Fprintf(os.Stderr, "warning: failed to close file: %v\n", closeErr)
+					warnf("failed to close file: %v", closeErr)
 				}
 				return err
 			}
@@ -466,193 +939,371 @@ var (
 	portRe = regexp.MustCompile(`(?i)\b([A-Za-z0-9_.\-]*port[A-Za-z0-9_.\-]*)\s*[:=\s]\s*["']?([0-9]{2,5})["']?\b`)
 )
 
-func scanForIPPort(root string, includes, excludes []string) []matchRow {
-	var rows []matchRow
+func scanForIPPort(root string, opts scanOptions) []matchRow {
+	files := listFiles(root, opts.includes, opts.excludes)
+
+	// Files are scanned by a bounded pool of workers; each result goes into
+	// its file's slot so the output order stays that of the sorted walk.
+	results := newOrderedEmitter(len(files), opts.emit)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(opts.jobs, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if canceled() {
+					results.set(i, nil)
+					continue
+				}
+				rel, err := filepath.Rel(root, files[i])
+				if err != nil {
+					warnf("failed to get relative path for %s: %v", files[i], err)
+					results.set(i, nil)
+					continue
+				}
+				// Normalize path separators for cross-platform compatibility
+				rel = filepath.ToSlash(rel)
+				if sha, ok := opts.blobs[rel]; ok && opts.cache != nil {
+					results.set(i, opts.cache.scan(sha, rel, func() []matchRow { return scanFile(files[i], rel, opts) }))
+				} else {
+					results.set(i, scanFile(files[i], rel, opts))
+				}
+			}
+		}()
+	}
+	for i := range files {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return results.rows()
+}
+
+// listFiles returns the sorted paths of the files under root whose
+// slash-separated relative path matches includes and not excludes.
+func listFiles(root string, includes, excludes []string) []string {
 	var files []string
 
 	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := runCtx.Err(); err != nil {
+			return err
+		}
 		if d.IsDir() {
 			return nil
 		}
 		rel, err := filepath.Rel(root, path)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to get relative path for %s: %v\n", path, err)
+			warnf("failed to get relative path for %s: %v", path, err)
 			return nil // Continue walking instead of failing completely
 		}
 		// Normalize path separators for cross-platform compatibility
 		rel = filepath.ToSlash(rel)
 		if matchAny(rel, excludes) {
+			tracef("skip %s: excluded", rel)
 			return nil
 		}
 		if !matchAny(rel, includes) {
 			return nil
 		}
+		tracef("scan %s", rel)
 		files = append(files, path)
 		return nil
 	})
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "warning: error walking directory: %v\n", err)
+	if err != nil && !canceled() {
+		warnf("error walking directory: %v", err)
 	}
 
 	sort.Strings(files)
+	debugf("%d file(s) under %s match the include patterns", len(files), root)
+	return files
+}
 
-	for _, f := range files {
-		rel, err := filepath.Rel(root, f)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to get relative path for %s: %v\n", f, err)
+// scanFile reports the findings in one file.
+func scanFile(path, rel string, opts scanOptions) []matchRow {
+	data, err := os.ReadFile(path) // #nosec G304 - path is from controlled file walk
+	if err != nil {
+		return nil
+	}
+	return scanData(data, rel, opts)
+}
+
+// scanData reports the findings in the content of the file rel. Java
+// .properties files are parsed per the properties spec so continued
+// values are seen whole.
+func scanData(data []byte, rel string, opts scanOptions) []matchRow {
+	data, enc := decodeText(data)
+	if enc != "" {
+		tracef("%s: decoded from %s", rel, enc)
+	}
+	if strings.EqualFold(path.Ext(rel), ".properties") {
+		return scanProperties(data, rel, opts)
+	}
+
+	var rows []matchRow
+	maxLine := opts.maxLineLength
+	if maxLine <= 0 {
+		maxLine = defaultMaxLineLength
+	}
+	lines, cut := textLines(data, maxLine)
+	if cut > 0 {
+		warnf("%s: %d line(s) longer than %s scanned only up to that length (--max-line-length)", rel, cut, humanSize(int64(maxLine)))
+	}
+	for i, line := range lines {
+		lineNo := i + 1
+		if strings.TrimSpace(line) == "" {
 			continue
 		}
-		// Normalize path separators for cross-platform compatibility
-		rel = filepath.ToSlash(rel)
 
-		// Use anonymous function to ensure file is closed immediately
-		func() {
-			fh, err := os.Open(f) // #nosec G304 - f is from controlled file walk
-			if err != nil {
-				return
+		var r matchRow
+		var ok bool
+		if m := kvRe.FindStringSubmatch(line); len(m) == 3 {
+			r, ok = matchKV(m[1], strings.TrimSpace(m[2]), opts)
+		} else {
+			r, ok = matchInline(line, opts)
+		}
+		if ok {
+			r.RelPath, r.LineNumber = rel, lineNo
+			if r.IPValue != "" {
+				r.host = lineHostname(line, r.IPKey)
 			}
-			defer func() {
-				if closeErr := fh.Close(); closeErr != nil {
-					fmt.Fprintf(os.Stderr, "warning: failed to close file %s: %v\n", f, closeErr)
-				}
-			}()
-
-			s := bufio.NewScanner(fh)
-			lineNo := 0
-			for s.Scan() {
-				lineNo++
-				line := s.Text()
-				if strings.TrimSpace(line) == "" {
-					continue
-				}
-
-				var ipKey, ipVal, portKey, portVal string
-				if m := kvRe.FindStringSubmatch(line); len(m) == 3 {
-					k, v := m[1], strings.TrimSpace(m[2])
-					if looksLikeIP(v) {
-						ipKey, ipVal = k, stripQuotes(v)
-					}
-					if looksLikePort(k, v) {
-						portKey, portVal = k, stripQuotes(v)
-					}
-				} else {
-					if ip := firstIP(line); ip != "" {
-						ipVal = ip
-					}
-					if pk, pv, ok := findInlinePort(line); ok {
-						portKey, portVal = pk, pv
-					}
-				}
+			rows = append(rows, r)
+		}
+	}
+	if opts.withContext {
+		attachContext(rows, lines, opts.context)
+	}
+	return rows
+}
 
-				if ipKey != "" || ipVal != "" || portKey != "" || portVal != "" {
-					rows = append(rows, matchRow{ipKey, ipVal, portKey, portVal, rel, lineNo})
-				}
+func scanProperties(data []byte, rel string, opts scanOptions) []matchRow {
+	var rows []matchRow
+	entries := props.Parse(data)
+	lines := props.SplitLines(data)
+	next := 0
+	for i, line := range lines {
+		lineNo := i + 1
+		var r matchRow
+		var ok bool
+		switch {
+		case next < len(entries) && entries[next].Line == lineNo:
+			e := entries[next]
+			next++
+			r, ok = matchKV(e.Key, strings.TrimSpace(e.Value), opts)
+		case next > 0 && lineNo <= entries[next-1].EndLine:
+			continue // continuation of the previous entry
+		case isCommentOrBlank(line) || strings.HasPrefix(strings.TrimSpace(line), "!"):
+			// Commented-out addresses are still worth reporting.
+			r, ok = matchInline(line, opts)
+		}
+		if ok {
+			r.RelPath, r.LineNumber = rel, lineNo
+			if r.IPValue != "" {
+				r.host = lineHostname(line, r.IPKey)
 			}
-		}()
+			rows = append(rows, r)
+		}
+	}
+	if opts.withContext {
+		attachContext(rows, lines, opts.context)
 	}
 	return rows
 }
 
-func printRows(rows []matchRow, mode outputMode) error {
+// matchKV inspects a key/value pair for IP and port values.
+func matchKV(k, v string, opts scanOptions) (matchRow, bool) {
+	var r matchRow
+	if looksLikeIP(v) && acceptIPValue(k, v, opts.strictIP) {
+		r.IPKey, r.IPValue = k, stripQuotes(v)
+	}
+	if looksLikePort(k, v) {
+		r.PortKey, r.PortValue = k, stripQuotes(v)
+	}
+	return r, r.IPKey != "" || r.PortKey != ""
+}
+
+// matchInline inspects free text that isn't a key/value pair.
+func matchInline(line string, opts scanOptions) (matchRow, bool) {
+	var r matchRow
+	if ip := firstIP(line); ip != "" && acceptInlineIP(line, ip, opts.strictIP) {
+		r.IPValue = ip
+	}
+	if pk, pv, ok := findInlinePort(line); ok {
+		r.PortKey, r.PortValue = pk, pv
+	}
+	return r, r.IPValue != "" || r.PortKey != ""
+}
+
+// scanOptions controls which files are scanned and which findings are kept.
+type scanOptions struct {
+	includes []string
+	excludes []string
+	ports    portFilter
+	strictIP bool
+	jobs     int // concurrent file scanners; values below 1 mean 1
+
+	// withContext records each finding's line and context lines around it.
+	withContext bool
+	context     int
+
+	// maxLineLength is how many bytes of a line are scanned; 0 means
+	// defaultMaxLineLength.
+	maxLineLength int
+
+	// blobs maps relative paths to git blob SHAs; files listed there are
+	// looked up in cache before being scanned.
+	blobs map[string]string
+	cache *blobCache
+
+	// cacheDir holds per-commit results for single-ref scans; empty
+	// disables the cache. noCache forces a rescan that refreshes it.
+	cacheDir string
+	noCache  bool
+
+	// flagged holds the scan flags given on the command line; the
+	// scanned repository's config supplies the others.
+	flagged map[string]bool
+
+	// emit, if set, receives findings in output order as soon as they are
+	// available; they are then not returned by the scan functions.
+	emit func([]matchRow)
+}
+
+// scanTree scans root and applies the repository's value suppressions and
+// the configured finding filters.
+func scanTree(root string, opts scanOptions) ([]matchRow, error) {
+	ignores, err := loadValueIgnores(root)
+	if err != nil {
+		return nil, err
+	}
+	filter := newRowFilter(ignores, opts)
+	if emit := opts.emit; emit != nil {
+		opts.emit = func(rows []matchRow) { emit(filter(rows)) }
+		scanForIPPort(root, opts)
+		return nil, nil
+	}
+	return filter(scanForIPPort(root, opts)), nil
+}
+
+// newRowFilter returns a function that applies value suppressions and the
+// configured finding filters to raw scan results. Stale suppressions are
+// reported once, when the filter is created.
+func newRowFilter(ignores []valueIgnore, opts scanOptions) func([]matchRow) []matchRow {
+	now := time.Now()
+	_, stale := splitExpiredIgnores(ignores, now)
+	warnStaleIgnores(stale)
+	return func(rows []matchRow) []matchRow {
+		rows, _ = applyValueIgnores(rows, ignores, now)
+		return opts.ports.apply(rows)
+	}
+}
+
+// printRows prints rows with the given columns; nil means the defaults.
+// JUnit reports fail the test cases of the findings policy rejects.
+func printRows(rows []matchRow, mode outputMode, cols []rowColumn, policy *failPolicy) error {
+	if cols == nil {
+		cols = defaultColumns()
+	}
 	switch mode {
 	case outCSV:
-		fmt.Println("IP Key,IP Value,Port Key,Port Value,File Path,Line Number")
+		fmt.Fprintln(stdout(), csvHeader(cols))
 		for _, r := range rows {
-			fmt.Printf("%s,%s,%s,%s,%s,%d\n",
-				csvEsc(r.IPKey), csvEsc(r.IPValue), csvEsc(r.PortKey), csvEsc(r.PortValue),
-				csvEsc(r.RelPath), r.LineNumber)
+			fmt.Fprintln(stdout(), csvLine(r, cols))
 		}
 	case outTable:
 		w := newTable()
-		w.AddRow("IP Key", "IP Value", "Port Key", "Port Value", "File Path", "Line")
+		header := make([]string, len(cols))
+		for i, c := range cols {
+			header[i] = c.tableHeader()
+		}
+		w.AddRow(header...)
+		w.style = findingStyle(cols)
 		for _, r := range rows {
-			w.AddRow(r.IPKey, r.IPValue, r.PortKey, r.PortValue, r.RelPath, fmt.Sprintf("%d", r.LineNumber))
+			values := make([]string, len(cols))
+			for i, c := range cols {
+				values[i] = c.Value(r)
+			}
+			w.AddRow(values...)
 		}
 		w.Render()
 	case outJSON:
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		return enc.Encode(rows)
+		data, err := columnsJSON(rows, cols)
+		if err != nil {
+			return err
+		}
+		_, err = stdout().Write(data)
+		return err
+	case outXLSX:
+		return printXLSX(rows, cols)
+	case outJUnit:
+		return printJUnit(rows, cols, policy)
+	case outNDJSON:
+		for _, r := range rows {
+			fmt.Fprintf(stdout(), "%s\n", columnsObject(r, cols))
+		}
 	}
 	return nil
 }
 
-func scanAllBranches(repo, includes, excludes string, mode outputMode) error {
-	tmpDir, cleanup, err := cloneAllBranches(repo)
-	if err != nil {
-		return err
-	}
-	defer cleanup()
-
-	// Get all branch names
-	branches, err := getAllBranches(tmpDir)
-	if err != nil {
-		return fmt.Errorf("failed to get branches: %w", err)
+// scanRef scans a single ref (default branch when empty).
+func scanRef(repo, ref string, opts scanOptions) ([]matchRow, error) {
+	// Results are cached per commit, so an unchanged ref is not cloned
+	// again. If the commit cannot be resolved the cache is bypassed.
+	var key, commit string
+	if opts.cacheDir != "" {
+		if sha, err := resolveCommit(repo, ref); err == nil {
+			commit, key = sha, scanCacheKey(repo, sha, opts)
+		}
 	}
-
-	var allRows []matchRow
-	inc := splitCSV(includes, []string{"**/*"})
-	exc := splitCSV(excludes, []string{"**/.git/**", "**/node_modules/**"})
-
-	for _, branch := range branches {
-		// Checkout each branch
-		if err := gitIn(tmpDir, "checkout", branch); err != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to checkout branch %s: %v\n", branch, err)
-			continue
+	if key != "" && !opts.noCache {
+		if entry, ok := loadScanCache(opts.cacheDir, key); ok {
+			return entry.finish(opts)
 		}
+	}
 
-		// Scan this branch
-		rows := scanForIPPort(tmpDir, inc, exc)
-
-		// Add branch information to each row
-		for i := range rows {
-			rows[i].RelPath = fmt.Sprintf("[%s] %s", branch, rows[i].RelPath)
+	// A repository that can't be cloned is scanned from its tarball as
+	// it downloads, without extracting it.
+	var entry *scanCacheEntry
+	var cache *scanCacheWriter // the cache of a streamed scan, written as it goes
+	tmpDir, cleanup, err := cloneShallow(repo, ref)
+	streamed := err == nil && opts.emit != nil
+	switch {
+	case err == nil:
+		defer cleanup()
+		applyRepoScanConfig(tmpDir, &opts)
+		if streamed && key != "" {
+			var cerr error
+			if cache, cerr = createScanCache(opts.cacheDir, key, repo, commit); cerr != nil {
+				warnf("failed to write scan cache: %v", cerr)
+			}
+			defer cache.abort()
 		}
-
-		allRows = append(allRows, rows...)
+		entry, err = scanRaw(tmpDir, opts, cache)
+	case !canceled():
+		debugf("clone of %s failed (%v); scanning the tarball instead", repo, err)
+		entry, err = scanTarball(repo, ref, err, opts)
 	}
-
-	return printRows(allRows, mode)
-}
-
-func getAllBranches(repoDir string) ([]string, error) {
-	cmd := exec.Command("git", "branch", "-r", "--format=%(refname:short)")
-	cmd.Dir = repoDir
-	output, err := cmd.Output()
 	if err != nil {
-		// Fallback for older Git versions that don't support --format
-		cmd = exec.Command("git", "branch", "-r")
-		cmd.Dir = repoDir
-		output, err = cmd.Output()
-		if err != nil {
-			return nil, err
-		}
+		return nil, err
 	}
-
-	var branches []string
-	seenBranches := make(map[string]bool)
-	// Handle both Unix (\n) and Windows (\r\n) line endings
-	outputStr := strings.ReplaceAll(string(output), "\r\n", "\n")
-	lines := strings.Split(strings.TrimSpace(outputStr), "\n")
-	for _, line := range lines {
-		branch := strings.TrimSpace(line)
-		if branch != "" && !strings.Contains(branch, "HEAD") {
-			// Remove origin/ prefix and any leading whitespace/asterisks
-			branch = strings.TrimSpace(strings.TrimPrefix(branch, "*"))
-			branch = strings.TrimPrefix(branch, "origin/")
-			// Only add unique branches
-			if branch != "" && !seenBranches[branch] {
-				seenBranches[branch] = true
-				branches = append(branches, branch)
-			}
+	if key != "" && !canceled() {
+		save := func() error { return saveScanCache(opts.cacheDir, key, repo, commit, entry) }
+		if streamed {
+			save = func() error { return cache.commit(entry.Ignores) }
+		}
+		if err := save(); err != nil {
+			warnf("failed to write scan cache: %v", err)
 		}
 	}
-
-	return branches, nil
+	if streamed {
+		return nil, nil // already streamed by scanRaw
+	}
+	return entry.finish(opts)
 }
 
 func csvEsc(s string) string {
@@ -669,27 +1320,192 @@ func printChangeReport(changes []change, mode outputMode) error {
 		w := newTable()
 		w.AddRow("Adapter", "Old", "New", "File")
 		for _, c := range changes {
-			w.AddRow(c.Adapter, c.OldValue, c.NewValue, c.FilePath)
+			old := c.OldValue
+			if c.Created {
+				old = "(new)"
+			}
+			w.AddRow(c.Adapter, old, c.NewValue, c.FilePath)
 		}
 		w.Render()
 	case outJSON:
-		enc := json.NewEncoder(os.Stdout)
+		enc := json.NewEncoder(stdout())
 		enc.SetIndent("", "  ")
 		return enc.Encode(changes)
 	}
 	return nil
 }
 
-// --- utils
+// flipCSVHeader names the columns of printChangeCSV.
+const flipCSVHeader = "repo,env,adapter,old,new,file,created,pr,issue"
 
-func parseKV(line string) (key, val string, ok bool) {
-	m := kvRe.FindStringSubmatch(line)
-	if len(m) != 3 {
-		return "", "", false
+// printChangeCSV writes changes as CSV records, one per adapter, for
+// appending to change logs kept outside the repository.
+func printChangeCSV(repo string, changes []change, header bool) {
+	if header {
+		fmt.Fprintln(stdout(), flipCSVHeader)
+	}
+	for _, c := range changes {
+		env := path.Base(path.Dir(c.FilePath))
+		fmt.Fprintln(stdout(), strings.Join([]string{csvEsc(repo), csvEsc(env), csvEsc(c.Adapter), csvEsc(c.OldValue), csvEsc(c.NewValue), csvEsc(c.FilePath), strconv.FormatBool(c.Created), c.PRURL, c.IssueURL}, ","))
 	}
-	return m[1], strings.TrimSpace(m[2]), true
 }
 
+// --- flipping
+
+// The flip functions work on the physical lines of a .properties file as
+// props.SplitLinesEOL returns them: each keeps its terminator (\n, \r\n or
+// \r), so joining them gives the file back with its line endings.
+
+// indexKeys maps each property key to its entry; later definitions win, as
+// they do when Java loads the file.
+func indexKeys(lines []string) map[string]props.Entry {
+	m := map[string]props.Entry{}
+	for _, e := range props.Parse([]byte(strings.Join(lines, ""))) {
+		m[e.Key] = e
+	}
+	return m
+}
+
+// trimEOL drops the terminator of a physical line.
+func trimEOL(line string) string {
+	return strings.TrimRight(line, "\r\n")
+}
+
+// entryText returns the physical lines an entry spans, without their
+// terminators, or "" if lines doesn't hold the entry.
+func entryText(lines []string, e props.Entry) string {
+	if e.Line < 1 || e.EndLine < e.Line || e.EndLine > len(lines) {
+		return ""
+	}
+	text := make([]string, 0, e.EndLine-e.Line+1)
+	for _, l := range lines[e.Line-1 : e.EndLine] {
+		text = append(text, trimEOL(l))
+	}
+	return strings.Join(text, "\n")
+}
+
+// toggleNext is the planValues step for flips.
+func toggleNext(pairs []togglePair) func(adapter, v string) (string, bool) {
+	return func(adapter, v string) (string, bool) {
+		newV, ok := toggleValue(v, pairs)
+		if !ok {
+			warnf("adapter %q has non-toggle value %q; skipping (see --toggle-pair, or toggle.pairs in config)", adapter, v)
+		}
+		return newV, ok
+	}
+}
+
+// planValues plans a change for each wanted adapter whose new value next
+// returns; next reports its own reasons for skipping an adapter.
+func planValues(lines []string, index map[string]props.Entry, want []string, propPath string, next func(adapter, v string) (string, bool)) []change {
+	changes := make([]change, 0)
+	planned := map[string]bool{}
+	for _, a := range want {
+		e, ok := index[a]
+		if !ok {
+			warnf("adapter %q not found in %s%s", a, propPath, didYouMean(suggestKeys(a, mapKeys(index))))
+			continue
+		}
+		if planned[a] {
+			continue
+		}
+		v := valueWithoutComment(e.Value)
+		newV, ok := next(e.Key, v)
+		if !ok {
+			continue
+		}
+		planned[a] = true
+		changes = append(changes, change{
+			Adapter: e.Key, OldValue: v, NewValue: newV, FilePath: propPath,
+			line: entryText(lines, e), lineIdx: e.Line - 1, lineEnd: e.EndLine - 1,
+		})
+	}
+	return changes
+}
+
+// unplanned returns the wanted adapters that have no planned change, in
+// want order.
+func unplanned(want []string, changes []change) []string {
+	planned := map[string]bool{}
+	for _, c := range changes {
+		planned[c.Adapter] = true
+	}
+	var out []string
+	for _, a := range want {
+		if !planned[a] {
+			planned[a] = true
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// applyFlips returns lines with each planned value replaced in place, so
+// the separator style, trailing comments and line endings are kept. Entries
+// whose value can't be located are rewritten as a single key=value line,
+// and created adapters are appended.
+func applyFlips(lines []string, changes []change) []string {
+	var ordered, creates []change
+	for _, c := range changes {
+		if c.Created {
+			creates = append(creates, c)
+		} else {
+			ordered = append(ordered, c)
+		}
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].lineIdx > ordered[j].lineIdx })
+	for _, c := range ordered {
+		if idx, line, ok := spliceValue(lines, c); ok {
+			lines[idx] = line
+			continue
+		}
+		newLine := fmt.Sprintf("%s=%s", props.EscapeKey(c.Adapter), props.EscapeValue(c.NewValue))
+		newLine += lines[c.lineEnd][len(trimEOL(lines[c.lineEnd])):]
+		tail := append([]string{newLine}, lines[c.lineEnd+1:]...)
+		lines = append(lines[:c.lineIdx], tail...)
+	}
+	return appendProperties(lines, creates)
+}
+
+// spliceValue replaces the old value of c where it is written: after the
+// separator on the entry's first line, or at the start of its last line
+// when the first line only holds a continuation.
+func spliceValue(lines []string, c change) (int, string, bool) {
+	idx := c.lineIdx
+	body := trimEOL(lines[idx])
+	start := props.ValueStart(body)
+	if c.lineEnd > c.lineIdx {
+		if strings.TrimRight(body[start:], " \t\f") != "\\" {
+			return 0, "", false
+		}
+		idx = c.lineEnd
+		body = trimEOL(lines[idx])
+		start = len(body) - len(strings.TrimLeft(body, " \t\f"))
+	}
+	old := props.EscapeValue(c.OldValue)
+	if old == "" || !strings.HasPrefix(body[start:], old) {
+		return 0, "", false
+	}
+	rest := body[start+len(old):]
+	if rest != "" && !strings.ContainsAny(rest[:1], " \t\f#!") {
+		return 0, "", false
+	}
+	return idx, body[:start] + props.EscapeValue(c.NewValue) + rest + lines[idx][len(body):], true
+}
+
+// valueWithoutComment trims a property value and drops a trailing
+// " # comment". Properties files have no inline comments, but they are
+// common enough in hand-edited files that flipping should keep them.
+func valueWithoutComment(v string) string {
+	v = strings.TrimSpace(v)
+	if i := strings.IndexAny(v, "#!"); i > 0 && strings.ContainsAny(v[i-1:i], " \t\f") {
+		return strings.TrimSpace(v[:i])
+	}
+	return v
+}
+
+// --- utils
+
 func isCommentOrBlank(line string) bool {
 	trim := strings.TrimSpace(line)
 	return trim == "" || strings.HasPrefix(trim, "#") || strings.HasPrefix(trim, ";")
@@ -750,7 +1566,7 @@ func matchAny(path string, patterns []string) bool {
 		normalizedPattern := filepath.ToSlash(p)
 		ok, err := doublestar.PathMatch(normalizedPattern, normalizedPath)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "warning: invalid pattern %q: %v\n", p, err)
+			warnf("invalid pattern %q: %v", p, err)
 			continue
 		}
 		if ok {
@@ -780,6 +1596,10 @@ func splitCSV(s string, def []string) []string {
 type table struct {
 	rows   [][]string
 	widths []int
+
+	// style optionally picks an ANSI color for a body cell on a color
+	// terminal; see color.go. row counts the header as 0.
+	style func(row, col int, cell string) string
 }
 
 func newTable() *table { return &table{} }
@@ -797,24 +1617,25 @@ func (t *table) AddRow(cols ...string) {
 }
 
 func (t *table) Render() {
+	color := colorEnabled(stdout())
 	for r, cols := range t.rows {
 		for i, c := range cols {
 			pad := t.widths[i] - displayWidth(c)
-			fmt.Print(c)
+			fmt.Fprint(stdout(), t.paint(color, r, i, c))
 			if i < len(cols)-1 {
-				fmt.Print(strings.Repeat(" ", pad+2))
+				fmt.Fprint(stdout(), strings.Repeat(" ", pad+2))
 			}
 		}
-		fmt.Println()
+		fmt.Fprintln(stdout())
 		if r == 0 {
 			// header underlines
 			for i := range cols {
-				fmt.Print(strings.Repeat("-", t.widths[i]))
+				fmt.Fprint(stdout(), strings.Repeat("-", t.widths[i]))
 				if i < len(cols)-1 {
-					fmt.Print("  ")
+					fmt.Fprint(stdout(), "  ")
 				}
 			}
-			fmt.Println()
+			fmt.Fprintln(stdout())
 		}
 	}
 }
@@ -823,24 +1644,34 @@ func displayWidth(s string) int { return len([]rune(s)) }
 
 // --- subprocess helpers ---
 
-func execCommand(name string, args ...string) error {
-	cmd := exec.Command(name, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+// traceCommand logs a command about to run, at -vv.
+func traceCommand(name string, args []string) {
+	tracef("run %s %s", name, strings.Join(args, " "))
 }
 
 func gitIn(dir string, args ...string) error {
-	cmd := exec.Command("git", args...)
-	cmd.Dir = dir
+	traceCommand("git", args)
+	cmd := command(dir, "git", args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
 
+// gitOutput runs git in dir and returns its trimmed stdout.
+func gitOutput(dir string, args ...string) (string, error) {
+	traceCommand("git", args)
+	cmd := command(dir, "git", args...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
 func ghIn(dir string, args ...string) error {
-	cmd := exec.Command("gh", args...)
-	cmd.Dir = dir
+	traceCommand("gh", args)
+	cmd := command(dir, "gh", args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
@@ -855,128 +1686,12 @@ func parseMode(s string, def outputMode) outputMode {
 		return outTable
 	case "json":
 		return outJSON
+	case "xlsx":
+		return outXLSX
+	case "junit":
+		return outJUnit
+	case "ndjson", "jsonl":
+		return outNDJSON
 	}
 	return def
 }
-
-// --- adapter storage functions ---
-
-func getAdapterConfigPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	configDir := filepath.Join(homeDir, ".gh-aca-utils")
-	if err := os.MkdirAll(configDir, 0750); err != nil {
-		return "", fmt.Errorf("failed to create config directory: %w", err)
-	}
-
-	return filepath.Join(configDir, "adapters.txt"), nil
-}
-
-func storeAdapters(adapters string) error {
-	configPath, err := getAdapterConfigPath()
-	if err != nil {
-		return err
-	}
-
-	// Parse and validate adapters
-	adapterList := splitCSV(adapters, nil)
-	if len(adapterList) == 0 {
-		return fmt.Errorf("no valid adapters provided")
-	}
-
-	// Filter out empty adapter names and validate
-	validAdapters := make([]string, 0, len(adapterList))
-	for _, adapter := range adapterList {
-		trimmed := strings.TrimSpace(adapter)
-		if trimmed == "" {
-			return fmt.Errorf("empty adapter name not allowed: %q", adapter)
-		}
-		validAdapters = append(validAdapters, trimmed)
-	}
-
-	if len(validAdapters) == 0 {
-		return fmt.Errorf("no valid adapters provided after validation")
-	}
-
-	// Write to file (overwrite existing)
-	content := strings.Join(validAdapters, "\n") + "\n"
-	if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
-		return fmt.Errorf("failed to write adapter file: %w", err)
-	}
-
-	fmt.Printf("Stored %d adapter(s) in %s:\n", len(validAdapters), configPath)
-	for _, adapter := range validAdapters {
-		fmt.Printf("  - %s\n", adapter)
-	}
-
-	return nil
-}
-
-func listStoredAdapters() error {
-	configPath, err := getAdapterConfigPath()
-	if err != nil {
-		return err
-	}
-
-	adapters, err := loadStoredAdapters()
-	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Printf("No adapters stored yet. Use 'gh aca set-adapters --adapters adapter1,adapter2' to store adapters.\n")
-			return nil
-		}
-		return err
-	}
-
-	if len(adapters) == 0 {
-		fmt.Printf("No adapters stored in %s\n", configPath)
-	} else {
-		fmt.Printf("Stored adapters (%s):\n", configPath)
-		for _, adapter := range adapters {
-			fmt.Printf("  - %s\n", adapter)
-		}
-	}
-
-	return nil
-}
-
-func clearStoredAdapters() error {
-	configPath, err := getAdapterConfigPath()
-	if err != nil {
-		return err
-	}
-
-	if err := os.Remove(configPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to clear adapters file: %w", err)
-	}
-
-	fmt.Printf("Cleared stored adapters from %s\n", configPath)
-	return nil
-}
-
-func loadStoredAdapters() ([]string, error) {
-	configPath, err := getAdapterConfigPath()
-	if err != nil {
-		return nil, err
-	}
-
-	content, err := os.ReadFile(configPath) // #nosec G304 - configPath is controlled
-	if err != nil {
-		return nil, err
-	}
-
-	// Handle both Unix (\n) and Windows (\r\n) line endings
-	contentStr := strings.ReplaceAll(string(content), "\r\n", "\n")
-	lines := strings.Split(strings.TrimSpace(contentStr), "\n")
-	var adapters []string
-	for _, line := range lines {
-		adapter := strings.TrimSpace(line)
-		if adapter != "" && !strings.HasPrefix(adapter, "#") {
-			adapters = append(adapters, adapter)
-		}
-	}
-
-	return adapters, nil
-}
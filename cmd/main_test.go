@@ -9,33 +9,6 @@ import (
 	"testing"
 )
 
-func TestParseKV(t *testing.T) {
-	tests := []struct {
-		input   string
-		wantKey string
-		wantVal string
-		wantOk  bool
-	}{
-		{"key=value", "key", "value", true},
-		{"host.ip=192.168.1.1", "host.ip", "192.168.1.1", true},
-		{"port: 8080", "port", "8080", true},
-		{"  spaced_key  =  spaced value  ", "spaced_key", "spaced value", true},
-		{"# comment line", "", "", false},
-		{"invalid line", "", "", false},
-		{"", "", "", false},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.input, func(t *testing.T) {
-			gotKey, gotVal, gotOk := parseKV(tt.input)
-			if gotKey != tt.wantKey || gotVal != tt.wantVal || gotOk != tt.wantOk {
-				t.Errorf("parseKV(%q) = (%q, %q, %v), want (%q, %q, %v)",
-					tt.input, gotKey, gotVal, gotOk, tt.wantKey, tt.wantVal, tt.wantOk)
-			}
-		})
-	}
-}
-
 func TestIsCommentOrBlank(t *testing.T) {
 	tests := []struct {
 		input string
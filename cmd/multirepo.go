@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// flipResult is the outcome of flip-adapters for one repository.
+type flipResult struct {
+	Repo     string   `json:"repo"`
+	Changes  []change `json:"changes"`
+	Branch   string   `json:"branch,omitempty"`
+	PRURL    string   `json:"prUrl,omitempty"`
+	IssueURL string   `json:"issueUrl,omitempty"`
+	Checks   string   `json:"checks,omitempty"` // --wait-checks result
+	Error    string   `json:"error,omitempty"`
+}
+
+// loadRepos merges --repo values with the repositories listed in file, in
+// order and without duplicates.
+func loadRepos(flags []string, file string) ([]string, error) {
+	list := append([]string(nil), flags...)
+	if file != "" {
+		f, err := os.Open(file) // #nosec G304 - user-provided repos file
+		if err != nil {
+			return nil, fmt.Errorf("open repos file: %w", err)
+		}
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if i := strings.Index(line, "#"); i >= 0 {
+				line = strings.TrimSpace(line[:i])
+			}
+			if line != "" {
+				list = append(list, line)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("read repos file: %w", err)
+		}
+	}
+
+	var repos []string
+	seen := map[string]bool{}
+	for _, r := range list {
+		r = strings.TrimSpace(r)
+		if r == "" || seen[strings.ToLower(r)] {
+			continue
+		}
+		if owner, name, ok := strings.Cut(r, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return nil, usageErrorf("invalid repository %q: expected ORG/REPO", r)
+		}
+		seen[strings.ToLower(r)] = true
+		repos = append(repos, r)
+	}
+	return repos, nil
+}
+
+// printFlipResults prints the consolidated report of a multi-repository
+// flip and fails if any repository failed.
+func printFlipResults(results []flipResult, mode outputMode) error {
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
+		}
+	}
+	if mode == outCSV {
+		fmt.Fprintln(stdout(), flipCSVHeader)
+		for _, r := range results {
+			changes := append([]change(nil), r.Changes...)
+			for i := range changes {
+				if changes[i].PRURL == "" {
+					changes[i].PRURL = r.PRURL
+				}
+				if changes[i].IssueURL == "" {
+					changes[i].IssueURL = r.IssueURL
+				}
+			}
+			printChangeCSV(r.Repo, changes, false)
+		}
+	} else if mode == outJSON {
+		for i := range results {
+			if results[i].Changes == nil {
+				results[i].Changes = []change{}
+			}
+		}
+		enc := json.NewEncoder(stdout())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintln(stdout())
+		w := newTable()
+		w.AddRow("Repo", "Changes", "Branch", "PR", "Status")
+		for _, r := range results {
+			status := "ok"
+			switch {
+			case r.Error != "":
+				status = "error: " + r.Error
+			case len(r.Changes) == 0:
+				status = "no changes"
+			case r.Checks != "":
+				status = "checks: " + r.Checks
+			}
+			w.AddRow(r.Repo, fmt.Sprint(len(r.Changes)), r.Branch, r.PRURL, status)
+		}
+		w.Render()
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d repositories failed", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadRepos(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "repos.txt")
+	content := "# payment services\norg/billing\n\norg/Search  # owned by team-x\norg/crm\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	repos, err := loadRepos([]string{"org/search", "org/api"}, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(repos, ","); got != "org/search,org/api,org/billing,org/crm" {
+		t.Errorf("loadRepos = %s", got)
+	}
+	for _, bad := range []string{"org", "org/a/b", "/repo"} {
+		if _, err := loadRepos([]string{bad}, ""); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+	if _, err := loadRepos(nil, filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected error for a missing repos file")
+	}
+}
+
+func TestPrintFlipResults(t *testing.T) {
+	var out bytes.Buffer
+	resultOutput = &out
+	defer func() { resultOutput = nil }()
+
+	results := []flipResult{
+		{Repo: "org/a", Changes: []change{{Adapter: "billing", OldValue: "0", NewValue: "1"}}, Branch: "toggle/adapters-dev", PRURL: "https://github.com/org/a/pull/1"},
+		{Repo: "org/b"},
+		{Repo: "org/c", Error: "read env/dev/parameters.properties: no such file"},
+	}
+	err := printFlipResults(results, outJSON)
+	if err == nil || !strings.Contains(err.Error(), "1 of 3") {
+		t.Errorf("Expected a failure for org/c, got %v", err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	if len(decoded) != 3 || decoded[0]["prUrl"] != "https://github.com/org/a/pull/1" || decoded[1]["changes"] == nil {
+		t.Errorf("Unexpected report: %s", out.String())
+	}
+
+	out.Reset()
+	if err := printFlipResults(results[:2], outTable); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "no changes") || !strings.Contains(out.String(), "pull/1") {
+		t.Errorf("Unexpected table:\n%s", out.String())
+	}
+}
+
+func TestPrintFlipResultsCSV(t *testing.T) {
+	var out bytes.Buffer
+	resultOutput = &out
+	defer func() { resultOutput = nil }()
+
+	results := []flipResult{
+		{Repo: "org/a", Changes: []change{{Adapter: "billing", OldValue: "0", NewValue: "1", FilePath: "env/dev/parameters.properties"}}, PRURL: "https://github.com/org/a/pull/1"},
+		{Repo: "org/b", Changes: []change{{Adapter: "note", NewValue: "a,b", FilePath: "env/dev/parameters.properties", Created: true}}},
+	}
+	if err := printFlipResults(results, outCSV); err != nil {
+		t.Fatal(err)
+	}
+	want := "repo,env,adapter,old,new,file,created,pr,issue\n" +
+		"org/a,dev,billing,0,1,env/dev/parameters.properties,false,https://github.com/org/a/pull/1,\n" +
+		"org/b,dev,note,,\"a,b\",env/dev/parameters.properties,true,,\n"
+	if out.String() != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", out.String(), want)
+	}
+}
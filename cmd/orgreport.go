@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// orgReport is where each adapter is defined across an organization's
+// repositories, and with which values.
+type orgReport struct {
+	Org      string       `json:"org"`
+	File     string       `json:"file"`
+	Scanned  int          `json:"scanned"` // repositories looked at
+	Adapters []orgAdapter `json:"adapters"`
+}
+
+type orgAdapter struct {
+	Adapter string         `json:"adapter"`
+	Repos   []orgAdapterIn `json:"repos"`
+}
+
+// orgAdapterIn holds an adapter's value in each environment of one
+// repository.
+type orgAdapterIn struct {
+	Repo   string            `json:"repo"`
+	Values map[string]string `json:"values"`
+}
+
+// matchesAny reports whether s matches one of the glob patterns.
+func matchesAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// listOrgRepos returns the unarchived repositories of org, sorted, a page
+// at a time.
+func listOrgRepos(org string) ([]string, error) {
+	var repos []string
+	for page := 1; ; page++ {
+		var list []struct {
+			FullName string `json:"full_name"`
+			Archived bool   `json:"archived"`
+		}
+		if err := ghAPI("GET", fmt.Sprintf("orgs/%s/repos?per_page=100&page=%d", org, page), nil, &list); err != nil {
+			return nil, err
+		}
+		for _, r := range list {
+			if !r.Archived {
+				repos = append(repos, r.FullName)
+			}
+		}
+		if len(list) < 100 {
+			break
+		}
+	}
+	sort.Strings(repos)
+	return repos, nil
+}
+
+// readOrgRepo reads paramFile of every environment in repo's default
+// branch through the contents API. A repository without env/ has no
+// values.
+func readOrgRepo(repo, paramFile string) ([]adapterValue, error) {
+	api, err := newAPICheckout(repo)
+	if err != nil {
+		return nil, err
+	}
+	dir, cleanup, err := api.checkout()
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	entries, err := os.ReadDir(filepath.Join(dir, "env"))
+	if err != nil {
+		return nil, nil // env/ holds no environment directories
+	}
+	var values []adapterValue
+	for _, e := range entries {
+		rel := path.Join("env", e.Name(), paramFile)
+		b, err := api.read(rel)
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		vs, err := parseEnvValues(b, e.Name(), rel, nil)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, vs...)
+	}
+	return values, nil
+}
+
+// buildOrgReport groups the values read per repository by adapter,
+// keeping adapters matching filters when given. Adapters are sorted by
+// name and repositories follow repos.
+func buildOrgReport(org, paramFile string, repos []string, values [][]adapterValue, filters []string) orgReport {
+	r := orgReport{Org: org, File: paramFile, Scanned: len(repos), Adapters: make([]orgAdapter, 0)}
+	index := map[string]int{}
+	for i, repo := range repos {
+		for _, v := range values[i] {
+			if v.Missing || (len(filters) > 0 && !matchesAny(filters, v.Adapter)) {
+				continue
+			}
+			j, ok := index[v.Adapter]
+			if !ok {
+				j = len(r.Adapters)
+				index[v.Adapter] = j
+				r.Adapters = append(r.Adapters, orgAdapter{Adapter: v.Adapter})
+			}
+			a := &r.Adapters[j]
+			if n := len(a.Repos); n == 0 || a.Repos[n-1].Repo != repo {
+				a.Repos = append(a.Repos, orgAdapterIn{Repo: repo, Values: map[string]string{}})
+			}
+			a.Repos[len(a.Repos)-1].Values[v.Env] = v.Value
+		}
+	}
+	sort.SliceStable(r.Adapters, func(i, j int) bool { return r.Adapters[i].Adapter < r.Adapters[j].Adapter })
+	return r
+}
+
+// envValues formats values as env=value pairs in environment order.
+func envValues(values map[string]string) string {
+	envs := mapKeys(values)
+	sort.Strings(envs)
+	parts := make([]string, len(envs))
+	for i, env := range envs {
+		parts[i] = env + "=" + values[env]
+	}
+	return strings.Join(parts, " ")
+}
+
+func printOrgReport(r orgReport, mode outputMode) error {
+	switch mode {
+	case outJSON:
+		enc := json.NewEncoder(stdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case outCSV:
+		fmt.Fprintln(stdout(), "adapter,repo,env,value")
+		for _, a := range r.Adapters {
+			for _, in := range a.Repos {
+				envs := mapKeys(in.Values)
+				sort.Strings(envs)
+				for _, env := range envs {
+					fmt.Fprintf(stdout(), "%s,%s,%s,%s\n", csvEsc(a.Adapter), csvEsc(in.Repo), csvEsc(env), csvEsc(in.Values[env]))
+				}
+			}
+		}
+		return nil
+	}
+	if len(r.Adapters) == 0 {
+		fmt.Fprintf(stdout(), "No adapters found in %d repositories of %s.\n", r.Scanned, r.Org)
+		return nil
+	}
+	w := newTable()
+	w.AddRow("Adapter", "Repo", "Values")
+	using := map[string]bool{}
+	for _, a := range r.Adapters {
+		for i, in := range a.Repos {
+			name := a.Adapter
+			if i > 0 {
+				name = ""
+			}
+			w.AddRow(name, in.Repo, envValues(in.Values))
+			using[in.Repo] = true
+		}
+	}
+	w.Render()
+	fmt.Fprintf(stdout(), "\n%d adapter(s) in %d of %d repositories of %s.\n", len(r.Adapters), len(using), r.Scanned, r.Org)
+	return nil
+}
+
+func cmdAdaptersReport() *cobra.Command {
+	var org, adaptersCSV, paramFile, mode string
+	var jobs int
+
+	cmd := &cobra.Command{
+		Use:   "adapters-report",
+		Short: "Show which repositories of an organization define each adapter, and with which values",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if org == "" {
+				return usageErrorf("--org ORG is required")
+			}
+			if err := checkParamFile(paramFile); err != nil {
+				return err
+			}
+			filters := splitCSV(adaptersCSV, nil)
+			for _, f := range filters {
+				if _, err := path.Match(f, ""); err != nil {
+					return usageErrorf("invalid --adapters pattern %q: %w", f, err)
+				}
+			}
+			modeVal := parseMode(mode, outTable)
+
+			repos, err := listOrgRepos(org)
+			if err != nil {
+				return err
+			}
+			values := make([][]adapterValue, len(repos))
+			index := map[string]int{}
+			for i, repo := range repos {
+				index[repo] = i
+			}
+			var mu sync.Mutex
+			failed := 0
+			forEachLimit(repos, jobs, func(repo string) {
+				vs, err := readOrgRepo(repo, paramFile)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					warnf("%s: %v", repo, err)
+					failed++
+					return
+				}
+				values[index[repo]] = vs
+			})
+			if err := printOrgReport(buildOrgReport(org, paramFile, repos, values, filters), modeVal); err != nil {
+				return err
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d repositories could not be read", failed, len(repos))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&org, "org", "", "GitHub organization whose repositories to scan (required)")
+	cmd.Flags().StringVar(&adaptersCSV, "adapters", "", "Comma-separated adapter keys or globs to report (default: every key)")
+	cmd.Flags().StringVar(&paramFile, "file", "parameters.properties", "Parameters file in each env directory")
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", 4, "Number of repositories to read concurrently")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table|json|csv")
+	setOutputModes(cmd, "table", "json", "csv")
+	return cmd
+}
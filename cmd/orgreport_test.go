@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBuildOrgReport(t *testing.T) {
+	repos := []string{"org/api", "org/billing", "org/docs"}
+	values := [][]adapterValue{
+		{{Env: "dev", Adapter: "legacy.auth", Value: "1"}, {Env: "prod", Adapter: "legacy.auth", Value: "0"}, {Env: "dev", Adapter: "search", Value: "on"}},
+		{{Env: "dev", Adapter: "legacy.auth", Value: "1"}, {Env: "dev", Adapter: "gone", Missing: true}},
+		nil, // no env/ directory
+	}
+	var out bytes.Buffer
+	resultOutput = &out
+	defer func() { resultOutput = nil }()
+
+	r := buildOrgReport("org", "parameters.properties", repos, values, nil)
+	if len(r.Adapters) != 2 || r.Adapters[0].Adapter != "legacy.auth" || len(r.Adapters[0].Repos) != 2 || r.Scanned != 3 {
+		t.Fatalf("report = %+v", r)
+	}
+	if err := printOrgReport(r, outCSV); err != nil {
+		t.Fatal(err)
+	}
+	want := "adapter,repo,env,value\n" +
+		"legacy.auth,org/api,dev,1\n" +
+		"legacy.auth,org/api,prod,0\n" +
+		"legacy.auth,org/billing,dev,1\n" +
+		"search,org/api,dev,on\n"
+	if out.String() != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	r = buildOrgReport("org", "parameters.properties", repos, values, []string{"legacy.*"})
+	if len(r.Adapters) != 1 || r.Adapters[0].Adapter != "legacy.auth" {
+		t.Errorf("filtered report = %+v", r)
+	}
+	if got := envValues(r.Adapters[0].Repos[0].Values); got != "dev=1 prod=0" {
+		t.Errorf("envValues = %q", got)
+	}
+}
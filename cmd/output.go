@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// resultOutput receives command results when --output-file is set.
+// Progress, warnings and subprocess output keep going to the terminal.
+var resultOutput io.Writer
+
+// stdout returns where command results are written.
+func stdout() io.Writer {
+	if resultOutput != nil {
+		return resultOutput
+	}
+	return os.Stdout
+}
+
+// atomicFile is written under a temporary name next to its destination and
+// renamed into place on commit, so readers never see a partial file and a
+// failed run leaves an existing file untouched.
+type atomicFile struct {
+	*os.File
+	path string
+}
+
+func createAtomic(path string) (*atomicFile, error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	return &atomicFile{File: tmp, path: path}, nil
+}
+
+// commit flushes the temporary file and renames it over the destination.
+func (f *atomicFile) commit() error {
+	if err := f.Sync(); err != nil {
+		f.abort()
+		return fmt.Errorf("failed to write %s: %w", f.path, err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("failed to write %s: %w", f.path, err)
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil { // #nosec G302 - result files are meant to be shared
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("failed to replace %s: %w", f.path, err)
+	}
+	return nil
+}
+
+// abort discards the temporary file.
+func (f *atomicFile) abort() {
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+}
+
+// runWithOutputFile runs fn with results redirected to path (if non-empty)
+// and replaces path only if fn succeeds, fails on policy, or was stopped by
+// Ctrl-C or --timeout with partial results.
+func runWithOutputFile(path string, fn func() error) error {
+	if path == "" {
+		return fn()
+	}
+	f, err := createAtomic(path)
+	if err != nil {
+		return err
+	}
+	resultOutput = f
+	defer func() { resultOutput = nil }()
+
+	if err := fn(); err != nil {
+		// Policy failures still produced a complete result, and a stopped
+		// run keeps what it printed before it stopped.
+		var fe *findingsError
+		if !errors.As(err, &fe) && exitCode(err) != exitStopped {
+			f.abort()
+			return err
+		}
+		if cerr := f.commit(); cerr != nil {
+			return cerr
+		}
+		return err
+	}
+	return f.commit()
+}
+
+// wrapRunE wraps the RunE of cmd and all its subcommands with wrap. Flags
+// are only parsed inside Execute, so per-run setup such as opening the
+// output file has to happen around RunE.
+func wrapRunE(cmd *cobra.Command, wrap func(run func() error) error) {
+	if runE := cmd.RunE; runE != nil {
+		cmd.RunE = func(c *cobra.Command, args []string) error {
+			return wrap(func() error { return runE(c, args) })
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		wrapRunE(sub, wrap)
+	}
+}
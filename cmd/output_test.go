@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRunWithOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "findings.csv")
+	if err := os.WriteFile(path, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed run keeps the previous file and leaves no temp file behind.
+	err := runWithOutputFile(path, func() error {
+		_ = printRows([]matchRow{{IPValue: "10.0.0.9"}}, outCSV, nil, nil)
+		return errors.New("clone failed")
+	})
+	if err == nil {
+		t.Fatal("Expected error from failed run")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old\n" {
+		t.Errorf("Expected previous file to be kept, got %q", data)
+	}
+
+	rows := []matchRow{{IPKey: "db.host", IPValue: "10.0.0.5", RelPath: "a.env", LineNumber: 1}}
+	if err := runWithOutputFile(path, func() error { return printRows(rows, outCSV, nil, nil) }); err != nil {
+		t.Fatalf("runWithOutputFile failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := csvHeader(defaultColumns()) + "\n" + csvLine(rows[0], defaultColumns()) + "\n"; string(data) != want {
+		t.Errorf("Output file = %q, want %q", data, want)
+	}
+	if stdout() != os.Stdout {
+		t.Error("Expected results to go back to stdout after the run")
+	}
+
+	// A policy failure still writes its complete results.
+	err = runWithOutputFile(path, func() error {
+		_ = printRows(rows[:0], outCSV, nil, nil)
+		return &findingsError{"1 finding(s) reported"}
+	})
+	if _, ok := err.(*findingsError); !ok {
+		t.Errorf("Expected the findings error to be returned, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != csvHeader(defaultColumns())+"\n" {
+		t.Errorf("Expected results to be written despite the policy failure, got %q", data)
+	}
+
+	// A run stopped by Ctrl-C or --timeout keeps its partial results.
+	err = runWithOutputFile(path, func() error {
+		_ = printRows(rows, outCSV, nil, nil)
+		return withExitCode(exitStopped, errors.New("interrupted"))
+	})
+	if exitCode(err) != exitStopped {
+		t.Errorf("Expected the stop to be returned, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "10.0.0.5") {
+		t.Errorf("Expected partial results to be written after a stop, got %q", data)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the output file in %s, got %d entries", dir, len(entries))
+	}
+}
+
+func TestWrapRunE(t *testing.T) {
+	var calls []string
+	root := &cobra.Command{Use: "root"}
+	sub := &cobra.Command{Use: "sub", RunE: func(*cobra.Command, []string) error {
+		calls = append(calls, "run")
+		return nil
+	}}
+	root.AddCommand(sub)
+
+	wrapRunE(root, func(run func() error) error {
+		calls = append(calls, "before")
+		return run()
+	})
+	root.SetArgs([]string{"sub"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(calls, ",") != "before,run" {
+		t.Errorf("Expected wrapper around subcommand RunE, got %v", calls)
+	}
+}
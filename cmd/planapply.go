@@ -0,0 +1,413 @@
+package cmd
+
+import (
+	"crypto/sha1" // #nosec G505 - git object IDs are SHA-1
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// changePlanVersion is the format version written by plan.
+const changePlanVersion = 1
+
+// changePlan is the document `plan` writes and `apply` executes: the flips
+// pending in each environment of each repository, with the blob each
+// parameters file had when it was read.
+type changePlan struct {
+	Version   int        `json:"version"`
+	CreatedAt time.Time  `json:"createdAt"`
+	File      string     `json:"file"`
+	Repos     []repoPlan `json:"repos"`
+}
+
+type repoPlan struct {
+	Repo  string     `json:"repo"`
+	Base  string     `json:"base"` // default branch commit the files were read at
+	Files []filePlan `json:"files"`
+}
+
+type filePlan struct {
+	Env     string       `json:"env"`
+	Path    string       `json:"path"`
+	BlobSHA string       `json:"blobSha"`
+	Changes []planChange `json:"changes"`
+}
+
+type planChange struct {
+	Adapter string `json:"adapter"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+}
+
+// gitBlobSHA returns the git object ID of a file with content b, which is
+// what GitHub reports as the file's SHA.
+func gitBlobSHA(b []byte) string {
+	h := sha1.New() // #nosec G401 - git object IDs are SHA-1
+	fmt.Fprintf(h, "blob %d\x00", len(b))
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// changes returns the plan's changes as a flat list for reports.
+func (p changePlan) changes() []change {
+	var out []change
+	for _, r := range p.Repos {
+		for _, f := range r.Files {
+			for _, c := range f.Changes {
+				out = append(out, change{Adapter: c.Adapter, OldValue: c.Old, NewValue: c.New, FilePath: r.Repo + ":" + f.Path})
+			}
+		}
+	}
+	return out
+}
+
+func loadChangePlan(file string) (changePlan, error) {
+	var p changePlan
+	b, err := os.ReadFile(file) // #nosec G304 - user-supplied plan file
+	if err != nil {
+		return p, fmt.Errorf("read plan: %w", err)
+	}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return p, fmt.Errorf("%s: %w", file, err)
+	}
+	if p.Version != changePlanVersion {
+		return p, fmt.Errorf("%s: unsupported plan version %d (want %d); re-run plan with this version", file, p.Version, changePlanVersion)
+	}
+	for _, r := range p.Repos {
+		for _, f := range r.Files {
+			if f.Path != path.Join("env", f.Env, p.File) || f.Env == "" || isEnvPattern(f.Env) || strings.Contains(f.Env, "/") {
+				return p, fmt.Errorf("%s: invalid file %q for environment %q", file, f.Path, f.Env)
+			}
+		}
+	}
+	return p, nil
+}
+
+// openAPIRepo checks out repo through the contents API: a scratch
+// directory with its environments and repository config.
+func openAPIRepo(repo string) (*apiCheckout, string, func(), error) {
+	api, err := newAPICheckout(repo)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	dir, cleanup, err := api.checkout()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	api.fetchConfig(dir)
+	return api, dir, cleanup, nil
+}
+
+// planRepo plans flipping want in paramFile of the environments matching
+// envPatterns in repo.
+func planRepo(repo string, envPatterns []string, paramFile string, want []string, pairs []togglePair) (repoPlan, []change, error) {
+	rp := repoPlan{Repo: repo}
+	api, dir, cleanup, err := openAPIRepo(repo)
+	if err != nil {
+		return rp, nil, err
+	}
+	defer cleanup()
+	rp.Base = api.baseSHA
+	envs, err := resolveEnvList(dir, envPatterns)
+	if err != nil {
+		return rp, nil, err
+	}
+	cfg := loadConfig(dir)
+	cfgPairs, _ := cfg["toggle.pairs"].Value.([]togglePair)
+	byAdapter, _ := cfg["toggle.adapters"].Value.(map[string]togglePair)
+	next := toggleNextFor(withConfigPairs(pairs, cfgPairs), byAdapter)
+
+	var all []change
+	for _, env := range envs {
+		rel := path.Join("env", env, paramFile)
+		if err := api.fetch(dir, rel); err != nil {
+			return rp, nil, err
+		}
+		b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel))) // #nosec G304 - fetched above
+		if err != nil {
+			return rp, nil, err
+		}
+		changes, _, _, err := planFile(dir, rel, want, next)
+		if err != nil {
+			return rp, nil, err
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		f := filePlan{Env: env, Path: rel, BlobSHA: gitBlobSHA(b)}
+		for _, c := range changes {
+			f.Changes = append(f.Changes, planChange{Adapter: c.Adapter, Old: c.OldValue, New: c.NewValue})
+		}
+		rp.Files = append(rp.Files, f)
+		all = append(all, changes...)
+	}
+	if err := checkConstraints(cfg, all); err != nil {
+		return rp, nil, err
+	}
+	return rp, all, nil
+}
+
+func cmdPlan() *cobra.Command {
+	var repos, togglePairs []string
+	var reposFile, envsCSV, adaptersCSV, paramFile, out string
+
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Save the adapter flips pending across repositories and environments to a plan file for apply",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoList, err := loadRepos(repos, reposFile)
+			if err != nil {
+				return err
+			}
+			if len(repoList) == 0 {
+				return usageErrorf("--repo ORG/REPO or --repos-file is required")
+			}
+			if envsCSV == "" {
+				return usageErrorf("--env is required (e.g., dev,qa or 'prod-*')")
+			}
+			if err := checkParamFile(paramFile); err != nil {
+				return err
+			}
+			pairs, err := parseTogglePairs(togglePairs)
+			if err != nil {
+				return err
+			}
+
+			plan := changePlan{Version: changePlanVersion, CreatedAt: time.Now().UTC(), File: paramFile, Repos: make([]repoPlan, 0, len(repoList))}
+			for _, repo := range repoList {
+				want, err := adaptersOrStored(adaptersCSV, repo, envsCSV)
+				if err != nil {
+					return err
+				}
+				rp, _, err := planRepo(repo, splitCSV(envsCSV, nil), paramFile, want, pairs)
+				if err != nil {
+					return fmt.Errorf("%s: %w", repo, err)
+				}
+				if len(rp.Files) > 0 {
+					plan.Repos = append(plan.Repos, rp)
+				}
+			}
+
+			changes := plan.changes()
+			if len(changes) == 0 {
+				fmt.Fprintln(stdout(), "No changes. Nothing to plan.")
+				return nil
+			}
+			b, err := json.MarshalIndent(plan, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(out, append(b, '\n'), 0600); err != nil {
+				return fmt.Errorf("write plan: %w", err)
+			}
+			if err := printChangeReport(changes, outTable); err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout(), "\nPlan: %d change(s) in %d repositories saved to %s. Apply it with: gh aca-utils apply %s\n", len(changes), len(plan.Repos), out, out)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&repos, "repo", nil, "Target repo as ORG/REPO (repeat or comma-separate for several)")
+	cmd.Flags().StringVar(&reposFile, "repos-file", "", "File with one ORG/REPO per line (# comments allowed)")
+	cmd.Flags().StringVar(&envsCSV, "env", "", "Comma-separated environments or globs (required)")
+	cmd.Flags().StringVar(&adaptersCSV, "adapters", "", "Comma-separated adapter keys (default: the stored list for each repo)")
+	cmd.Flags().StringVar(&paramFile, "file", "parameters.properties", "Parameters file in each env directory; .json files take JSON pointers as adapters")
+	cmd.Flags().StringSliceVar(&togglePairs, "toggle-pair", nil, "Extra value pair to toggle between as A:B, in addition to 0:1, true:false, on:off, enabled:disabled and yes:no")
+	cmd.Flags().StringVar(&out, "out", "plan.json", "Plan file to write")
+	return cmd
+}
+
+// staleFiles returns the files of r whose content in the repository no
+// longer matches the plan, as repo:path.
+func staleFiles(r repoPlan, contents map[string][]byte) []string {
+	var stale []string
+	for _, f := range r.Files {
+		if gitBlobSHA(contents[f.Path]) != f.BlobSHA {
+			stale = append(stale, r.Repo+":"+f.Path)
+		}
+	}
+	return stale
+}
+
+// applyPlanNext returns the planValues step that makes the planned
+// changes of f.
+func applyPlanNext(f filePlan) func(adapter, v string) (string, bool) {
+	planned := map[string]planChange{}
+	for _, c := range f.Changes {
+		planned[c.Adapter] = c
+	}
+	return func(adapter, v string) (string, bool) {
+		c, ok := planned[adapter]
+		return c.New, ok && c.Old == v
+	}
+}
+
+func cmdApplyPlan() *cobra.Command {
+	var branch string
+	var doPR, yes bool
+
+	cmd := &cobra.Command{
+		Use:   "apply PLAN",
+		Short: "Execute a plan file written by plan, refusing if any planned file changed since",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			planPath := args[0]
+			plan, err := loadChangePlan(planPath)
+			if err != nil {
+				return err
+			}
+			changes := plan.changes()
+			if len(changes) == 0 {
+				fmt.Fprintln(stdout(), "The plan has no changes.")
+				return nil
+			}
+			if branch == "" {
+				branch = "plan/" + branchSlug(strings.TrimSuffix(filepath.Base(planPath), filepath.Ext(planPath))) + "-" + plan.CreatedAt.Format("20060102-150405")
+			}
+
+			// Read everything first: nothing is applied unless every
+			// planned file is still as it was.
+			type target struct {
+				api      *apiCheckout
+				dir      string
+				contents map[string][]byte
+				approved approval
+			}
+			targets := make([]target, len(plan.Repos))
+			var stale []string
+			for i, r := range plan.Repos {
+				api, dir, cleanup, err := openAPIRepo(r.Repo)
+				if err != nil {
+					return fmt.Errorf("%s: %w", r.Repo, err)
+				}
+				defer cleanup()
+				t := target{api: api, dir: dir, contents: map[string][]byte{}}
+				for _, f := range r.Files {
+					if err := api.fetch(dir, f.Path); err != nil {
+						return fmt.Errorf("%s: %w", r.Repo, err)
+					}
+					if t.contents[f.Path], err = os.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Path))); err != nil { // #nosec G304 - path validated by loadChangePlan
+						return err
+					}
+				}
+				stale = append(stale, staleFiles(r, t.contents)...)
+				targets[i] = t
+			}
+			if len(stale) > 0 {
+				return fmt.Errorf("the remote state changed since %s was planned, nothing was applied: %s; run plan again", planPath, strings.Join(stale, ", "))
+			}
+
+			if err := printChangeReport(changes, outTable); err != nil {
+				return err
+			}
+			p := newPrompter()
+			if !yes {
+				if !p.interactive {
+					return fmt.Errorf("pass --yes to apply %s without a terminal", planPath)
+				}
+				ok, err := p.confirm(fmt.Sprintf("Apply %d change(s) to %d repositories? Type 'yes' to confirm:", len(changes), len(plan.Repos)), "yes")
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return fmt.Errorf("apply cancelled")
+				}
+			}
+			for i, r := range plan.Repos {
+				var envs []string
+				for _, f := range r.Files {
+					envs = append(envs, f.Env)
+				}
+				cfg := loadConfig(targets[i].dir)
+				patterns, _ := cfg["protect.environments"].Value.([]string)
+				typeName, _ := cfg["protect.typeName"].Value.(bool)
+				if err := confirmProtected(r.Repo, protectedEnvs(envs, patterns), typeName, yes, p); err != nil {
+					return err
+				}
+				if targets[i].approved, err = requireApproval(r.Repo, protectedEnvs(envs, patterns), cfg); err != nil {
+					return err
+				}
+				access, err := preflight(r.Repo, false)
+				if err != nil {
+					return err
+				}
+				if access.fork {
+					return fmt.Errorf("apply needs push access to %s", r.Repo)
+				}
+			}
+
+			for i, r := range plan.Repos {
+				t := targets[i]
+				files := map[string][]byte{}
+				var envs, adapters []string
+				entry := journalEntry{Command: "flip", Repo: r.Repo, File: plan.File, Branch: branch, Approver: t.approved.By, ApprovalRun: t.approved.Run}
+				for _, f := range r.Files {
+					repoChanges, _, after, err := planFile(t.dir, f.Path, adapterNames(f.Changes), applyPlanNext(f))
+					if err != nil {
+						return err
+					}
+					files[f.Path] = []byte(after)
+					envs = append(envs, f.Env)
+					for _, c := range repoChanges {
+						adapters = appendUnique(adapters, c.Adapter)
+						entry.Changes = append(entry.Changes, journalChange{Env: f.Env, Adapter: c.Adapter, Old: c.OldValue, New: c.NewValue})
+					}
+				}
+				msg := fmt.Sprintf("chore(env:%s): flip adapters %s", strings.Join(envs, ","), strings.Join(adapters, ","))
+				if _, err := t.api.commit(branch, msg, files, "", branchFail); err != nil {
+					return fmt.Errorf("%s: %w", r.Repo, err)
+				}
+				if doPR {
+					title := fmt.Sprintf("Flip adapters in %s: %s", strings.Join(envs, ", "), strings.Join(adapters, ", "))
+					body := fmt.Sprintf("Automated via gh aca-utils apply of plan `%s`, created %s.", filepath.Base(planPath), plan.CreatedAt.Format(time.RFC3339))
+					paths := make([]string, 0, len(files))
+					for _, f := range r.Files {
+						paths = append(paths, f.Path)
+					}
+					opts := prOptions{reviewers: codeownersReviewers(t.api.read, paths, ghLogin)}
+					prURL, err := t.api.openPR(branch, title, body, opts)
+					if err != nil {
+						return fmt.Errorf("%s: %w", r.Repo, err)
+					}
+					entry.PRURL = prURL
+					fmt.Fprintln(stdout(), prURL)
+				}
+				recordJournal(entry)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&branch, "branch", "", "Branch to create in each repository (default: plan/<plan file name>-<plan time>)")
+	cmd.Flags().BoolVar(&doPR, "pr", true, "Open a pull request in each repository (--pr=false only pushes the branches)")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Apply without asking, including to protected environments")
+	cmd.Flags().StringVar(&approvalRun, "approval-run", "", "Workflow run whose approved deployment review approves protected environments (protect.approval: environment)")
+	return cmd
+}
+
+// adapterNames returns the adapters of changes, in order.
+func adapterNames(changes []planChange) []string {
+	names := make([]string, len(changes))
+	for i, c := range changes {
+		names[i] = c.Adapter
+	}
+	return names
+}
+
+// appendUnique appends s to list unless it is already there.
+func appendUnique(list []string, s string) []string {
+	for _, have := range list {
+		if have == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
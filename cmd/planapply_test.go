@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitBlobSHA(t *testing.T) {
+	tests := []struct {
+		content, want string
+	}{
+		{"", "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"},
+		{"hello\n", "ce013625030ba8dba906f756967f9e9ca394464a"},
+	}
+	for _, tt := range tests {
+		if got := gitBlobSHA([]byte(tt.content)); got != tt.want {
+			t.Errorf("gitBlobSHA(%q) = %s, want %s", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestPlanWritesPlanFile(t *testing.T) {
+	fakeGH(t)
+	t.Setenv("ACA_CONFIG_DIR", t.TempDir())
+	var out bytes.Buffer
+	resultOutput = &out
+	defer func() { resultOutput = nil }()
+
+	file := filepath.Join(t.TempDir(), "plan.json")
+	cmd := cmdPlan()
+	cmd.SetArgs([]string{"--repo", "org/svc", "--env", "dev", "--adapters", "billing", "--out", file})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := loadChangePlan(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Repos) != 1 || plan.Repos[0].Base != "base123" || len(plan.Repos[0].Files) != 1 {
+		t.Fatalf("plan = %+v", plan)
+	}
+	f := plan.Repos[0].Files[0]
+	if f.Env != "dev" || f.Path != "env/dev/parameters.properties" || f.BlobSHA != gitBlobSHA([]byte("billing=0\n")) {
+		t.Errorf("file = %+v", f)
+	}
+	if len(f.Changes) != 1 || f.Changes[0] != (planChange{Adapter: "billing", Old: "0", New: "1"}) {
+		t.Errorf("changes = %+v", f.Changes)
+	}
+	if !strings.Contains(out.String(), "apply "+file) {
+		t.Errorf("output has no apply hint:\n%s", out.String())
+	}
+}
+
+func TestStaleFiles(t *testing.T) {
+	r := repoPlan{Repo: "org/svc", Files: []filePlan{
+		{Env: "dev", Path: "env/dev/parameters.properties", BlobSHA: gitBlobSHA([]byte("billing=0\n"))},
+		{Env: "qa", Path: "env/qa/parameters.properties", BlobSHA: gitBlobSHA([]byte("billing=0\n"))},
+	}}
+	tests := []struct {
+		name     string
+		contents map[string][]byte
+		want     string
+	}{
+		{"unchanged", map[string][]byte{"env/dev/parameters.properties": []byte("billing=0\n"), "env/qa/parameters.properties": []byte("billing=0\n")}, ""},
+		{"one changed", map[string][]byte{"env/dev/parameters.properties": []byte("billing=1\n"), "env/qa/parameters.properties": []byte("billing=0\n")}, "org/svc:env/dev/parameters.properties"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(staleFiles(r, tt.contents), ","); got != tt.want {
+				t.Errorf("staleFiles = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadChangePlanRejects(t *testing.T) {
+	tests := []struct {
+		name, doc, want string
+	}{
+		{"version", `{"version":2}`, "unsupported plan version"},
+		{"path outside env", `{"version":1,"file":"parameters.properties","repos":[{"repo":"org/svc","files":[{"env":"dev","path":".github/workflows/ci.yml"}]}]}`, "invalid file"},
+		{"env pattern", `{"version":1,"file":"parameters.properties","repos":[{"repo":"org/svc","files":[{"env":"*","path":"env/*/parameters.properties"}]}]}`, "invalid file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "plan.json")
+			if err := os.WriteFile(file, []byte(tt.doc), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadChangePlan(file); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPlanNext(t *testing.T) {
+	next := applyPlanNext(filePlan{Changes: []planChange{{Adapter: "billing", Old: "0", New: "1"}}})
+	if v, ok := next("billing", "0"); !ok || v != "1" {
+		t.Errorf("next(billing, 0) = %q, %v", v, ok)
+	}
+	if _, ok := next("billing", "1"); ok {
+		t.Error("next changed a value that no longer matches the plan")
+	}
+	if _, ok := next("audit", "0"); ok {
+		t.Error("next changed an adapter that isn't in the plan")
+	}
+}
@@ -0,0 +1,314 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy rule names used in violation reports.
+const (
+	ruleForbiddenCIDR  = "forbidden-cidr"
+	ruleForbiddenLabel = "forbidden-label"
+	ruleRequiredPort   = "required-port"
+)
+
+// scanPolicy is the rules file read by `aca check --policy`.
+type scanPolicy struct {
+	ForbiddenCIDRs  []policyCIDR      `yaml:"forbidden_cidrs"`
+	ForbiddenLabels []string          `yaml:"forbidden_labels"`
+	RequiredPorts   []policyPort      `yaml:"required_ports"`
+	Exceptions      []policyException `yaml:"exceptions"`
+}
+
+// policyCIDR is a range no finding may fall into. It may be written as a
+// plain string or as a mapping with a reason.
+type policyCIDR struct {
+	CIDR   string `yaml:"cidr"`
+	Reason string `yaml:"reason"`
+	prefix netip.Prefix
+}
+
+func (c *policyCIDR) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		c.CIDR = n.Value
+		return nil
+	}
+	type plain policyCIDR
+	return n.Decode((*plain)(c))
+}
+
+// policyPort is a port that must be configured in at least one file
+// matching Path (any file if empty).
+type policyPort struct {
+	Port int    `yaml:"port"`
+	Path string `yaml:"path"`
+}
+
+// policyException exempts findings in files matching Path from the
+// forbidden rules, optionally only for addresses within CIDR.
+type policyException struct {
+	Path   string `yaml:"path"`
+	CIDR   string `yaml:"cidr"`
+	Reason string `yaml:"reason"`
+	prefix netip.Prefix
+}
+
+type policyViolation struct {
+	Rule     string `json:"rule"`
+	Subject  string `json:"subject"`
+	Location string `json:"location,omitempty"`
+	Message  string `json:"message"`
+}
+
+// parsePolicy decodes a policy file, rejecting unknown keys so typos
+// don't silently disable a rule.
+func parsePolicy(data []byte) (*scanPolicy, error) {
+	var p scanPolicy
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	for i := range p.ForbiddenCIDRs {
+		c := &p.ForbiddenCIDRs[i]
+		prefix, err := parsePolicyCIDR(c.CIDR)
+		if err != nil {
+			return nil, fmt.Errorf("forbidden_cidrs[%d]: %w", i, err)
+		}
+		c.prefix = prefix
+	}
+	for i, rp := range p.RequiredPorts {
+		if rp.Port < 1 || rp.Port > 65535 {
+			return nil, fmt.Errorf("required_ports[%d]: port must be a number between 1 and 65535", i)
+		}
+		if rp.Path != "" && !doublestar.ValidatePattern(rp.Path) {
+			return nil, fmt.Errorf("required_ports[%d]: invalid path pattern %q", i, rp.Path)
+		}
+	}
+	for i := range p.Exceptions {
+		e := &p.Exceptions[i]
+		if e.Path == "" && e.CIDR == "" {
+			return nil, fmt.Errorf("exceptions[%d]: path or cidr is required", i)
+		}
+		if e.Path != "" && !doublestar.ValidatePattern(e.Path) {
+			return nil, fmt.Errorf("exceptions[%d]: invalid path pattern %q", i, e.Path)
+		}
+		if e.CIDR != "" {
+			prefix, err := parsePolicyCIDR(e.CIDR)
+			if err != nil {
+				return nil, fmt.Errorf("exceptions[%d]: %w", i, err)
+			}
+			e.prefix = prefix
+		}
+	}
+	return &p, nil
+}
+
+// parsePolicyCIDR accepts a prefix or a single address.
+func parsePolicyCIDR(s string) (netip.Prefix, error) {
+	if a, err := netip.ParseAddr(s); err == nil {
+		return netip.PrefixFrom(a.Unmap(), a.Unmap().BitLen()), nil
+	}
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid cidr %q", s)
+	}
+	return p.Masked(), nil
+}
+
+func loadPolicy(file string) (*scanPolicy, error) {
+	data, err := os.ReadFile(file) // #nosec G304 - user-supplied policy file
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	p, err := parsePolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return p, nil
+}
+
+func (p *scanPolicy) excepted(r matchRow) bool {
+	for _, e := range p.Exceptions {
+		if e.Path != "" {
+			if ok, _ := doublestar.Match(e.Path, r.RelPath); !ok {
+				continue
+			}
+		}
+		if e.prefix.IsValid() {
+			if addr, ok := rowAddr(r); !ok || !e.prefix.Contains(addr) {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// evaluate checks classified findings against the policy and returns the
+// violations in finding order, followed by missing required ports.
+func (p *scanPolicy) evaluate(rows []matchRow) []policyViolation {
+	var out []policyViolation
+	for _, r := range rows {
+		if p.excepted(r) {
+			continue
+		}
+		loc := r.location() + ":" + strconv.Itoa(r.LineNumber)
+		if addr, ok := rowAddr(r); ok {
+			for _, c := range p.ForbiddenCIDRs {
+				if c.prefix.Contains(addr) {
+					msg := fmt.Sprintf("%s is in forbidden range %s", addr, c.CIDR)
+					if c.Reason != "" {
+						msg += ": " + c.Reason
+					}
+					out = append(out, policyViolation{Rule: ruleForbiddenCIDR, Subject: r.IPValue, Location: loc, Message: msg})
+					break
+				}
+			}
+		}
+		for _, l := range p.ForbiddenLabels {
+			if containsLabel(r.Severity, l) {
+				subject := r.IPValue
+				if subject == "" {
+					subject = r.PortValue
+				}
+				out = append(out, policyViolation{Rule: ruleForbiddenLabel, Subject: subject, Location: loc,
+					Message: fmt.Sprintf("finding is labelled %s", l)})
+				break
+			}
+		}
+	}
+	for _, rp := range p.RequiredPorts {
+		if !portPresent(rows, rp) {
+			msg := fmt.Sprintf("port %d is not configured", rp.Port)
+			if rp.Path != "" {
+				msg += " in " + rp.Path
+			}
+			out = append(out, policyViolation{Rule: ruleRequiredPort, Subject: strconv.Itoa(rp.Port), Message: msg})
+		}
+	}
+	return out
+}
+
+func portPresent(rows []matchRow, rp policyPort) bool {
+	want := strconv.Itoa(rp.Port)
+	for _, r := range rows {
+		if stripQuotes(r.PortValue) != want {
+			continue
+		}
+		if rp.Path == "" {
+			return true
+		}
+		if ok, _ := doublestar.Match(rp.Path, r.RelPath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func cmdCheck() *cobra.Command {
+	var repo, ref, policyFile, includes, excludes, mode, classifyRulesFile string
+	var strictIP, noCache bool
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Evaluate ip-port findings against a policy file and report violations",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if repo == "" {
+				return usageErrorf("--repo ORG/REPO is required")
+			}
+			modeVal := parseMode(mode, outTable)
+			var policy *scanPolicy
+			var err error
+			if policyFile != "" {
+				policy, err = loadPolicy(policyFile)
+			} else {
+				var source string
+				if policy, source, err = repoPolicy(repo); policy == nil && err == nil {
+					return usageErrorf("--policy FILE is required: %s sets no policy.file in its .aca.yaml", repo)
+				}
+				debugf("using policy %s", source)
+			}
+			if err != nil {
+				return err
+			}
+			rules, err := loadClassifyRules(classifyRulesFile)
+			if err != nil {
+				return err
+			}
+
+			rows, err := scanRef(repo, ref, scanOptions{
+				includes: splitCSV(includes, []string{"**/*"}),
+				excludes: splitCSV(excludes, []string{"**/.git/**", "**/node_modules/**"}),
+				strictIP: strictIP,
+				cacheDir: defaultScanCacheDir(),
+				noCache:  noCache,
+				flagged:  scanFlagsGiven(cmd),
+			})
+			if err != nil {
+				return err
+			}
+			classifyRows(rows, rules)
+
+			violations := policy.evaluate(rows)
+			if err := printPolicyReport(violations, len(rows), modeVal); err != nil {
+				return err
+			}
+			if len(violations) > 0 {
+				cmd.SilenceUsage = true
+				return &findingsError{fmt.Sprintf("policy check failed with %d violation(s)", len(violations))}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Target repo as ORG/REPO")
+	cmd.Flags().StringVar(&ref, "ref", "", "Branch or tag (default: default branch)")
+	cmd.Flags().StringVar(&policyFile, "policy", "", "Policy YAML file (forbidden_cidrs, forbidden_labels, required_ports, exceptions; default: policy.file in the repo's .aca.yaml)")
+	cmd.Flags().StringVar(&classifyRulesFile, "classify-rules", "", "Extra severity labelling rules, as for ip-port")
+	cmd.Flags().StringVar(&includes, "include", defaultIncludes, "Comma-separated glob patterns to include")
+	cmd.Flags().StringVar(&excludes, "exclude", defaultExcludes, "Comma-separated glob patterns to exclude")
+	cmd.Flags().BoolVar(&strictIP, "strict-ip", false, "Only report IPs that are whole values or appear in a network context")
+	cmd.Flags().BoolVar(&noCache, "no-cache", false, "Rescan even if results for this commit are cached")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table|json")
+	setOutputModes(cmd, "table", "json")
+	return cmd
+}
+
+func printPolicyReport(violations []policyViolation, findings int, mode outputMode) error {
+	if mode == outJSON {
+		if violations == nil {
+			violations = []policyViolation{}
+		}
+		enc := json.NewEncoder(stdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Passed     bool              `json:"passed"`
+			Findings   int               `json:"findings"`
+			Violations []policyViolation `json:"violations"`
+		}{len(violations) == 0, findings, violations})
+	}
+
+	if len(violations) == 0 {
+		fmt.Fprintf(stdout(), "PASS: %d finding(s), no policy violations.\n", findings)
+		return nil
+	}
+	w := newTable()
+	w.AddRow("Rule", "Subject", "Location", "Message")
+	for _, v := range violations {
+		w.AddRow(v.Rule, v.Subject, v.Location, v.Message)
+	}
+	w.Render()
+	fmt.Fprintf(stdout(), "\nFAIL: %d violation(s) in %d finding(s).\n", len(violations), findings)
+	return nil
+}
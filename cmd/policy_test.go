@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+const testPolicy = `
+forbidden_cidrs:
+  - 0.0.0.0/0
+  - cidr: 10.66.0.0/16
+    reason: decommissioned DC
+forbidden_labels: [privileged-port]
+required_ports:
+  - port: 443
+    path: "deploy/**"
+  - port: 8080
+exceptions:
+  - path: "test/**"
+  - cidr: 8.8.8.8
+    reason: public resolver
+`
+
+func TestParsePolicy(t *testing.T) {
+	p, err := parsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("parsePolicy failed: %v", err)
+	}
+	if len(p.ForbiddenCIDRs) != 2 || p.ForbiddenCIDRs[1].Reason != "decommissioned DC" || !p.ForbiddenCIDRs[0].prefix.IsValid() {
+		t.Errorf("Unexpected forbidden CIDRs: %+v", p.ForbiddenCIDRs)
+	}
+	if p.Exceptions[1].prefix.Bits() != 32 {
+		t.Errorf("Expected a single address exception, got %v", p.Exceptions[1].prefix)
+	}
+
+	if p, err := parsePolicy(nil); err != nil || len(p.ForbiddenCIDRs) != 0 {
+		t.Errorf("Expected an empty policy, got %+v, %v", p, err)
+	}
+	for _, bad := range []string{
+		"forbiden_cidrs: [10.0.0.0/8]",
+		"forbidden_cidrs: [bogus]",
+		"required_ports: [{port: 0}]",
+		"exceptions: [{reason: x}]",
+		"exceptions: [{path: '['}]",
+	} {
+		if _, err := parsePolicy([]byte(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestEvaluatePolicy(t *testing.T) {
+	p, err := parsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []matchRow{
+		{IPValue: "10.66.1.2", RelPath: "app.env", LineNumber: 3},
+		{IPValue: "10.66.1.2", RelPath: "test/app.env", LineNumber: 1},
+		{IPValue: "8.8.8.8", RelPath: "app.env", LineNumber: 4},
+		{PortValue: "22", RelPath: "app.env", LineNumber: 5, Severity: []string{"privileged-port"}},
+		{PortValue: "443", RelPath: "other/app.env", LineNumber: 1},
+		{PortValue: "\"8080\"", RelPath: "app.env", LineNumber: 6},
+	}
+	var got []string
+	for _, v := range p.evaluate(rows) {
+		got = append(got, v.Rule+" "+v.Subject+" "+v.Location)
+	}
+	want := []string{
+		"forbidden-cidr 10.66.1.2 app.env:3",
+		"forbidden-label 22 app.env:5",
+		"required-port 443 ",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Violations =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
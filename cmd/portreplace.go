@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// portValueRe matches a port at the start of a value, optionally quoted and
+// followed by a comment or more text.
+var portValueRe = regexp.MustCompile(`^["']?(\d{1,5})["']?(?:\s|$|#|;|,)`)
+
+// portRewriter changes the port assigned to keys matching keyGlob. If from
+// is non-zero only that value is replaced.
+func portRewriter(keyGlob string, from, to int) lineRewriter {
+	return func(_, line string) (string, string, string, bool) {
+		m := kvRe.FindStringSubmatchIndex(line)
+		if m == nil || !keyMatches(keyGlob, line[m[2]:m[3]]) {
+			return line, "", "", false
+		}
+		value := line[m[4]:]
+		v := portValueRe.FindStringSubmatchIndex(value)
+		if v == nil {
+			return line, "", "", false
+		}
+		old := value[v[2]:v[3]]
+		n, err := strconv.Atoi(old)
+		if err != nil || n < 1 || n > 65535 || (from != 0 && n != from) || n == to {
+			return line, "", "", false
+		}
+		start := m[4] + v[2]
+		return line[:start] + strconv.Itoa(to) + line[m[4]+v[3]:], old, strconv.Itoa(to), true
+	}
+}
+
+func cmdPortReplace() *cobra.Command {
+	var repo, key, fromStr, toStr, branch, includes, excludes, mode string
+	var doCommit, doPR, dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "port-replace",
+		Short: "Change the port assigned to a key across a repository and optionally open a PR",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if repo == "" {
+				return usageErrorf("--repo ORG/REPO is required")
+			}
+			if key == "" {
+				return usageErrorf("--key is required (e.g. server.port or '*_PORT')")
+			}
+			if _, err := path.Match(key, ""); err != nil {
+				return usageErrorf("invalid --key pattern %q: %w", key, err)
+			}
+			to, err := parsePort(toStr)
+			if err != nil {
+				return usageErrorf("invalid --to: %w", err)
+			}
+			from := 0
+			if fromStr != "" {
+				if from, err = parsePort(fromStr); err != nil {
+					return usageErrorf("invalid --from: %w", err)
+				}
+			}
+			modeVal := parseMode(mode, outTable)
+			doCommit = doCommit || doPR
+			var access pushAccess
+			if doCommit && !dryRun {
+				if access, err = preflight(repo, false); err != nil {
+					return err
+				}
+			}
+
+			tmpDir, cleanup, err := cloneOrDownload(repo, "")
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			files := listFiles(tmpDir, splitCSV(includes, []string{"**/*"}), splitCSV(excludes, []string{"**/.git/**"}))
+			edits, err := planRewrites(tmpDir, files, portRewriter(key, from, to))
+			if err != nil {
+				return err
+			}
+			if dryRun || len(edits) == 0 {
+				return printRewrites(edits, modeVal)
+			}
+
+			changed, err := applyRewrites(tmpDir, edits)
+			if err != nil {
+				return err
+			}
+			if err := printRewrites(edits, modeVal); err != nil {
+				return err
+			}
+			if !doCommit {
+				return nil
+			}
+			if branch == "" {
+				branch = fmt.Sprintf("replace/port-%d", to)
+			}
+			summary := fmt.Sprintf("%s → %d", key, to)
+			if from != 0 {
+				summary = fmt.Sprintf("%s %d → %d", key, from, to)
+			}
+			msg := "chore: change port " + summary
+			body := fmt.Sprintf("Automated via gh aca-utils port-replace.\n\nChanged %d line(s) in %d file(s).", len(edits), len(changed))
+			if err := access.prepare(tmpDir); err != nil {
+				return err
+			}
+			_, err = commitRewrites(tmpDir, branch, changed, msg, "Change port: "+summary, body, doPR)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Target repo as ORG/REPO (required)")
+	cmd.Flags().StringVar(&key, "key", "", "Key whose port to change; a case-insensitive glob such as server.port or '*_PORT' (required)")
+	cmd.Flags().StringVar(&fromStr, "from", "", "Only change this current port value (default: any)")
+	cmd.Flags().StringVar(&toStr, "to", "", "New port value (required)")
+	cmd.Flags().StringVar(&includes, "include", defaultIncludes, "Comma-separated glob patterns to include")
+	cmd.Flags().StringVar(&excludes, "exclude", defaultExcludes, "Comma-separated glob patterns to exclude")
+	cmd.Flags().StringVar(&branch, "branch", "", "Branch name to create (with --commit, default replace/port-<TO>)")
+	cmd.Flags().BoolVar(&doCommit, "commit", false, "Commit the change to a new branch and push")
+	cmd.Flags().BoolVar(&doPR, "pr", false, "Create a pull request (implies --commit)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", true, "Show a diff of the planned changes without writing")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table (diff)|json")
+	setOutputModes(cmd, "table", "json")
+	return cmd
+}
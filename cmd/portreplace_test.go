@@ -0,0 +1,26 @@
+package cmd
+
+import "testing"
+
+func TestPortRewriter(t *testing.T) {
+	tests := []struct {
+		key      string
+		from, to int
+		line     string
+		want     string
+	}{
+		{"server.port", 0, 9090, "server.port=8080", "server.port=9090"},
+		{"server.port", 8080, 9090, "server.port = \"8080\" # http", "server.port = \"9090\" # http"},
+		{"server.port", 8081, 9090, "server.port=8080", "server.port=8080"},
+		{"*_PORT", 0, 9090, "  db_port: 5432", "  db_port: 9090"},
+		{"*_PORT", 0, 9090, "DB_HOST=10.0.0.5", "DB_HOST=10.0.0.5"},
+		{"port", 0, 9090, "port: ${PORT}", "port: ${PORT}"},
+		{"port", 0, 9090, "port: 80801", "port: 80801"},
+	}
+	for _, tt := range tests {
+		got, _, _, ok := portRewriter(tt.key, tt.from, tt.to)("a.yml", tt.line)
+		if got != tt.want || ok != (tt.want != tt.line) {
+			t.Errorf("portRewriter(%q, %d, %d)(%q) = %q, %t; want %q", tt.key, tt.from, tt.to, tt.line, got, ok, tt.want)
+		}
+	}
+}
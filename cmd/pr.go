@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// prOptions routes the pull request opened with --pr.
+type prOptions struct {
+	labels    []string
+	reviewers []string // users, or ORG/TEAM for teams
+	assignees []string
+	draft     bool
+	milestone string // title
+}
+
+// createArgs returns the extra `gh pr create` flags.
+func (o prOptions) createArgs() []string {
+	var args []string
+	for _, l := range o.labels {
+		args = append(args, "--label", l)
+	}
+	for _, r := range o.reviewers {
+		args = append(args, "--reviewer", r)
+	}
+	for _, a := range o.assignees {
+		args = append(args, "--assignee", a)
+	}
+	if o.draft {
+		args = append(args, "--draft")
+	}
+	if o.milestone != "" {
+		args = append(args, "--milestone", o.milestone)
+	}
+	return args
+}
+
+// splitReviewers separates user logins from team slugs given as ORG/TEAM.
+func splitReviewers(reviewers []string) (users, teams []string) {
+	for _, r := range reviewers {
+		if _, team, ok := strings.Cut(r, "/"); ok {
+			teams = append(teams, team)
+		} else {
+			users = append(users, r)
+		}
+	}
+	return users, teams
+}
+
+// applyAPI sets labels, assignees, milestone and reviewers on pull request
+// number of repo through the REST API, for PRs opened without `gh pr
+// create`. Drafts are requested when the PR is created.
+func (o prOptions) applyAPI(repo string, number int) error {
+	issue := map[string]any{}
+	if len(o.labels) > 0 {
+		issue["labels"] = o.labels
+	}
+	if len(o.assignees) > 0 {
+		issue["assignees"] = o.assignees
+	}
+	if o.milestone != "" {
+		var milestones []struct {
+			Number int    `json:"number"`
+			Title  string `json:"title"`
+		}
+		if err := ghAPI("GET", fmt.Sprintf("repos/%s/milestones?state=open&per_page=100", repo), nil, &milestones); err != nil {
+			return err
+		}
+		for _, m := range milestones {
+			if m.Title == o.milestone {
+				issue["milestone"] = m.Number
+			}
+		}
+		if issue["milestone"] == nil {
+			return fmt.Errorf("no open milestone %q in %s", o.milestone, repo)
+		}
+	}
+	if len(issue) > 0 {
+		if err := ghAPI("PATCH", fmt.Sprintf("repos/%s/issues/%d", repo, number), issue, nil); err != nil {
+			return fmt.Errorf("update pull request #%d: %w", number, err)
+		}
+	}
+	if len(o.reviewers) > 0 {
+		users, teams := splitReviewers(o.reviewers)
+		req := map[string][]string{"reviewers": users, "team_reviewers": teams}
+		if err := ghAPI("POST", fmt.Sprintf("repos/%s/pulls/%d/requested_reviewers", repo, number), req, nil); err != nil {
+			return fmt.Errorf("request reviewers: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPROptionsCreateArgs(t *testing.T) {
+	opts := prOptions{labels: []string{"flags", "prod"}, reviewers: []string{"org/platform"}, assignees: []string{"bob"}, draft: true, milestone: "Q3"}
+	got := strings.Join(opts.createArgs(), " ")
+	if got != "--label flags --label prod --reviewer org/platform --assignee bob --draft --milestone Q3" {
+		t.Errorf("createArgs = %s", got)
+	}
+	if args := (prOptions{}).createArgs(); len(args) != 0 {
+		t.Errorf("createArgs = %v, want none", args)
+	}
+}
+
+func TestSplitReviewers(t *testing.T) {
+	users, teams := splitReviewers([]string{"alice", "org/platform", "bob"})
+	if strings.Join(users, ",") != "alice,bob" || strings.Join(teams, ",") != "platform" {
+		t.Errorf("splitReviewers = %v, %v", users, teams)
+	}
+}
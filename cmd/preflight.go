@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// repoAccess is the part of GET /repos/{repo} the preflight needs.
+type repoAccess struct {
+	Private     bool `json:"private"`
+	Permissions struct {
+		Push bool `json:"push"`
+	} `json:"permissions"`
+}
+
+// tokenScopes returns the OAuth scopes of the user's token. known is false
+// for tokens that don't report scopes, such as fine-grained tokens and
+// GITHUB_TOKEN in Actions, whose permissions are only checked by the push.
+func tokenScopes() (scopes []string, known bool, err error) {
+	resp, err := apiRequest("", "GET", "user", nil)
+	if err != nil {
+		return nil, false, err
+	}
+	_ = resp.Body.Close()
+	if _, ok := resp.Header["X-Oauth-Scopes"]; !ok {
+		return nil, false, nil
+	}
+	for _, s := range strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, true, nil
+}
+
+// missingScopes returns the scopes a token with have lacks to push to a
+// repository; workflow is needed to write under .github/workflows.
+func missingScopes(have []string, private, workflow bool) []string {
+	has := map[string]bool{}
+	for _, s := range have {
+		has[s] = true
+	}
+	var missing []string
+	if !has["repo"] && (private || !has["public_repo"]) {
+		missing = append(missing, "repo")
+	}
+	if workflow && !has["workflow"] {
+		missing = append(missing, "workflow")
+	}
+	return missing
+}
+
+// noFork is the global --no-fork flag.
+var noFork bool
+
+// pushAccess is how a run publishes its branch to a repository: directly,
+// or, for users without push access, through their fork of it. The zero
+// value pushes directly.
+type pushAccess struct {
+	repo string
+	fork bool
+}
+
+// preflight checks, before anything is cloned or changed, that the
+// authenticated user can push a branch to repo, or else fork it, so a run
+// fails fast with what to do about it instead of at the final git push.
+func preflight(repo string, workflow bool) (pushAccess, error) {
+	var r repoAccess
+	if err := ghAPI("GET", "repos/"+repo, nil, &r); err != nil {
+		if isNotFound(err) {
+			return pushAccess{}, withExitCode(exitNotFound, fmt.Errorf("preflight: %s does not exist or is not visible to the account gh is logged in with (check `gh auth status`)", repo))
+		}
+		return pushAccess{}, fmt.Errorf("preflight: %w", err)
+	}
+	access := pushAccess{repo: repo, fork: !r.Permissions.Push}
+	if access.fork && noFork {
+		return pushAccess{}, withExitCode(exitAuth, fmt.Errorf("preflight: you don't have push access to %s; ask its maintainers for write access, or run without --no-fork to open the pull request from your fork", repo))
+	}
+	scopes, known, err := tokenScopes()
+	if err != nil {
+		warnf("preflight: cannot read token scopes: %v", err)
+		return access, nil
+	}
+	if !known {
+		return access, nil
+	}
+	if missing := missingScopes(scopes, r.Private, workflow); len(missing) > 0 {
+		return pushAccess{}, withExitCode(exitAuth, fmt.Errorf("preflight: gh's token lacks the %s scope(s) needed to push to %s; run `gh auth refresh -s %s`", strings.Join(missing, ", "), repo, strings.Join(missing, ",")))
+	}
+	return access, nil
+}
+
+// prepare readies the clone at dir for pushing. For a fork it forks the
+// repository (or finds the existing fork) and makes the fork the clone's
+// origin and the repository its upstream, so the branch is pushed to the
+// fork and createPR opens a cross-repository pull request, as `gh pr
+// create` does when run by hand.
+func (a pushAccess) prepare(dir string) error {
+	if !a.fork {
+		return nil
+	}
+	infof("You don't have push access to %s; pushing to your fork instead", a.repo)
+	if err := forkRemote(dir, a.repo); err != nil {
+		return fmt.Errorf("fork %s: %w", a.repo, err)
+	}
+	return nil
+}
+
+// headOwner returns the owner of the branch a pull request is opened from:
+// the repository's owner, or the authenticated user for a fork.
+func (a pushAccess) headOwner(repo string) string {
+	if a.fork {
+		if login := ghLogin(); login != "" {
+			return login
+		}
+	}
+	owner, _, _ := strings.Cut(repo, "/")
+	return owner
+}
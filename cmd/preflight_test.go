@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestMissingScopes(t *testing.T) {
+	tests := []struct {
+		name              string
+		have              []string
+		private, workflow bool
+		want              string
+	}{
+		{"repo scope", []string{"repo", "read:org"}, true, false, ""},
+		{"public_repo on a public repo", []string{"public_repo"}, false, false, ""},
+		{"public_repo on a private repo", []string{"public_repo"}, true, false, "repo"},
+		{"no scopes", nil, false, false, "repo"},
+		{"workflow missing", []string{"repo"}, false, true, "workflow"},
+		{"workflow present", []string{"repo", "workflow"}, true, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(missingScopes(tt.have, tt.private, tt.workflow), ","); got != tt.want {
+				t.Errorf("missingScopes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	fakeGitHub(t, func(_ *http.Request, call string) (int, string) {
+		switch call {
+		case "GET user":
+			return 200, `{}`
+		case "GET repos/org/public":
+			return 200, `{"private":false,"permissions":{"push":true}}`
+		case "GET repos/org/private":
+			return 200, `{"private":true,"permissions":{"push":true}}`
+		case "GET repos/org/readonly":
+			return 200, `{"private":false,"permissions":{"push":false}}`
+		}
+		return 404, ""
+	})
+	apiTransport = scopesTransport{apiTransport, "public_repo, read:org"}
+
+	tests := []struct {
+		repo     string
+		workflow bool
+		fork     bool
+		want     string // substring of the error; "" for success
+	}{
+		{"org/public", false, false, ""},
+		{"org/public", true, false, "gh auth refresh -s workflow"},
+		{"org/private", false, false, "lacks the repo scope"},
+		{"org/readonly", false, true, ""},
+		{"org/missing", false, false, "does not exist"},
+	}
+	for _, tt := range tests {
+		access, err := preflight(tt.repo, tt.workflow)
+		if tt.want == "" {
+			if err != nil || access.fork != tt.fork {
+				t.Errorf("preflight(%s) = %+v, %v; want fork %t", tt.repo, access, err, tt.fork)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("preflight(%s, %t) = %v, want %q", tt.repo, tt.workflow, err, tt.want)
+		}
+	}
+
+	noFork = true
+	defer func() { noFork = false }()
+	if _, err := preflight("org/readonly", false); err == nil || !strings.Contains(err.Error(), "--no-fork") {
+		t.Errorf("preflight with --no-fork = %v", err)
+	}
+}
+
+// scopesTransport reports scopes as the OAuth scopes of the token.
+type scopesTransport struct {
+	http.RoundTripper
+	scopes string
+}
+
+func (st scopesTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := st.RoundTripper.RoundTrip(r)
+	if err == nil && r.URL.Path == "/user" {
+		resp.Header.Set("X-OAuth-Scopes", st.scopes)
+	}
+	return resp, err
+}
+
+func TestPushAccessPrepare(t *testing.T) {
+	log := fakeGitHub(t, func(_ *http.Request, call string) (int, string) {
+		switch call {
+		case "GET user":
+			return 200, `{"login":"octocat"}`
+		case "POST repos/org/svc/forks":
+			return 202, `{"full_name":"octocat/svc"}`
+		}
+		return 404, ""
+	})
+	t.Setenv("GH_HOST", "github.com")
+	t.Setenv("GITHUB_SERVER_URL", "")
+
+	var direct pushAccess
+	if err := direct.prepare(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if owner := direct.headOwner("org/svc"); owner != "org" {
+		t.Errorf("headOwner = %q, want org", owner)
+	}
+	if _, err := os.Stat(log); err == nil {
+		t.Error("pushing directly must not call the API")
+	}
+
+	dir := initTestRepo(t, map[string]map[string]string{"main": {"README.md": "hi\n"}}, "main")
+	if out, err := exec.Command("git", "-C", dir, "remote", "add", "origin", "https://github.com/org/svc.git").CombinedOutput(); err != nil {
+		t.Fatalf("remote add: %v\n%s", err, out)
+	}
+	fork := pushAccess{repo: "org/svc", fork: true}
+	if err := fork.prepare(dir); err != nil {
+		t.Fatal(err)
+	}
+	if owner := fork.headOwner("org/svc"); owner != "octocat" {
+		t.Errorf("headOwner = %q, want octocat", owner)
+	}
+	if origin, upstream := remoteRepo(dir, "origin"), remoteRepo(dir, "upstream"); origin != "octocat/svc" || upstream != "org/svc" {
+		t.Errorf("origin = %q, upstream = %q", origin, upstream)
+	}
+	b, _ := os.ReadFile(log)
+	want := "POST repos/org/svc/forks\n{}\nGET user\n"
+	if string(b) != want {
+		t.Errorf("API calls =\n%s\nwant\n%s", b, want)
+	}
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	probeOpen     = "open"
+	probeClosed   = "closed"
+	probeFiltered = "filtered"
+)
+
+var probeColumn = rowColumn{Name: "probe", Header: "Probe", Value: func(r matchRow) string { return r.Probe }}
+
+// trailingPortRe picks up a port written directly after an IP, e.g. in
+// "10.0.0.5:5432" or "http://10.0.0.5:8080/path".
+var trailingPortRe = regexp.MustCompile(`^\]?:(\d{1,5})\b`)
+
+// dialFunc matches net.Dialer.DialContext.
+type dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// probeTarget returns the host:port to probe for a row: its IP plus either
+// the port found alongside it or one written inline after the IP.
+func probeTarget(r matchRow) (string, bool) {
+	addr, ok := rowAddr(r)
+	if !ok {
+		return "", false
+	}
+	port := r.PortValue
+	if port == "" {
+		ip := firstIP(r.IPValue)
+		if i := strings.Index(r.IPValue, ip); ip != "" && i >= 0 {
+			if m := trailingPortRe.FindStringSubmatch(r.IPValue[i+len(ip):]); m != nil {
+				port = m[1]
+			}
+		}
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return "", false
+	}
+	return netip.AddrPortFrom(addr, uint16(n)).String(), true
+}
+
+// classifyDial maps a TCP connect result onto open/closed/filtered: a
+// refused connection means the host answered, anything else (timeouts,
+// unreachable networks) is treated as filtered.
+func classifyDial(err error) string {
+	switch {
+	case err == nil:
+		return probeOpen
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return probeClosed
+	default:
+		return probeFiltered
+	}
+}
+
+// probeRows attempts a TCP connection to every distinct IP:port pair in
+// rows and records the outcome in their Probe field. Rows without a
+// complete pair are left untouched.
+func probeRows(rows []matchRow, jobs int, timeout time.Duration, dial dialFunc) {
+	var targets []string
+	seen := map[string]bool{}
+	for _, r := range rows {
+		if t, ok := probeTarget(r); ok && !seen[t] {
+			seen[t] = true
+			targets = append(targets, t)
+		}
+	}
+
+	results := make(map[string]string, len(targets))
+	var mu sync.Mutex
+	forEachLimit(targets, jobs, func(target string) {
+		ctx, cancel := context.WithTimeout(runCtx, timeout)
+		defer cancel()
+		conn, err := dial(ctx, "tcp", target)
+		if conn != nil {
+			conn.Close()
+		}
+		mu.Lock()
+		results[target] = classifyDial(err)
+		mu.Unlock()
+	})
+
+	for i := range rows {
+		if t, ok := probeTarget(rows[i]); ok {
+			rows[i].Probe = results[t]
+		}
+	}
+}
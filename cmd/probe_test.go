@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbeTarget(t *testing.T) {
+	tests := []struct {
+		row  matchRow
+		want string
+		ok   bool
+	}{
+		{matchRow{IPValue: "10.0.0.5", PortValue: "5432"}, "10.0.0.5:5432", true},
+		{matchRow{IPValue: "10.0.0.5:6379"}, "10.0.0.5:6379", true},
+		{matchRow{IPValue: "http://192.168.1.10:8080/api"}, "192.168.1.10:8080", true},
+		{matchRow{IPValue: "[2001:db8::1]:443"}, "[2001:db8::1]:443", true},
+		{matchRow{IPValue: "10.0.0.5"}, "", false},
+		{matchRow{PortValue: "8080"}, "", false},
+		{matchRow{IPValue: "10.0.0.5", PortValue: "70000"}, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := probeTarget(tt.row)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("probeTarget(%+v) = %q, %v, want %q, %v", tt.row, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestProbeRows(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+	openPort := ln.Addr().(*net.TCPAddr).Port
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	rows := []matchRow{
+		{IPValue: "127.0.0.1", PortValue: strconv.Itoa(openPort)},
+		{IPValue: "127.0.0.1", PortValue: strconv.Itoa(closedPort)},
+		{IPValue: "10.255.255.1", PortValue: "9"},
+		{IPValue: "127.0.0.1"},
+	}
+
+	real := (&net.Dialer{}).DialContext
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		if strings.HasPrefix(address, "10.") {
+			<-ctx.Done() // simulate a silently dropped SYN
+			return nil, ctx.Err()
+		}
+		return real(ctx, network, address)
+	}
+	probeRows(rows, 4, 100*time.Millisecond, dial)
+
+	want := []string{probeOpen, probeClosed, probeFiltered, ""}
+	for i, w := range want {
+		if rows[i].Probe != w {
+			t.Errorf("row %d Probe = %q, want %q", i, rows[i].Probe, w)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// prompter asks the operator questions on stderr and reads answers from
+// stdin. When stdin isn't a terminal, callers must not prompt and should
+// fail with an actionable error instead.
+type prompter struct {
+	in          *bufio.Reader
+	out         io.Writer
+	interactive bool
+}
+
+func newPrompter() *prompter {
+	return &prompter{in: bufio.NewReader(os.Stdin), out: os.Stderr, interactive: isTerminal(os.Stdin)}
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// choose prints question with its options and returns the selected option
+// key. It re-asks until the answer matches one of the keys.
+func (p *prompter) choose(question string, options [][2]string) (string, error) {
+	for {
+		fmt.Fprintln(p.out, question)
+		keys := make([]string, 0, len(options))
+		for _, o := range options {
+			fmt.Fprintf(p.out, "  [%s] %s\n", o[0], o[1])
+			keys = append(keys, o[0])
+		}
+		fmt.Fprintf(p.out, "Choice (%s): ", strings.Join(keys, "/"))
+
+		answer, err := p.in.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		for _, k := range keys {
+			if answer == k {
+				return k, nil
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", fmt.Errorf("no answer given")
+			}
+			return "", err
+		}
+		fmt.Fprintf(p.out, "Please answer one of: %s\n", strings.Join(keys, ", "))
+	}
+}
+
+// confirm prints question and reports whether the answer is exactly want.
+func (p *prompter) confirm(question, want string) (bool, error) {
+	fmt.Fprintf(p.out, "%s ", question)
+	answer, err := p.in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	return strings.TrimSpace(answer) == want, nil
+}
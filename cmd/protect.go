@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// protectedEnvs returns the environments matching any of patterns.
+func protectedEnvs(envs, patterns []string) []string {
+	var out []string
+	for _, env := range envs {
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, env); ok {
+				out = append(out, env)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// confirmProtected asks the operator to confirm a write to protected
+// environments: "yes", or with typeName the environment names themselves.
+// --yes skips the question; without a terminal the run fails instead.
+func confirmProtected(repo string, protected []string, typeName, yes bool, p *prompter) error {
+	if len(protected) == 0 || yes {
+		return nil
+	}
+	list := strings.Join(protected, ", ")
+	if !p.interactive {
+		return fmt.Errorf("%s in %s is protected (protect.environments); pass --yes to confirm", list, repo)
+	}
+	question, want := fmt.Sprintf("%s in %s is protected. Type yes to continue:", list, repo), "yes"
+	if typeName {
+		want = strings.Join(protected, ",")
+		question = fmt.Sprintf("%s in %s is protected. Type %q to continue:", list, repo, want)
+	}
+	ok, err := p.confirm(question, want)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("aborted: %s not confirmed", list)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProtectedEnvs(t *testing.T) {
+	got := protectedEnvs([]string{"dev", "preprod", "prod", "prod-eu"}, []string{"prod*", "staging"})
+	if strings.Join(got, ",") != "prod,prod-eu" {
+		t.Errorf("protectedEnvs = %v", got)
+	}
+}
+
+func TestConfirmProtected(t *testing.T) {
+	prompt := func(answer string) *prompter {
+		return &prompter{in: bufio.NewReader(strings.NewReader(answer)), out: io.Discard, interactive: true}
+	}
+	tests := []struct {
+		name     string
+		typeName bool
+		yes      bool
+		p        *prompter
+		ok       bool
+	}{
+		{"yes flag", false, true, &prompter{}, true},
+		{"not a terminal", false, false, &prompter{}, false},
+		{"typed yes", false, false, prompt("yes\n"), true},
+		{"typed no", false, false, prompt("y\n"), false},
+		{"typed name", true, false, prompt("prod,prod-eu\n"), true},
+		{"typed yes for name", true, false, prompt("yes\n"), false},
+		{"no answer", false, false, prompt(""), false},
+	}
+	for _, tt := range tests {
+		err := confirmProtected("org/svc", []string{"prod", "prod-eu"}, tt.typeName, tt.yes, tt.p)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+	}
+	if err := confirmProtected("org/svc", nil, true, false, &prompter{}); err != nil {
+		t.Errorf("no protected envs: %v", err)
+	}
+}
+
+func TestLoadConfigRepoProtect(t *testing.T) {
+	t.Setenv("ACA_CONFIG_DIR", t.TempDir())
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".aca.yml"), []byte("protect:\n  environments: [prod*]\n  typeName: true\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := loadConfig(dir)
+	if envs, _ := cfg["protect.environments"].Value.([]string); strings.Join(envs, ",") != "prod*" {
+		t.Errorf("protect.environments = %v", cfg["protect.environments"])
+	}
+	if typeName, _ := cfg["protect.typeName"].Value.(bool); !typeName {
+		t.Errorf("protect.typeName = %v", cfg["protect.typeName"])
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// proxyFlag is the global --proxy flag.
+var proxyFlag string
+
+// parseProxy checks a --proxy URL: an HTTP(S) proxy, or SOCKS5 with
+// socks5:// (names resolved locally) or socks5h:// (by the proxy).
+func parseProxy(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return nil, usageErrorf("invalid --proxy %q: want a URL such as http://proxy.example.com:3128", s)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return u, nil
+	}
+	return nil, usageErrorf("invalid --proxy %q: scheme must be http, https, socks5 or socks5h", s)
+}
+
+// setupProxy sends the tool's HTTP traffic through the proxy at s, except
+// to the hosts in NO_PROXY. Without --proxy, HTTPS_PROXY, HTTP_PROXY and
+// NO_PROXY apply as they do for gh and git. The proxy is also exported to
+// the environment of git and gh, and of go-git's transport.
+func setupProxy(s string) error {
+	if s == "" {
+		return nil
+	}
+	u, err := parseProxy(s)
+	if err != nil {
+		return err
+	}
+	debugf("using proxy %s", u.Redacted())
+	for _, k := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+		if err := os.Setenv(k, u.String()); err != nil {
+			return err
+		}
+	}
+	apiTransport = proxyTransport(u)
+	return nil
+}
+
+// proxyTransport returns the default transport sending requests through
+// proxy, but for the hosts NO_PROXY exempts.
+func proxyTransport(proxy *url.URL) http.RoundTripper {
+	noProxy := os.Getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = os.Getenv("no_proxy")
+	}
+	cfg := &httpproxy.Config{HTTPProxy: proxy.String(), HTTPSProxy: proxy.String(), NoProxy: noProxy}
+	proxyFunc := cfg.ProxyFunc()
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = func(r *http.Request) (*url.URL, error) { return proxyFunc(r.URL) }
+	return t
+}
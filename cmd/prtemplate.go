@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// changeList prints as one "- adapter: old → new (file)" line per change,
+// so {{.Changes}} works on its own, and can still be ranged over.
+type changeList []change
+
+func (l changeList) String() string {
+	var b strings.Builder
+	for i, c := range l {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		old := c.OldValue
+		if c.Created {
+			old = "(new)"
+		}
+		fmt.Fprintf(&b, "- %s: %s → %s (%s)", c.Adapter, old, c.NewValue, c.FilePath)
+	}
+	return b.String()
+}
+
+// flipMessageData is available in --commit-message-template and
+// --pr-body-file templates.
+type flipMessageData struct {
+	Repo     string
+	Env      string // comma-separated
+	Envs     []string
+	Adapters string // comma-separated
+	Branch   string
+	Verb     string // flip or revert
+	Issue    string // tracking issue reference (#N or ORG/REPO#N), if any
+	Changes  changeList
+}
+
+// flipTemplates holds the optional commit message and PR body templates;
+// nil templates keep the built-in text.
+type flipTemplates struct {
+	commit, body *template.Template
+}
+
+// loadFlipTemplates compiles --commit-message-template and reads and
+// compiles --pr-body-file.
+func loadFlipTemplates(commitTemplate, bodyFile string) (flipTemplates, error) {
+	var t flipTemplates
+	var err error
+	if commitTemplate != "" {
+		commitTemplate = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(commitTemplate)
+		if t.commit, err = template.New("commit").Funcs(formatFuncs).Parse(commitTemplate); err != nil {
+			return t, usageErrorf("invalid --commit-message-template: %w", err)
+		}
+	}
+	if bodyFile != "" {
+		b, err := os.ReadFile(bodyFile) // #nosec G304 - user-provided template
+		if err != nil {
+			return t, fmt.Errorf("read --pr-body-file: %w", err)
+		}
+		if t.body, err = template.New(bodyFile).Funcs(formatFuncs).Parse(string(b)); err != nil {
+			return t, usageErrorf("invalid --pr-body-file: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// renderFlipTemplate executes tmpl with d, or returns def when there is no template.
+func renderFlipTemplate(tmpl *template.Template, def string, d flipMessageData) (string, error) {
+	if tmpl == nil {
+		return def, nil
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, d); err != nil {
+		return "", fmt.Errorf("template %s: %w", tmpl.Name(), err)
+	}
+	if strings.TrimSpace(b.String()) == "" {
+		return "", fmt.Errorf("template %s produced an empty message", tmpl.Name())
+	}
+	return b.String(), nil
+}
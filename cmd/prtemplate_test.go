@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderFlipTemplate(t *testing.T) {
+	dir := t.TempDir()
+	body := filepath.Join(dir, "body.md")
+	if err := os.WriteFile(body, []byte("## Changes\n{{.Changes}}\n{{range .Changes}}[{{.Adapter}}]{{end}}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tmpls, err := loadFlipTemplates(`feat(env:{{.Env}}): {{.Verb}} {{.Adapters}}\n\nRefs: {{upper .Branch}}`, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := flipMessageData{Env: "dev,prod", Adapters: "a,b", Branch: "flip/dev", Verb: "flip", Changes: changeList{
+		{Adapter: "a", OldValue: "0", NewValue: "1", FilePath: "env/dev/parameters.properties"},
+		{Adapter: "b", NewValue: "on", FilePath: "env/prod/parameters.properties", Created: true},
+	}}
+
+	tests := []struct {
+		name string
+		got  func() (string, error)
+		want string
+	}{
+		{"commit", func() (string, error) { return renderFlipTemplate(tmpls.commit, "default", data) }, "feat(env:dev,prod): flip a,b\n\nRefs: FLIP/DEV"},
+		{"body", func() (string, error) { return renderFlipTemplate(tmpls.body, "default", data) },
+			"## Changes\n- a: 0 → 1 (env/dev/parameters.properties)\n- b: (new) → on (env/prod/parameters.properties)\n[a][b]"},
+		{"default", func() (string, error) { return renderFlipTemplate(nil, "default", data) }, "default"},
+	}
+	for _, tt := range tests {
+		got, err := tt.got()
+		if err != nil || got != tt.want {
+			t.Errorf("%s = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoadFlipTemplatesErrors(t *testing.T) {
+	if _, err := loadFlipTemplates("{{.Env", ""); err == nil {
+		t.Error("want parse error")
+	}
+	if _, err := loadFlipTemplates("", filepath.Join(t.TempDir(), "missing.md")); err == nil {
+		t.Error("want read error")
+	}
+	tmpls, err := loadFlipTemplates("{{.Nope}}", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := renderFlipTemplate(tmpls.commit, "", flipMessageData{}); err == nil {
+		t.Error("want error for unknown field")
+	}
+	tmpls, _ = loadFlipTemplates("  ", "")
+	if _, err := renderFlipTemplate(tmpls.commit, "", flipMessageData{}); err == nil {
+		t.Error("want error for empty message")
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// redactAddr masks the host part of an address: the last octet of IPv4
+// and the interface identifier (last four groups) of IPv6.
+func redactAddr(addr netip.Addr) string {
+	if addr.Is4() || addr.Is4In6() {
+		s := addr.Unmap().String()
+		return s[:strings.LastIndex(s, ".")+1] + "x"
+	}
+	b := addr.As16()
+	groups := make([]string, 4)
+	for i := range groups {
+		groups[i] = fmt.Sprintf("%x", uint16(b[2*i])<<8|uint16(b[2*i+1]))
+	}
+	return strings.Join(groups, ":") + ":x:x:x:x"
+}
+
+// redactIPs masks every IP literal in s.
+func redactIPs(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range ipTokens(s) {
+		addr, err := netip.ParseAddr(s[loc[0]:loc[1]])
+		if err != nil {
+			continue
+		}
+		b.WriteString(s[last:loc[0]])
+		b.WriteString(redactAddr(addr))
+		last = loc[1]
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// redactPort replaces every digit of a port value, keeping any quotes.
+func redactPort(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return 'x'
+		}
+		return r
+	}, s)
+}
+
+// redactHost masks the host label of a DNS name, keeping its domain as
+// redactAddr keeps the network. PTR names are often built from the address
+// (ip-10-1-2-3.ec2.internal); for ip6.arpa names the labels of the
+// interface identifier are masked.
+func redactHost(name string) string {
+	if name == "" {
+		return name
+	}
+	labels := strings.Split(name, ".")
+	lower := strings.ToLower(name)
+	n := 1
+	if strings.HasSuffix(lower, ".ip6.arpa") {
+		n = max(1, min(16, len(labels)-2))
+	}
+	for i := range n {
+		labels[i] = "x"
+	}
+	if strings.HasSuffix(lower, ".arpa") {
+		// The remaining labels are the network, reversed.
+		return strings.Join(labels, ".")
+	}
+	return redactIPs(strings.Join(labels, "."))
+}
+
+// redactRows masks addresses and ports in every field that can carry them,
+// so reports can be shared without revealing internal topology.
+func redactRows(rows []matchRow) {
+	for i := range rows {
+		r := &rows[i]
+		text := redactIPs
+		if port := stripQuotes(r.PortValue); port != "" {
+			re := regexp.MustCompile(`\b` + regexp.QuoteMeta(port) + `\b`)
+			text = func(s string) string { return re.ReplaceAllStringFunc(redactIPs(s), redactPort) }
+		}
+		r.IPValue = redactIPs(r.IPValue)
+		r.PortValue = redactPort(r.PortValue)
+		r.DNS = redactIPs(r.DNS)
+		r.PTR = redactHost(r.PTR)
+		r.Hostname = redactHost(r.Hostname)
+		r.Line = text(r.Line)
+		for j := range r.Before {
+			r.Before[j] = text(r.Before[j])
+		}
+		for j := range r.After {
+			r.After[j] = text(r.After[j])
+		}
+	}
+}
+
+// sortAndRedact orders rows by key and, with redact, masks them after:
+// redaction only changes what is shown, so --sort ip and --sort port still
+// compare the real values.
+func sortAndRedact(rows []matchRow, key string, desc, redact bool) {
+	sortRows(rows, key, desc)
+	if redact {
+		redactRows(rows)
+	}
+}
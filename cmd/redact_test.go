@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactIPs(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"10.0.0.5", "10.0.0.x"},
+		{"hosts=10.0.0.5,192.168.1.20", "hosts=10.0.0.x,192.168.1.x"},
+		{"fd00:1:2:3:4:5:6:7", "fd00:1:2:3:x:x:x:x"},
+		{"[2001:db8::1]:443", "[2001:db8:0:0:x:x:x:x]:443"},
+		{"no address here", "no address here"},
+	}
+	for _, tt := range tests {
+		if got := redactIPs(tt.in); got != tt.want {
+			t.Errorf("redactIPs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRedactRows(t *testing.T) {
+	rows := []matchRow{{
+		IPKey: "db.host", IPValue: "10.0.0.5", PortValue: "\"5432\"",
+		Line: "db.url=10.0.0.5:5432", Before: []string{"# 10.0.0.6 port 5432 old"}, After: []string{"x=15432"},
+		DNS: "mismatch (now 10.0.0.9)", PTR: "ip-10-0-0-5.ec2.internal", Hostname: "db-5.internal.example",
+	}}
+	redactRows(rows)
+	r := rows[0]
+	got := strings.Join([]string{r.IPValue, r.PortValue, r.Line, r.Before[0], r.After[0], r.DNS, r.PTR, r.Hostname}, "|")
+	want := `10.0.0.x|"xxxx"|db.url=10.0.0.x:xxxx|# 10.0.0.x port xxxx old|x=15432|mismatch (now 10.0.0.x)|x.ec2.internal|x.internal.example`
+	if got != want {
+		t.Errorf("redactRows =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRedactHost(t *testing.T) {
+	tests := map[string]string{
+		"":                         "",
+		"ip-10-1-2-3.ec2.internal": "x.ec2.internal",
+		"3.2.1.10.in-addr.arpa":    "x.2.1.10.in-addr.arpa",
+		"db01":                     "x",
+		"10.1.2.3":                 "x.1.2.3",
+		"host.10.1.2.3.example":    "x.10.1.2.x.example",
+		"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa": "x.x.x.x.x.x.x.x.x.x.x.x.x.x.x.x.8.b.d.0.1.0.0.2.ip6.arpa",
+	}
+	for in, want := range tests {
+		if got := redactHost(in); got != want {
+			t.Errorf("redactHost(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSortAndRedact(t *testing.T) {
+	rows := []matchRow{
+		{RelPath: "a", IPValue: "10.0.0.20", PortValue: "9000"},
+		{RelPath: "b", IPValue: "10.0.0.3", PortValue: "80"},
+		{RelPath: "c", IPValue: "10.0.0.100", PortValue: "443"},
+	}
+	for _, tt := range []struct{ key, want string }{
+		{"ip", "b,a,c"},
+		{"port", "b,c,a"},
+	} {
+		got := append([]matchRow(nil), rows...)
+		sortAndRedact(got, tt.key, false, true)
+		var order []string
+		for _, r := range got {
+			order = append(order, r.RelPath)
+			if r.IPValue != "10.0.0.x" {
+				t.Errorf("--sort %s: %s not redacted", tt.key, r.IPValue)
+			}
+		}
+		if strings.Join(order, ",") != tt.want {
+			t.Errorf("--redact --sort %s = %v, want %s", tt.key, order, tt.want)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// useRepoCache is the global --cache flag: clones are made from a copy of
+// each repository kept in the cache directory, which later runs fetch
+// into instead of cloning again.
+var useRepoCache bool
+
+// repoCacheRoot returns where --cache keeps repositories: repos/ under the
+// cache directory.
+func repoCacheRoot() (string, error) {
+	dir, err := getCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "repos"), nil
+}
+
+// cacheableRepo reports whether repo is an OWNER/REPO on GitHub, rather
+// than a URL or a local repository, which aren't cached.
+func cacheableRepo(repo string) bool {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" || strings.ContainsAny(name, "/:") || strings.HasPrefix(owner, ".") {
+		return false
+	}
+	info, err := os.Stat(repo)
+	return err != nil || !info.IsDir()
+}
+
+// updateRepoCache brings the cached copy of repo up to date with url: a
+// bare clone of its branches and tags the first time, a fetch afterwards.
+// It returns the copy's directory.
+func updateRepoCache(repo, url string) (string, error) {
+	root, err := repoCacheRoot()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(root, filepath.FromSlash(repo))
+	if _, err := os.Stat(filepath.Join(dir, "HEAD")); err == nil {
+		debugf("fetching %s into the repository cache", repo)
+		err := withRetry("fetch "+repo, func() error {
+			return netCommand(dir, "git", "fetch", "--quiet", "--prune", "--tags", "origin")
+		})
+		if err != nil {
+			return "", err
+		}
+	} else {
+		debugf("caching %s in %s", repo, dir)
+		if err := os.MkdirAll(filepath.Dir(dir), 0750); err != nil {
+			return "", err
+		}
+		_ = os.RemoveAll(dir) // what an interrupted first clone left
+		if err := retryInto("clone "+repo, dir, func() error {
+			return netCommand("", "git", "clone", "--bare", "--quiet", url, dir)
+		}); err != nil {
+			_ = os.RemoveAll(dir)
+			return "", err
+		}
+		// A bare clone maps branches onto branches but doesn't fetch
+		// them again; make it.
+		if err := gitIn(dir, "config", "remote.origin.fetch", "+refs/heads/*:refs/heads/*"); err != nil {
+			return "", err
+		}
+	}
+	now := time.Now()
+	_ = os.Chtimes(dir, now, now) // last used, for cache clean --older-than
+	return dir, nil
+}
+
+// cloneFromCache clones repo into dir from its cached copy, updated from
+// url first, sharing the copy's objects. The clone's origin is url, so
+// it pushes and fetches as a clone of url would.
+func cloneFromCache(repo, url, dir string, o cloneOptions) error {
+	cached, err := updateRepoCache(repo, url)
+	if err != nil {
+		return err
+	}
+	args := []string{"clone", "--shared", "--quiet"}
+	if o.branch != "" {
+		args = append(args, "--branch", o.branch)
+	}
+	if o.noCheckout {
+		args = append(args, "--no-checkout")
+	}
+	if o.bare {
+		args = append(args, "--bare")
+	}
+	if err := gitIn("", append(args, cached, dir)...); err != nil {
+		return err
+	}
+	return gitIn(dir, "remote", "set-url", "origin", url)
+}
+
+// cachedClone clones repo from url into dir through the repository cache
+// when --cache is set and repo can be cached. ok is false when the clone
+// should be made the usual way instead: without --cache, for URLs and
+// local repositories, without the git binary or when the cache fails.
+func cachedClone(repo, url, dir string, o cloneOptions) (ok bool, err error) {
+	if !useRepoCache || !cacheableRepo(repo) {
+		return false, nil
+	}
+	if useGoGit() {
+		warnf("--cache needs the git binary; cloning %s without the cache", repo)
+		return false, nil
+	}
+	if err := cloneFromCache(repo, url, dir, o); err != nil {
+		if canceled() {
+			return true, err
+		}
+		warnf("repository cache for %s failed (%v); cloning instead", repo, err)
+		return false, emptyDir(dir)
+	}
+	return true, nil
+}
+
+// cachedRepo is a repository in the cache.
+type cachedRepo struct {
+	Repo     string    `json:"repo"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"lastUsed"`
+	dir      string
+}
+
+// listRepoCache returns the cached repositories under root, by name.
+func listRepoCache(root string) ([]cachedRepo, error) {
+	owners, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var repos []cachedRepo
+	for _, o := range owners {
+		if !o.IsDir() {
+			continue
+		}
+		names, err := os.ReadDir(filepath.Join(root, o.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range names {
+			dir := filepath.Join(root, o.Name(), n.Name())
+			info, err := os.Stat(dir)
+			if err != nil || !info.IsDir() {
+				continue
+			}
+			repos = append(repos, cachedRepo{Repo: o.Name() + "/" + n.Name(), Size: dirSize(dir), LastUsed: info.ModTime(), dir: dir})
+		}
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Repo < repos[j].Repo })
+	return repos, nil
+}
+
+// dirSize returns the bytes taken by the files under dir.
+func dirSize(dir string) int64 {
+	var n int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				n += info.Size()
+			}
+		}
+		return nil
+	})
+	return n
+}
+
+// humanSize formats n bytes as B, KiB, MiB or GiB.
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 2; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMG"[exp])
+}
+
+func cmdCache() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the repositories kept by --cache",
+	}
+	cmd.AddCommand(cmdCacheList())
+	cmd.AddCommand(cmdCacheClean())
+	return cmd
+}
+
+func cmdCacheList() *cobra.Command {
+	var mode string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List cached repositories with their size and when they were last used",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := repoCacheRoot()
+			if err != nil {
+				return err
+			}
+			repos, err := listRepoCache(root)
+			if err != nil {
+				return err
+			}
+			if parseMode(mode, outTable) == outJSON {
+				enc := json.NewEncoder(stdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(repos)
+			}
+			w := newTable()
+			w.AddRow("REPO", "SIZE", "LAST USED")
+			var total int64
+			for _, r := range repos {
+				w.AddRow(r.Repo, humanSize(r.Size), r.LastUsed.Format("2006-01-02 15:04"))
+				total += r.Size
+			}
+			w.Render()
+			infof("%d repositories, %s in %s", len(repos), humanSize(total), root)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table|json")
+	setOutputModes(cmd, "table", "json")
+	return cmd
+}
+
+func cmdCacheClean() *cobra.Command {
+	var olderThan time.Duration
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "clean [ORG/REPO...]",
+		Short: "Remove cached repositories: all of them, the ones named, or those unused for --older-than",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := repoCacheRoot()
+			if err != nil {
+				return err
+			}
+			repos, err := listRepoCache(root)
+			if err != nil {
+				return err
+			}
+			named := map[string]bool{}
+			for _, a := range args {
+				named[a] = true
+			}
+			var freed int64
+			removed := 0
+			for _, r := range repos {
+				if len(args) > 0 && !named[r.Repo] {
+					continue
+				}
+				delete(named, r.Repo)
+				if olderThan > 0 && time.Since(r.LastUsed) < olderThan {
+					continue
+				}
+				if dryRun {
+					infof("Would remove %s (%s)", r.Repo, humanSize(r.Size))
+				} else {
+					if err := os.RemoveAll(r.dir); err != nil {
+						return fmt.Errorf("remove %s: %w", r.Repo, err)
+					}
+					_ = os.Remove(filepath.Dir(r.dir)) // the owner, once empty
+					infof("Removed %s (%s)", r.Repo, humanSize(r.Size))
+				}
+				freed += r.Size
+				removed++
+			}
+			for repo := range named {
+				warnf("%s is not cached", repo)
+			}
+			verb := "Freed"
+			if dryRun {
+				verb = "Would free"
+			}
+			infof("%s %s from %d repositories", verb, humanSize(freed), removed)
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&olderThan, "older-than", 0, "Only remove repositories not used for this long (e.g. 720h)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be removed without removing it")
+	return cmd
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCacheableRepo(t *testing.T) {
+	tests := []struct {
+		repo string
+		want bool
+	}{
+		{"org/svc", true},
+		{"org/svc.git", true},
+		{"https://github.com/org/svc.git", false},
+		{"org", false},
+		{"org/svc/extra", false},
+		{"./svc", false},
+		{t.TempDir(), false},
+	}
+	for _, tt := range tests {
+		if got := cacheableRepo(tt.repo); got != tt.want {
+			t.Errorf("cacheableRepo(%q) = %v, want %v", tt.repo, got, tt.want)
+		}
+	}
+}
+
+func TestRepoCache(t *testing.T) {
+	work := initTestRepo(t, map[string]map[string]string{
+		"main":    {"app.properties": "db.host=10.0.0.1\n"},
+		"feature": {"app.properties": "db.host=10.0.0.2\n"},
+	}, "main", "feature")
+	bare := serveRepo(t, work, "org/svc")
+	cacheHome := t.TempDir()
+	t.Setenv("ACA_CONFIG_DIR", cacheHome)
+	old := useRepoCache
+	useRepoCache = true
+	t.Cleanup(func() { useRepoCache = old })
+
+	clone := func(branch string) string {
+		t.Helper()
+		dir := t.TempDir()
+		if err := cloneRepo("org/svc", dir, cloneOptions{depth: 1, branch: branch, quiet: true}); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, "app.properties"))
+		return string(data)
+	}
+	if got := clone("main"); got != "db.host=10.0.0.1\n" {
+		t.Errorf("first clone: app.properties = %q", got)
+	}
+	cached := filepath.Join(cacheHome, "cache", "repos", "org", "svc")
+	if _, err := os.Stat(filepath.Join(cached, "HEAD")); err != nil {
+		t.Fatalf("repository not cached: %v", err)
+	}
+
+	// A later run fetches what changed into the cache.
+	if err := os.WriteFile(filepath.Join(work, "app.properties"), []byte("db.host=10.0.0.9\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, work, "commit", "--quiet", "-am", "move db")
+	runGit(t, work, "push", "--quiet", bare, "main")
+	dir := t.TempDir()
+	if err := cloneRepo("org/svc", dir, cloneOptions{branch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "app.properties")); string(data) != "db.host=10.0.0.9\n" {
+		t.Errorf("after fetch: app.properties = %q", data)
+	}
+	if got := gitRemoteURL(dir, "origin"); got != "file://"+bare {
+		t.Errorf("origin = %q, want the repository, not the cache", got)
+	}
+	if got := clone("feature"); got != "db.host=10.0.0.2\n" {
+		t.Errorf("feature: app.properties = %q", got)
+	}
+
+	rows, err := scanAllBranches("org/svc", scanOptions{includes: []string{"**/*"}, excludes: []string{"**/.git/**"}, jobs: 2})
+	if err != nil || len(rows) != 2 {
+		t.Errorf("scanAllBranches from the cache = %v, %v; want a row per branch", rows, err)
+	}
+
+	repos, err := listRepoCache(filepath.Join(cacheHome, "cache", "repos"))
+	if err != nil || len(repos) != 1 || repos[0].Repo != "org/svc" || repos[0].Size == 0 {
+		t.Fatalf("listRepoCache = %+v, %v", repos, err)
+	}
+	clean := cmdCacheClean()
+	clean.SetArgs([]string{"--older-than", "1h", "org/svc"})
+	if err := clean.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cached); err != nil {
+		t.Errorf("cache clean --older-than removed a repository used just now")
+	}
+	clean = cmdCacheClean()
+	clean.SetArgs([]string{"org/svc"})
+	if err := clean.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cached); !os.IsNotExist(err) {
+		t.Errorf("cache clean left %s: %v", cached, err)
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	tests := map[int64]string{512: "512 B", 1536: "1.5 KiB", 5 << 20: "5.0 MiB", 3 << 30: "3.0 GiB"}
+	for n, want := range tests {
+		if got := humanSize(n); got != want {
+			t.Errorf("humanSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+// serveRepo serves a bare copy of the repository at work as repo on a
+// GitHub whose clone URLs are file:// URLs, and returns its path.
+func serveRepo(t *testing.T, work, repo string) string {
+	t.Helper()
+	server := t.TempDir()
+	bare := filepath.Join(server, filepath.FromSlash(repo)+".git")
+	runGit(t, "", "clone", "--quiet", "--bare", work, bare)
+	runGit(t, bare, "config", "uploadpack.allowFilter", "true")
+	t.Setenv("GITHUB_SERVER_URL", "file://"+server)
+	return bare
+}
+
+// runGit runs git in dir as a test user.
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_CONFIG_GLOBAL=/dev/null")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// fromRepoConfig reports whether v was set by the repository config file
+// of the checkout at dir, rather than by a default or the user's config.
+func fromRepoConfig(v configValue, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	for _, name := range repoConfigFiles {
+		if v.Source == filepath.Join(dir, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanFlagsGiven returns which of the scan flags the repository config can
+// supply were given to cmd.
+func scanFlagsGiven(cmd *cobra.Command) map[string]bool {
+	given := map[string]bool{}
+	for _, name := range []string{"include", "exclude", "strict-ip"} {
+		given[name] = cmd.Flags().Changed(name)
+	}
+	return given
+}
+
+// applyRepoScanConfig lets the scanned repository's scan.include,
+// scan.exclude and scan.strictIP replace the options the user didn't give
+// on the command line.
+func applyRepoScanConfig(dir string, opts *scanOptions) {
+	cfg := loadConfig(dir)
+	if v := cfg["scan.include"]; !opts.flagged["include"] && fromRepoConfig(v, dir) {
+		opts.includes, _ = v.Value.([]string)
+		debugf("scan.include from %s: %s", v.Source, strings.Join(opts.includes, ","))
+	}
+	if v := cfg["scan.exclude"]; !opts.flagged["exclude"] && fromRepoConfig(v, dir) {
+		opts.excludes, _ = v.Value.([]string)
+		debugf("scan.exclude from %s: %s", v.Source, strings.Join(opts.excludes, ","))
+	}
+	if v := cfg["scan.strictIP"]; !opts.flagged["strict-ip"] && fromRepoConfig(v, dir) {
+		opts.strictIP, _ = v.Value.(bool)
+	}
+}
+
+// applyRepoScanConfigFiles is applyRepoScanConfig for a repository that
+// isn't checked out: files holds its config files by name. The config
+// reader works on a checkout, so they are written to a temporary one.
+func applyRepoScanConfigFiles(files map[string][]byte, opts *scanOptions) error {
+	dir, err := os.MkdirTemp(workDir, "gh-aca-utils-config-")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	for _, name := range repoConfigFiles {
+		if data, ok := files[name]; ok {
+			if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+				return err
+			}
+		}
+	}
+	applyRepoScanConfig(dir, opts)
+	return nil
+}
+
+// repoParamFile returns the parameters file to use in a repository: the
+// --file flag if given, else adapters.file from the repository config.
+func repoParamFile(flag string, given bool, cfg map[string]configValue) (string, error) {
+	v := cfg["adapters.file"]
+	file, _ := v.Value.(string)
+	if given || file == "" {
+		return flag, nil
+	}
+	if err := checkParamFile(file); err != nil {
+		return "", fmt.Errorf("adapters.file in %s: %w", v.Source, err)
+	}
+	return file, nil
+}
+
+// repoPolicy reads the policy file named by policy.file in repo's config,
+// from its default branch. It returns nil when the repository has none.
+func repoPolicy(repo string) (*scanPolicy, string, error) {
+	api, err := newAPICheckout(repo)
+	if err != nil {
+		return nil, "", err
+	}
+	dir, err := os.MkdirTemp(workDir, "aca-config-*")
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	api.fetchConfig(dir)
+	v := loadConfig(dir)["policy.file"]
+	file, _ := v.Value.(string)
+	if file == "" || !fromRepoConfig(v, dir) {
+		return nil, "", nil
+	}
+	file = path.Clean(strings.TrimPrefix(file, "/"))
+	if strings.HasPrefix(file, "..") {
+		return nil, "", fmt.Errorf("policy.file %q leaves the repository", file)
+	}
+	data, err := api.read(file)
+	if err != nil {
+		return nil, "", fmt.Errorf("policy.file: %w", err)
+	}
+	p, err := parsePolicy(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s:%s: %w", repo, file, err)
+	}
+	return p, repo + ":" + file, nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestApplyRepoScanConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		global  string
+		repo    string
+		flagged map[string]bool
+		want    scanOptions
+	}{
+		{
+			name: "repository config",
+			repo: "scan:\n  include: ['**/*.yml']\n  exclude: ['**/test/**']\n  strictIP: true\n",
+			want: scanOptions{includes: []string{"**/*.yml"}, excludes: []string{"**/test/**"}, strictIP: true},
+		},
+		{
+			name:    "flags win",
+			repo:    "scan:\n  include: ['**/*.yml']\n  strictIP: true\n",
+			flagged: map[string]bool{"include": true, "strict-ip": true},
+			want:    scanOptions{includes: []string{"**/*.properties"}},
+		},
+		{
+			name:   "global config is left to the flag defaults",
+			global: "scan:\n  include: ['**/*.yml']\n",
+			want:   scanOptions{includes: []string{"**/*.properties"}},
+		},
+		{
+			name: "no config",
+			want: scanOptions{includes: []string{"**/*.properties"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfgDir, dir := t.TempDir(), t.TempDir()
+			t.Setenv("ACA_CONFIG_DIR", cfgDir)
+			if tt.global != "" {
+				if err := os.WriteFile(filepath.Join(cfgDir, globalConfigFile), []byte(tt.global), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.repo != "" {
+				if err := os.WriteFile(filepath.Join(dir, ".aca.yaml"), []byte(tt.repo), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			opts := scanOptions{includes: []string{"**/*.properties"}, flagged: tt.flagged}
+			applyRepoScanConfig(dir, &opts)
+			if !reflect.DeepEqual(opts.includes, tt.want.includes) || !reflect.DeepEqual(opts.excludes, tt.want.excludes) || opts.strictIP != tt.want.strictIP {
+				t.Errorf("got includes=%v excludes=%v strictIP=%v, want includes=%v excludes=%v strictIP=%v",
+					opts.includes, opts.excludes, opts.strictIP, tt.want.includes, tt.want.excludes, tt.want.strictIP)
+			}
+		})
+	}
+}
+
+func TestRepoParamFile(t *testing.T) {
+	cfg := func(file string) map[string]configValue {
+		return map[string]configValue{"adapters.file": {Key: "adapters.file", Value: file, Source: "/repo/.aca.yaml"}}
+	}
+	tests := []struct {
+		name    string
+		flag    string
+		given   bool
+		cfg     map[string]configValue
+		want    string
+		wantErr bool
+	}{
+		{name: "default", flag: "parameters.properties", cfg: cfg(""), want: "parameters.properties"},
+		{name: "repository config", flag: "parameters.properties", cfg: cfg("application.properties"), want: "application.properties"},
+		{name: "flag wins", flag: "other.properties", given: true, cfg: cfg("application.properties"), want: "other.properties"},
+		{name: "invalid file", flag: "parameters.properties", cfg: cfg("../secrets.properties"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repoParamFile(tt.flag, tt.given, tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"syscall"
+	"time"
+)
+
+// The global --retries and --retry-delay flags: how often a clone,
+// download, API call or push that failed for a transient reason is tried
+// again, and the wait before the first retry, doubled for each one after.
+var (
+	retries    = 3
+	retryDelay = time.Second
+)
+
+// maxRetryDelay caps the wait between two attempts.
+const maxRetryDelay = 30 * time.Second
+
+func checkRetries(n int, delay time.Duration) error {
+	if n < 0 {
+		return usageErrorf("--retries must be 0 or more, got %d", n)
+	}
+	if delay < 0 {
+		return usageErrorf("--retry-delay must not be negative, got %s", delay)
+	}
+	return nil
+}
+
+// transientError marks a failure, recognized from a command's output, that
+// is worth retrying.
+type transientError struct{ err error }
+
+func (e transientError) Error() string { return e.err.Error() }
+func (e transientError) Unwrap() error { return e.err }
+
+// transientOutput matches what git, gh and go-git report when the network
+// or GitHub, rather than the request, is at fault.
+var transientOutput = regexp.MustCompile(`(?i)could not resolve host|connection (reset|refused|timed out)|operation timed out|early eof|unexpected disconnect|remote end hung up|rpc failed|returned error: (429|5\d\d)|status code: (429|5\d\d)|tls connection was non-properly terminated|failed to connect|i/o timeout|broken pipe`)
+
+// isTransient reports whether err is a failure that may not happen again:
+// a 429 or 5xx from the API, or a network error.
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.As(err, new(transientError)) {
+		return true
+	}
+	if s := httpStatus(err); s != 0 {
+		return s == http.StatusTooManyRequests || s >= 500
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	return transientOutput.MatchString(err.Error())
+}
+
+// retryWait returns the jittered wait before retry number attempt (from
+// 0): between half and all of retryDelay doubled attempt times.
+func retryWait(attempt int) time.Duration {
+	d := retryDelay
+	for range attempt {
+		if d >= maxRetryDelay {
+			break
+		}
+		d *= 2
+	}
+	d = min(d, maxRetryDelay)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1) // #nosec G404 - jitter needs no crypto
+}
+
+// withRetry runs fn, running it again after a growing wait each time it
+// fails with a transient error, up to --retries times. what names the
+// operation in the warnings.
+func withRetry(what string, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= retries || canceled() || !isTransient(err) {
+			return err
+		}
+		wait := retryWait(attempt)
+		warnf("%s failed (%v); retrying in %s (%d/%d)", what, err, wait.Round(time.Millisecond), attempt+1, retries)
+		sleepCtx(wait)
+	}
+}
+
+// netCommand runs name with args in dir, as gitIn does, for a command that
+// goes over the network. A failure whose output looks like a network or
+// server problem is a transientError; one on credentials or a missing
+// repository gets its exit status.
+func netCommand(dir, name string, args ...string) error {
+	traceCommand(name, args)
+	var errOut bytes.Buffer
+	cmd := command(dir, name, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, &errOut)
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+	if transientOutput.Match(errOut.Bytes()) {
+		return transientError{err}
+	}
+	return classifyOutput(err, errOut.Bytes())
+}
+
+// emptyDir removes everything in dir, leaving it in place for another
+// attempt at a clone.
+func emptyDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
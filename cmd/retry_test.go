@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// withRetries runs the rest of the test with n retries and no wait.
+func withRetries(t *testing.T, n int) {
+	t.Helper()
+	oldN, oldDelay := retries, retryDelay
+	retries, retryDelay = n, 0
+	t.Cleanup(func() { retries, retryDelay = oldN, oldDelay })
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"bad gateway", fmt.Errorf("GET x: %w", &api.HTTPError{StatusCode: 502}), true},
+		{"too many requests", &api.HTTPError{StatusCode: 429}, true},
+		{"not found", &api.HTTPError{StatusCode: 404}, false},
+		{"dns", &net.DNSError{Err: "no such host", Name: "api.github.com"}, true},
+		{"cut off", fmt.Errorf("untar: %w", io.ErrUnexpectedEOF), true},
+		{"git output", transientError{errors.New("exit status 128")}, true},
+		{"go-git", errors.New("unexpected client error: unexpected requesting ... status code: 503"), true},
+		{"rejected push", errors.New("non-fast-forward update"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.want {
+				t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	withRetries(t, 2)
+	flaky := errors.New("connection reset by peer")
+	tests := []struct {
+		name      string
+		fails     int
+		err       error
+		wantCalls int
+		wantErr   bool
+	}{
+		{"succeeds", 0, nil, 1, false},
+		{"recovers", 2, flaky, 3, false},
+		{"gives up", 5, flaky, 3, true},
+		{"permanent", 5, errors.New("repository not found"), 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := withRetry("test", func() error {
+				calls++
+				if calls <= tt.fails {
+					return tt.err
+				}
+				return nil
+			})
+			if calls != tt.wantCalls || (err != nil) != tt.wantErr {
+				t.Errorf("calls = %d, err = %v; want %d calls, error %v", calls, err, tt.wantCalls, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	old := retryDelay
+	retryDelay = time.Second
+	t.Cleanup(func() { retryDelay = old })
+	for attempt, upper := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if w := retryWait(attempt); w < upper/2 || w > upper {
+			t.Errorf("retryWait(%d) = %s, want between %s and %s", attempt, w, upper/2, upper)
+		}
+	}
+	if w := retryWait(20); w > maxRetryDelay {
+		t.Errorf("retryWait(20) = %s, want at most %s", w, maxRetryDelay)
+	}
+}
+
+func TestAPIRetry(t *testing.T) {
+	withRetries(t, 3)
+	calls := map[string]int{}
+	log := fakeGitHub(t, func(r *http.Request, call string) (int, string) {
+		calls[call]++
+		if calls[call] <= 2 {
+			return 502, ""
+		}
+		return 200, `{"login":"alice"}`
+	})
+	if got := ghLogin(); got != "alice" {
+		t.Errorf("ghLogin() = %q after two 502s, want alice", got)
+	}
+	if err := ghAPI("POST", "repos/org/svc/pulls", map[string]string{"title": "t"}, nil); httpStatus(err) != 502 {
+		t.Errorf("POST: err = %v, want the 502 without a retry", err)
+	}
+	data, _ := os.ReadFile(log)
+	if n := strings.Count(string(data), "POST repos/org/svc/pulls"); n != 1 {
+		t.Errorf("POST sent %d times, want 1", n)
+	}
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// lineEdit is one line rewritten by a bulk replacement command.
+type lineEdit struct {
+	File   string `json:"filePath"`
+	Line   int    `json:"lineNumber"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// lineRewriter returns the rewritten line and the old and new values it
+// replaced, or ok=false to leave the line alone. rel is the file's
+// slash-separated path relative to the repository root.
+type lineRewriter func(rel, line string) (after, from, to string, ok bool)
+
+// planRewrites runs rw over every line of files without modifying them.
+// Line endings are preserved since lines are split on "\n" only.
+func planRewrites(root string, files []string, rw lineRewriter) ([]lineEdit, error) {
+	var edits []lineEdit
+	for _, path := range files {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+		b, err := os.ReadFile(path) // #nosec G304 - path is from controlled file walk
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", rel, err)
+		}
+		for i, line := range strings.Split(string(b), "\n") {
+			if after, from, to, ok := rw(rel, line); ok && after != line {
+				edits = append(edits, lineEdit{File: rel, Line: i + 1, Old: from, New: to, Before: line, After: after})
+			}
+		}
+	}
+	return edits, nil
+}
+
+// applyRewrites writes the planned edits under root and returns the
+// changed files in edit order.
+func applyRewrites(root string, edits []lineEdit) ([]string, error) {
+	var files []string
+	byFile := map[string][]lineEdit{}
+	for _, e := range edits {
+		if _, ok := byFile[e.File]; !ok {
+			files = append(files, e.File)
+		}
+		byFile[e.File] = append(byFile[e.File], e)
+	}
+	for _, rel := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		b, err := os.ReadFile(path) // #nosec G304 - path is from controlled file walk
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", rel, err)
+		}
+		lines := strings.Split(string(b), "\n")
+		for _, e := range byFile[rel] {
+			if e.Line > len(lines) || lines[e.Line-1] != e.Before {
+				return nil, fmt.Errorf("%s:%d changed since it was planned", rel, e.Line)
+			}
+			lines[e.Line-1] = e.After
+		}
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), info.Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("write %s: %w", rel, err)
+		}
+	}
+	return files, nil
+}
+
+// printRewrites prints edits as JSON or as a unified-style diff.
+func printRewrites(edits []lineEdit, mode outputMode) error {
+	if mode == outJSON {
+		if edits == nil {
+			edits = []lineEdit{}
+		}
+		enc := json.NewEncoder(stdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(edits)
+	}
+	if len(edits) == 0 {
+		fmt.Fprintln(stdout(), "No changes made.")
+		return nil
+	}
+	w := stdout()
+	files := map[string]bool{}
+	for i, e := range edits {
+		if !files[e.File] {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "--- a/%s\n+++ b/%s\n", e.File, e.File)
+			files[e.File] = true
+		}
+		fmt.Fprintf(w, "@@ line %d @@\n", e.Line)
+		for _, l := range strings.Split(e.Before, "\n") {
+			fmt.Fprintf(w, "-%s\n", strings.TrimSuffix(l, "\r"))
+		}
+		for _, l := range strings.Split(e.After, "\n") {
+			fmt.Fprintf(w, "+%s\n", strings.TrimSuffix(l, "\r"))
+		}
+	}
+	fmt.Fprintf(w, "\n%d line(s) in %d file(s)\n", len(edits), len(files))
+	return nil
+}
+
+// commitRewrites commits files on a new branch, pushes it and optionally
+// opens a pull request, as flip-adapters does. It returns the PR URL.
+func commitRewrites(dir, branch string, files []string, msg, prTitle, prBody string, doPR bool) (string, error) {
+	if err := gitCommitBranch(dir, branch, msg, files, false, branchReuse); err != nil {
+		return "", err
+	}
+	if !doPR {
+		return "", nil
+	}
+	opts := prOptions{reviewers: codeownersReviewers(readIn(dir), files, ghLogin)}
+	prURL, err := createPR(dir, prTitle, prBody, opts)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintln(stdout(), prURL)
+	return prURL, nil
+}
+
+// fileEdits describes the difference between two versions of a file as
+// line edits. Files with the same number of lines get one edit per changed
+// line; otherwise the changed region is one multi-line edit.
+func fileEdits(rel, before, after string) []lineEdit {
+	old, cur := strings.Split(before, "\n"), strings.Split(after, "\n")
+	var edits []lineEdit
+	if len(old) == len(cur) {
+		for i := range old {
+			if old[i] != cur[i] {
+				edits = append(edits, lineEdit{File: rel, Line: i + 1, Before: old[i], After: cur[i]})
+			}
+		}
+		return edits
+	}
+	p := 0
+	for p < len(old) && p < len(cur) && old[p] == cur[p] {
+		p++
+	}
+	s := 0
+	for s < len(old)-p && s < len(cur)-p && old[len(old)-1-s] == cur[len(cur)-1-s] {
+		s++
+	}
+	return []lineEdit{{
+		File:   rel,
+		Line:   p + 1,
+		Before: strings.Join(old[p:len(old)-s], "\n"),
+		After:  strings.Join(cur[p:len(cur)-s], "\n"),
+	}}
+}
@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var prURLRe = regexp.MustCompile(`^https://[^/]+/([^/]+/[^/]+)/pull/(\d+)(?:[/?#].*)?$`)
+
+// parsePRRef reads a pull request given as a number, #number or URL. repo
+// is --repo, which a URL may omit but must not contradict.
+func parsePRRef(ref, repo string) (string, int, error) {
+	if m := prURLRe.FindStringSubmatch(ref); m != nil {
+		if repo != "" && !strings.EqualFold(repo, m[1]) {
+			return "", 0, fmt.Errorf("pull request %s is not in --repo %s", ref, repo)
+		}
+		n, _ := strconv.Atoi(m[2])
+		return m[1], n, nil
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(ref, "#"))
+	if err != nil || n <= 0 {
+		return "", 0, usageErrorf("invalid --pr %q: want a number or pull request URL", ref)
+	}
+	if repo == "" {
+		return "", 0, usageErrorf("--repo ORG/REPO is required with a pull request number")
+	}
+	return repo, n, nil
+}
+
+// isParamsPath reports whether rel is a parameters file, env/<env>/<file>
+// with a .properties or .json extension.
+func isParamsPath(rel string) bool {
+	parts := strings.Split(rel, "/")
+	if len(parts) != 3 || parts[0] != "env" {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(rel))
+	return ext == ".properties" || ext == ".json"
+}
+
+// diffValues lists the values that differ between two versions of a
+// parameters file; keys only in after are marked created.
+func diffValues(env string, before, after []adapterValue) []journalChange {
+	old := map[string]string{}
+	for _, v := range before {
+		old[v.Adapter] = v.Value
+	}
+	var changes []journalChange
+	for _, v := range after {
+		was, ok := old[v.Adapter]
+		if ok && was == v.Value {
+			continue
+		}
+		changes = append(changes, journalChange{Env: env, Adapter: v.Adapter, Old: was, New: v.Value, Created: !ok})
+	}
+	return changes
+}
+
+// mergedPRChanges reads the adapter values pull request number in repo
+// changed, as one journal entry per parameters file name.
+func mergedPRChanges(repo string, number int) (pullRequest, []journalEntry, error) {
+	var pr pullRequest
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/pulls/%d", repo, number), nil, &pr); err != nil {
+		return pr, nil, err
+	}
+	if !pr.Merged {
+		return pr, nil, fmt.Errorf("pull request #%d is not merged", number)
+	}
+	base, err := prBaseCommit(repo, pr)
+	if err != nil {
+		return pr, nil, err
+	}
+	files, err := prFiles(repo, number)
+	if err != nil {
+		return pr, nil, err
+	}
+
+	dir, cleanup, err := makeTemp("aca-rollback-*")
+	if err != nil {
+		return pr, nil, err
+	}
+	defer cleanup()
+	before := &apiCheckout{repo: repo, baseSHA: base}
+	after := &apiCheckout{repo: repo, baseSHA: pr.MergeCommitSHA}
+	var entries []journalEntry
+	byFile := map[string]int{}
+	for _, f := range files {
+		if !isParamsPath(f.Filename) {
+			continue
+		}
+		if f.Status != "modified" {
+			warnf("%s was %s by the pull request; skipping", f.Filename, f.Status)
+			continue
+		}
+		env, name := path.Base(path.Dir(f.Filename)), path.Base(f.Filename)
+		var versions [2][]adapterValue
+		for i, c := range []*apiCheckout{before, after} {
+			root := filepath.Join(dir, strconv.Itoa(i))
+			if err := os.MkdirAll(filepath.Join(root, "env", env), 0750); err != nil {
+				return pr, nil, err
+			}
+			if err := c.fetch(root, f.Filename); err != nil {
+				return pr, nil, err
+			}
+			if versions[i], err = readEnvValues(root, env, name, nil); err != nil {
+				return pr, nil, err
+			}
+		}
+		changes := diffValues(env, versions[0], versions[1])
+		if len(changes) == 0 {
+			continue
+		}
+		i, ok := byFile[name]
+		if !ok {
+			i = len(entries)
+			byFile[name] = i
+			entries = append(entries, journalEntry{Repo: repo, File: name})
+		}
+		entries[i].Changes = append(entries[i].Changes, changes...)
+	}
+	return pr, entries, nil
+}
+
+type pullRequest struct {
+	Number         int    `json:"number"`
+	Title          string `json:"title"`
+	HTMLURL        string `json:"html_url"`
+	Merged         bool   `json:"merged"`
+	MergeCommitSHA string `json:"merge_commit_sha"`
+	Commits        int    `json:"commits"`
+}
+
+// prBaseCommit returns the commit of the base branch the merged pull
+// request pr went on top of. That is the first parent of a merge commit
+// or of a squashed commit. A rebase merge puts each of the PR's commits on
+// the base branch, ending at the merge commit, so the parent is followed
+// back past every commit that belongs to the PR.
+func prBaseCommit(repo string, pr pullRequest) (string, error) {
+	sha := pr.MergeCommitSHA
+	for i := 0; ; i++ {
+		var commit struct {
+			Parents []struct {
+				SHA string `json:"sha"`
+			} `json:"parents"`
+		}
+		if err := ghAPI("GET", fmt.Sprintf("repos/%s/commits/%s", repo, sha), nil, &commit); err != nil {
+			return "", err
+		}
+		if len(commit.Parents) == 0 {
+			return "", fmt.Errorf("commit %s has no parent", sha)
+		}
+		parent := commit.Parents[0].SHA
+		if len(commit.Parents) > 1 || i+1 >= pr.Commits {
+			return parent, nil
+		}
+		var pulls []pullRequest // the pull requests the parent came from
+		if err := ghAPI("GET", fmt.Sprintf("repos/%s/commits/%s/pulls", repo, parent), nil, &pulls); err != nil {
+			return "", err
+		}
+		if !slices.ContainsFunc(pulls, func(p pullRequest) bool { return p.Number == pr.Number }) {
+			return parent, nil
+		}
+		sha = parent
+	}
+}
+
+// prFile is a file changed by a pull request.
+type prFile struct {
+	Filename string `json:"filename"`
+	Status   string `json:"status"`
+}
+
+// prFiles lists the files pull request number changed, a page at a time.
+func prFiles(repo string, number int) ([]prFile, error) {
+	var files []prFile
+	for page := 1; ; page++ {
+		var list []prFile
+		if err := ghAPI("GET", fmt.Sprintf("repos/%s/pulls/%d/files?per_page=100&page=%d", repo, number, page), nil, &list); err != nil {
+			return nil, err
+		}
+		files = append(files, list...)
+		if len(list) < 100 {
+			return files, nil
+		}
+	}
+}
+
+func cmdRollback() *cobra.Command {
+	var repo, prRef, branch, mode string
+	var dryRun, yes bool
+
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Open a pull request restoring the adapter values a merged pull request changed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if prRef == "" {
+				return usageErrorf("--pr NUMBER|URL is required")
+			}
+			repo, number, err := parsePRRef(prRef, repo)
+			if err != nil {
+				return err
+			}
+			modeVal := parseMode(mode, outTable)
+
+			pr, entries, err := mergedPRChanges(repo, number)
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				return fmt.Errorf("pull request #%d changed no adapter values under env/", number)
+			}
+			var access pushAccess
+			if !dryRun {
+				if access, err = preflight(repo, false); err != nil {
+					return err
+				}
+			}
+
+			tmpDir, cleanup, err := cloneOrDownload(repo, "")
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			var changes []change
+			var rels []string
+			after := map[string]string{}
+			for _, e := range entries {
+				for _, env := range e.envs() {
+					want, next := e.revertPlan(env)
+					rel := path.Join("env", env, e.File)
+					envChanges, _, content, err := planFile(tmpDir, rel, want, next)
+					if err != nil {
+						return err
+					}
+					if len(envChanges) > 0 {
+						changes = append(changes, envChanges...)
+						rels = append(rels, rel)
+						after[rel] = content
+					}
+				}
+			}
+			cfg := loadConfig(tmpDir)
+			if err := checkConstraints(cfg, changes); err != nil {
+				return err
+			}
+			if err := printChangeReport(changes, modeVal); err != nil {
+				return err
+			}
+			if dryRun || len(changes) == 0 {
+				if len(changes) == 0 {
+					infof("Nothing to roll back.")
+				}
+				return nil
+			}
+
+			var envs []string
+			for _, rel := range rels {
+				envs = append(envs, path.Base(path.Dir(rel)))
+			}
+			patterns, _ := cfg["protect.environments"].Value.([]string)
+			typeName, _ := cfg["protect.typeName"].Value.(bool)
+			if err := confirmProtected(repo, protectedEnvs(envs, patterns), typeName, yes, newPrompter()); err != nil {
+				return err
+			}
+			approved, err := requireApproval(repo, protectedEnvs(envs, patterns), cfg)
+			if err != nil {
+				return err
+			}
+			for _, rel := range rels {
+				if err := os.WriteFile(filepath.Join(tmpDir, filepath.FromSlash(rel)), []byte(after[rel]), 0600); err != nil {
+					return fmt.Errorf("write %s: %w", rel, err)
+				}
+			}
+			if branch == "" {
+				branch = fmt.Sprintf("rollback/pr-%d", number)
+			}
+			msg := fmt.Sprintf("chore(env:%s): roll back #%d", strings.Join(envs, ","), number)
+			title := fmt.Sprintf("Roll back #%d: %s", number, pr.Title)
+			body := fmt.Sprintf("Automated via gh aca-utils rollback.\n\nRestores the %d adapter value(s) changed by %s.", len(changes), pr.HTMLURL)
+			if err := access.prepare(tmpDir); err != nil {
+				return err
+			}
+			prURL, err := commitRewrites(tmpDir, branch, rels, msg, title, body, true)
+			if err != nil {
+				return err
+			}
+
+			for _, e := range entries {
+				entry := journalEntry{Command: "rollback", Repo: repo, File: e.File, Branch: branch, PRURL: prURL, Approver: approved.By, ApprovalRun: approved.Run}
+				for _, c := range changes {
+					if path.Base(c.FilePath) == e.File {
+						entry.Changes = append(entry.Changes, journalChange{Env: path.Base(path.Dir(c.FilePath)), Adapter: c.Adapter, Old: c.OldValue, New: c.NewValue})
+					}
+				}
+				if len(entry.Changes) > 0 {
+					recordJournal(entry)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&prRef, "pr", "", "Merged pull request to roll back, as a number or URL (required)")
+	cmd.Flags().StringVar(&repo, "repo", "", "Repo as ORG/REPO (required with a pull request number)")
+	cmd.Flags().StringVar(&branch, "branch", "", "Branch name to create (default: rollback/pr-<number>)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", true, "Show the changes without opening the pull request")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Don't ask before changing protected environments")
+	cmd.Flags().StringVar(&approvalRun, "approval-run", "", "Workflow run whose approved deployment review approves protected environments (protect.approval: environment)")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table|json")
+	setOutputModes(cmd, "table", "json")
+	return cmd
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParsePRRef(t *testing.T) {
+	tests := []struct {
+		ref, repo, want string
+	}{
+		{"42", "org/svc", "org/svc#42"},
+		{"#42", "org/svc", "org/svc#42"},
+		{"https://github.com/org/svc/pull/42", "", "org/svc#42"},
+		{"https://github.com/org/svc/pull/42/files", "Org/Svc", "org/svc#42"},
+		{"https://github.com/org/svc/pull/42", "org/other", "error"},
+		{"42", "", "error"},
+		{"abc", "org/svc", "error"},
+		{"-1", "org/svc", "error"},
+	}
+	for _, tt := range tests {
+		got := "error"
+		if repo, n, err := parsePRRef(tt.ref, tt.repo); err == nil {
+			got = fmt.Sprintf("%s#%d", repo, n)
+		}
+		if got != tt.want {
+			t.Errorf("parsePRRef(%q, %q) = %s, want %s", tt.ref, tt.repo, got, tt.want)
+		}
+	}
+}
+
+func TestIsParamsPath(t *testing.T) {
+	for rel, want := range map[string]bool{
+		"env/dev/parameters.properties": true,
+		"env/dev/appsettings.JSON":      true,
+		"env/dev/README.md":             false,
+		"env/dev/sub/parameters.json":   false,
+		"config/dev/parameters.json":    false,
+	} {
+		if got := isParamsPath(rel); got != want {
+			t.Errorf("isParamsPath(%s) = %v", rel, got)
+		}
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	before := []adapterValue{{Adapter: "billing", Value: "0"}, {Adapter: "search", Value: "1"}, {Adapter: "gone", Value: "1"}}
+	after := []adapterValue{{Adapter: "billing", Value: "1"}, {Adapter: "search", Value: "1"}, {Adapter: "crm", Value: "on"}}
+	got := fmt.Sprint(diffValues("dev", before, after))
+	if want := "[{dev billing 0 1 false} {dev crm  on true}]"; got != want {
+		t.Errorf("diffValues = %s, want %s", got, want)
+	}
+}
+
+func TestPRBaseCommit(t *testing.T) {
+	// main: base0 <- r1 <- r2 <- r3, where r1..r3 are PR #7's three
+	// commits rebased onto base0; m1 merges feature into base0; s1 is
+	// the squash of #7 onto base0.
+	fakeGitHub(t, func(_ *http.Request, call string) (int, string) {
+		switch call {
+		case "GET repos/org/svc/commits/r3":
+			return 200, `{"parents":[{"sha":"r2"}]}`
+		case "GET repos/org/svc/commits/r2":
+			return 200, `{"parents":[{"sha":"r1"}]}`
+		case "GET repos/org/svc/commits/r1", "GET repos/org/svc/commits/s1":
+			return 200, `{"parents":[{"sha":"base0"}]}`
+		case "GET repos/org/svc/commits/m1":
+			return 200, `{"parents":[{"sha":"base0"},{"sha":"feature"}]}`
+		case "GET repos/org/svc/commits/r2/pulls", "GET repos/org/svc/commits/r1/pulls":
+			return 200, `[{"number":7}]`
+		case "GET repos/org/svc/commits/base0/pulls":
+			return 200, `[{"number":3}]`
+		}
+		return 404, ""
+	})
+	for _, merge := range []string{"r3", "m1", "s1"} {
+		got, err := prBaseCommit("org/svc", pullRequest{Number: 7, MergeCommitSHA: merge, Commits: 3})
+		if err != nil || got != "base0" {
+			t.Errorf("prBaseCommit(%s) = %q, %v; want base0", merge, got, err)
+		}
+	}
+}
+
+func TestPRFilesPages(t *testing.T) {
+	fakeGitHub(t, func(_ *http.Request, call string) (int, string) {
+		switch call {
+		case "GET repos/org/svc/pulls/7/files?per_page=100&page=1":
+			return 200, "[" + strings.Repeat(`{"filename":"x","status":"modified"},`, 99) + `{"filename":"y","status":"modified"}]`
+		case "GET repos/org/svc/pulls/7/files?per_page=100&page=2":
+			return 200, `[{"filename":"env/dev/parameters.properties","status":"modified"}]`
+		}
+		return 404, ""
+	})
+	files, err := prFiles("org/svc", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 101 || files[100].Filename != "env/dev/parameters.properties" {
+		t.Errorf("prFiles returned %d file(s), want 101 over two pages", len(files))
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScanFile_Properties(t *testing.T) {
+	root := t.TempDir()
+	content := "# primary db was 10.9.9.9\n" +
+		"db.host : 10.0.0.5\n" +
+		"db.port 5432\n" +
+		"cluster.nodes=10.0.0.1,\\\n" +
+		"    10.0.0.2\n" +
+		"greeting=hello\n"
+	path := filepath.Join(root, "app.properties")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	want := []matchRow{
+		{IPValue: "10.9.9.9", RelPath: "app.properties", LineNumber: 1},
+		{IPKey: "db.host", IPValue: "10.0.0.5", RelPath: "app.properties", LineNumber: 2},
+		{PortKey: "db.port", PortValue: "5432", RelPath: "app.properties", LineNumber: 3},
+		{IPKey: "cluster.nodes", IPValue: "10.0.0.1,10.0.0.2", RelPath: "app.properties", LineNumber: 4},
+	}
+	got := scanFile(path, "app.properties", scanOptions{})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scanFile() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestScanFile_ContinuedLastEntry(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "app.properties")
+	if err := os.WriteFile(path, []byte("hosts=a,\\\n  # 10.0.0.1\n"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	got := scanFile(path, "app.properties", scanOptions{})
+	if len(got) != 1 || got[0].LineNumber != 1 || got[0].IPKey != "hosts" {
+		t.Errorf("Expected a single row for the continued entry, got %+v", got)
+	}
+}
+
+func TestScanForIPPort_JobsDeterministic(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 50; i++ {
+		name := filepath.Join(root, fmt.Sprintf("svc%02d", i), "app.properties")
+		if err := os.MkdirAll(filepath.Dir(name), 0750); err != nil {
+			t.Fatal(err)
+		}
+		body := fmt.Sprintf("db.host=10.0.%d.1\ndb.port=%d\n", i, 5000+i)
+		if err := os.WriteFile(name, []byte(body), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := scanOptions{includes: []string{"**/*"}, jobs: 1}
+	want := scanForIPPort(root, opts)
+	if len(want) != 100 {
+		t.Fatalf("Expected 100 rows, got %d", len(want))
+	}
+	for _, jobs := range []int{0, 4, 64} {
+		opts.jobs = jobs
+		if got := scanForIPPort(root, opts); !reflect.DeepEqual(got, want) {
+			t.Errorf("scanForIPPort with jobs=%d differs from sequential scan", jobs)
+		}
+	}
+}
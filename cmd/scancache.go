@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"bufio"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// scanCacheVersion is bumped whenever scanner changes would make cached
+// results stale.
+const scanCacheVersion = 2
+
+// scanCacheEntry is the unfiltered result of scanning one tree: the raw
+// findings and the tree's value-ignore file. Suppressions and port filters
+// are applied after loading so expiry dates are evaluated on every run.
+type scanCacheEntry struct {
+	Rows    []matchRow
+	Ignores string
+}
+
+// cachedRow persists the unexported scanner fields alongside a row.
+type cachedRow struct {
+	matchRow
+	Host string `json:"host,omitempty"`
+}
+
+type scanCacheFile struct {
+	Version int         `json:"version"`
+	Repo    string      `json:"repo"`
+	Commit  string      `json:"commit"`
+	Rows    []cachedRow `json:"rows"`
+	Ignores string      `json:"ignores,omitempty"`
+}
+
+// defaultScanCacheDir returns the per-user scan cache directory, e.g.
+// ~/.cache/gh-aca-utils/scans on Linux.
+func defaultScanCacheDir() string {
+	dir, err := getCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "scans")
+}
+
+// resolveCommit returns the commit SHA that ref (default branch if empty)
+// currently points to.
+func resolveCommit(repo, ref string) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	var out struct {
+		SHA string `json:"sha"`
+	}
+	if err := ghAPI("GET", fmt.Sprintf("repos/%s/commits/%s", repo, url.PathEscape(ref)), nil, &out); err != nil {
+		return "", err
+	}
+	if out.SHA == "" {
+		return "", fmt.Errorf("no commit found for %s@%s", repo, ref)
+	}
+	return out.SHA, nil
+}
+
+// scanCacheKey identifies a scan of repo@commit with the options that
+// affect raw findings. Port filters are applied after the cache.
+func scanCacheKey(repo, commit string, opts scanOptions) string {
+	h := sha256.New()
+	context := -1
+	if opts.withContext {
+		context = opts.context
+	}
+	fmt.Fprintf(h, "v%d\x00%s\x00%s\x00%s\x00%s\x00%t\x00%d\x00%d",
+		scanCacheVersion, repo, commit,
+		strings.Join(opts.includes, ","), strings.Join(opts.excludes, ","), opts.strictIP, context, opts.maxLineLength)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func loadScanCache(dir, key string) (*scanCacheEntry, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, key+".json")) // #nosec G304 - key is a hex digest
+	if err != nil {
+		return nil, false
+	}
+	var f scanCacheFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Version != scanCacheVersion {
+		return nil, false
+	}
+	entry := &scanCacheEntry{Ignores: f.Ignores, Rows: make([]matchRow, len(f.Rows))}
+	for i, r := range f.Rows {
+		entry.Rows[i] = r.matchRow
+		entry.Rows[i].host = r.Host
+	}
+	return entry, true
+}
+
+// saveScanCache writes the entry atomically so concurrent runs never read
+// a partial file.
+func saveScanCache(dir, key, repo, commit string, entry *scanCacheEntry) error {
+	c, err := createScanCache(dir, key, repo, commit)
+	if err != nil {
+		return err
+	}
+	c.write(entry.Rows)
+	return c.commit(entry.Ignores)
+}
+
+// scanCacheWriter writes a cache file row by row, so a streamed scan is
+// cached without keeping its rows. The file is written under a temporary
+// name and renamed into place by commit.
+type scanCacheWriter struct {
+	f    *os.File
+	w    *bufio.Writer
+	path string
+	n    int
+	err  error
+}
+
+func createScanCache(dir, key, repo, commit string) (*scanCacheWriter, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(dir, key+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	c := &scanCacheWriter{f: f, w: bufio.NewWriter(f), path: filepath.Join(dir, key+".json")}
+	// The fields of scanCacheFile, with the ignore file last as it is
+	// only known once the scan is done.
+	c.printf(`{"version":%d,"repo":%s,"commit":%s,"rows":[`, scanCacheVersion, jsonString(repo), jsonString(commit))
+	return c, nil
+}
+
+func (c *scanCacheWriter) printf(format string, args ...any) {
+	if c.err == nil {
+		_, c.err = fmt.Fprintf(c.w, format, args...)
+	}
+}
+
+// write appends rows to the file. A nil writer, for a scan that isn't
+// cached, does nothing.
+func (c *scanCacheWriter) write(rows []matchRow) {
+	if c == nil {
+		return
+	}
+	for _, r := range rows {
+		data, err := json.Marshal(cachedRow{matchRow: r, Host: r.host})
+		if err != nil {
+			c.err = cmp.Or(c.err, err)
+			return
+		}
+		if c.n > 0 {
+			c.printf(",")
+		}
+		c.printf("%s", data)
+		c.n++
+	}
+}
+
+// commit finishes the file with the scanned tree's value-ignore file and
+// moves it into place.
+func (c *scanCacheWriter) commit(ignores string) error {
+	if c == nil {
+		return nil
+	}
+	c.printf(`],"ignores":%s}`, jsonString(ignores))
+	if c.err == nil {
+		c.err = c.w.Flush()
+	}
+	if err := cmp.Or(c.err, c.f.Close()); err != nil {
+		_ = os.Remove(c.f.Name())
+		return err
+	}
+	return os.Rename(c.f.Name(), c.path)
+}
+
+// abort discards the file unless it was committed.
+func (c *scanCacheWriter) abort() {
+	if c == nil {
+		return
+	}
+	if c.f.Close() == nil {
+		_ = os.Remove(c.f.Name())
+	}
+}
+
+func jsonString(s string) []byte {
+	data, _ := json.Marshal(s)
+	return data
+}
+
+// scanRaw scans root without applying suppressions or filters. When
+// streaming, filtered rows are emitted as they are found and the raw rows
+// go to cache, if not nil, instead of being kept.
+func scanRaw(root string, opts scanOptions, cache *scanCacheWriter) (*scanCacheEntry, error) {
+	data, err := os.ReadFile(filepath.Join(root, valueIgnoreFile)) // #nosec G304 - fixed name under the scan root
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	entry := &scanCacheEntry{Ignores: string(data)}
+	if emit := opts.emit; emit != nil {
+		ignores, err := parseValueIgnores(strings.NewReader(entry.Ignores))
+		if err != nil {
+			return nil, err
+		}
+		filter := newRowFilter(ignores, opts)
+		opts.emit = func(rows []matchRow) {
+			cache.write(rows)
+			emit(filter(rows))
+		}
+		scanForIPPort(root, opts)
+		return entry, nil
+	}
+	entry.Rows = scanForIPPort(root, opts)
+	return entry, nil
+}
+
+// finish applies the entry's value suppressions and the port filter, and
+// emits the result instead of returning it when streaming.
+func (e *scanCacheEntry) finish(opts scanOptions) ([]matchRow, error) {
+	ignores, err := parseValueIgnores(strings.NewReader(e.Ignores))
+	if err != nil {
+		return nil, err
+	}
+	rows := newRowFilter(ignores, opts)(e.Rows)
+	if opts.emit != nil {
+		opts.emit(rows)
+		return nil, nil
+	}
+	return rows, nil
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScanCacheKey(t *testing.T) {
+	base := scanOptions{includes: []string{"**/*.yml"}, excludes: []string{"**/.git/**"}}
+	key := scanCacheKey("org/repo", "abc123", base)
+
+	variants := map[string]string{
+		"commit":  scanCacheKey("org/repo", "def456", base),
+		"repo":    scanCacheKey("org/other", "abc123", base),
+		"include": scanCacheKey("org/repo", "abc123", scanOptions{includes: []string{"**/*"}, excludes: base.excludes}),
+		"strict":  scanCacheKey("org/repo", "abc123", scanOptions{includes: base.includes, excludes: base.excludes, strictIP: true}),
+	}
+	for name, other := range variants {
+		if other == key {
+			t.Errorf("Expected %s to change the cache key", name)
+		}
+	}
+
+	// Port filters and job counts only affect post-processing.
+	same := base
+	same.jobs, same.ports = 8, portFilter{{1, 1024}}
+	if scanCacheKey("org/repo", "abc123", same) != key {
+		t.Error("Expected jobs and port filters not to change the cache key")
+	}
+}
+
+func TestScanCache_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	entry := &scanCacheEntry{
+		Rows: []matchRow{
+			{IPKey: "db.host", IPValue: "10.0.0.5", RelPath: "app.properties", LineNumber: 1, host: "db.example.com"},
+			{PortKey: "db.port", PortValue: "5432", RelPath: "app.properties", LineNumber: 2},
+		},
+		Ignores: "10.0.0.0/24\n",
+	}
+	if err := saveScanCache(dir, "k1", "org/repo", "abc123", entry); err != nil {
+		t.Fatalf("saveScanCache failed: %v", err)
+	}
+
+	got, ok := loadScanCache(dir, "k1")
+	if !ok {
+		t.Fatal("Expected cache hit")
+	}
+	if !reflect.DeepEqual(got, entry) {
+		t.Errorf("loadScanCache() =\n%+v\nwant\n%+v", got, entry)
+	}
+
+	// Suppressions from the cached ignore file are applied on load.
+	rows, err := got.finish(scanOptions{})
+	if err != nil {
+		t.Fatalf("finish failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0].PortValue != "5432" {
+		t.Errorf("Expected the suppressed IP row to be dropped, got %+v", rows)
+	}
+
+	if _, ok := loadScanCache(dir, "missing"); ok {
+		t.Error("Expected cache miss for unknown key")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "old.json"), []byte(`{"version":0,"rows":[]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loadScanCache(dir, "old"); ok {
+		t.Error("Expected entries from another cache version to be ignored")
+	}
+}
+
+func TestScanRawStreamsToCache(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "app.properties"), []byte("db.host=10.0.0.5\ncache.host=10.0.0.6\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, valueIgnoreFile), []byte("10.0.0.6\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	cache, err := createScanCache(dir, "k1", "org/repo", "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var emitted []matchRow
+	opts := scanOptions{includes: []string{"**/*.properties"}, emit: func(rows []matchRow) { emitted = append(emitted, rows...) }}
+	entry, err := scanRaw(root, opts, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Rows) != 0 {
+		t.Errorf("kept %d row(s) in memory while streaming", len(entry.Rows))
+	}
+	if len(emitted) != 1 || emitted[0].IPValue != "10.0.0.5" {
+		t.Errorf("emitted %+v, want the unsuppressed row", emitted)
+	}
+	if err := cache.commit(entry.Ignores); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := loadScanCache(dir, "k1")
+	if !ok {
+		t.Fatal("Expected cache hit")
+	}
+	if len(got.Rows) != 2 || got.Ignores != "10.0.0.6\n" {
+		t.Errorf("cached %+v, want both raw rows and the ignore file", got)
+	}
+}
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// scheduledFlip is a flip-adapters run to perform later from a generated
+// GitHub Actions workflow.
+type scheduledFlip struct {
+	At          time.Time
+	Env         string
+	Adapters    []string
+	File        string
+	TogglePairs []string
+	AutoMerge   bool
+	TokenSecret string // secret holding the token the workflow runs gh with
+	Confirmed   bool   // protected environments were confirmed when scheduling
+}
+
+// Field order is the order keys are written in the workflow file.
+type workflow struct {
+	Name        string                 `yaml:"name"`
+	On          workflowTriggers       `yaml:"on"`
+	Permissions map[string]string      `yaml:"permissions"`
+	Jobs        map[string]workflowJob `yaml:"jobs"`
+}
+
+type workflowTriggers struct {
+	Schedule         []map[string]string `yaml:"schedule"`
+	WorkflowDispatch struct{}            `yaml:"workflow_dispatch"`
+}
+
+type workflowJob struct {
+	RunsOn string            `yaml:"runs-on"`
+	Env    map[string]string `yaml:"env"`
+	Steps  []workflowStep    `yaml:"steps"`
+}
+
+type workflowStep struct {
+	Name string `yaml:"name"`
+	Run  string `yaml:"run"`
+}
+
+// atLayouts are the accepted --at formats; a time zone is required.
+var atLayouts = []string{time.RFC3339, "2006-01-02T15:04Z07:00"}
+
+// parseAt parses --at, which must be in the future.
+func parseAt(s string, now time.Time) (time.Time, error) {
+	for _, layout := range atLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			if !t.After(now) {
+				return time.Time{}, usageErrorf("--at %s is in the past", s)
+			}
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, usageErrorf("invalid --at %q: want a time with a zone such as 2025-06-01T02:00Z", s)
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// path is where the workflow is committed.
+func (f scheduledFlip) path() string {
+	return fmt.Sprintf(".github/workflows/aca-flip-%s-%s.yml", branchSlug(f.Env), f.At.Format("200601021504"))
+}
+
+// workflow builds the Actions workflow that performs the flip. Schedules
+// repeat yearly, so the job only flips on the scheduled date; it can also be
+// started by hand.
+func (f scheduledFlip) workflow() ([]byte, error) {
+	args := []string{"gh", "aca-utils", "flip-adapters", `--repo "$GITHUB_REPOSITORY"`,
+		"--env", shellQuote(f.Env), "--adapters", shellQuote(strings.Join(f.Adapters, ",")), "--file", shellQuote(f.File)}
+	for _, p := range f.TogglePairs {
+		args = append(args, "--toggle-pair", shellQuote(p))
+	}
+	args = append(args, "--strict", "--dry-run=false", "--pr", "--branch-suffix-timestamp")
+	if f.AutoMerge {
+		args = append(args, "--auto-merge")
+	}
+	if f.Confirmed {
+		args = append(args, "--yes")
+	}
+	date := f.At.Format("2006-01-02")
+	w := workflow{
+		Name: fmt.Sprintf("Flip adapters in %s at %s", f.Env, f.At.Format("2006-01-02 15:04 UTC")),
+		On: workflowTriggers{Schedule: []map[string]string{{
+			"cron": fmt.Sprintf("%d %d %d %d *", f.At.Minute(), f.At.Hour(), f.At.Day(), int(f.At.Month())),
+		}}},
+		Permissions: map[string]string{"contents": "write", "pull-requests": "write"},
+		Jobs: map[string]workflowJob{"flip": {
+			RunsOn: "ubuntu-latest",
+			Env:    map[string]string{"GH_TOKEN": "${{ secrets." + f.TokenSecret + " }}"},
+			Steps: []workflowStep{
+				{Name: "Check date", Run: fmt.Sprintf(`if [ "${{ github.event_name }}" = schedule ] && [ "$(date -u +%%F)" != %s ]; then
+  echo "Scheduled for %s; nothing to do" >> "$GITHUB_STEP_SUMMARY"
+  echo SKIP=1 >> "$GITHUB_ENV"
+fi
+`, date, date)},
+				{Name: "Flip adapters", Run: fmt.Sprintf(`[ -n "$SKIP" ] && exit 0
+gh extension install greenstevester/gh-aca-utils
+git config --global user.name "github-actions[bot]"
+git config --global user.email "41898282+github-actions[bot]@users.noreply.github.com"
+%s
+`, strings.Join(args, " "))},
+			},
+		}},
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Generated by gh aca-utils schedule-flip; safe to delete after %s.\n", date)
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(w); err != nil {
+		return nil, fmt.Errorf("encode workflow: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+func cmdScheduleFlip() *cobra.Command {
+	var repo, at, envName, adaptersCSV, paramFile, branch, tokenSecret string
+	var togglePairs []string
+	var autoMerge, doCommit, doPR, dryRun, yes bool
+
+	cmd := &cobra.Command{
+		Use:   "schedule-flip",
+		Short: "Commit a GitHub Actions workflow that flips adapters at a given time",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if repo == "" {
+				return usageErrorf("--repo ORG/REPO is required")
+			}
+			if envName == "" {
+				return usageErrorf("--env is required (e.g., dev)")
+			}
+			when, err := parseAt(at, time.Now())
+			if err != nil {
+				return err
+			}
+			want, err := adaptersOrStored(adaptersCSV, repo, envName)
+			if err != nil {
+				return err
+			}
+			if err := checkParamFile(paramFile); err != nil {
+				return err
+			}
+			if _, err := parseTogglePairs(togglePairs); err != nil {
+				return err
+			}
+			if tokenSecret == "" || strings.ContainsAny(tokenSecret, " ${}") {
+				return usageErrorf("invalid --token-secret %q", tokenSecret)
+			}
+			doCommit = doCommit || doPR
+
+			flip := scheduledFlip{At: when, Env: envName, Adapters: want, File: paramFile, TogglePairs: togglePairs, AutoMerge: autoMerge, TokenSecret: tokenSecret}
+			var access pushAccess
+			if doCommit && !dryRun {
+				if access, err = preflight(repo, true); err != nil {
+					return err
+				}
+			}
+
+			tmpDir, cleanup, err := cloneOrDownload(repo, "")
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			envs, err := resolveEnvs(tmpDir, envName)
+			if err != nil {
+				return err
+			}
+			// The scheduled run has no one to ask, so protected
+			// environments are confirmed now, and the run passes --yes.
+			cfg := loadConfig(tmpDir)
+			patterns, _ := cfg["protect.environments"].Value.([]string)
+			if protected := protectedEnvs(envs, patterns); len(protected) > 0 {
+				if mode, _ := cfg["protect.approval"].Value.(string); mode != "" && mode != approvalNone {
+					return fmt.Errorf("%s in %s needs a second approver (protect.approval: %s), which a scheduled run can't get; flip it by hand at %s instead", strings.Join(protected, ", "), repo, mode, when.Format(time.RFC3339))
+				}
+				if !dryRun {
+					typeName, _ := cfg["protect.typeName"].Value.(bool)
+					if err := confirmProtected(repo, protected, typeName, yes, newPrompter()); err != nil {
+						return err
+					}
+				}
+				flip.Confirmed = true
+			}
+			content, err := flip.workflow()
+			if err != nil {
+				return err
+			}
+			rel := flip.path()
+			target := filepath.Join(tmpDir, filepath.FromSlash(rel))
+			if _, err := os.Stat(target); err == nil {
+				return fmt.Errorf("%s already exists in %s", rel, repo)
+			}
+
+			infof("Workflow %s (runs at %s):", rel, when.Format(time.RFC3339))
+			if dryRun {
+				_, err := stdout().Write(content)
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+				return err
+			}
+			if err := os.WriteFile(target, content, 0600); err != nil {
+				return fmt.Errorf("write %s: %w", rel, err)
+			}
+			if !doCommit {
+				return nil
+			}
+			if branch == "" {
+				branch = "schedule/" + strings.TrimSuffix(filepath.Base(rel), ".yml")
+			}
+			msg := fmt.Sprintf("ci: schedule adapter flip in %s at %s", envName, when.Format(time.RFC3339))
+			title := fmt.Sprintf("Schedule adapter flip in %s at %s: %s", envName, when.Format("2006-01-02 15:04 UTC"), strings.Join(want, ", "))
+			body := fmt.Sprintf("Automated via gh aca-utils schedule-flip.\n\nAdds `%s`, which flips %s in env/%s at %s. Scheduled workflows only run from the default branch, so merge this before then; the run opens its own pull request with the flip.",
+				rel, strings.Join(want, ", "), envName, when.Format(time.RFC3339))
+			if err := access.prepare(tmpDir); err != nil {
+				return err
+			}
+			_, err = commitRewrites(tmpDir, branch, []string{rel}, msg, title, body, doPR)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Target repo as ORG/REPO (required)")
+	cmd.Flags().StringVar(&at, "at", "", "When to flip, with a time zone, e.g. 2025-06-01T02:00Z (required)")
+	cmd.Flags().StringVar(&envName, "env", "", "Environment name or glob, as for flip-adapters (required)")
+	cmd.Flags().StringVar(&adaptersCSV, "adapters", "", "Comma-separated adapter keys (or use stored adapters from 'set-adapters')")
+	cmd.Flags().StringVar(&paramFile, "file", "parameters.properties", "Parameters file in each env directory")
+	cmd.Flags().StringSliceVar(&togglePairs, "toggle-pair", nil, "Extra value pair to toggle between as A:B")
+	cmd.Flags().BoolVar(&autoMerge, "auto-merge", false, "Have the scheduled run queue its pull request for auto-merge")
+	cmd.Flags().StringVar(&tokenSecret, "token-secret", "GITHUB_TOKEN", "Repository secret with the token the workflow runs gh with")
+	cmd.Flags().StringVar(&branch, "branch", "", "Branch for the workflow commit (default: schedule/aca-flip-<env>-<time>)")
+	cmd.Flags().BoolVar(&doCommit, "commit", false, "Commit the workflow to a new branch and push")
+	cmd.Flags().BoolVar(&doPR, "pr", false, "Create a pull request (implies --commit)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", true, "Print the workflow without writing it")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Confirm scheduling a flip of protected environments (protect.environments in config) without prompting")
+	return cmd
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseAt(t *testing.T) {
+	now := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in, want string
+	}{
+		{"2025-06-01T02:00Z", "2025-06-01T02:00:00Z"},
+		{"2025-06-01T04:30:00+02:00", "2025-06-01T02:30:00Z"},
+		{"2025-04-01T02:00Z", "error"},
+		{"2025-06-01T02:00", "error"},
+		{"tomorrow", "error"},
+	}
+	for _, tt := range tests {
+		got := "error"
+		if at, err := parseAt(tt.in, now); err == nil {
+			got = at.Format(time.RFC3339)
+		}
+		if got != tt.want {
+			t.Errorf("parseAt(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestScheduledFlipWorkflow(t *testing.T) {
+	f := scheduledFlip{
+		At:          time.Date(2025, 6, 1, 2, 5, 0, 0, time.UTC),
+		Env:         "prod-*",
+		Adapters:    []string{"billing", "it's"},
+		File:        "parameters.properties",
+		TogglePairs: []string{"live:paused"},
+		AutoMerge:   true,
+		TokenSecret: "FLIP_TOKEN",
+	}
+	if got := f.path(); got != ".github/workflows/aca-flip-prod-202506010205.yml" {
+		t.Errorf("path = %s", got)
+	}
+	b, err := f.workflow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w workflow
+	if err := yaml.Unmarshal(b, &w); err != nil {
+		t.Fatalf("generated workflow is not YAML: %v\n%s", err, b)
+	}
+	if cron := w.On.Schedule[0]["cron"]; cron != "5 2 1 6 *" {
+		t.Errorf("cron = %q", cron)
+	}
+	job := w.Jobs["flip"]
+	if job.Env["GH_TOKEN"] != "${{ secrets.FLIP_TOKEN }}" {
+		t.Errorf("GH_TOKEN = %q", job.Env["GH_TOKEN"])
+	}
+	if !strings.Contains(job.Steps[0].Run, `!= 2025-06-01 ]`) {
+		t.Errorf("date guard missing:\n%s", job.Steps[0].Run)
+	}
+	run := job.Steps[1].Run
+	for _, want := range []string{
+		`gh aca-utils flip-adapters --repo "$GITHUB_REPOSITORY" --env 'prod-*' --adapters 'billing,it'\''s'`,
+		`--toggle-pair 'live:paused'`,
+		`--strict --dry-run=false --pr --branch-suffix-timestamp --auto-merge`,
+	} {
+		if !strings.Contains(run, want) {
+			t.Errorf("run step missing %q:\n%s", want, run)
+		}
+	}
+	if strings.Contains(run, "--yes") {
+		t.Errorf("unconfirmed run passes --yes:\n%s", run)
+	}
+
+	// Protected environments confirmed when scheduling are confirmed for
+	// the run, which has no terminal.
+	f.Confirmed = true
+	if b, err = f.workflow(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "--auto-merge --yes") {
+		t.Errorf("confirmed run doesn't pass --yes:\n%s", b)
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var sortKeys = []string{"ip", "port", "file", "line"}
+
+// parseSortKey validates a --sort value. Empty means walk order.
+func parseSortKey(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || slices.Contains(sortKeys, s) {
+		return s, nil
+	}
+	return "", usageErrorf("invalid --sort %q (want %s)", s, strings.Join(sortKeys, "|"))
+}
+
+// sortRows orders rows in place by key. IPs and ports compare numerically;
+// rows lacking the key sort last in either direction, and ties keep file
+// and line order.
+func sortRows(rows []matchRow, key string, desc bool) {
+	if key == "" {
+		return
+	}
+	position := func(a, b matchRow) int {
+		return cmp.Or(strings.Compare(a.Branch, b.Branch), strings.Compare(a.RelPath, b.RelPath),
+			cmp.Compare(a.LineNumber, b.LineNumber))
+	}
+	slices.SortStableFunc(rows, func(a, b matchRow) int {
+		var c int
+		switch key {
+		case "ip":
+			aa, aok := rowAddr(a)
+			ba, bok := rowAddr(b)
+			if aok != bok {
+				return missingLast(aok)
+			}
+			c = aa.Compare(ba)
+		case "port":
+			ap, aerr := strconv.Atoi(a.PortValue)
+			bp, berr := strconv.Atoi(b.PortValue)
+			if (aerr == nil) != (berr == nil) {
+				return missingLast(aerr == nil)
+			}
+			c = cmp.Compare(ap, bp)
+		case "file":
+			c = position(a, b)
+		case "line":
+			c = cmp.Compare(a.LineNumber, b.LineNumber)
+		}
+		if desc {
+			c = -c
+		}
+		return cmp.Or(c, position(a, b))
+	})
+}
+
+// missingLast orders a row that has the sort key before one that doesn't.
+func missingLast(aHasKey bool) int {
+	if aHasKey {
+		return -1
+	}
+	return 1
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSortRows(t *testing.T) {
+	base := []matchRow{
+		{IPValue: "10.0.0.10", PortValue: "8080", RelPath: "b.env", LineNumber: 3},
+		{PortValue: "443", RelPath: "a.env", LineNumber: 9},
+		{IPValue: "10.0.0.9", RelPath: "c.env", LineNumber: 1},
+		{IPValue: "10.0.0.10", PortValue: "22", RelPath: "a.env", LineNumber: 2},
+	}
+	label := func(rows []matchRow) string {
+		var out []string
+		for _, r := range rows {
+			out = append(out, r.RelPath+":"+strconv.Itoa(r.LineNumber))
+		}
+		return strings.Join(out, " ")
+	}
+
+	tests := []struct {
+		key  string
+		desc bool
+		want string
+	}{
+		{"", false, "b.env:3 a.env:9 c.env:1 a.env:2"},
+		{"ip", false, "c.env:1 a.env:2 b.env:3 a.env:9"},
+		{"ip", true, "a.env:2 b.env:3 c.env:1 a.env:9"},
+		{"port", false, "a.env:2 a.env:9 b.env:3 c.env:1"},
+		{"port", true, "b.env:3 a.env:9 a.env:2 c.env:1"},
+		{"file", false, "a.env:2 a.env:9 b.env:3 c.env:1"},
+		{"file", true, "c.env:1 b.env:3 a.env:9 a.env:2"},
+		{"line", false, "c.env:1 a.env:2 b.env:3 a.env:9"},
+	}
+	for _, tt := range tests {
+		rows := append([]matchRow(nil), base...)
+		sortRows(rows, tt.key, tt.desc)
+		if got := label(rows); got != tt.want {
+			t.Errorf("sortRows(%q, desc=%v) = %s, want %s", tt.key, tt.desc, got, tt.want)
+		}
+	}
+
+	if _, err := parseSortKey("severity"); err == nil {
+		t.Error("Expected error for unknown sort key")
+	}
+	if k, err := parseSortKey(" IP "); err != nil || k != "ip" {
+		t.Errorf("parseSortKey(\" IP \") = %q, %v", k, err)
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// flip-adapters edits one file per environment, so it clones without file
+// contents (a blobless partial clone) and checks out only the env/ENV/
+// directories it flips, plus the files at the top of the repository such
+// as .aca.yaml. Flipping a line in a large monorepo then downloads little
+// more than its commit and trees.
+
+// sparseClone makes a blobless clone of repo's default branch with
+// nothing checked out, holding an empty directory for every environment
+// under env/ so environment patterns resolve as they do in a full clone.
+// Call sparseCheckout with the environments to flip next.
+func sparseClone(repo string) (string, func(), error) {
+	if useGoGit() {
+		return "", nil, fmt.Errorf("sparse checkouts need the git binary")
+	}
+	tmp, cleanup, err := makeTemp("gh-aca-utils-")
+	if err != nil {
+		return "", nil, err
+	}
+	debugf("cloning %s without file contents into %s", repo, tmp)
+	if err := cloneRepo(repo, tmp, cloneOptions{depth: 1, partial: true, noCheckout: true}); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	envs, err := gitOutput(tmp, "ls-tree", "-d", "--name-only", "HEAD:env")
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("list environments: %w", err)
+	}
+	for _, env := range strings.Split(envs, "\n") {
+		if env == "" {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Join(tmp, "env", filepath.FromSlash(env)), 0750); err != nil {
+			cleanup()
+			return "", nil, err
+		}
+	}
+	return tmp, cleanup, nil
+}
+
+// sparseCheckout checks out env/ENV/ for each of envs, and the top-level
+// files, in a clone made by sparseClone.
+func sparseCheckout(dir string, envs []string) error {
+	dirs := make([]string, len(envs))
+	for i, env := range envs {
+		dirs[i] = path.Join("env", env)
+	}
+	debugf("checking out %s", strings.Join(dirs, ", "))
+	if err := gitIn(dir, append([]string{"sparse-checkout", "set", "--cone", "--"}, dirs...)...); err != nil {
+		warnf("sparse checkout failed (%v); checking out the whole repository", err)
+	}
+	return gitIn(dir, "read-tree", "-mu", "HEAD")
+}
+
+// sparseRead returns a reader for files of the sparse clone at dir that
+// reads those outside its checkout, such as .github/CODEOWNERS, from
+// its head commit.
+func sparseRead(dir string) func(rel string) ([]byte, error) {
+	read := readIn(dir)
+	return func(rel string) ([]byte, error) {
+		if b, err := read(rel); err == nil {
+			return b, nil
+		}
+		cmd := command(dir, "git", "show", "HEAD:"+rel)
+		return cmd.Output()
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSparseClone(t *testing.T) {
+	work := initTestRepo(t, map[string]map[string]string{
+		"main": {
+			"env/dev/parameters.properties":  "billing=0\n",
+			"env/prod/parameters.properties": "billing=1\n",
+			"services/big/data.bin":          "large\n",
+			".github/CODEOWNERS":             "/env/ @org/platform\n",
+			".aca.yaml":                      "adapters:\n  file: parameters.properties\n",
+		},
+	}, "main")
+	serveRepo(t, work, "org/svc")
+	// Clone with git, not gh, whatever is installed.
+	git, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not available")
+	}
+	bin := t.TempDir()
+	if err := os.Symlink(git, filepath.Join(bin, "git")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	dir, cleanup, err := sparseClone("org/svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	envs, err := resolveEnvList(dir, []string{"*"})
+	if err != nil || !reflect.DeepEqual(envs, []string{"dev", "prod"}) {
+		t.Fatalf("environments before checkout = %v, %v", envs, err)
+	}
+	if err := sparseCheckout(dir, []string{"dev"}); err != nil {
+		t.Fatal(err)
+	}
+	exists := func(rel string) bool {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(rel)))
+		return err == nil
+	}
+	for rel, want := range map[string]bool{
+		"env/dev/parameters.properties":  true,
+		".aca.yaml":                      true,
+		"env/prod/parameters.properties": false,
+		"services/big/data.bin":          false,
+	} {
+		if got := exists(rel); got != want {
+			t.Errorf("%s checked out = %v, want %v", rel, got, want)
+		}
+	}
+	if b, err := sparseRead(dir)(".github/CODEOWNERS"); err != nil || string(b) != "/env/ @org/platform\n" {
+		t.Errorf("sparseRead(CODEOWNERS) = %q, %v", b, err)
+	}
+	if _, err := sparseRead(dir)("missing"); err == nil {
+		t.Error("sparseRead(missing) succeeded")
+	}
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/greenstevester/gh-aca-utils/pkg/jsonptr"
+	"github.com/greenstevester/gh-aca-utils/pkg/props"
+	"github.com/spf13/cobra"
+)
+
+// adapterValue is the current value of an adapter in one environment.
+type adapterValue struct {
+	Env     string `json:"env"`
+	Adapter string `json:"adapter"`
+	Value   string `json:"value"`
+	File    string `json:"file"`
+	Missing bool   `json:"missing,omitempty"`
+}
+
+// readEnvValues reads env/<env>/<paramFile> under root and returns the
+// values of want, or of every key (every scalar, for JSON) when want is
+// empty.
+func readEnvValues(root, env, paramFile string, want []string) ([]adapterValue, error) {
+	rel := filepath.ToSlash(filepath.Join("env", env, paramFile))
+	b, err := os.ReadFile(filepath.Join(root, rel)) // #nosec G304 - env and file names are validated by the caller
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", rel, err)
+	}
+	return parseEnvValues(b, env, rel, want)
+}
+
+// parseEnvValues is readEnvValues for the content b of the file rel.
+func parseEnvValues(b []byte, env, rel string, want []string) ([]adapterValue, error) {
+	var err error
+	values := make([]adapterValue, 0)
+	if isJSONParams(rel) {
+		if !json.Valid(b) {
+			return nil, fmt.Errorf("%s: invalid JSON", rel)
+		}
+		if len(want) == 0 {
+			if want, err = jsonptr.Leaves(b); err != nil {
+				return nil, fmt.Errorf("%s: %w", rel, err)
+			}
+		}
+		for _, a := range want {
+			v := adapterValue{Env: env, Adapter: a, File: rel}
+			if span, err := jsonptr.Find(b, adapterPointer(a)); err != nil {
+				v.Missing = true
+			} else {
+				v.Value, _ = jsonValue(string(b[span.Start:span.End]))
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+
+	entries := props.Parse(b)
+	if len(want) == 0 {
+		for _, e := range entries {
+			values = append(values, adapterValue{Env: env, Adapter: e.Key, Value: valueWithoutComment(e.Value), File: rel})
+		}
+		return values, nil
+	}
+	index := map[string]string{}
+	for _, e := range entries {
+		index[e.Key] = valueWithoutComment(e.Value)
+	}
+	for _, a := range want {
+		v, ok := index[a]
+		values = append(values, adapterValue{Env: env, Adapter: a, Value: v, File: rel, Missing: !ok})
+	}
+	return values, nil
+}
+
+func cmdStatus() *cobra.Command {
+	var repo, ref, envName, adaptersCSV, paramFile, mode string
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the current value of each adapter in env/<ENV>/parameters.properties",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if repo == "" {
+				return usageErrorf("--repo ORG/REPO is required")
+			}
+			if envName == "" {
+				return usageErrorf("--env is required (e.g., dev)")
+			}
+			if err := checkParamFile(paramFile); err != nil {
+				return err
+			}
+			modeVal := parseMode(mode, outTable)
+
+			tmpDir, cleanup, err := cloneOrDownload(repo, ref)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			if paramFile, err = repoParamFile(paramFile, cmd.Flags().Changed("file"), loadConfig(tmpDir)); err != nil {
+				return err
+			}
+
+			envs, err := resolveEnvs(tmpDir, envName)
+			if err != nil {
+				return err
+			}
+			values := make([]adapterValue, 0)
+			for _, env := range envs {
+				envValues, err := readEnvValues(tmpDir, env, paramFile, splitCSV(adaptersCSV, nil))
+				if err != nil {
+					return err
+				}
+				values = append(values, envValues...)
+			}
+			return printAdapterValues(values, modeVal)
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Target repo as ORG/REPO (required)")
+	cmd.Flags().StringVar(&ref, "ref", "", "Branch or tag (default: default branch)")
+	cmd.Flags().StringVar(&envName, "env", "", "Environment directory under env/, or a glob such as 'dev*' (required)")
+	cmd.Flags().StringVar(&adaptersCSV, "adapters", "", "Comma-separated adapter keys (default: every key in the file)")
+	cmd.Flags().StringVar(&paramFile, "file", "parameters.properties", "Parameters file in each env directory; .json files take JSON pointers as adapters")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table|json")
+	setOutputModes(cmd, "table", "json")
+	return cmd
+}
+
+func printAdapterValues(values []adapterValue, mode outputMode) error {
+	if mode == outJSON {
+		enc := json.NewEncoder(stdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(values)
+	}
+	w := newTable()
+	w.AddRow("Env", "Adapter", "Value", "File")
+	for _, v := range values {
+		value := v.Value
+		if v.Missing {
+			value = "(missing)"
+		}
+		w.AddRow(v.Env, v.Adapter, value, v.File)
+	}
+	w.Render()
+	return nil
+}
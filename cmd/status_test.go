@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadEnvValues(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"env/dev/parameters.properties": "# adapters\nbilling = 1 # on since May\nsearch: off\n",
+		"env/dev/appsettings.json":      `{"Features": {"Billing": true, "Mode": "auto"}, "Level": 2}`,
+	}
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		file, adapters, want string
+	}{
+		{"parameters.properties", "", "billing=1,search=off"},
+		{"parameters.properties", "search,missing", "search=off,missing?"},
+		{"appsettings.json", "", "/Features/Billing=true,/Features/Mode=auto,/Level=2"},
+		{"appsettings.json", "Level,/Features/Mode,/Nope", "Level=2,/Features/Mode=auto,/Nope?"},
+	}
+	for _, tt := range tests {
+		values, err := readEnvValues(root, "dev", tt.file, splitCSV(tt.adapters, nil))
+		if err != nil {
+			t.Fatalf("readEnvValues(%s, %q): %v", tt.file, tt.adapters, err)
+		}
+		var got []string
+		for _, v := range values {
+			if v.Missing {
+				got = append(got, v.Adapter+"?")
+				continue
+			}
+			got = append(got, fmt.Sprintf("%s=%s", v.Adapter, v.Value))
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("readEnvValues(%s, %q) = %v, want %s", tt.file, tt.adapters, got, tt.want)
+		}
+	}
+	if _, err := readEnvValues(root, "qa", "parameters.properties", nil); err == nil {
+		t.Error("Expected error for a missing environment")
+	}
+}
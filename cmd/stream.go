@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// orderedEmitter receives results that complete out of order (from a
+// worker pool) and hands them to emit in index order as soon as every
+// earlier result is in. Without an emit func it only collects.
+type orderedEmitter struct {
+	mu      sync.Mutex
+	results [][]matchRow
+	done    []bool
+	next    int
+	emit    func([]matchRow)
+}
+
+func newOrderedEmitter(n int, emit func([]matchRow)) *orderedEmitter {
+	return &orderedEmitter{results: make([][]matchRow, n), done: make([]bool, n), emit: emit}
+}
+
+// set records the result for index i. Calls to emit are serialised.
+func (o *orderedEmitter) set(i int, rows []matchRow) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.results[i], o.done[i] = rows, true
+	if o.emit == nil {
+		return
+	}
+	for o.next < len(o.done) && o.done[o.next] {
+		o.emit(o.results[o.next])
+		o.results[o.next] = nil // emitted rows are not retained
+		o.next++
+	}
+}
+
+// rows returns the collected results in index order. Emitted results are
+// not included.
+func (o *orderedEmitter) rows() []matchRow {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var out []matchRow
+	for _, r := range o.results {
+		out = append(out, r...)
+	}
+	return out
+}
+
+// rowStream prints rows as they are found, for output formats that need
+// no look-ahead.
+type rowStream struct {
+	w       io.Writer
+	mode    outputMode
+	cols    []rowColumn
+	tmpl    *template.Template // --format; replaces the CSV layout
+	started bool
+	err     error
+}
+
+// newRowStream returns a stream for mode or a --format template, or nil if
+// the mode has to see all rows before printing (e.g. table column widths).
+func newRowStream(mode outputMode, cols []rowColumn, tmpl *template.Template) *rowStream {
+	if mode != outCSV && mode != outNDJSON && tmpl == nil {
+		return nil
+	}
+	return &rowStream{w: stdout(), mode: mode, cols: cols, tmpl: tmpl}
+}
+
+func (s *rowStream) write(rows []matchRow) {
+	if s.tmpl != nil {
+		if s.err == nil {
+			s.err = writeTemplateRows(s.w, s.tmpl, rows)
+		}
+		return
+	}
+	if s.mode == outNDJSON {
+		for _, r := range rows {
+			fmt.Fprintf(s.w, "%s\n", columnsObject(r, s.cols))
+		}
+		return
+	}
+	if !s.started {
+		fmt.Fprintln(s.w, csvHeader(s.cols))
+		s.started = true
+	}
+	for _, r := range rows {
+		fmt.Fprintln(s.w, csvLine(r, s.cols))
+	}
+}
+
+// close finishes the output and returns the first write error. An empty
+// CSV scan still prints the header.
+func (s *rowStream) close() error {
+	s.write(nil)
+	return s.err
+}
+
+func csvHeader(cols []rowColumn) string {
+	headers := make([]string, len(cols))
+	for i, c := range cols {
+		headers[i] = csvEsc(c.Header)
+	}
+	return strings.Join(headers, ",")
+}
+
+func csvLine(r matchRow, cols []rowColumn) string {
+	values := make([]string, len(cols))
+	for i, c := range cols {
+		values[i] = csvEsc(c.Value(r))
+	}
+	return strings.Join(values, ",")
+}
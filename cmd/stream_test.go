@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOrderedEmitter(t *testing.T) {
+	var got []string
+	o := newOrderedEmitter(3, func(rows []matchRow) {
+		for _, r := range rows {
+			got = append(got, r.IPValue)
+		}
+	})
+
+	o.set(2, []matchRow{{IPValue: "c"}})
+	o.set(1, []matchRow{{IPValue: "b"}})
+	if len(got) != 0 {
+		t.Fatalf("Expected nothing emitted before index 0 completes, got %v", got)
+	}
+	o.set(0, []matchRow{{IPValue: "a"}})
+	if strings.Join(got, ",") != "a,b,c" {
+		t.Errorf("Expected in-order emission, got %v", got)
+	}
+	if rows := o.rows(); len(rows) != 0 {
+		t.Errorf("Expected emitted rows not to be retained, got %+v", rows)
+	}
+
+	collect := newOrderedEmitter(2, nil)
+	collect.set(1, []matchRow{{IPValue: "y"}})
+	collect.set(0, []matchRow{{IPValue: "x"}})
+	if rows := collect.rows(); len(rows) != 2 || rows[0].IPValue != "x" {
+		t.Errorf("Expected collected rows in index order, got %+v", rows)
+	}
+}
+
+func TestScanTree_Streaming(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 20; i++ {
+		name := filepath.Join(root, fmt.Sprintf("f%02d.env", i))
+		if err := os.WriteFile(name, []byte(fmt.Sprintf("HOST=10.0.0.%d\nAPP_PORT=%d\n", i, 8000+i)), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, valueIgnoreFile), []byte("10.0.0.3\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := scanOptions{includes: []string{"**/*.env"}, jobs: 4}
+	want, err := scanTree(root, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	stream := &rowStream{w: &buf, mode: outCSV, cols: defaultColumns()}
+	opts.emit = stream.write
+	rows, err := scanTree(root, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.close()
+	if rows != nil {
+		t.Errorf("Expected no rows to be returned when streaming, got %d", len(rows))
+	}
+
+	var expected strings.Builder
+	expected.WriteString(csvHeader(defaultColumns()) + "\n")
+	for _, r := range want {
+		expected.WriteString(csvLine(r, defaultColumns()) + "\n")
+	}
+	if buf.String() != expected.String() {
+		t.Errorf("Streamed output differs from buffered output:\n%s\nwant\n%s", buf.String(), expected.String())
+	}
+}
+
+func TestRowStream_EmptyScan(t *testing.T) {
+	var buf bytes.Buffer
+	s := &rowStream{w: &buf, mode: outCSV, cols: defaultColumns()}
+	s.close()
+	if got := strings.Split(buf.String(), "\n"); !reflect.DeepEqual(got, []string{csvHeader(defaultColumns()), ""}) {
+		t.Errorf("Expected header only, got %q", buf.String())
+	}
+}
+
+func TestRowStream_NDJSON(t *testing.T) {
+	cols, _ := parseColumns("ipValue,lineNumber")
+	var buf bytes.Buffer
+	s := newRowStream(outNDJSON, cols, nil)
+	s.w = &buf
+	s.write([]matchRow{{IPValue: "10.0.0.1", LineNumber: 3}})
+	s.write(nil)
+	s.write([]matchRow{{IPValue: "10.0.0.2", LineNumber: 4}})
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "{\"ipValue\":\"10.0.0.1\",\"lineNumber\":3}\n{\"ipValue\":\"10.0.0.2\",\"lineNumber\":4}\n"
+	if buf.String() != want {
+		t.Errorf("NDJSON stream = %q, want %q", buf.String(), want)
+	}
+}
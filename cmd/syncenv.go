@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/greenstevester/gh-aca-utils/pkg/props"
+	"github.com/spf13/cobra"
+)
+
+// syncNext is the planValues step that copies values from another
+// environment. Adapters missing from the source, or already equal, are
+// left alone.
+func syncNext(source []adapterValue, from string) func(adapter, v string) (string, bool) {
+	values := map[string]adapterValue{}
+	for _, s := range source {
+		values[s.Adapter] = s
+	}
+	return func(adapter, v string) (string, bool) {
+		s, ok := values[adapter]
+		if !ok || s.Missing {
+			warnf("adapter %q not found in env/%s; skipping", adapter, from)
+			return "", false
+		}
+		return s.Value, s.Value != v
+	}
+}
+
+// planSync plans copying want from one environment's parameters file to
+// another's and returns the changes with the target's current and new
+// content.
+func planSync(root, from, to, paramFile string, want []string) ([]change, string, string, error) {
+	source, err := readEnvValues(root, from, paramFile, want)
+	if err != nil {
+		return nil, "", "", err
+	}
+	rel := filepath.ToSlash(filepath.Join("env", to, paramFile))
+	return planFile(root, rel, want, syncNext(source, from))
+}
+
+// planFile plans the changes next makes to want in the parameters file rel
+// under root, and returns them with the file's current and new content.
+func planFile(root, rel string, want []string, next func(adapter, v string) (string, bool)) ([]change, string, string, error) {
+	b, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel))) // #nosec G304 - env and file names are validated by the caller
+	if err != nil {
+		return nil, "", "", fmt.Errorf("read %s: %w", rel, err)
+	}
+	if isJSONParams(rel) {
+		changes, err := planJSONValues(b, want, rel, next)
+		if err != nil {
+			return nil, "", "", err
+		}
+		return changes, string(b), string(applyJSONFlips(b, changes)), nil
+	}
+	lines := props.SplitLinesEOL(b)
+	changes := planValues(lines, indexKeys(lines), want, rel, next)
+	after := strings.Join(applyFlips(append([]string(nil), lines...), changes), "")
+	return changes, string(b), after, nil
+}
+
+func cmdSyncEnv() *cobra.Command {
+	var repo, from, to, adaptersCSV, paramFile, branch, mode string
+	var doCommit, doPR, dryRun, yes bool
+
+	cmd := &cobra.Command{
+		Use:   "sync-env",
+		Short: "Copy adapter values from one environment's parameters file to another",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if repo == "" {
+				return usageErrorf("--repo ORG/REPO is required")
+			}
+			if from == "" || to == "" {
+				return usageErrorf("--from and --to environments are required (e.g., --from staging --to prod)")
+			}
+			if from == to {
+				return usageErrorf("--from and --to are the same environment")
+			}
+			for _, env := range []string{from, to} {
+				if isEnvPattern(env) {
+					return usageErrorf("invalid environment %q: sync-env takes a single environment, not a pattern", env)
+				}
+			}
+			want, err := adaptersOrStored(adaptersCSV, repo, to)
+			if err != nil {
+				return err
+			}
+			if err := checkParamFile(paramFile); err != nil {
+				return err
+			}
+			modeVal := parseMode(mode, outTable)
+			doCommit = doCommit || doPR
+			var access pushAccess
+			if doCommit && !dryRun {
+				if access, err = preflight(repo, false); err != nil {
+					return err
+				}
+			}
+
+			tmpDir, cleanup, err := cloneOrDownload(repo, "")
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			if _, err := resolveEnvs(tmpDir, from); err != nil {
+				return err
+			}
+			if _, err := resolveEnvs(tmpDir, to); err != nil {
+				return err
+			}
+
+			changes, before, after, err := planSync(tmpDir, from, to, paramFile, want)
+			if err != nil {
+				return err
+			}
+			cfg := loadConfig(tmpDir)
+			if err := checkConstraints(cfg, changes); err != nil {
+				return err
+			}
+			rel := filepath.ToSlash(filepath.Join("env", to, paramFile))
+			if modeVal == outJSON {
+				if err := printChangeReport(changes, outJSON); err != nil {
+					return err
+				}
+			} else if err := printRewrites(fileEdits(rel, before, after), outTable); err != nil {
+				return err
+			}
+			if dryRun || len(changes) == 0 {
+				return nil
+			}
+
+			patterns, _ := cfg["protect.environments"].Value.([]string)
+			typeName, _ := cfg["protect.typeName"].Value.(bool)
+			if err := confirmProtected(repo, protectedEnvs([]string{to}, patterns), typeName, yes, newPrompter()); err != nil {
+				return err
+			}
+			approved, err := requireApproval(repo, protectedEnvs([]string{to}, patterns), cfg)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(tmpDir, filepath.FromSlash(rel)), []byte(after), 0600); err != nil {
+				return fmt.Errorf("write %s: %w", rel, err)
+			}
+			if !doCommit {
+				return nil
+			}
+			adapters := make([]string, len(changes))
+			for i, c := range changes {
+				adapters[i] = c.Adapter
+			}
+			if branch == "" {
+				branch = "sync/" + branchSlug(from) + "-to-" + branchSlug(to)
+			}
+			msg := fmt.Sprintf("chore(env:%s): sync adapters %s from %s", to, strings.Join(adapters, ","), from)
+			title := fmt.Sprintf("Sync adapters from %s to %s: %s", from, to, strings.Join(adapters, ", "))
+			body := fmt.Sprintf("Automated via gh aca-utils sync-env.\n\nCopies %d adapter value(s) from env/%s to env/%s.", len(changes), from, to)
+			if err := access.prepare(tmpDir); err != nil {
+				return err
+			}
+			prURL, err := commitRewrites(tmpDir, branch, []string{rel}, msg, title, body, doPR)
+			if err != nil {
+				return err
+			}
+
+			entry := journalEntry{Command: "sync", Repo: repo, File: paramFile, Branch: branch, PRURL: prURL, Approver: approved.By, ApprovalRun: approved.Run}
+			for _, c := range changes {
+				entry.Changes = append(entry.Changes, journalChange{Env: to, Adapter: c.Adapter, Old: c.OldValue, New: c.NewValue})
+			}
+			recordJournal(entry)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Target repo as ORG/REPO (required)")
+	cmd.Flags().StringVar(&from, "from", "", "Environment to copy values from (required)")
+	cmd.Flags().StringVar(&to, "to", "", "Environment to copy values to (required)")
+	cmd.Flags().StringVar(&adaptersCSV, "adapters", "", "Comma-separated adapter keys (or use stored adapters from 'set-adapters')")
+	cmd.Flags().StringVar(&paramFile, "file", "parameters.properties", "Parameters file in each env directory; .json files take JSON pointers as adapters")
+	cmd.Flags().StringVar(&branch, "branch", "", "Branch name to create (default: sync/<from>-to-<to>)")
+	cmd.Flags().BoolVar(&doCommit, "commit", false, "Commit the change to a new branch and push")
+	cmd.Flags().BoolVar(&doPR, "pr", false, "Create a pull request (implies --commit)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", true, "Show the diff without writing")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Don't ask before changing protected environments")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table (diff)|json")
+	setOutputModes(cmd, "table", "json")
+	cmd.Flags().StringVar(&approvalRun, "approval-run", "", "Workflow run whose approved deployment review approves protected environments (protect.approval: environment)")
+	return cmd
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlanSync(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"env/staging/parameters.properties": "billing=1\nsearch = off\ncrm=1\n",
+		"env/prod/parameters.properties":    "billing=0 # promoted later\r\nsearch = off\r\ncrm=0\r\n",
+		"env/staging/appsettings.json":      `{"Features": {"Billing": true, "Mode": "fast"}}`,
+		"env/prod/appsettings.json":         "{\n  \"Features\": {\n    \"Billing\": false,\n    \"Mode\": \"safe\"\n  }\n}\n",
+	}
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes, before, after, err := planSync(root, "staging", "prod", "parameters.properties", []string{"billing", "search", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Adapter != "billing" || changes[0].OldValue != "0" || changes[0].NewValue != "1" {
+		t.Fatalf("Unexpected changes: %+v", changes)
+	}
+	if want := strings.Replace(before, "billing=0", "billing=1", 1); after != want {
+		t.Errorf("after = %q, want %q", after, want)
+	}
+	edits := fileEdits("env/prod/parameters.properties", before, after)
+	if len(edits) != 1 || edits[0].Line != 1 || edits[0].After != "billing=1 # promoted later\r" {
+		t.Errorf("Unexpected edits: %+v", edits)
+	}
+
+	changes, _, after, err = planSync(root, "staging", "prod", "appsettings.json", []string{"/Features/Billing", "/Features/Mode"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || !strings.Contains(after, `"Billing": true,`) || !strings.Contains(after, `"Mode": "fast"`) {
+		t.Errorf("Unexpected JSON sync: %+v\n%s", changes, after)
+	}
+}
+
+func TestFileEdits_ChangedLineCount(t *testing.T) {
+	edits := fileEdits("a.properties", "a=1\nb=\\\n  0\nc=1", "a=1\nb=1\nc=1")
+	if len(edits) != 1 || edits[0].Line != 2 || edits[0].Before != "b=\\\n  0" || edits[0].After != "b=1" {
+		t.Errorf("Unexpected edits: %+v", edits)
+	}
+}
+
+func TestCmdSyncEnv_Protected(t *testing.T) {
+	work := initTestRepo(t, map[string]map[string]string{
+		"main": {
+			"env/staging/parameters.properties": "billing=1\n",
+			"env/prod/parameters.properties":    "billing=0\n",
+			".aca.yaml":                         "protect:\n  environments: [prod]\n",
+		},
+	}, "main")
+	serveRepo(t, work, "org/svc")
+	t.Setenv("ACA_CONFIG_DIR", t.TempDir())
+	git, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not available")
+	}
+	bin := t.TempDir()
+	if err := os.Symlink(git, filepath.Join(bin, "git")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	for _, tt := range []struct {
+		args []string
+		ok   bool
+	}{
+		{nil, false}, // not a terminal to confirm on
+		{[]string{"--yes"}, true},
+	} {
+		cmd := cmdSyncEnv()
+		cmd.SetArgs(append([]string{"--repo", "org/svc", "--from", "staging", "--to", "prod", "--adapters", "billing", "--dry-run=false"}, tt.args...))
+		cmd.SilenceUsage, cmd.SilenceErrors = true, true
+		if err := cmd.Execute(); (err == nil) != tt.ok {
+			t.Errorf("sync-env %v: err = %v", tt.args, err)
+		}
+	}
+}
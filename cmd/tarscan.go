@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+)
+
+// When a scanned repository can't be cloned, its tarball is scanned as it
+// downloads: entries matching the include patterns are read into memory
+// and scanned one at a time, and the rest are skipped unread, so nothing
+// is extracted to disk.
+
+// scanTarball scans the tarball of repo at ref (default branch if empty)
+// without extracting it. cloneErr is why the repository wasn't cloned.
+func scanTarball(repo, ref string, cloneErr error, opts scanOptions) (*scanCacheEntry, error) {
+	var entry *scanCacheEntry
+	err := withRetry("download "+tarballPath(repo, ref), func() error {
+		return downloadTarball(repo, ref, cloneErr, func(r io.Reader) error {
+			var err error
+			entry, err = scanTarGz(r, opts)
+			return err
+		})
+	})
+	return entry, err
+}
+
+// tarFile is a file read from a tarball.
+type tarFile struct {
+	rel  string
+	data []byte
+}
+
+// scanTarGz scans the files of a gzipped repository tarball whose entries
+// sit under one top-level directory, as GitHub's do, and applies the
+// repository's scan config from it. Entries come in path order, so the
+// config at the top of the tree is read before all but the few files
+// sorting ahead of it, which are held until it has been applied.
+func scanTarGz(r io.Reader, opts scanOptions) (*scanCacheEntry, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = gz.Close() }()
+
+	entry := &scanCacheEntry{}
+	meta := map[string][]byte{}
+	lastConfig := slices.Max(repoConfigFiles)
+	configured := false
+	var pending []tarFile
+	wanted := func(rel string) bool {
+		if matchAny(rel, opts.excludes) {
+			tracef("skip %s: excluded", rel)
+			return false
+		}
+		return matchAny(rel, opts.includes)
+	}
+	scan := func(f tarFile) {
+		if wanted(f.rel) {
+			tracef("scan %s", f.rel)
+			entry.Rows = append(entry.Rows, scanData(f.data, f.rel, opts)...)
+		}
+	}
+	configure := func() error {
+		configured = true
+		if err := applyRepoScanConfigFiles(meta, &opts); err != nil {
+			return err
+		}
+		for _, f := range pending {
+			scan(f)
+		}
+		pending = nil
+		return nil
+	}
+
+	tr := tar.NewReader(gz)
+	for !canceled() {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		_, rel, ok := strings.Cut(hdr.Name, "/")
+		if hdr.Typeflag != tar.TypeReg || !ok || rel == "" {
+			continue
+		}
+		if !configured && rel > lastConfig {
+			if err := configure(); err != nil {
+				return nil, err
+			}
+		}
+		isMeta := rel == valueIgnoreFile || slices.Contains(repoConfigFiles, rel)
+		if configured && !isMeta && !wanted(rel) {
+			continue
+		}
+
+		// Limit file size to prevent decompression bombs
+		data, err := io.ReadAll(io.LimitReader(tr, maxTarFileSize))
+		if err != nil {
+			return nil, err
+		}
+		if isMeta {
+			meta[rel] = data
+		}
+		if configured {
+			scan(tarFile{rel, data})
+		} else {
+			pending = append(pending, tarFile{rel, data})
+		}
+	}
+	if !configured {
+		if err := configure(); err != nil {
+			return nil, err
+		}
+	}
+	entry.Ignores = string(meta[valueIgnoreFile])
+	debugf("scanned %d finding(s) from the tarball", len(entry.Rows))
+	return entry, nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+)
+
+func TestScanTarGz(t *testing.T) {
+	t.Setenv("ACA_CONFIG_DIR", t.TempDir())
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	add := func(hdr *tar.Header, body string) {
+		t.Helper()
+		hdr.Size = int64(len(body))
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file := func(name, body string) {
+		t.Helper()
+		add(&tar.Header{Name: "org-svc-abc123/" + name, Typeflag: tar.TypeReg, Mode: 0644}, body)
+	}
+	add(&tar.Header{Name: "pax_global_header", Typeflag: tar.TypeXGlobalHeader, PAXRecords: map[string]string{"comment": "abc123"}}, "")
+	add(&tar.Header{Name: "org-svc-abc123/", Typeflag: tar.TypeDir, Mode: 0755}, "")
+	// Sorts ahead of the config, which still applies to it.
+	file("-early/app.properties", "db.host=10.0.0.5\n")
+	file(".aca.yaml", "scan:\n  exclude: ['-early/**', 'skip/**']\n")
+	file(".acaignore-values", "10.0.0.9\n")
+	file("app.properties", "db.host=10.0.0.1\n")
+	add(&tar.Header{Name: "org-svc-abc123/link.properties", Typeflag: tar.TypeSymlink, Linkname: "app.properties"}, "")
+	file("skip/app.properties", "db.host=10.0.0.2\n")
+	file("z/app.yml", "host: 10.0.0.9\n")
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := scanOptions{includes: []string{"**/*.properties", "**/*.yml"}}
+	entry, err := scanTarGz(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw []string
+	for _, r := range entry.Rows {
+		raw = append(raw, r.RelPath+"="+r.IPValue)
+	}
+	if want := []string{"app.properties=10.0.0.1", "z/app.yml=10.0.0.9"}; !reflect.DeepEqual(raw, want) {
+		t.Errorf("raw rows = %v, want %v", raw, want)
+	}
+	rows, err := entry.finish(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].IPValue != "10.0.0.1" {
+		t.Errorf("after .acaignore-values: %+v, want only 10.0.0.1", rows)
+	}
+}
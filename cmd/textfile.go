@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Scanned files are decoded to UTF-8 before they are split into lines:
+// a UTF-8 byte order mark is dropped, and UTF-16 files, with a byte order
+// mark or recognizably without one (as Windows tools write them), are
+// converted, so their addresses are found like any other.
+
+// defaultMaxLineLength is how much of a line is scanned when
+// --max-line-length isn't given. Minified JSON fits on one line.
+const defaultMaxLineLength = 16 << 20
+
+// decodeText returns data as UTF-8, and the encoding it was in when that
+// wasn't plain UTF-8.
+func decodeText(data []byte) ([]byte, string) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return data[3:], "UTF-8 with BOM"
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], binary.LittleEndian), "UTF-16LE"
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], binary.BigEndian), "UTF-16BE"
+	}
+	if order, enc := sniffUTF16(data); order != nil {
+		return decodeUTF16(data, order), enc + " without BOM"
+	}
+	return data, ""
+}
+
+// sniffUTF16 recognizes UTF-16 text without a byte order mark by the zero
+// high bytes of its ASCII characters: in the first KiB, most characters
+// have one and none has a zero low byte. It returns nil for anything else.
+func sniffUTF16(data []byte) (binary.ByteOrder, string) {
+	n := min(len(data), 1024) / 2
+	if n == 0 {
+		return nil, ""
+	}
+	var even, odd int
+	for i := 0; i < n; i++ {
+		if data[2*i] == 0 {
+			even++
+		}
+		if data[2*i+1] == 0 {
+			odd++
+		}
+	}
+	switch {
+	case even == 0 && odd > n/2:
+		return binary.LittleEndian, "UTF-16LE"
+	case odd == 0 && even > n/2:
+		return binary.BigEndian, "UTF-16BE"
+	}
+	return nil, ""
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+// textLines splits data into lines as bufio.ScanLines does, dropping the
+// \r of \r\n, but without its 64 KiB limit on a line. Lines longer than
+// limit bytes are cut to limit; cut counts them.
+func textLines(data []byte, limit int) (lines []string, cut int) {
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			data = nil
+		}
+		line = bytes.TrimSuffix(line, []byte{'\r'})
+		if limit > 0 && len(line) > limit {
+			line = line[:limit]
+			cut++
+		}
+		lines = append(lines, string(line))
+	}
+	return lines, cut
+}
+
+// parseSize parses a byte count such as 1048576, 512K, 512KiB or 16MB.
+// The units are binary: K is 1024 bytes.
+func parseSize(s string) (int, error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	t = strings.TrimSuffix(strings.TrimSuffix(t, "B"), "I")
+	mult := 1
+	for i, unit := range []string{"K", "M", "G"} {
+		if num, ok := strings.CutSuffix(t, unit); ok {
+			t, mult = num, 1<<(10*(i+1))
+			break
+		}
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(t))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q: want e.g. 512KiB or 16MiB", s)
+	}
+	return n * mult, nil
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// utf16Bytes encodes s as UTF-16 in order, without a byte order mark.
+func utf16Bytes(s string, order binary.AppendByteOrder) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = order.AppendUint16(b, u)
+	}
+	return b
+}
+
+func TestDecodeText(t *testing.T) {
+	const text = "db.host=10.0.0.1\r\nname=café\r\n"
+	tests := []struct {
+		name    string
+		data    []byte
+		wantEnc string
+	}{
+		{"UTF-8", []byte(text), ""},
+		{"UTF-8 BOM", append([]byte{0xEF, 0xBB, 0xBF}, text...), "UTF-8 with BOM"},
+		{"UTF-16LE BOM", append([]byte{0xFF, 0xFE}, utf16Bytes(text, binary.LittleEndian)...), "UTF-16LE"},
+		{"UTF-16BE BOM", append([]byte{0xFE, 0xFF}, utf16Bytes(text, binary.BigEndian)...), "UTF-16BE"},
+		{"UTF-16LE", utf16Bytes(text, binary.LittleEndian), "UTF-16LE without BOM"},
+		{"UTF-16BE", utf16Bytes(text, binary.BigEndian), "UTF-16BE without BOM"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, enc := decodeText(tt.data)
+			if string(got) != text || enc != tt.wantEnc {
+				t.Errorf("decodeText() = %q, %q; want %q, %q", got, enc, text, tt.wantEnc)
+			}
+		})
+	}
+
+	// Binary data with scattered zero bytes is left alone.
+	bin := []byte{0x7F, 'E', 'L', 'F', 2, 1, 1, 0, 0, 0, 0, 0, 3, 0, 0x3E, 0}
+	if got, enc := decodeText(bin); enc != "" || !reflect.DeepEqual(got, bin) {
+		t.Errorf("decodeText(binary) = %v, %q; want it unchanged", got, enc)
+	}
+}
+
+func TestTextLines(t *testing.T) {
+	lines, cut := textLines([]byte("a\r\nbbbbbb\n\nc"), 4)
+	if want := []string{"a", "bbbb", "", "c"}; !reflect.DeepEqual(lines, want) || cut != 1 {
+		t.Errorf("textLines() = %q, %d; want %q, 1", lines, cut, want)
+	}
+}
+
+func TestScanData_LongLinesAndUTF16(t *testing.T) {
+	// Minified JSON: one line far beyond bufio.Scanner's 64 KiB.
+	minified := `{"pad":"` + strings.Repeat("x", 1<<20) + `","upstream":"10.0.0.7"}` + "\nport=8080\n"
+	rows := scanData([]byte(minified), "bundle.json", scanOptions{})
+	if len(rows) != 2 || rows[0].IPValue != "10.0.0.7" || rows[1].LineNumber != 2 {
+		t.Errorf("minified JSON: %+v, want the IP on line 1 and the port on line 2", rows)
+	}
+	if rows := scanData([]byte(minified), "bundle.json", scanOptions{maxLineLength: 1024}); len(rows) != 1 || rows[0].PortValue != "8080" {
+		t.Errorf("with a 1 KiB line limit: %+v, want only the port", rows)
+	}
+
+	props := append([]byte{0xFF, 0xFE}, utf16Bytes("db.host=10.0.0.1\r\n", binary.LittleEndian)...)
+	if rows := scanData(props, "app.properties", scanOptions{}); len(rows) != 1 || rows[0].IPKey != "db.host" || rows[0].IPValue != "10.0.0.1" {
+		t.Errorf("UTF-16 properties: %+v", rows)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"1048576", 1 << 20},
+		{"512K", 512 << 10},
+		{"512KiB", 512 << 10},
+		{"16MB", 16 << 20},
+		{"16mib", 16 << 20},
+		{"1G", 1 << 30},
+	}
+	for _, tt := range tests {
+		if got, err := parseSize(tt.in); err != nil || got != tt.want {
+			t.Errorf("parseSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "0", "-1K", "12X", "MiB"} {
+		if _, err := parseSize(in); err == nil {
+			t.Errorf("parseSize(%q): want an error", in)
+		}
+	}
+}
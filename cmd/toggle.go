@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// togglePair is a pair of values flip-adapters switches between.
+type togglePair [2]string
+
+// defaultTogglePairs are the value schemes understood without --toggle-pair.
+var defaultTogglePairs = []togglePair{
+	{"0", "1"},
+	{"true", "false"},
+	{"on", "off"},
+	{"enabled", "disabled"},
+	{"yes", "no"},
+}
+
+// parseTogglePairs adds "A:B" pairs from --toggle-pair to the defaults.
+// Later pairs take precedence over earlier ones for the same value.
+func parseTogglePairs(specs []string) ([]togglePair, error) {
+	pairs := append([]togglePair(nil), defaultTogglePairs...)
+	for _, spec := range specs {
+		p, err := parseTogglePair(spec)
+		if err != nil {
+			return nil, usageErrorf("invalid --toggle-pair %q: %w", spec, err)
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, nil
+}
+
+// parseTogglePair reads a pair written as A:B or A<->B.
+func parseTogglePair(spec string) (togglePair, error) {
+	a, b, ok := strings.Cut(spec, "<->")
+	if !ok {
+		a, b, ok = strings.Cut(spec, ":")
+	}
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	if !ok || a == "" || b == "" || strings.EqualFold(a, b) {
+		return togglePair{}, fmt.Errorf("expected two different values as A:B")
+	}
+	return togglePair{a, b}, nil
+}
+
+// withConfigPairs inserts the toggle.pairs from config between the
+// defaults and the --toggle-pair values at the end of pairs, so flags win.
+func withConfigPairs(pairs, config []togglePair) []togglePair {
+	out := append([]togglePair(nil), defaultTogglePairs...)
+	out = append(out, config...)
+	return append(out, pairs[len(defaultTogglePairs):]...)
+}
+
+// toggleNextFor is toggleNext with per-adapter pairs from toggle.adapters
+// in config, which are tried before the general pairs.
+func toggleNextFor(pairs []togglePair, byAdapter map[string]togglePair) func(adapter, v string) (string, bool) {
+	general := toggleNext(pairs)
+	return func(adapter, v string) (string, bool) {
+		if p, ok := byAdapter[adapter]; ok {
+			if newV, ok := toggleValue(v, []togglePair{p}); ok {
+				return newV, true
+			}
+		}
+		return general(adapter, v)
+	}
+}
+
+// toggleValue returns the other value of the pair v belongs to, matching
+// case-insensitively and keeping v's capitalization style (TRUE → FALSE,
+// True → False).
+func toggleValue(v string, pairs []togglePair) (string, bool) {
+	for i := len(pairs) - 1; i >= 0; i-- {
+		p := pairs[i]
+		var other string
+		switch {
+		case strings.EqualFold(v, p[0]):
+			other = p[1]
+		case strings.EqualFold(v, p[1]):
+			other = p[0]
+		default:
+			continue
+		}
+		return matchCase(v, other), true
+	}
+	return "", false
+}
+
+// matchCase applies the capitalization style of model to s. Mixed-case
+// models leave s as configured.
+func matchCase(model, s string) string {
+	switch {
+	case model == strings.ToLower(model):
+		if model == strings.ToUpper(model) {
+			return s // no letters, e.g. 0/1
+		}
+		return strings.ToLower(s)
+	case model == strings.ToUpper(model):
+		return strings.ToUpper(s)
+	case model[:1] == strings.ToUpper(model[:1]) && model[1:] == strings.ToLower(model[1:]):
+		return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/greenstevester/gh-aca-utils/pkg/props"
+)
+
+func TestToggleValue(t *testing.T) {
+	pairs, err := parseTogglePairs([]string{"active:inactive", " live : dark "})
+	if err != nil {
+		t.Fatalf("parseTogglePairs failed: %v", err)
+	}
+	tests := []struct{ in, want string }{
+		{"0", "1"},
+		{"1", "0"},
+		{"true", "false"},
+		{"TRUE", "FALSE"},
+		{"False", "True"},
+		{"on", "off"},
+		{"Disabled", "Enabled"},
+		{"no", "yes"},
+		{"inactive", "active"},
+		{"DARK", "LIVE"},
+		{"auto", ""},
+	}
+	for _, tt := range tests {
+		got, ok := toggleValue(tt.in, pairs)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("toggleValue(%q) = %q, %t; want %q", tt.in, got, ok, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"on", "a:", "x:X"} {
+		if _, err := parseTogglePairs([]string{bad}); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestPlanFlips_BooleanWords(t *testing.T) {
+	lines := props.SplitLinesEOL([]byte("billing=true\nsearch = Off\nemail=ENABLED\nmode=auto"))
+	changes := planValues(lines, indexKeys(lines), []string{"billing", "search", "email", "mode"}, "p", toggleNext(defaultTogglePairs))
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Adapter+"="+c.NewValue)
+	}
+	if strings.Join(got, ",") != "billing=false,search=On,email=DISABLED" {
+		t.Errorf("Unexpected changes: %v", got)
+	}
+}
+
+func TestUnplanned(t *testing.T) {
+	lines := props.SplitLinesEOL([]byte("billing=true\nmode=auto"))
+	want := []string{"billing", "mode", "missing", "mode"}
+	changes := planValues(lines, indexKeys(lines), want, "p", toggleNext(defaultTogglePairs))
+	if got := strings.Join(unplanned(want, changes), ","); got != "mode,missing" {
+		t.Errorf("unplanned = %s, want mode,missing", got)
+	}
+	if got := unplanned(want[:1], changes); len(got) != 0 {
+		t.Errorf("unplanned = %v, want none", got)
+	}
+}
+
+func TestConfigTogglePairs(t *testing.T) {
+	layer, issues := parseConfig(".aca.yml", []byte(`toggle:
+  pairs: ["ACTIVE<->PASSIVE", "blue:green"]
+  adapters:
+    region: primary<->secondary
+    mode: on:standby
+`), scopeRepo)
+	if len(issues) > 0 {
+		t.Fatalf("issues: %v", issues)
+	}
+	flags, err := parseTogglePairs([]string{"green:red"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pairs := withConfigPairs(flags, layer.Values["toggle.pairs"].Value.([]togglePair))
+	next := toggleNextFor(pairs, layer.Values["toggle.adapters"].Value.(map[string]togglePair))
+
+	tests := []struct {
+		adapter, v, want string
+	}{
+		{"status", "Active", "Passive"},
+		{"region", "SECONDARY", "PRIMARY"},
+		{"mode", "on", "standby"},
+		{"other", "on", "off"},
+		{"color", "green", "red"}, // --toggle-pair wins over config
+		{"color", "blue", "green"},
+		{"region", "1", "0"}, // falls back to the general pairs
+		{"region", "east", ""},
+	}
+	for _, tt := range tests {
+		got, ok := next(tt.adapter, tt.v)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("%s=%s: got %q, %v; want %q", tt.adapter, tt.v, got, ok, tt.want)
+		}
+	}
+
+	for _, doc := range []string{"toggle:\n  pairs: [solo]\n", "toggle:\n  adapters:\n    mode: a:a\n", "toggle:\n  pairs: a:b\n"} {
+		if _, issues := parseConfig(".aca.yml", []byte(doc), scopeRepo); len(issues) != 1 {
+			t.Errorf("%q: issues = %v", doc, issues)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/auth"
+)
+
+// Without the gh binary, as on a bare CI runner, the API clients take the
+// token from GH_TOKEN or GITHUB_TOKEN, and git is given the same token as
+// an HTTP header.
+
+// haveGH reports whether the gh binary is on PATH.
+func haveGH() bool {
+	_, err := exec.LookPath("gh")
+	return err == nil
+}
+
+// envToken returns the token gh itself would take from the environment.
+func envToken() string {
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN"} {
+		if t := os.Getenv(name); t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+// githubHost is the GitHub host to talk to: GH_HOST, as for gh, the
+// server of an Actions run, or gh's default host.
+func githubHost() string {
+	if h := os.Getenv("GH_HOST"); h != "" {
+		return h
+	}
+	if u, err := url.Parse(os.Getenv("GITHUB_SERVER_URL")); err == nil && u.Host != "" {
+		return u.Host
+	}
+	host, _ := auth.DefaultHost()
+	return host
+}
+
+// gitBaseURL is where repositories are cloned from, GITHUB_SERVER_URL in
+// Actions.
+func gitBaseURL() string {
+	if u := os.Getenv("GITHUB_SERVER_URL"); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	return "https://" + githubHost()
+}
+
+// setupTokenAuth lets every git the run starts authenticate to GitHub
+// with the environment's token when gh, which would otherwise be git's
+// credential helper, isn't installed. The token goes into the environment
+// of git as an extra header, never into a command line or .git/config.
+func setupTokenAuth() {
+	token := envToken()
+	if haveGH() || token == "" {
+		return
+	}
+	debugf("gh not found; using the token from the environment for %s", githubHost())
+	for k, v := range gitAuthEnv(token, os.Getenv("GIT_CONFIG_COUNT")) {
+		_ = os.Setenv(k, v)
+	}
+}
+
+// gitAuthEnv returns the GIT_CONFIG_* variables that add token's
+// Authorization header to git's requests to GitHub, after the count
+// entries already set.
+func gitAuthEnv(token, count string) map[string]string {
+	n, _ := strconv.Atoi(count)
+	auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+	return map[string]string{
+		"GIT_CONFIG_COUNT":                    strconv.Itoa(n + 1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d", n):   "http." + gitBaseURL() + "/.extraheader",
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d", n): "AUTHORIZATION: basic " + auth,
+		"GIT_TERMINAL_PROMPT":                 "0",
+	}
+}
+
+// cloneRepo clones repo into dir with `gh repo clone`, or from its HTTPS
+// URL when gh or the git binary isn't installed.
+func cloneRepo(repo, dir string, o cloneOptions) error {
+	if ok, err := cachedClone(repo, gitBaseURL()+"/"+repo+".git", dir, o); ok {
+		return err
+	}
+	if haveGH() && !useGoGit() {
+		return retryInto("clone "+repo, dir, func() error {
+			return netCommand("", "gh", append([]string{"repo", "clone", repo, dir, "--"}, o.args()...)...)
+		})
+	}
+	return gitClone(gitBaseURL()+"/"+repo+".git", dir, o)
+}
+
+// remoteRepo returns the OWNER/REPO a remote of the clone at dir points
+// at, or "" when it has no such remote.
+func remoteRepo(dir, remote string) string {
+	u := strings.TrimSuffix(gitRemoteURL(dir, remote), ".git")
+	if pu, err := url.Parse(u); err == nil && pu.Host != "" {
+		u = pu.Path
+	} else if _, p, ok := strings.Cut(u, ":"); ok {
+		u = p // git@host:owner/repo
+	}
+	parts := strings.Split(strings.Trim(u, "/"), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.Join(parts[len(parts)-2:], "/")
+}
+
+// createPR opens a pull request from the branch checked out at dir, as
+// `gh pr create` does there: into the default branch of upstream if the
+// clone is a fork, else of origin.
+func createPR(dir, title, body string, opts prOptions) (string, error) {
+	origin := remoteRepo(dir, "origin")
+	if origin == "" {
+		return "", fmt.Errorf("create pull request: %s has no GitHub origin", dir)
+	}
+	branch, err := gitCurrentBranch(dir)
+	if err != nil {
+		return "", err
+	}
+	repo, head := origin, branch
+	if upstream := remoteRepo(dir, "upstream"); upstream != "" {
+		owner, _, _ := strings.Cut(origin, "/")
+		repo, head = upstream, owner+":"+branch
+	}
+	var info struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := ghAPI("GET", "repos/"+repo, nil, &info); err != nil {
+		return "", fmt.Errorf("create pull request: %w", err)
+	}
+	return (&apiCheckout{repo: repo, base: info.DefaultBranch}).openPR(head, title, body, opts)
+}
+
+// forkRemote forks repo, or finds the user's existing fork, and makes the
+// fork the origin of the clone at dir, with repo as its upstream.
+func forkRemote(dir, repo string) error {
+	var fork struct {
+		FullName string `json:"full_name"`
+	}
+	if err := ghAPI("POST", fmt.Sprintf("repos/%s/forks", repo), map[string]any{}, &fork); err != nil {
+		return err
+	}
+	return gitUseFork(dir, gitBaseURL()+"/"+fork.FullName+".git")
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWithoutGH(t *testing.T) {
+	fakeGitHub(t, func(r *http.Request, call string) (int, string) {
+		if requestToken(r) != "secret" {
+			return 401, `{"message":"Bad credentials"}`
+		}
+		switch {
+		case call == "GET user":
+			return 200, `{"login":"octocat"}`
+		case call == "GET orgs/org/repos?per_page=100&page=1":
+			var list []string
+			for i := range 100 {
+				list = append(list, fmt.Sprintf(`{"full_name":"org/r%03d","archived":%v}`, i, i%2 == 1))
+			}
+			return 200, "[" + strings.Join(list, ",") + "]"
+		case call == "GET orgs/org/repos?per_page=100&page=2":
+			return 200, `[{"full_name":"org/a","archived":false}]`
+		}
+		return 404, ""
+	})
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("GH_TOKEN", "secret")
+	if haveGH() {
+		t.Fatal("gh found on an empty PATH")
+	}
+	if got := ghLogin(); got != "octocat" {
+		t.Errorf("ghLogin() = %q, want octocat", got)
+	}
+	repos, err := listOrgRepos("org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 51 || repos[0] != "org/a" || repos[1] != "org/r000" {
+		t.Errorf("listOrgRepos() = %d repos starting %v", len(repos), repos[:min(2, len(repos))])
+	}
+	if _, err := tokenLogin("wrong"); err == nil || !strings.Contains(err.Error(), "HTTP 401: Bad credentials") {
+		t.Errorf("tokenLogin(wrong) err = %v", err)
+	}
+
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+	if err := ghAPI("GET", "user", nil, nil); err == nil || !strings.Contains(err.Error(), "GH_TOKEN") {
+		t.Errorf("without a token: err = %v", err)
+	}
+}
+
+func TestGitAuthEnv(t *testing.T) {
+	t.Setenv("GH_HOST", "github.com")
+	env := gitAuthEnv("secret", "2")
+	if env["GIT_CONFIG_COUNT"] != "3" || env["GIT_CONFIG_KEY_2"] != "http.https://github.com/.extraheader" {
+		t.Fatalf("gitAuthEnv() = %v", env)
+	}
+	auth, _ := strings.CutPrefix(env["GIT_CONFIG_VALUE_2"], "AUTHORIZATION: basic ")
+	if b, _ := base64.StdEncoding.DecodeString(auth); string(b) != "x-access-token:secret" {
+		t.Errorf("header = %q, want the token as basic auth", env["GIT_CONFIG_VALUE_2"])
+	}
+}
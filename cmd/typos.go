@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxSuggestions is how many close keys a not-found adapter lists.
+const maxSuggestions = 3
+
+// suggestKeys returns up to maxSuggestions keys close to name, closest
+// first: keys equal but for case, then by edit distance. Keys further than
+// a third of name's length (at least 2 edits) are not suggested.
+func suggestKeys(name string, keys []string) []string {
+	lower := strings.ToLower(name)
+	limit := max(2, len([]rune(name))/3)
+	type scored struct {
+		key  string
+		dist int
+	}
+	var close []scored
+	for _, k := range keys {
+		if k == name {
+			continue
+		}
+		d := levenshtein(lower, strings.ToLower(k))
+		if strings.EqualFold(k, name) {
+			d = 0
+		}
+		if d <= limit {
+			close = append(close, scored{k, d})
+		}
+	}
+	sort.SliceStable(close, func(i, j int) bool {
+		if close[i].dist != close[j].dist {
+			return close[i].dist < close[j].dist
+		}
+		return close[i].key < close[j].key
+	})
+	out := make([]string, 0, maxSuggestions)
+	for _, s := range close {
+		if len(out) == maxSuggestions {
+			break
+		}
+		out = append(out, s.key)
+	}
+	return out
+}
+
+// didYouMean is the hint appended to a not-found message.
+func didYouMean(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	return "; did you mean " + strings.Join(suggestions, ", ") + "?"
+}
+
+// mapKeys returns the keys of an indexKeys map.
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// fixTypos asks, for each wanted adapter the file doesn't have, which of
+// the close keys was meant, and returns want with the answers substituted.
+// Answers are kept in fixed so each typo is asked about once per run.
+func fixTypos(want []string, has func(string) bool, keys []string, fixed map[string]string, file string, p *prompter) ([]string, error) {
+	out := make([]string, 0, len(want))
+	for _, a := range want {
+		if has(a) {
+			out = append(out, a)
+			continue
+		}
+		if key, ok := fixed[a]; ok {
+			if key != "" && has(key) {
+				out = append(out, key)
+			} else {
+				out = append(out, a)
+			}
+			continue
+		}
+		suggestions := suggestKeys(a, keys)
+		if len(suggestions) == 0 {
+			out = append(out, a)
+			continue
+		}
+		if !p.interactive {
+			return nil, usageErrorf("--fix-typos needs a terminal to ask which key %q means in %s%s", a, file, didYouMean(suggestions))
+		}
+		options := make([][2]string, 0, len(suggestions)+1)
+		for i, s := range suggestions {
+			options = append(options, [2]string{strconv.Itoa(i + 1), s})
+		}
+		options = append(options, [2]string{"s", "skip " + a})
+		choice, err := p.choose(fmt.Sprintf("Adapter %q is not in %s. Did you mean:", a, file), options)
+		if err != nil {
+			return nil, err
+		}
+		fixed[a] = ""
+		if choice == "s" {
+			out = append(out, a)
+			continue
+		}
+		n, _ := strconv.Atoi(choice)
+		fixed[a] = suggestions[n-1]
+		out = append(out, suggestions[n-1])
+	}
+	return out, nil
+}
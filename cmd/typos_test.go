@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSuggestKeys(t *testing.T) {
+	keys := []string{"featureFlag", "featureFlags", "FeatureFlg", "otherFlag", "retries", "db.url"}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"featureFlg", []string{"FeatureFlg", "featureFlag", "featureFlags"}},
+		{"retrys", []string{"retries"}},
+		{"db.ulr", []string{"db.url"}},
+		{"timeout", []string{}},
+		{"featureFlag", []string{"FeatureFlg", "featureFlags"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := suggestKeys(tt.name, keys); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("suggestKeys(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDidYouMean(t *testing.T) {
+	if got := didYouMean(nil); got != "" {
+		t.Errorf("didYouMean(nil) = %q", got)
+	}
+	if got, want := didYouMean([]string{"a", "b"}), "; did you mean a, b?"; got != want {
+		t.Errorf("didYouMean = %q, want %q", got, want)
+	}
+}
+
+func TestJSONAdapterKeys(t *testing.T) {
+	got := jsonAdapterKeys([]byte(`{"featureFlag": true, "a/b": 1, "nested": {"retries": 3}, "list": [1]}`))
+	want := []string{"featureFlag", "a/b", "/nested/retries", "/list/0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("jsonAdapterKeys = %v, want %v", got, want)
+	}
+}
+
+func TestFixTypos(t *testing.T) {
+	keys := []string{"featureFlag", "retries"}
+	has := func(a string) bool { return a == "featureFlag" || a == "retries" }
+	prompt := func(answers string) *prompter {
+		return &prompter{in: bufio.NewReader(strings.NewReader(answers)), out: io.Discard, interactive: true}
+	}
+
+	fixed := map[string]string{}
+	got, err := fixTypos([]string{"featureFlg", "retrys", "unknown"}, has, keys, fixed, "env/dev/parameters.properties", prompt("1\ns\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"featureFlag", "retrys", "unknown"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fixTypos = %v, want %v", got, want)
+	}
+
+	// A second environment reuses the answers without asking.
+	got, err = fixTypos([]string{"featureFlg", "retrys"}, has, keys, fixed, "env/prod/parameters.properties", prompt(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"featureFlag", "retrys"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fixTypos (reused) = %v, want %v", got, want)
+	}
+
+	_, err = fixTypos([]string{"featureFlg"}, has, keys, map[string]string{}, "env/dev/parameters.properties", &prompter{})
+	if err == nil || !strings.Contains(err.Error(), "did you mean featureFlag?") {
+		t.Errorf("non-interactive error = %v", err)
+	}
+}
@@ -3,7 +3,6 @@ package cmd
 import (
 	"os"
 	"path/filepath"
-	"strings"
 	"testing"
 )
 
@@ -188,53 +187,6 @@ func TestTempOperations(t *testing.T) {
 	}
 }
 
-// Test edge cases in string processing
-func TestStringEdgeCases(t *testing.T) {
-	// Test empty and whitespace strings
-	emptyTests := []string{"", "   ", "\t", "\n", "\r\n"}
-
-	for _, input := range emptyTests {
-		t.Run("empty_"+input, func(t *testing.T) {
-			key, val, ok := parseKV(input)
-			if ok {
-				t.Errorf("parseKV(%q) should return false for empty/whitespace, got key=%q, val=%q",
-					input, key, val)
-			}
-		})
-	}
-
-	// Test very long strings
-	longKey := strings.Repeat("a", 1000)
-	longVal := strings.Repeat("b", 1000)
-	longLine := longKey + "=" + longVal
-
-	key, val, ok := parseKV(longLine)
-	if !ok {
-		t.Error("parseKV should handle long strings")
-	}
-	if key != longKey || strings.TrimSpace(val) != longVal {
-		t.Error("parseKV should correctly parse long strings")
-	}
-
-	// Test special characters
-	specialChars := []string{
-		"key.with.dots=value",
-		"key_with_underscores=value",
-		"key-with-dashes=value",
-		"key123=value456",
-		"UPPERCASE_KEY=UPPERCASE_VALUE",
-	}
-
-	for _, line := range specialChars {
-		t.Run("special_"+line, func(t *testing.T) {
-			_, _, ok := parseKV(line)
-			if !ok {
-				t.Errorf("parseKV should handle line with special chars: %q", line)
-			}
-		})
-	}
-}
-
 // Test comment detection edge cases
 func TestCommentDetection(t *testing.T) {
 	tests := []struct {
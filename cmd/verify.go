@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// expectation is one ADAPTER=VALUE of --expect.
+type expectation struct {
+	Adapter, Value string
+}
+
+// parseExpectations reads --expect: comma-separated ADAPTER=VALUE pairs.
+func parseExpectations(s string) ([]expectation, error) {
+	var out []expectation
+	seen := map[string]bool{}
+	for _, pair := range splitCSV(s, nil) {
+		a, v, ok := strings.Cut(pair, "=")
+		a = strings.TrimSpace(a)
+		if !ok || a == "" {
+			return nil, usageErrorf("invalid --expect %q: want ADAPTER=VALUE", pair)
+		}
+		if seen[a] {
+			return nil, usageErrorf("invalid --expect: %s is given twice", a)
+		}
+		seen[a] = true
+		out = append(out, expectation{Adapter: a, Value: strings.TrimSpace(v)})
+	}
+	if len(out) == 0 {
+		return nil, usageErrorf("--expect ADAPTER=VALUE[,...] is required")
+	}
+	return out, nil
+}
+
+// verifyResult is the outcome of one expectation in one environment.
+type verifyResult struct {
+	Env      string `json:"env"`
+	Adapter  string `json:"adapter"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Missing  bool   `json:"missing,omitempty"`
+	OK       bool   `json:"ok"`
+}
+
+// checkExpectations compares the values read from one environment with
+// the expectations, in order.
+func checkExpectations(values []adapterValue, expect []expectation) []verifyResult {
+	byAdapter := map[string]adapterValue{}
+	for _, v := range values {
+		byAdapter[v.Adapter] = v
+	}
+	out := make([]verifyResult, 0, len(expect))
+	for _, e := range expect {
+		v := byAdapter[e.Adapter]
+		out = append(out, verifyResult{Env: v.Env, Adapter: e.Adapter, Expected: e.Value, Actual: v.Value, Missing: v.Missing, OK: !v.Missing && v.Value == e.Value})
+	}
+	return out
+}
+
+func printVerifyResults(results []verifyResult, mode outputMode) error {
+	if mode == outJSON {
+		enc := json.NewEncoder(stdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+	w := newTable()
+	w.AddRow("Env", "Adapter", "Expected", "Actual", "Status")
+	for _, r := range results {
+		actual, status := r.Actual, "ok"
+		if r.Missing {
+			actual = "(missing)"
+		}
+		if !r.OK {
+			status = "MISMATCH"
+		}
+		w.AddRow(r.Env, r.Adapter, r.Expected, actual, status)
+	}
+	w.Render()
+	return nil
+}
+
+func cmdVerify() *cobra.Command {
+	var repo, envName, expectCSV, paramFile, mode string
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check that adapters have the expected values on the default branch, exiting non-zero if not",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if repo == "" {
+				return usageErrorf("--repo ORG/REPO is required")
+			}
+			if envName == "" {
+				return usageErrorf("--env is required (e.g., prod)")
+			}
+			if err := checkParamFile(paramFile); err != nil {
+				return err
+			}
+			expect, err := parseExpectations(expectCSV)
+			if err != nil {
+				return err
+			}
+			modeVal := parseMode(mode, outTable)
+			want := make([]string, len(expect))
+			for i, e := range expect {
+				want[i] = e.Adapter
+			}
+
+			// Read through the API at the current head of the default
+			// branch, so a merge that just happened is seen.
+			api, dir, cleanup, err := openAPIRepo(repo)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			if paramFile, err = repoParamFile(paramFile, cmd.Flags().Changed("file"), loadConfig(dir)); err != nil {
+				return err
+			}
+			envs, err := resolveEnvList(dir, splitCSV(envName, nil))
+			if err != nil {
+				return err
+			}
+			results := make([]verifyResult, 0)
+			for _, env := range envs {
+				rel := path.Join("env", env, paramFile)
+				b, err := api.read(rel)
+				if err != nil {
+					return err
+				}
+				values, err := parseEnvValues(b, env, rel, want)
+				if err != nil {
+					return err
+				}
+				results = append(results, checkExpectations(values, expect)...)
+			}
+			if err := printVerifyResults(results, modeVal); err != nil {
+				return err
+			}
+			failed := 0
+			for _, r := range results {
+				if !r.OK {
+					failed++
+				}
+			}
+			if failed > 0 {
+				cmd.SilenceUsage = true
+				return &findingsError{fmt.Sprintf("%d of %d adapter value(s) in %s@%s don't match --expect", failed, len(results), repo, shortSHA(api.baseSHA))}
+			}
+			infof("All %d adapter value(s) in %s@%s match", len(results), repo, shortSHA(api.baseSHA))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Target repo as ORG/REPO (required)")
+	cmd.Flags().StringVar(&envName, "env", "", "Comma-separated environments or globs (required)")
+	cmd.Flags().StringVar(&expectCSV, "expect", "", "Expected values as ADAPTER=VALUE, comma-separated (required)")
+	cmd.Flags().StringVar(&paramFile, "file", "parameters.properties", "Parameters file in each env directory; .json files take JSON pointers as adapters")
+	cmd.Flags().StringVar(&mode, "output", "table", "Output: table|json")
+	setOutputModes(cmd, "table", "json")
+	return cmd
+}
+
+// shortSHA abbreviates a commit SHA for messages.
+func shortSHA(sha string) string {
+	return sha[:min(7, len(sha))]
+}
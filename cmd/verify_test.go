@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseExpectations(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []expectation
+		wantErr bool
+	}{
+		{in: "billing=1,search=0", want: []expectation{{"billing", "1"}, {"search", "0"}}},
+		{in: "db.url=jdbc:x?a=b", want: []expectation{{"db.url", "jdbc:x?a=b"}}},
+		{in: "empty=", want: []expectation{{"empty", ""}}},
+		{in: "billing", wantErr: true},
+		{in: "=1", wantErr: true},
+		{in: "billing=1,billing=0", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseExpectations(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyCommand(t *testing.T) {
+	tests := []struct {
+		expect   string
+		wantFail bool
+		wantOut  string
+	}{
+		{"billing=0", false, "ok"},
+		{"billing=1", true, "MISMATCH"},
+		{"search=0", true, "(missing)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expect, func(t *testing.T) {
+			fakeGH(t)
+			var out bytes.Buffer
+			resultOutput = &out
+			defer func() { resultOutput = nil }()
+
+			cmd := cmdVerify()
+			cmd.SetArgs([]string{"--repo", "org/svc", "--env", "dev", "--expect", tt.expect})
+			cmd.SilenceErrors = true
+			err := cmd.Execute()
+			var fe *findingsError
+			if tt.wantFail != errors.As(err, &fe) {
+				t.Fatalf("err = %v, want failure %v", err, tt.wantFail)
+			}
+			if !tt.wantFail && err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output has no %q:\n%s", tt.wantOut, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"os"
+)
+
+// workDir is the global --work-dir flag: where clones, checkouts and
+// extracted tarballs are made. Empty means the system temp directory.
+var workDir string
+
+// keepTemp is the global --keep-temp flag: leave those directories in
+// place after the run, for debugging, instead of removing them.
+var keepTemp bool
+
+// checkWorkDir creates dir, the --work-dir, if it doesn't exist yet.
+func checkWorkDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return usageErrorf("--work-dir: %w", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return usageErrorf("--work-dir: %s is not a directory", dir)
+	}
+	return nil
+}
+
+// makeTemp creates a working directory named after pattern, as
+// os.MkdirTemp does, under --work-dir. The returned function removes it,
+// or under --keep-temp prints where it was kept.
+func makeTemp(pattern string) (string, func(), error) {
+	dir, err := os.MkdirTemp(workDir, pattern)
+	if err != nil {
+		return "", nil, err
+	}
+	return dir, func() {
+		if keepTemp {
+			infof("Kept %s (--keep-temp)", dir)
+			return
+		}
+		_ = os.RemoveAll(dir)
+	}, nil
+}
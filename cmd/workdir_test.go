@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMakeTemp(t *testing.T) {
+	defer func(old *slog.Logger) { logger = old }(logger)
+	var buf bytes.Buffer
+	logger = newLogger(&buf, "text", 0)
+	oldDir, oldKeep := workDir, keepTemp
+	t.Cleanup(func() { workDir, keepTemp = oldDir, oldKeep })
+
+	workDir = filepath.Join(t.TempDir(), "work")
+	if err := checkWorkDir(workDir); err != nil {
+		t.Fatal(err)
+	}
+	dir, cleanup, err := makeTemp("gh-aca-utils-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(dir) != workDir {
+		t.Errorf("made %s, want it under --work-dir %s", dir, workDir)
+	}
+	cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("cleanup left %s: %v", dir, err)
+	}
+
+	keepTemp = true
+	dir, cleanup, err = makeTemp("gh-aca-utils-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup()
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("--keep-temp removed %s: %v", dir, err)
+	}
+	if !strings.Contains(buf.String(), dir) {
+		t.Errorf("logged %q, want the kept directory", buf.String())
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkWorkDir(file); err == nil {
+		t.Error("checkWorkDir(a file): want an error")
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	findingsSheet = "Findings"
+	summarySheet  = "Summary"
+)
+
+// printXLSX writes rows as an Excel workbook to the result output. Refuses
+// to write binary data to a terminal.
+func printXLSX(rows []matchRow, cols []rowColumn) error {
+	w := stdout()
+	if f, ok := w.(*os.File); ok && isTerminal(f) {
+		return fmt.Errorf("xlsx output is binary; use --output-file or redirect it to a file")
+	}
+	return writeXLSX(w, rows, cols, time.Now())
+}
+
+// writeXLSX writes a workbook with one sheet of findings and one of summary
+// statistics.
+func writeXLSX(w io.Writer, rows []matchRow, cols []rowColumn, now time.Time) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName("Sheet1", findingsSheet); err != nil {
+		return err
+	}
+	if _, err := f.NewSheet(summarySheet); err != nil {
+		return err
+	}
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+
+	header := make([]any, len(cols))
+	for i, c := range cols {
+		header[i] = c.Header
+	}
+	if err := setRow(f, findingsSheet, 1, header); err != nil {
+		return err
+	}
+	for i, r := range rows {
+		values := make([]any, len(cols))
+		for j, c := range cols {
+			values[j] = c.Value(r)
+			if n, err := strconv.Atoi(c.Value(r)); c.number && err == nil {
+				values[j] = n
+			}
+		}
+		if err := setRow(f, findingsSheet, i+2, values); err != nil {
+			return err
+		}
+	}
+	lastCol, err := excelize.ColumnNumberToName(len(header))
+	if err != nil {
+		return err
+	}
+	if err := f.SetCellStyle(findingsSheet, "A1", lastCol+"1", bold); err != nil {
+		return err
+	}
+	if err := f.AutoFilter(findingsSheet, fmt.Sprintf("A1:%s%d", lastCol, len(rows)+1), nil); err != nil {
+		return err
+	}
+	if err := f.SetPanes(findingsSheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+
+	if err := writeSummarySheet(f, rows, bold, now); err != nil {
+		return err
+	}
+	return f.Write(w)
+}
+
+func writeSummarySheet(f *excelize.File, rows []matchRow, bold int, now time.Time) error {
+	files := map[string]int{}
+	ips := map[string]bool{}
+	ports := map[string]bool{}
+	for _, r := range rows {
+		files[r.location()]++
+		if r.IPValue != "" {
+			ips[r.IPValue] = true
+		}
+		if r.PortValue != "" {
+			ports[r.PortValue] = true
+		}
+	}
+
+	stats := [][]any{
+		{"Metric", "Value"},
+		{"Findings", len(rows)},
+		{"Files with findings", len(files)},
+		{"Unique IPs", len(ips)},
+		{"Unique ports", len(ports)},
+		{"Generated", now.Format(time.RFC3339)},
+		{},
+		{"File Path", "Findings"},
+	}
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if files[paths[i]] != files[paths[j]] {
+			return files[paths[i]] > files[paths[j]]
+		}
+		return paths[i] < paths[j]
+	})
+	for _, p := range paths {
+		stats = append(stats, []any{p, files[p]})
+	}
+
+	for i, values := range stats {
+		if err := setRow(f, summarySheet, i+1, values); err != nil {
+			return err
+		}
+	}
+	if err := f.SetCellStyle(summarySheet, "A1", "B1", bold); err != nil {
+		return err
+	}
+	return f.SetCellStyle(summarySheet, "A8", "B8", bold)
+}
+
+func setRow(f *excelize.File, sheet string, row int, values []any) error {
+	if len(values) == 0 {
+		return nil
+	}
+	cell, err := excelize.CoordinatesToCellName(1, row)
+	if err != nil {
+		return err
+	}
+	return f.SetSheetRow(sheet, cell, &values)
+}
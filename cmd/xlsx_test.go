@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWriteXLSX(t *testing.T) {
+	rows := []matchRow{
+		{IPKey: "db.host", IPValue: "10.0.0.5", PortKey: "db.port", PortValue: "5432", RelPath: "a.properties", LineNumber: 2, PTR: "db.example"},
+		{IPKey: "gw", IPValue: "10.0.0.1", RelPath: "b.yml", LineNumber: 7},
+		{IPKey: "gw2", IPValue: "10.0.0.5", RelPath: "a.properties", LineNumber: 9},
+	}
+
+	var buf bytes.Buffer
+	if err := writeXLSX(&buf, rows, append(defaultColumns(), ptrColumn), time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("writeXLSX failed: %v", err)
+	}
+
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("Failed to read workbook: %v", err)
+	}
+	defer f.Close()
+
+	if got := f.GetSheetList(); len(got) != 2 || got[0] != findingsSheet || got[1] != summarySheet {
+		t.Fatalf("Unexpected sheets: %v", got)
+	}
+
+	findings, err := f.GetRows(findingsSheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 4 {
+		t.Fatalf("Expected header and 3 findings, got %d rows", len(findings))
+	}
+	if findings[0][6] != "PTR" || findings[1][1] != "10.0.0.5" || findings[1][5] != "2" || findings[1][6] != "db.example" {
+		t.Errorf("Unexpected findings sheet: %q", findings)
+	}
+
+	tests := []struct{ cell, want string }{
+		{"B2", "3"}, // findings
+		{"B3", "2"}, // files
+		{"B4", "2"}, // unique IPs
+		{"B5", "1"}, // unique ports
+		{"A9", "a.properties"},
+		{"B9", "2"},
+		{"A10", "b.yml"},
+	}
+	for _, tt := range tests {
+		if got, _ := f.GetCellValue(summarySheet, tt.cell); got != tt.want {
+			t.Errorf("Summary %s = %q, want %q", tt.cell, got, tt.want)
+		}
+	}
+}
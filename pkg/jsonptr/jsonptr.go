@@ -0,0 +1,223 @@
+// Package jsonptr locates values in a JSON document by RFC 6901 JSON
+// pointer and reports their byte span, so callers can replace a value in
+// place without re-encoding (and reformatting) the whole document.
+package jsonptr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is returned when the pointer does not resolve.
+var ErrNotFound = errors.New("no value at pointer")
+
+// Span is the byte range [Start, End) of a value within a document.
+type Span struct {
+	Start, End int
+}
+
+// Find returns the span of the value addressed by pointer ("" is the whole
+// document, "/a/0/b~1c" addresses key "b/c" of the first element of "a").
+func Find(data []byte, pointer string) (Span, error) {
+	tokens, err := Parse(pointer)
+	if err != nil {
+		return Span{}, err
+	}
+	if !json.Valid(data) {
+		return Span{}, errors.New("invalid JSON document")
+	}
+	s := &scanner{data: data}
+	s.skipSpace()
+	for _, tok := range tokens {
+		if err := s.descend(tok); err != nil {
+			return Span{}, err
+		}
+	}
+	start := s.pos
+	end, err := s.skipValue()
+	if err != nil {
+		return Span{}, err
+	}
+	return Span{start, end}, nil
+}
+
+// Parse splits a JSON pointer into unescaped reference tokens.
+func Parse(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must start with /", pointer)
+	}
+	parts := strings.Split(pointer[1:], "/")
+	for i, p := range parts {
+		parts[i] = strings.ReplaceAll(strings.ReplaceAll(p, "~1", "/"), "~0", "~")
+	}
+	return parts, nil
+}
+
+// scanner walks a document already checked by json.Valid, so it only has
+// to find boundaries, not report syntax errors.
+type scanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *scanner) skipSpace() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+// descend moves from the container at pos to the start of its member tok.
+func (s *scanner) descend(tok string) error {
+	switch s.data[s.pos] {
+	case '{':
+		s.pos++
+		for {
+			s.skipSpace()
+			if s.data[s.pos] == '}' {
+				return fmt.Errorf("%w: key %q", ErrNotFound, tok)
+			}
+			keyStart := s.pos
+			keyEnd, _ := s.skipValue()
+			var key string
+			_ = json.Unmarshal(s.data[keyStart:keyEnd], &key)
+			s.skipSpace()
+			s.pos++ // ':'
+			s.skipSpace()
+			if key == tok {
+				return nil
+			}
+			if _, err := s.skipValue(); err != nil {
+				return err
+			}
+			s.skipSpace()
+			if s.data[s.pos] == ',' {
+				s.pos++
+			}
+		}
+	case '[':
+		idx, err := strconv.Atoi(tok)
+		if err != nil || idx < 0 || (len(tok) > 1 && tok[0] == '0') {
+			return fmt.Errorf("%w: %q is not an array index", ErrNotFound, tok)
+		}
+		s.pos++
+		for i := 0; ; i++ {
+			s.skipSpace()
+			if s.data[s.pos] == ']' {
+				return fmt.Errorf("%w: index %d out of range", ErrNotFound, idx)
+			}
+			if i == idx {
+				return nil
+			}
+			if _, err := s.skipValue(); err != nil {
+				return err
+			}
+			s.skipSpace()
+			if s.data[s.pos] == ',' {
+				s.pos++
+			}
+		}
+	}
+	return fmt.Errorf("%w: %q is inside a scalar", ErrNotFound, tok)
+}
+
+// skipValue moves past the value at pos and returns its end offset.
+func (s *scanner) skipValue() (int, error) {
+	if s.pos >= len(s.data) {
+		return 0, errors.New("unexpected end of JSON")
+	}
+	switch c := s.data[s.pos]; c {
+	case '"':
+		s.pos++
+		for s.data[s.pos] != '"' {
+			if s.data[s.pos] == '\\' {
+				s.pos++
+			}
+			s.pos++
+		}
+		s.pos++
+	case '{', '[':
+		depth := 0
+		for {
+			switch s.data[s.pos] {
+			case '"':
+				if _, err := s.skipValue(); err != nil {
+					return 0, err
+				}
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+			s.pos++
+			if depth == 0 {
+				break
+			}
+		}
+	default:
+		for s.pos < len(s.data) && !strings.ContainsRune(" \t\r\n,}]", rune(s.data[s.pos])) {
+			s.pos++
+		}
+	}
+	return s.pos, nil
+}
+
+// Leaves returns the pointers of every scalar value in the document, in
+// document order.
+func Leaves(data []byte) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out []string
+	if err := walk(dec, "", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func walk(dec *json.Decoder, ptr string, out *[]string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := walk(dec, ptr+"/"+Escape(key.(string)), out); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			if err := walk(dec, ptr+"/"+strconv.Itoa(i), out); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	}
+	*out = append(*out, ptr)
+	return nil
+}
+
+// Escape encodes a key as a pointer reference token.
+func Escape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,82 @@
+package jsonptr
+
+import (
+	"errors"
+	"testing"
+)
+
+const doc = `{
+  "Logging": { "Level": "Info" },
+  "Features": {
+    "Billing": true,
+    "a/b": 1,
+    "m~n": "on",
+    "quote\"d": false
+  },
+  "Adapters": [ {"Name": "x", "Enabled": false}, [1, {"z": null}] ],
+  "Empty": {}
+}`
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		pointer, want string
+	}{
+		{"/Features/Billing", "true"},
+		{"/Features/a~1b", "1"},
+		{"/Features/m~0n", `"on"`},
+		{`/Features/quote"d`, "false"},
+		{"/Logging", `{ "Level": "Info" }`},
+		{"/Adapters/0/Enabled", "false"},
+		{"/Adapters/1/1/z", "null"},
+		{"/Empty", "{}"},
+	}
+	for _, tt := range tests {
+		span, err := Find([]byte(doc), tt.pointer)
+		if err != nil {
+			t.Errorf("Find(%q) failed: %v", tt.pointer, err)
+			continue
+		}
+		if got := doc[span.Start:span.End]; got != tt.want {
+			t.Errorf("Find(%q) = %q, want %q", tt.pointer, got, tt.want)
+		}
+	}
+
+	if span, err := Find([]byte(doc), ""); err != nil || span.Start != 0 || span.End != len(doc) {
+		t.Errorf("Find(\"\") = %v, %v", span, err)
+	}
+
+	for _, missing := range []string{"/Nope", "/Features/Billing/x", "/Adapters/2", "/Adapters/01", "/Adapters/x", "/Empty/a"} {
+		if _, err := Find([]byte(doc), missing); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Find(%q) = %v, want ErrNotFound", missing, err)
+		}
+	}
+	if _, err := Find([]byte(doc), "Features"); err == nil {
+		t.Error("Expected error for pointer without leading slash")
+	}
+	if _, err := Find([]byte(`{"a":`), "/a"); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+}
+
+func TestLeaves(t *testing.T) {
+	got, err := Leaves([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/Logging/Level", "/Features/Billing", "/Features/a~1b", "/Features/m~0n", `/Features/quote"d`,
+		"/Adapters/0/Name", "/Adapters/0/Enabled", "/Adapters/1/0", "/Adapters/1/1/z"}
+	if len(got) != len(want) {
+		t.Fatalf("Leaves = %q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Leaves[%d] = %q, want %q", i, got[i], want[i])
+		}
+		if _, err := Find([]byte(doc), got[i]); err != nil {
+			t.Errorf("Find(%q): %v", got[i], err)
+		}
+	}
+	if _, err := Leaves([]byte(`{"a":`)); err == nil {
+		t.Error("Expected error for truncated JSON")
+	}
+}
@@ -0,0 +1,272 @@
+// Package props parses Java .properties files as specified by
+// java.util.Properties#load: line continuations, `=`, `:` and whitespace
+// separators, `#`/`!` comments and backslash escapes including \uXXXX.
+package props
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// Entry is a single key/value pair. Line and EndLine are 1-based physical
+// line numbers; they differ when the entry uses line continuations.
+type Entry struct {
+	Key     string
+	Value   string
+	Line    int
+	EndLine int
+}
+
+// Parse returns the entries of a properties document in file order. The
+// format has no syntax errors: malformed escapes are kept literally.
+func Parse(data []byte) []Entry {
+	phys := splitLines(string(data))
+	var entries []Entry
+	for i := 0; i < len(phys); i++ {
+		seg := trimLeftSpace(phys[i])
+		if seg == "" || seg[0] == '#' || seg[0] == '!' {
+			continue
+		}
+
+		start := i
+		var logical strings.Builder
+		for {
+			cont := continues(seg)
+			if cont {
+				seg = seg[:len(seg)-1]
+			}
+			logical.WriteString(seg)
+			if !cont || i+1 >= len(phys) {
+				break
+			}
+			i++
+			seg = trimLeftSpace(phys[i])
+		}
+
+		key, value := splitKeyValue(logical.String())
+		entries = append(entries, Entry{Key: unescape(key), Value: unescape(value), Line: start + 1, EndLine: i + 1})
+	}
+	return entries
+}
+
+// ParseReader reads r fully and parses it.
+func ParseReader(r io.Reader) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data), nil
+}
+
+// SplitLines returns the physical lines of data as numbered by Parse,
+// without line terminators.
+func SplitLines(data []byte) []string {
+	return splitLines(string(data))
+}
+
+// SplitLinesEOL returns the physical lines of data as numbered by Parse,
+// each with its line terminator, so joining them gives data back.
+func SplitLinesEOL(data []byte) []string {
+	s := string(data)
+	var lines []string
+	for s != "" {
+		i := strings.IndexAny(s, "\r\n")
+		if i < 0 {
+			lines = append(lines, s)
+			break
+		}
+		n := i + 1
+		if s[i] == '\r' && n < len(s) && s[n] == '\n' {
+			n++
+		}
+		lines = append(lines, s[:n])
+		s = s[n:]
+	}
+	return lines
+}
+
+// splitLines splits on \n, \r\n and \r without keeping terminators.
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	lines := strings.Split(s, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\f'
+}
+
+func trimLeftSpace(s string) string {
+	i := 0
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+	return s[i:]
+}
+
+// continues reports whether a physical line ends in an odd number of
+// backslashes, i.e. an unescaped line continuation.
+func continues(s string) bool {
+	n := 0
+	for i := len(s) - 1; i >= 0 && s[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitKeyValue splits a logical line at the first unescaped `=`, `:` or
+// whitespace. Whitespace around the separator is dropped; trailing
+// whitespace of the value is kept, as in Java.
+func splitKeyValue(line string) (key, value string) {
+	keyEnd, sepSeen := len(line), false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' {
+			i++
+			continue
+		}
+		if c == '=' || c == ':' {
+			keyEnd, sepSeen = i, true
+			break
+		}
+		if isSpace(c) {
+			keyEnd = i
+			break
+		}
+	}
+
+	rest := line[keyEnd:]
+	if sepSeen {
+		rest = rest[1:]
+	} else {
+		rest = trimLeftSpace(rest)
+		if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+			rest = rest[1:]
+		}
+	}
+	return line[:keyEnd], trimLeftSpace(rest)
+}
+
+// ValueStart returns the byte offset in a physical line at which the
+// value of the entry starting on that line begins, after the key, the
+// separator and the whitespace around it. The result is len(line) when
+// the line holds no value text.
+func ValueStart(line string) int {
+	lead := len(line) - len(trimLeftSpace(line))
+	_, value := splitKeyValue(line[lead:])
+	return len(line) - len(value)
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(s) {
+			break // a trailing lone backslash is dropped
+		}
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			r, ok := hex4(s, i+1)
+			if !ok {
+				b.WriteByte('u')
+				continue
+			}
+			i += 4
+			// Combine UTF-16 surrogate pairs written as two escapes.
+			if utf16.IsSurrogate(r) && i+2 < len(s) && s[i+1] == '\\' && s[i+2] == 'u' {
+				if r2, ok := hex4(s, i+3); ok {
+					if c := utf16.DecodeRune(r, r2); c != unicode.ReplacementChar {
+						r = c
+						i += 6
+					}
+				}
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func hex4(s string, at int) (rune, bool) {
+	if at+4 > len(s) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s[at:at+4], 16, 16)
+	if err != nil {
+		return 0, false
+	}
+	return rune(n), true
+}
+
+// EscapeKey escapes a key so that Parse returns it unchanged.
+func EscapeKey(s string) string {
+	return escape(s, true)
+}
+
+// EscapeValue escapes a value so that Parse returns it unchanged.
+func EscapeValue(s string) string {
+	return escape(s, false)
+}
+
+func escape(s string, isKey bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\f':
+			b.WriteString(`\f`)
+		case ' ':
+			if isKey || i == 0 {
+				b.WriteString(`\ `)
+			} else {
+				b.WriteByte(c)
+			}
+		case '=', ':':
+			if isKey {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(c)
+		case '#', '!':
+			if i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
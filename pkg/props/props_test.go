@@ -0,0 +1,160 @@
+package props
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Entry
+	}{
+		{"equals", "key=value\n", []Entry{{"key", "value", 1, 1}}},
+		{"colon", "key:value", []Entry{{"key", "value", 1, 1}}},
+		{"whitespace separator", "key value", []Entry{{"key", "value", 1, 1}}},
+		{"spaced separator", "  key  =  value  \n", []Entry{{"key", "value  ", 1, 1}}},
+		{"whitespace then colon", "key : value", []Entry{{"key", "value", 1, 1}}},
+		{"key only", "flag\n", []Entry{{"flag", "", 1, 1}}},
+		{"empty value", "key=\n", []Entry{{"key", "", 1, 1}}},
+		{"second separator is value", "key==value", []Entry{{"key", "=value", 1, 1}}},
+		{"comments and blanks", "# comment\n! bang\n\n   \nkey=v\n", []Entry{{"key", "v", 5, 5}}},
+		{"escaped separators in key", `a\=b\:c\ d=e`, []Entry{{"a=b:c d", "e", 1, 1}}},
+		{"escapes", `k=tab\there\nnl\\slash\q`, []Entry{{"k", "tab\there\nnl\\slashq", 1, 1}}},
+		{"unicode escape", `greeting=caf\u00e9`, []Entry{{"greeting", "café", 1, 1}}},
+		{"surrogate pair", `emoji=\ud83d\ude80`, []Entry{{"emoji", "🚀", 1, 1}}},
+		{"malformed unicode", `k=\u12`, []Entry{{"k", "u12", 1, 1}}},
+		{
+			"continuation",
+			"servers=10.0.0.1,\\\n    10.0.0.2,\\\n    10.0.0.3\nnext=1\n",
+			[]Entry{{"servers", "10.0.0.1,10.0.0.2,10.0.0.3", 1, 3}, {"next", "1", 4, 4}},
+		},
+		{"escaped backslash is not continuation", "path=c:\\\\\nnext=1", []Entry{{"path", "c:\\", 1, 1}, {"next", "1", 2, 2}}},
+		{"continuation at EOF", "k=v\\", []Entry{{"k", "v", 1, 1}}},
+		{"continued comment chars", "k=a\\\n# not a comment\n", []Entry{{"k", "a# not a comment", 1, 2}}},
+		{"comment does not continue", "# c \\\nk=v\n", []Entry{{"k", "v", 2, 2}}},
+		{"crlf", "a=1\r\nb=2\r\n", []Entry{{"a", "1", 1, 1}, {"b", "2", 2, 2}}},
+		{"cr only", "a=1\rb=2", []Entry{{"a", "1", 1, 1}, {"b", "2", 2, 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse([]byte(tt.input))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) =\n%+v\nwant\n%+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReader(t *testing.T) {
+	got, err := ParseReader(strings.NewReader("a=1\nb:2\n"))
+	if err != nil {
+		t.Fatalf("ParseReader failed: %v", err)
+	}
+	if len(got) != 2 || got[1].Key != "b" || got[1].Value != "2" {
+		t.Errorf("Unexpected entries: %+v", got)
+	}
+}
+
+func TestEscapeRoundTrip(t *testing.T) {
+	tests := []struct{ key, value string }{
+		{"simple", "value"},
+		{"with space", " leading and trailing "},
+		{"a=b:c", "x=y:z"},
+		{"#hash", "!bang"},
+		{"tab\tkey", "line\nbreak\r\f"},
+		{`back\slash`, `c:\dir\`},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			line := EscapeKey(tt.key) + "=" + EscapeValue(tt.value)
+			got := Parse([]byte(line))
+			if len(got) != 1 || got[0].Key != tt.key || got[0].Value != tt.value {
+				t.Errorf("round trip of (%q, %q) via %q = %+v", tt.key, tt.value, line, got)
+			}
+		})
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	seeds := []string{
+		"key=value\n",
+		"a b\\\n  c\n",
+		"# comment\n!x\nk:v\r\nz=\\u0041\\ud83d\\ude80\n",
+		"\\\\\\\n=\n",
+		"\t\f key \t= value\\",
+		"a=1\rb=0\r\n\rc\\\r d",
+	}
+	for _, s := range seeds {
+		f.Add([]byte(s))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		entries := Parse(data)
+		lines := len(splitLines(string(data)))
+		if withEOL := SplitLinesEOL(data); len(withEOL) != lines || strings.Join(withEOL, "") != string(data) {
+			t.Fatalf("SplitLinesEOL gave %d lines (want %d) that join to %q", len(withEOL), lines, strings.Join(withEOL, ""))
+		}
+
+		prevEnd := 0
+		var rebuilt strings.Builder
+		for _, e := range entries {
+			if e.Line <= prevEnd || e.EndLine < e.Line || e.EndLine > lines {
+				t.Fatalf("invalid line span %d-%d after %d (of %d lines)", e.Line, e.EndLine, prevEnd, lines)
+			}
+			prevEnd = e.EndLine
+			rebuilt.WriteString(EscapeKey(e.Key) + "=" + EscapeValue(e.Value) + "\n")
+		}
+
+		// Re-serialising parsed entries must parse back to the same pairs.
+		again := Parse([]byte(rebuilt.String()))
+		if len(again) != len(entries) {
+			t.Fatalf("round trip changed entry count: %d != %d", len(again), len(entries))
+		}
+		for i := range entries {
+			if again[i].Key != entries[i].Key || again[i].Value != entries[i].Value {
+				t.Fatalf("round trip mismatch at %d: (%q, %q) != (%q, %q)",
+					i, again[i].Key, again[i].Value, entries[i].Key, entries[i].Value)
+			}
+		}
+	})
+}
+
+func FuzzEscape(f *testing.F) {
+	f.Add("key", "value")
+	f.Add(" k=:#!", "\\ v\n")
+	f.Fuzz(func(t *testing.T, key, value string) {
+		if !utf8.ValidString(key) || !utf8.ValidString(value) {
+			t.Skip()
+		}
+		got := Parse([]byte(EscapeKey(key) + "=" + EscapeValue(value)))
+		if len(got) != 1 || got[0].Key != key || got[0].Value != value {
+			t.Fatalf("escape round trip of (%q, %q) = %+v", key, value, got)
+		}
+	})
+}
+
+func TestValueStart(t *testing.T) {
+	tests := []struct {
+		line, value string
+	}{
+		{"key=value", "value"},
+		{"  key = value  ", "value  "},
+		{"key:value", "value"},
+		{"key value", "value"},
+		{`a\=b = c`, "c"},
+		{"key = \\", "\\"},
+		{"key", ""},
+		{"key =   ", ""},
+	}
+	for _, tt := range tests {
+		if got := tt.line[ValueStart(tt.line):]; got != tt.value {
+			t.Errorf("ValueStart(%q) leaves %q, want %q", tt.line, got, tt.value)
+		}
+	}
+}